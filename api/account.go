@@ -5,25 +5,27 @@ import (
 	"github.com/gin-gonic/gin"
 	"github.com/lib/pq"
 	db "github.com/techschool/simplebank/db/sqlc"
-	"github.com/techschool/simplebank/token"
 	"log"
 	"net/http"
 )
 
 type createAccountRequest struct {
-	Owner    string `json:"owner" binding:"required"`
-	Currency string `json:"currency" binding:"required,oneof=USD EUR"`
+	Currency string `json:"currency" binding:"required,currency"`
 }
 
 // createAccount POST创建账户
 func (server *Server) createAccount(ctx *gin.Context) {
 	var req createAccountRequest
-	if err := ctx.ShouldBind(&req); err != nil {
+	if err := ctx.ShouldBindJSON(&req); err != nil {
 		ctx.JSON(http.StatusBadRequest, errorResponse(err))
 		return
 	}
-	// 加入权限验证的部分
-	authPayload := ctx.MustGet(authorizationHeaderKey).(*token.Payload)
+	// 账户的所有者就是当前登录的用户
+	authPayload, ok := authPayloadFromContext(ctx)
+	if !ok {
+		ctx.JSON(http.StatusUnauthorized, errorResponse(errUnauthenticated))
+		return
+	}
 	// 对请求传递过的数据进行进一步加工处理
 	arg := db.CreateAccountParams{
 		Owner: authPayload.Username,
@@ -70,12 +72,17 @@ func (server *Server) getAccount(ctx *gin.Context) {
 		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
 		return
 	}
+
+	// 储户只能查看自己的账户，银行职员可以查看所有客户的账户
+	if !server.authorizeAccount(ctx, account, readAccess) {
+		return
+	}
 	ctx.JSON(http.StatusOK, account)
 }
 
 type listAccountRequest struct {
-	PageID int32 `form:"page_id" binding:"required,min=1"`
-	PageSize int32 `form:"page_size" binding:"required,min=5,max=10"`
+	Owner string `form:"owner" binding:"omitempty,alphanum"`
+	pageRequest
 }
 
 // listAccount 分页查询
@@ -86,9 +93,32 @@ func (server *Server) listAccount(ctx *gin.Context) {
 		return
 	}
 
+	page, valid := server.parsePage(ctx, req.pageRequest)
+	if !valid {
+		return
+	}
+
+	authPayload, ok := authPayloadFromContext(ctx)
+	if !ok {
+		ctx.JSON(http.StatusUnauthorized, errorResponse(errUnauthenticated))
+		return
+	}
+
+	// 默认查询自己的账户，只有银行职员可以查询其他客户的账户
+	owner := authPayload.Username
+	if len(req.Owner) > 0 && req.Owner != owner {
+		if !isStaff(authPayload) {
+			ctx.JSON(http.StatusForbidden, errorResponse(errAccountForbidden))
+			return
+		}
+		owner = req.Owner
+	}
+
 	arg := db.ListAccountsParams{
-		Limit: req.PageSize,
-		Offset: (req.PageID-1) * req.PageSize,
+		Owner: owner,
+		CursorCreatedAt: page.after().CreatedAt,
+		CursorID: page.after().ID,
+		PageSize: page.limit(),
 	}
 
 	accounts, err := server.store.ListAccounts(ctx, arg)
@@ -96,7 +126,11 @@ func (server *Server) listAccount(ctx *gin.Context) {
 		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
 		return
 	}
-	ctx.JSON(http.StatusOK, accounts)
+
+	n, next := page.next(len(accounts), func(i int) pageCursor {
+		return pageCursor{CreatedAt: accounts[i].CreatedAt, ID: accounts[i].ID}
+	})
+	ctx.JSON(http.StatusOK, pageResponse{Items: accounts[:n], NextCursor: next})
 }
 
 
@@ -109,3 +143,52 @@ func (server *Server) listAccount(ctx *gin.Context) {
 
 
 
+
+type accountBalanceResponse struct {
+	AccountID			int64	`json:"account_id"`
+	Currency			string	`json:"currency"`
+	Balance				int64	`json:"balance"`
+	HeldAmount			int64	`json:"held_amount"`
+	AvailableBalance	int64	`json:"available_balance"`
+}
+
+// getAccountBalance 查询账户的可用余额，即余额减去有效的预授权冻结的金额，指定 at 时查询该时刻的余额
+func (server *Server) getAccountBalance(ctx *gin.Context) {
+	var req getAccountRequest
+	if err := ctx.ShouldBindUri(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	account, valid := server.fetchAccount(ctx, req.ID)
+	if !valid {
+		return
+	}
+	if !server.authorizeAccount(ctx, account, readAccess) {
+		return
+	}
+
+	var balanceReq balanceAtRequest
+	if err := ctx.ShouldBindQuery(&balanceReq); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+	// 指定了 at 时查询历史余额
+	if !balanceReq.At.IsZero() {
+		server.getBalanceAt(ctx, account, balanceReq.At)
+		return
+	}
+
+	held, err := server.store.GetActiveHoldsAmount(ctx, account.ID)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+	ctx.JSON(http.StatusOK, accountBalanceResponse{
+		AccountID:			account.ID,
+		Currency:			account.Currency,
+		Balance:			account.Balance,
+		HeldAmount:			held,
+		AvailableBalance:	account.Balance - held,
+	})
+}
@@ -0,0 +1,74 @@
+package api
+
+import (
+	"errors"
+	"github.com/gin-gonic/gin"
+	db "github.com/techschool/simplebank/db/sqlc"
+	"github.com/techschool/simplebank/token"
+	"github.com/techschool/simplebank/util"
+	"net/http"
+)
+
+// accountAccess 表示当前用户对账户的操作类型
+type accountAccess int
+
+const (
+	// readAccess 查看账户，账户所有者、银行职员和管理员可以访问
+	readAccess accountAccess = iota
+	// writeAccess 从账户转出资金，只有账户所有者可以访问
+	writeAccess
+)
+
+var (
+	errUnauthenticated  = errors.New("authorization payload is not provided")
+	errAccountForbidden = errors.New("account doesn't belong to the authenticated user")
+)
+
+// authPayloadFromContext 取出 authMiddleware 写入的 token payload
+func authPayloadFromContext(ctx *gin.Context) (*token.Payload, bool) {
+	value, ok := ctx.Get(authorizationHeaderKey)
+	if !ok {
+		return nil, false
+	}
+	payload, ok := value.(*token.Payload)
+	return payload, ok
+}
+
+// isStaff 银行职员和管理员可以查看所有客户的数据
+func isStaff(payload *token.Payload) bool {
+	return payload.Role == util.BankerRole || payload.Role == util.AdminRole
+}
+
+// canAccessOwner 判断 payload 对应的用户能否以 access 的方式访问 owner 的数据
+func canAccessOwner(payload *token.Payload, owner string, access accountAccess) bool {
+	if owner == payload.Username {
+		return true
+	}
+	return access == readAccess && isStaff(payload)
+}
+
+// canAccessAccount 判断 payload 对应的用户能否以 access 的方式访问账户
+func canAccessAccount(payload *token.Payload, account db.Account, access accountAccess) bool {
+	return canAccessOwner(payload, account.Owner, access)
+}
+
+// authorizeAccount 对账户进行权限检查，未登录返回 401，没有权限返回 403。
+// 检查不通过时已经写入了响应，调用者直接返回即可。
+func (server *Server) authorizeAccount(ctx *gin.Context, account db.Account, access accountAccess) bool {
+	return server.authorizeOwner(ctx, account.Owner, access, errAccountForbidden)
+}
+
+// authorizeOwner 对属于 owner 的数据进行权限检查，没有权限时返回 forbiddenErr
+func (server *Server) authorizeOwner(ctx *gin.Context, owner string, access accountAccess, forbiddenErr error) bool {
+	authPayload, ok := authPayloadFromContext(ctx)
+	if !ok {
+		ctx.JSON(http.StatusUnauthorized, errorResponse(errUnauthenticated))
+		return false
+	}
+
+	if !canAccessOwner(authPayload, owner, access) {
+		ctx.JSON(http.StatusForbidden, errorResponse(forbiddenErr))
+		return false
+	}
+	return true
+}
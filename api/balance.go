@@ -0,0 +1,108 @@
+package api
+
+import (
+	"errors"
+	"github.com/gin-gonic/gin"
+	db "github.com/techschool/simplebank/db/sqlc"
+	"net/http"
+	"time"
+)
+
+// maxBalanceHistoryDays 每日余额查询的最大天数
+const maxBalanceHistoryDays = 366
+
+// dateLayout 每日余额的日期格式，按 UTC 自然日计算
+const dateLayout = "2006-01-02"
+
+var errBalanceHistoryRange = errors.New("the date range of balance history cannot exceed 366 days")
+
+type balanceAtRequest struct {
+	At	time.Time	`form:"at"`
+}
+
+type balanceAtResponse struct {
+	AccountID	int64		`json:"account_id"`
+	Currency	string		`json:"currency"`
+	At			time.Time	`json:"at"`
+	Balance		int64		`json:"balance"`
+}
+
+// getBalanceAt 查询账户在某个时刻的余额，包含该时刻创建的流水
+func (server *Server) getBalanceAt(ctx *gin.Context, account db.Account, at time.Time) {
+	balance, err := server.store.GetBalanceAt(ctx, db.GetBalanceAtParams{
+		AccountID:	account.ID,
+		At:			at,
+	})
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+	ctx.JSON(http.StatusOK, balanceAtResponse{
+		AccountID:	account.ID,
+		Currency:	account.Currency,
+		At:			at,
+		Balance:	balance,
+	})
+}
+
+type dailyBalanceRequest struct {
+	From	time.Time	`form:"from" time_format:"2006-01-02" time_utc:"1" binding:"required"`
+	To		time.Time	`form:"to" time_format:"2006-01-02" time_utc:"1" binding:"required,gtefield=From"`
+}
+
+type dailyBalance struct {
+	Date	string	`json:"date"`
+	Balance	int64	`json:"balance"`
+}
+
+type dailyBalanceResponse struct {
+	AccountID	int64			`json:"account_id"`
+	Currency	string			`json:"currency"`
+	Balances	[]dailyBalance	`json:"balances"`
+}
+
+// listDailyBalances 查询账户在日期范围内每天结束时的余额，用于绘制余额曲线
+func (server *Server) listDailyBalances(ctx *gin.Context) {
+	var uri getAccountRequest
+	if err := ctx.ShouldBindUri(&uri); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+	var req dailyBalanceRequest
+	if err := ctx.ShouldBindQuery(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+	if req.To.Sub(req.From) >= maxBalanceHistoryDays*24*time.Hour {
+		ctx.JSON(http.StatusBadRequest, errorResponse(errBalanceHistoryRange))
+		return
+	}
+
+	account, valid := server.fetchAccount(ctx, uri.ID)
+	if !valid {
+		return
+	}
+	if !server.authorizeAccount(ctx, account, readAccess) {
+		return
+	}
+
+	balances, err := server.store.ListDailyBalances(ctx, db.ListDailyBalancesParams{
+		AccountID:	account.ID,
+		From:		req.From,
+		To:			req.To,
+	})
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	rsp := dailyBalanceResponse{
+		AccountID:	account.ID,
+		Currency:	account.Currency,
+		Balances:	make([]dailyBalance, len(balances)),
+	}
+	for i, balance := range balances {
+		rsp.Balances[i] = dailyBalance{Date: balance.Date.Format(dateLayout), Balance: balance.Balance}
+	}
+	ctx.JSON(http.StatusOK, rsp)
+}
@@ -0,0 +1,198 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/require"
+	mockdb "github.com/techschool/simplebank/db/mock"
+	db "github.com/techschool/simplebank/db/sqlc"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestGetBalanceAtAPI(t *testing.T) {
+	user, _ := randomUser(t)
+	account := randomAccount(user.Username)
+	other, _ := randomUser(t)
+	at := time.Date(2021, time.March, 1, 9, 30, 0, 0, time.UTC)
+
+	testCases := []struct{
+		name			string
+		query			string
+		username		string
+		buildStubs		func(store *mockdb.MockStore)
+		checkResponse	func(t *testing.T, recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name: "OK",
+			query: "?at=2021-03-01T09:30:00Z",
+			username: user.Username,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account.ID)).Times(1).Return(account, nil)
+				store.EXPECT().
+					GetBalanceAt(gomock.Any(), gomock.Eq(db.GetBalanceAtParams{AccountID: account.ID, At: at})).
+					Times(1).
+					Return(int64(120), nil)
+				store.EXPECT().GetActiveHoldsAmount(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+
+				var got balanceAtResponse
+				err := json.Unmarshal(recorder.Body.Bytes(), &got)
+				require.NoError(t, err)
+				require.Equal(t, account.ID, got.AccountID)
+				require.Equal(t, int64(120), got.Balance)
+				require.True(t, at.Equal(got.At))
+			},
+		},
+		{
+			name: "InvalidTime",
+			query: "?at=yesterday",
+			username: user.Username,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account.ID)).Times(1).Return(account, nil)
+				store.EXPECT().GetBalanceAt(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+		{
+			name: "Forbidden",
+			query: "?at=2021-03-01T09:30:00Z",
+			username: other.Username,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account.ID)).Times(1).Return(account, nil)
+				store.EXPECT().GetBalanceAt(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusForbidden, recorder.Code)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			buildAuthStubs(store, tc.username)
+			tc.buildStubs(store)
+
+			server := newTestServer(t, store)
+			recorder := httptest.NewRecorder()
+
+			url := fmt.Sprintf("/accounts/%d/balance%s", account.ID, tc.query)
+			request, err := http.NewRequest(http.MethodGet, url, nil)
+			require.NoError(t, err)
+
+			addAuthorization(t, request, server.tokenMaker, authorizationTypeBearer, tc.username, user.Role, time.Minute)
+			server.router.ServeHTTP(recorder, request)
+			tc.checkResponse(t, recorder)
+		})
+	}
+}
+
+func TestListDailyBalancesAPI(t *testing.T) {
+	user, _ := randomUser(t)
+	account := randomAccount(user.Username)
+	from := time.Date(2021, time.March, 1, 0, 0, 0, 0, time.UTC)
+
+	testCases := []struct{
+		name			string
+		query			string
+		buildStubs		func(store *mockdb.MockStore)
+		checkResponse	func(t *testing.T, recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name: "OK",
+			query: "?from=2021-03-01&to=2021-03-02",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account.ID)).Times(1).Return(account, nil)
+				store.EXPECT().
+					ListDailyBalances(gomock.Any(), gomock.Eq(db.ListDailyBalancesParams{
+						AccountID:	account.ID,
+						From:		from,
+						To:			from.AddDate(0, 0, 1),
+					})).
+					Times(1).
+					Return([]db.DailyBalance{
+						{Date: from, Balance: 100},
+						{Date: from.AddDate(0, 0, 1), Balance: 80},
+					}, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+
+				var got dailyBalanceResponse
+				err := json.Unmarshal(recorder.Body.Bytes(), &got)
+				require.NoError(t, err)
+				require.Equal(t, []dailyBalance{
+					{Date: "2021-03-01", Balance: 100},
+					{Date: "2021-03-02", Balance: 80},
+				}, got.Balances)
+			},
+		},
+		{
+			name: "ToBeforeFrom",
+			query: "?from=2021-03-02&to=2021-03-01",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().ListDailyBalances(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+		{
+			name: "RangeTooLong",
+			query: "?from=2020-01-01&to=2021-03-01",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().ListDailyBalances(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+		{
+			name: "MissingFrom",
+			query: "?to=2021-03-01",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().ListDailyBalances(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			buildAuthStubs(store, user.Username)
+			tc.buildStubs(store)
+
+			server := newTestServer(t, store)
+			recorder := httptest.NewRecorder()
+
+			url := fmt.Sprintf("/accounts/%d/balance/daily%s", account.ID, tc.query)
+			request, err := http.NewRequest(http.MethodGet, url, nil)
+			require.NoError(t, err)
+
+			addAuthorization(t, request, server.tokenMaker, authorizationTypeBearer, user.Username, user.Role, time.Minute)
+			server.router.ServeHTTP(recorder, request)
+			tc.checkResponse(t, recorder)
+		})
+	}
+}
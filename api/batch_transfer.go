@@ -0,0 +1,154 @@
+package api
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/gin-gonic/gin"
+	db "github.com/techschool/simplebank/db/sqlc"
+	"github.com/techschool/simplebank/fraud"
+	"net/http"
+)
+
+// 批量转账的模式
+const (
+	batchAllOrNothing	= "all_or_nothing"
+	batchIndependent	= "independent"
+)
+
+type batchTransferItemRequest struct {
+	ToAccountID			int64			`json:"to_account_id" binding:"required,min=1"`
+	Amount				int64			`json:"amount" binding:"required,gt=0"`
+	Memo				string			`json:"memo" binding:"max=255"`
+	ExternalReference	string			`json:"external_reference" binding:"max=128"`
+	Metadata			json.RawMessage	`json:"metadata" binding:"omitempty,metadata"`
+}
+
+type batchTransferRequest struct {
+	FromAccountID	int64						`json:"from_account_id" binding:"required,min=1"`
+	Currency		string						`json:"currency" binding:"required,currency"`
+	// Mode 默认 all_or_nothing，任意一项失败则全部不执行；independent 每一项单独成功或失败
+	Mode			string						`json:"mode" binding:"omitempty,oneof=all_or_nothing independent"`
+	Items			[]batchTransferItemRequest	`json:"items" binding:"required,min=1,max=500,dive"`
+}
+
+// createBatchTransfer 从一个账户向多个收款人转账，所有的转账在同一个事务中执行
+func (server *Server) createBatchTransfer(ctx *gin.Context) {
+	var req batchTransferRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+	allOrNothing := req.Mode == "" || req.Mode == batchAllOrNothing
+
+	fromAccount, valid := server.validAccount(ctx, req.FromAccountID, req.Currency)
+	if !valid {
+		return
+	}
+	if !server.authorizeAccount(ctx, fromAccount, writeAccess) {
+		return
+	}
+	authPayload, _ := authPayloadFromContext(ctx)
+
+	// 每一项单独进行风控检查，被拒绝的项不会提交到数据库
+	items := make([]db.BatchTransferItemResult, len(req.Items))
+	decisions := make([]db.FraudDecision, len(req.Items))
+	var arg db.BatchTransferTxParams
+	var submitted []int
+	blocked := 0
+	for i, item := range req.Items {
+		items[i] = db.BatchTransferItemResult{ToAccountID: item.ToAccountID, Amount: item.Amount}
+
+		transferArg := db.TransferTxParams{
+			FromAccountID:		req.FromAccountID,
+			ToAccountID:		item.ToAccountID,
+			Amount:				item.Amount,
+			RequestedBy:		authPayload.Username,
+			RequireApproval:	server.requireApproval(item.Amount, fromAccount.Currency),
+		}
+		decision, err := server.checkFraud(ctx, transferArg, fromAccount.Currency)
+		if err != nil {
+			ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+			return
+		}
+		decisions[i] = decision
+
+		if decision.Decision == string(fraud.Block) {
+			items[i].Status = db.BatchItemFailed
+			items[i].Error = errTransferBlocked.Error()
+			blocked++
+			continue
+		}
+		arg.Items = append(arg.Items, db.BatchTransferItem{
+			ToAccountID:		item.ToAccountID,
+			Amount:				item.Amount,
+			RequireApproval:	transferArg.RequireApproval || decision.Decision == string(fraud.Review),
+			Memo:				item.Memo,
+			ExternalReference:	item.ExternalReference,
+			Metadata:			item.Metadata,
+		})
+		submitted = append(submitted, i)
+	}
+
+	result := db.BatchTransferTxResult{Items: items, Failed: blocked}
+	if allOrNothing && blocked > 0 {
+		for i := range items {
+			if items[i].Status != db.BatchItemFailed {
+				items[i].Status = db.BatchItemNotApplied
+			}
+		}
+		err := fmt.Errorf("%w: %d items are blocked by fraud screening", db.ErrBatchFailed, blocked)
+		ctx.JSON(http.StatusUnprocessableEntity, batchTransferErrorResponse(err, result))
+		return
+	}
+	if len(submitted) == 0 {
+		result.FromAccount = fromAccount
+		ctx.JSON(http.StatusOK, result)
+		return
+	}
+
+	arg.FromAccountID = req.FromAccountID
+	arg.RequestedBy = authPayload.Username
+	arg.AllOrNothing = allOrNothing
+	txResult, err := server.store.BatchTransferTx(ctx, arg)
+
+	// 按请求中的顺序合并数据库返回的结果
+	result.FromAccount = txResult.FromAccount
+	result.Succeeded = txResult.Succeeded
+	result.Failed += txResult.Failed
+	for j, i := range submitted {
+		if j < len(txResult.Items) {
+			items[i] = txResult.Items[j]
+		}
+	}
+
+	if err != nil {
+		if errors.Is(err, db.ErrBatchFailed) {
+			ctx.JSON(http.StatusUnprocessableEntity, batchTransferErrorResponse(err, result))
+			return
+		}
+		if err == sql.ErrNoRows {
+			ctx.JSON(http.StatusNotFound, errorResponse(err))
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	for i, item := range items {
+		if item.Transfer != nil {
+			server.linkFraudDecision(ctx, decisions[i], *item.Transfer)
+		}
+	}
+	ctx.JSON(http.StatusOK, result)
+}
+
+// batchTransferErrorResponse 批量转账失败时同时返回每一项的结果
+func batchTransferErrorResponse(err error, result db.BatchTransferTxResult) gin.H {
+	return gin.H{
+		"message":	err.Error(),
+		"items":	result.Items,
+		"failed":	result.Failed,
+	}
+}
@@ -0,0 +1,273 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/require"
+	mockdb "github.com/techschool/simplebank/db/mock"
+	db "github.com/techschool/simplebank/db/sqlc"
+	"github.com/techschool/simplebank/fraud"
+	"github.com/techschool/simplebank/util"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestBatchTransferAPI(t *testing.T) {
+	user1, _ := randomUser(t)
+	user2, _ := randomUser(t)
+
+	account1 := randomAccount(user1.Username)
+	account1.Currency = util.USD
+	payee1 := util.RandomInt(1001, 2000)
+	payee2 := util.RandomInt(2001, 3000)
+
+	items := []gin.H{
+		{"to_account_id": payee1, "amount": 10},
+		{"to_account_id": payee2, "amount": 20},
+	}
+
+	testCases := []struct{
+		name			string
+		body			gin.H
+		user			db.User
+		checker			fraud.Checker
+		buildStubs		func(store *mockdb.MockStore)
+		checkResponse	func(t *testing.T, recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name: "AllOrNothing",
+			body: gin.H{"from_account_id": account1.ID, "currency": util.USD, "items": items},
+			user: user1,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account1.ID)).Times(1).Return(account1, nil)
+				store.EXPECT().
+					BatchTransferTx(gomock.Any(), gomock.Any()).
+					Times(1).
+					DoAndReturn(func(_ interface{}, arg db.BatchTransferTxParams) (db.BatchTransferTxResult, error) {
+						require.Equal(t, account1.ID, arg.FromAccountID)
+						require.Equal(t, user1.Username, arg.RequestedBy)
+						require.True(t, arg.AllOrNothing)
+						require.Equal(t, []db.BatchTransferItem{
+							{ToAccountID: payee1, Amount: 10},
+							{ToAccountID: payee2, Amount: 20},
+						}, arg.Items)
+						return db.BatchTransferTxResult{
+							Items: []db.BatchTransferItemResult{
+								{ToAccountID: payee1, Amount: 10, Status: db.BatchItemSucceeded, Transfer: &db.Transfer{ID: 1}},
+								{ToAccountID: payee2, Amount: 20, Status: db.BatchItemSucceeded, Transfer: &db.Transfer{ID: 2}},
+							},
+							Succeeded: 2,
+						}, nil
+					})
+				store.EXPECT().SetFraudDecisionTransfer(gomock.Any(), gomock.Any()).Times(2)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+
+				var result db.BatchTransferTxResult
+				err := json.Unmarshal(recorder.Body.Bytes(), &result)
+				require.NoError(t, err)
+				require.Equal(t, 2, result.Succeeded)
+				require.Len(t, result.Items, 2)
+			},
+		},
+		{
+			name: "AllOrNothingFailed",
+			body: gin.H{"from_account_id": account1.ID, "currency": util.USD, "items": items},
+			user: user1,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account1.ID)).Times(1).Return(account1, nil)
+				store.EXPECT().
+					BatchTransferTx(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.BatchTransferTxResult{
+						Items: []db.BatchTransferItemResult{
+							{ToAccountID: payee1, Amount: 10, Status: db.BatchItemNotApplied},
+							{ToAccountID: payee2, Amount: 20, Status: db.BatchItemFailed, Error: "insufficient funds"},
+						},
+						Failed: 1,
+					}, fmt.Errorf("%w: 1 of 2 items failed", db.ErrBatchFailed))
+				store.EXPECT().SetFraudDecisionTransfer(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnprocessableEntity, recorder.Code)
+
+				var got struct {
+					Items	[]db.BatchTransferItemResult	`json:"items"`
+				}
+				err := json.Unmarshal(recorder.Body.Bytes(), &got)
+				require.NoError(t, err)
+				require.Equal(t, db.BatchItemNotApplied, got.Items[0].Status)
+				require.Equal(t, db.BatchItemFailed, got.Items[1].Status)
+			},
+		},
+		{
+			name: "IndependentWithBlockedItem",
+			body: gin.H{"from_account_id": account1.ID, "currency": util.USD, "mode": "independent", "items": items},
+			user: user1,
+			checker: fraudCheckerFunc(func(ctx context.Context, input fraud.Input) (fraud.Result, error) {
+				if input.Transfer.ToAccountID == payee1 {
+					return fraud.Result{Decision: fraud.Block, Rule: "blocked_payee"}, nil
+				}
+				return fraud.Result{Decision: fraud.Allow}, nil
+			}),
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account1.ID)).Times(1).Return(account1, nil)
+				store.EXPECT().
+					CreateFraudDecision(gomock.Any(), gomock.Any()).
+					Times(2).
+					DoAndReturn(func(_ interface{}, arg db.CreateFraudDecisionParams) (db.FraudDecision, error) {
+						return db.FraudDecision{Decision: arg.Decision}, nil
+					})
+				store.EXPECT().
+					BatchTransferTx(gomock.Any(), gomock.Any()).
+					Times(1).
+					DoAndReturn(func(_ interface{}, arg db.BatchTransferTxParams) (db.BatchTransferTxResult, error) {
+						require.False(t, arg.AllOrNothing)
+						require.Equal(t, []db.BatchTransferItem{{ToAccountID: payee2, Amount: 20}}, arg.Items)
+						return db.BatchTransferTxResult{
+							Items: []db.BatchTransferItemResult{
+								{ToAccountID: payee2, Amount: 20, Status: db.BatchItemSucceeded, Transfer: &db.Transfer{ID: 2}},
+							},
+							Succeeded: 1,
+						}, nil
+					})
+				store.EXPECT().SetFraudDecisionTransfer(gomock.Any(), gomock.Any()).Times(1)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+
+				var result db.BatchTransferTxResult
+				err := json.Unmarshal(recorder.Body.Bytes(), &result)
+				require.NoError(t, err)
+				require.Equal(t, 1, result.Succeeded)
+				require.Equal(t, 1, result.Failed)
+				require.Equal(t, db.BatchItemFailed, result.Items[0].Status)
+				require.Equal(t, db.BatchItemSucceeded, result.Items[1].Status)
+			},
+		},
+		{
+			name: "AllOrNothingBlocked",
+			body: gin.H{"from_account_id": account1.ID, "currency": util.USD, "items": items},
+			user: user1,
+			checker: fraudCheckerFunc(func(ctx context.Context, input fraud.Input) (fraud.Result, error) {
+				return fraud.Result{Decision: fraud.Block}, nil
+			}),
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account1.ID)).Times(1).Return(account1, nil)
+				store.EXPECT().
+					CreateFraudDecision(gomock.Any(), gomock.Any()).
+					Times(2).
+					Return(db.FraudDecision{Decision: string(fraud.Block)}, nil)
+				store.EXPECT().BatchTransferTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnprocessableEntity, recorder.Code)
+			},
+		},
+		{
+			name: "ApprovalRequired",
+			body: gin.H{"from_account_id": account1.ID, "currency": util.USD, "items": []gin.H{
+				{"to_account_id": payee1, "amount": testApprovalThreshold + 1},
+			}},
+			user: user1,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account1.ID)).Times(1).Return(account1, nil)
+				store.EXPECT().
+					BatchTransferTx(gomock.Any(), gomock.Any()).
+					Times(1).
+					DoAndReturn(func(_ interface{}, arg db.BatchTransferTxParams) (db.BatchTransferTxResult, error) {
+						require.True(t, arg.Items[0].RequireApproval)
+						return db.BatchTransferTxResult{Items: make([]db.BatchTransferItemResult, 1)}, nil
+					})
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+			},
+		},
+		{
+			name: "NotOwner",
+			body: gin.H{"from_account_id": account1.ID, "currency": util.USD, "items": items},
+			user: user2,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account1.ID)).Times(1).Return(account1, nil)
+				store.EXPECT().BatchTransferTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusForbidden, recorder.Code)
+			},
+		},
+		{
+			name: "NoItems",
+			body: gin.H{"from_account_id": account1.ID, "currency": util.USD, "items": []gin.H{}},
+			user: user1,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Any()).Times(0)
+				store.EXPECT().BatchTransferTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+		{
+			name: "InvalidItemAmount",
+			body: gin.H{"from_account_id": account1.ID, "currency": util.USD, "items": []gin.H{
+				{"to_account_id": payee1, "amount": -1},
+			}},
+			user: user1,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().BatchTransferTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+		{
+			name: "InvalidMode",
+			body: gin.H{"from_account_id": account1.ID, "currency": util.USD, "mode": "best_effort", "items": items},
+			user: user1,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().BatchTransferTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			buildAuthStubs(store, tc.user.Username)
+			tc.buildStubs(store)
+			buildFraudStubs(store)
+
+			server := newTestServer(t, store)
+			if tc.checker != nil {
+				server.fraudChecker = tc.checker
+			}
+			recorder := httptest.NewRecorder()
+
+			data, err := json.Marshal(tc.body)
+			require.NoError(t, err)
+
+			request, err := http.NewRequest(http.MethodPost, "/transfers/batch", bytes.NewReader(data))
+			require.NoError(t, err)
+
+			addAuthorization(t, request, server.tokenMaker, authorizationTypeBearer, tc.user.Username, tc.user.Role, time.Minute)
+			server.router.ServeHTTP(recorder, request)
+			tc.checkResponse(t, recorder)
+		})
+	}
+}
@@ -0,0 +1,67 @@
+package api
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"github.com/gin-gonic/gin"
+	db "github.com/techschool/simplebank/db/sqlc"
+	"net/http"
+)
+
+type cashRequest struct {
+	AccountID	int64	`json:"account_id" binding:"required,min=1"`
+	Amount		int64	`json:"amount" binding:"required,gt=0"`
+	Currency	string	`json:"currency" binding:"required,currency"`
+	Memo		string	`json:"memo,omitempty" binding:"max=255"`
+}
+
+// createDeposit 柜台存款，资金从该币种的现金账户转入客户账户，银行职员和管理员可用
+func (server *Server) createDeposit(ctx *gin.Context) {
+	server.cashOperation(ctx, server.store.DepositTx)
+}
+
+// createWithdrawal 柜台取款，资金从客户账户转入该币种的现金账户，不能超过可用余额
+func (server *Server) createWithdrawal(ctx *gin.Context) {
+	server.cashOperation(ctx, server.store.WithdrawTx)
+}
+
+// cashOperation 检查请求和账户后执行存款或取款，柜台业务不做风控检查
+func (server *Server) cashOperation(ctx *gin.Context, post func(context.Context, db.CashTxParams) (db.CashTxResult, error)) {
+	var req cashRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	if _, valid := server.validAccount(ctx, req.AccountID, req.Currency); !valid {
+		return
+	}
+
+	authPayload, ok := authPayloadFromContext(ctx)
+	if !ok {
+		ctx.JSON(http.StatusUnauthorized, errorResponse(errUnauthenticated))
+		return
+	}
+
+	result, err := post(ctx, db.CashTxParams{
+		AccountID:	req.AccountID,
+		Amount:		req.Amount,
+		Memo:		req.Memo,
+		CreatedBy:	authPayload.Username,
+	})
+	if err != nil {
+		// 账户或者该币种的现金账户不存在，现金账户的错误是包装过的
+		if errors.Is(err, sql.ErrNoRows) {
+			ctx.JSON(http.StatusNotFound, errorResponse(err))
+			return
+		}
+		if errors.Is(err, db.ErrInsufficientFunds) || errors.Is(err, db.ErrSystemAccount) {
+			ctx.JSON(http.StatusUnprocessableEntity, errorResponse(err))
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+	ctx.JSON(http.StatusOK, result)
+}
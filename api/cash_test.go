@@ -0,0 +1,176 @@
+package api
+
+import (
+	"bytes"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/require"
+	mockdb "github.com/techschool/simplebank/db/mock"
+	db "github.com/techschool/simplebank/db/sqlc"
+	"github.com/techschool/simplebank/util"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestCashAPI(t *testing.T) {
+	banker, _ := randomUser(t)
+	banker.Role = util.BankerRole
+	depositor, _ := randomUser(t)
+
+	account := randomAccount(depositor.Username)
+	account.Currency = util.USD
+	systemAccount := randomAccount(util.SystemUsername)
+	systemAccount.Currency = util.USD
+	systemAccount.SystemCode = util.SystemCash
+
+	testCases := []struct{
+		name			string
+		path			string
+		body			gin.H
+		user			db.User
+		buildStubs		func(store *mockdb.MockStore)
+		checkResponse	func(t *testing.T, recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name: "Deposit",
+			path: "/deposits",
+			body: gin.H{"account_id": account.ID, "amount": 100, "currency": util.USD, "memo": "cash"},
+			user: banker,
+			buildStubs: func(store *mockdb.MockStore) {
+				arg := db.CashTxParams{AccountID: account.ID, Amount: 100, Memo: "cash", CreatedBy: banker.Username}
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account.ID)).Times(1).Return(account, nil)
+				// 柜台业务不做风控检查
+				store.EXPECT().CreateFraudDecision(gomock.Any(), gomock.Any()).Times(0)
+				store.EXPECT().
+					DepositTx(gomock.Any(), gomock.Eq(arg)).
+					Times(1).
+					Return(db.CashTxResult{
+						Journal: db.Journal{ID: 1, Kind: util.JournalDeposit},
+						Account: db.Account{ID: account.ID, Balance: account.Balance + 100},
+					}, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+
+				var got db.CashTxResult
+				err := json.Unmarshal(recorder.Body.Bytes(), &got)
+				require.NoError(t, err)
+				require.Equal(t, util.JournalDeposit, got.Journal.Kind)
+				require.Equal(t, account.Balance+100, got.Account.Balance)
+			},
+		},
+		{
+			name: "WithdrawalInsufficientFunds",
+			path: "/withdrawals",
+			body: gin.H{"account_id": account.ID, "amount": 100, "currency": util.USD},
+			user: banker,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account.ID)).Times(1).Return(account, nil)
+				store.EXPECT().
+					WithdrawTx(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.CashTxResult{}, fmt.Errorf("%w: test", db.ErrInsufficientFunds))
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnprocessableEntity, recorder.Code)
+			},
+		},
+		{
+			name: "NoCashAccount",
+			path: "/deposits",
+			body: gin.H{"account_id": account.ID, "amount": 100, "currency": util.USD},
+			user: banker,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account.ID)).Times(1).Return(account, nil)
+				store.EXPECT().
+					DepositTx(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.CashTxResult{}, fmt.Errorf("no %s system account of %s: %w", util.SystemCash, util.USD, sql.ErrNoRows))
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusNotFound, recorder.Code)
+			},
+		},
+		{
+			name: "SystemAccount",
+			path: "/deposits",
+			body: gin.H{"account_id": systemAccount.ID, "amount": 100, "currency": util.USD},
+			user: banker,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(systemAccount.ID)).Times(1).Return(systemAccount, nil)
+				store.EXPECT().DepositTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnprocessableEntity, recorder.Code)
+			},
+		},
+		{
+			name: "CurrencyMismatch",
+			path: "/withdrawals",
+			body: gin.H{"account_id": account.ID, "amount": 100, "currency": util.EUR},
+			user: banker,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account.ID)).Times(1).Return(account, nil)
+				store.EXPECT().WithdrawTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+		{
+			name: "Forbidden",
+			path: "/deposits",
+			body: gin.H{"account_id": account.ID, "amount": 100, "currency": util.USD},
+			user: depositor,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().DepositTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusForbidden, recorder.Code)
+			},
+		},
+		{
+			name: "InvalidAmount",
+			path: "/deposits",
+			body: gin.H{"account_id": account.ID, "amount": -100, "currency": util.USD},
+			user: banker,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().DepositTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			buildAuthStubs(store, tc.user.Username)
+			tc.buildStubs(store)
+
+			server := newTestServer(t, store)
+			recorder := httptest.NewRecorder()
+
+			data, err := json.Marshal(tc.body)
+			require.NoError(t, err)
+
+			request, err := http.NewRequest(http.MethodPost, tc.path, bytes.NewReader(data))
+			require.NoError(t, err)
+
+			addAuthorization(t, request, server.tokenMaker, authorizationTypeBearer, tc.user.Username, tc.user.Role, time.Minute)
+			server.router.ServeHTTP(recorder, request)
+			tc.checkResponse(t, recorder)
+		})
+	}
+}
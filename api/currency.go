@@ -0,0 +1,12 @@
+package api
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/techschool/simplebank/util"
+	"net/http"
+)
+
+// listCurrencies 返回所有可以开户和转账的币种
+func (server *Server) listCurrencies(ctx *gin.Context) {
+	ctx.JSON(http.StatusOK, util.SupportedCurrencies())
+}
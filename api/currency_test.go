@@ -0,0 +1,26 @@
+package api
+
+import (
+	"encoding/json"
+	"github.com/stretchr/testify/require"
+	"github.com/techschool/simplebank/util"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestListCurrenciesAPI(t *testing.T) {
+	server := newTestServer(t, nil)
+	recorder := httptest.NewRecorder()
+
+	request, err := http.NewRequest(http.MethodGet, "/currencies", nil)
+	require.NoError(t, err)
+
+	server.router.ServeHTTP(recorder, request)
+	require.Equal(t, http.StatusOK, recorder.Code)
+
+	var currencies []util.Currency
+	err = json.Unmarshal(recorder.Body.Bytes(), &currencies)
+	require.NoError(t, err)
+	require.Equal(t, util.SupportedCurrencies(), currencies)
+}
@@ -0,0 +1,33 @@
+package api
+
+import (
+	"database/sql"
+	"github.com/gin-gonic/gin"
+	db "github.com/techschool/simplebank/db/sqlc"
+	"net/http"
+)
+
+type verifyEntryChainRequest struct {
+	// AccountID 只校验该账户，默认校验所有的账户
+	AccountID	int64	`form:"account_id" binding:"omitempty,min=1"`
+}
+
+// verifyEntryChain 校验流水的哈希链，返回第一处断开的位置，只有管理员可用
+func (server *Server) verifyEntryChain(ctx *gin.Context) {
+	var req verifyEntryChainRequest
+	if err := ctx.ShouldBindQuery(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	result, err := server.store.VerifyEntryChain(ctx, db.VerifyEntryChainParams{AccountID: req.AccountID})
+	if err != nil {
+		if err == sql.ErrNoRows {
+			ctx.JSON(http.StatusNotFound, errorResponse(err))
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+	ctx.JSON(http.StatusOK, result)
+}
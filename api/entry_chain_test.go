@@ -0,0 +1,132 @@
+package api
+
+import (
+	"database/sql"
+	"encoding/json"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/require"
+	mockdb "github.com/techschool/simplebank/db/mock"
+	db "github.com/techschool/simplebank/db/sqlc"
+	"github.com/techschool/simplebank/util"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestVerifyEntryChainAPI(t *testing.T) {
+	admin, _ := randomUser(t)
+	admin.Role = util.AdminRole
+	banker, _ := randomUser(t)
+	banker.Role = util.BankerRole
+
+	testCases := []struct{
+		name			string
+		query			string
+		user			db.User
+		buildStubs		func(store *mockdb.MockStore)
+		checkResponse	func(t *testing.T, recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name: "Intact",
+			user: admin,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					VerifyEntryChain(gomock.Any(), gomock.Eq(db.VerifyEntryChainParams{})).
+					Times(1).
+					Return(db.VerifyEntryChainResult{AccountsChecked: 2, EntriesChecked: 10}, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+
+				var got db.VerifyEntryChainResult
+				err := json.Unmarshal(recorder.Body.Bytes(), &got)
+				require.NoError(t, err)
+				require.Equal(t, int64(10), got.EntriesChecked)
+				require.Nil(t, got.Break)
+			},
+		},
+		{
+			name: "Broken",
+			query: "?account_id=7",
+			user: admin,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					VerifyEntryChain(gomock.Any(), gomock.Eq(db.VerifyEntryChainParams{AccountID: 7})).
+					Times(1).
+					Return(db.VerifyEntryChainResult{
+						AccountsChecked:	1,
+						EntriesChecked:		3,
+						Break:				&db.EntryChainBreak{AccountID: 7, EntryID: 42, Reason: "hash mismatch"},
+					}, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+
+				var got db.VerifyEntryChainResult
+				err := json.Unmarshal(recorder.Body.Bytes(), &got)
+				require.NoError(t, err)
+				require.NotNil(t, got.Break)
+				require.Equal(t, int64(42), got.Break.EntryID)
+			},
+		},
+		{
+			name: "AccountNotFound",
+			query: "?account_id=7",
+			user: admin,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					VerifyEntryChain(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.VerifyEntryChainResult{}, sql.ErrNoRows)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusNotFound, recorder.Code)
+			},
+		},
+		{
+			name: "InvalidAccountID",
+			query: "?account_id=-1",
+			user: admin,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().VerifyEntryChain(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+		{
+			name: "Forbidden",
+			user: banker,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().VerifyEntryChain(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusForbidden, recorder.Code)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			buildAuthStubs(store, tc.user.Username)
+			tc.buildStubs(store)
+
+			server := newTestServer(t, store)
+			recorder := httptest.NewRecorder()
+
+			request, err := http.NewRequest(http.MethodGet, "/entries/verify"+tc.query, nil)
+			require.NoError(t, err)
+
+			addAuthorization(t, request, server.tokenMaker, authorizationTypeBearer, tc.user.Username, tc.user.Role, time.Minute)
+			server.router.ServeHTTP(recorder, request)
+			tc.checkResponse(t, recorder)
+		})
+	}
+}
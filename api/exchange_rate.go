@@ -0,0 +1,74 @@
+package api
+
+import (
+	"github.com/gin-gonic/gin"
+	db "github.com/techschool/simplebank/db/sqlc"
+	"net/http"
+)
+
+type createExchangeRateRequest struct {
+	BaseCurrency	string	`json:"base_currency" binding:"required,currency"`
+	QuoteCurrency	string	`json:"quote_currency" binding:"required,currency,nefield=BaseCurrency"`
+	// Rate 1 个单位的 base currency 可以兑换的 quote currency，使用字符串避免浮点数误差
+	Rate			string	`json:"rate" binding:"required,exchange_rate"`
+}
+
+// createExchangeRate 录入新的汇率，仅管理员可用，之后的跨币种转账使用最新的汇率
+func (server *Server) createExchangeRate(ctx *gin.Context) {
+	var req createExchangeRateRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	authPayload, ok := authPayloadFromContext(ctx)
+	if !ok {
+		ctx.JSON(http.StatusUnauthorized, errorResponse(errUnauthenticated))
+		return
+	}
+
+	exchangeRate, err := server.store.CreateExchangeRate(ctx, db.CreateExchangeRateParams{
+		BaseCurrency:	req.BaseCurrency,
+		QuoteCurrency:	req.QuoteCurrency,
+		Rate:			req.Rate,
+		CreatedBy:		authPayload.Username,
+	})
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+	ctx.JSON(http.StatusOK, exchangeRate)
+}
+
+type listExchangeRatesRequest struct {
+	pageRequest
+}
+
+// listExchangeRates 分页查询汇率，最新录入的在前
+func (server *Server) listExchangeRates(ctx *gin.Context) {
+	var req listExchangeRatesRequest
+	if err := ctx.ShouldBindQuery(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	page, valid := server.parsePage(ctx, req.pageRequest)
+	if !valid {
+		return
+	}
+
+	exchangeRates, err := server.store.ListExchangeRates(ctx, db.ListExchangeRatesParams{
+		CursorCreatedAt:	page.before().CreatedAt,
+		CursorID:			page.before().ID,
+		PageSize:			page.limit(),
+	})
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	n, next := page.next(len(exchangeRates), func(i int) pageCursor {
+		return pageCursor{CreatedAt: exchangeRates[i].CreatedAt, ID: exchangeRates[i].ID}
+	})
+	ctx.JSON(http.StatusOK, pageResponse{Items: exchangeRates[:n], NextCursor: next})
+}
@@ -0,0 +1,191 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/require"
+	mockdb "github.com/techschool/simplebank/db/mock"
+	db "github.com/techschool/simplebank/db/sqlc"
+	"github.com/techschool/simplebank/util"
+	"math"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestCreateExchangeRateAPI(t *testing.T) {
+	admin, _ := randomUser(t)
+	admin.Role = util.AdminRole
+	depositor, _ := randomUser(t)
+
+	exchangeRate := randomExchangeRate(util.USD, util.EUR)
+	exchangeRate.CreatedBy = admin.Username
+
+	testCases := []struct{
+		name			string
+		body			gin.H
+		user			db.User
+		buildStubs		func(store *mockdb.MockStore)
+		checkResponse	func(t *testing.T, recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name: "OK",
+			body: gin.H{
+				"base_currency":	exchangeRate.BaseCurrency,
+				"quote_currency":	exchangeRate.QuoteCurrency,
+				"rate":				exchangeRate.Rate,
+			},
+			user: admin,
+			buildStubs: func(store *mockdb.MockStore) {
+				arg := db.CreateExchangeRateParams{
+					BaseCurrency:	exchangeRate.BaseCurrency,
+					QuoteCurrency:	exchangeRate.QuoteCurrency,
+					Rate:			exchangeRate.Rate,
+					CreatedBy:		admin.Username,
+				}
+				store.EXPECT().CreateExchangeRate(gomock.Any(), gomock.Eq(arg)).Times(1).Return(exchangeRate, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+
+				data, err := ioutil.ReadAll(recorder.Body)
+				require.NoError(t, err)
+				var got db.ExchangeRate
+				err = json.Unmarshal(data, &got)
+				require.NoError(t, err)
+				require.Equal(t, exchangeRate.ID, got.ID)
+				require.Equal(t, exchangeRate.Rate, got.Rate)
+			},
+		},
+		{
+			name: "NotAdmin",
+			body: gin.H{
+				"base_currency":	exchangeRate.BaseCurrency,
+				"quote_currency":	exchangeRate.QuoteCurrency,
+				"rate":				exchangeRate.Rate,
+			},
+			user: depositor,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().CreateExchangeRate(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusForbidden, recorder.Code)
+			},
+		},
+		{
+			name: "SameCurrency",
+			body: gin.H{
+				"base_currency":	util.USD,
+				"quote_currency":	util.USD,
+				"rate":				"1",
+			},
+			user: admin,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().CreateExchangeRate(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+		{
+			name: "InvalidRate",
+			body: gin.H{
+				"base_currency":	exchangeRate.BaseCurrency,
+				"quote_currency":	exchangeRate.QuoteCurrency,
+				"rate":				"-0.5",
+			},
+			user: admin,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().CreateExchangeRate(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			buildAuthStubs(store, tc.user.Username)
+			tc.buildStubs(store)
+
+			server := newTestServer(t, store)
+			recorder := httptest.NewRecorder()
+
+			data, err := json.Marshal(tc.body)
+			require.NoError(t, err)
+
+			request, err := http.NewRequest(http.MethodPost, "/exchange_rates", bytes.NewReader(data))
+			require.NoError(t, err)
+
+			addAuthorization(t, request, server.tokenMaker, authorizationTypeBearer, tc.user.Username, tc.user.Role, time.Minute)
+			server.router.ServeHTTP(recorder, request)
+			tc.checkResponse(t, recorder)
+		})
+	}
+}
+
+func TestListExchangeRatesAPI(t *testing.T) {
+	user, _ := randomUser(t)
+
+	n := 5
+	exchangeRates := make([]db.ExchangeRate, n)
+	for i := 0; i < n; i++ {
+		exchangeRates[i] = randomExchangeRate(util.USD, util.EUR)
+	}
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	store := mockdb.NewMockStore(ctrl)
+	buildAuthStubs(store, user.Username)
+	store.EXPECT().
+		ListExchangeRates(gomock.Any(), gomock.Eq(db.ListExchangeRatesParams{
+			CursorCreatedAt:	maxCursorTime,
+			CursorID:			math.MaxInt64,
+			PageSize:			int32(n + 1),
+		})).
+		Times(1).
+		Return(exchangeRates, nil)
+
+	server := newTestServer(t, store)
+	recorder := httptest.NewRecorder()
+
+	url := fmt.Sprintf("/exchange_rates?page_size=%d", n)
+	request, err := http.NewRequest(http.MethodGet, url, nil)
+	require.NoError(t, err)
+
+	addAuthorization(t, request, server.tokenMaker, authorizationTypeBearer, user.Username, user.Role, time.Minute)
+	server.router.ServeHTTP(recorder, request)
+	require.Equal(t, http.StatusOK, recorder.Code)
+
+	var got struct {
+		Items		[]db.ExchangeRate	`json:"items"`
+		NextCursor	string				`json:"next_cursor"`
+	}
+	err = json.Unmarshal(recorder.Body.Bytes(), &got)
+	require.NoError(t, err)
+	require.Len(t, got.Items, n)
+	require.Empty(t, got.NextCursor)
+}
+
+func randomExchangeRate(baseCurrency, quoteCurrency string) db.ExchangeRate {
+	return db.ExchangeRate{
+		ID:				util.RandomInt(1, 1000),
+		BaseCurrency:	baseCurrency,
+		QuoteCurrency:	quoteCurrency,
+		Rate:			"0.9200000000",
+		CreatedBy:		util.RandomOwnerName(),
+	}
+}
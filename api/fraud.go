@@ -0,0 +1,82 @@
+package api
+
+import (
+	"errors"
+	"github.com/gin-gonic/gin"
+	db "github.com/techschool/simplebank/db/sqlc"
+	"github.com/techschool/simplebank/fraud"
+	"log"
+	"net/http"
+)
+
+var errTransferBlocked = errors.New("transfer is blocked by fraud screening")
+
+// checkFraud 在转账前进行风控检查并记录结果。
+// 以下资金变动不做风控检查：柜台存取款由银行职员在柜台办理，只记账不创建转账；
+// 撤销转账只把资金退回原来的转出账户；审批通过的转账在创建时已经检查过
+func (server *Server) checkFraud(ctx *gin.Context, arg db.TransferTxParams, currency string) (db.FraudDecision, error) {
+	return fraud.Screen(ctx, server.fraudChecker, server.store, arg, currency, fraud.Metadata{
+		Username:	arg.RequestedBy,
+		ClientIP:	ctx.ClientIP(),
+		UserAgent:	ctx.Request.UserAgent(),
+	})
+}
+
+// screenTransfer 风控检查单笔转账，被拒绝时已经写入了响应
+func (server *Server) screenTransfer(ctx *gin.Context, arg db.TransferTxParams, currency string) (db.FraudDecision, bool) {
+	decision, err := server.checkFraud(ctx, arg, currency)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return decision, false
+	}
+
+	if decision.Decision == string(fraud.Block) {
+		ctx.JSON(http.StatusUnprocessableEntity, gin.H{
+			"message":	errTransferBlocked.Error(),
+			"rule":		decision.Rule,
+		})
+		return decision, false
+	}
+	return decision, true
+}
+
+// linkFraudDecision 把风控记录关联到创建的转账上，转账已经完成，关联失败只记录日志
+func (server *Server) linkFraudDecision(ctx *gin.Context, decision db.FraudDecision, transfer db.Transfer) {
+	err := fraud.LinkTransfer(ctx, server.store, decision, transfer)
+	if err != nil {
+		log.Printf("cannot link fraud decision [%d] to transfer [%d], err: %v", decision.ID, transfer.ID, err)
+	}
+}
+
+type listFraudDecisionsRequest struct {
+	pageRequest
+}
+
+// listFraudDecisions 分页查询风控记录，最新的在前，银行职员和管理员可用
+func (server *Server) listFraudDecisions(ctx *gin.Context) {
+	var req listFraudDecisionsRequest
+	if err := ctx.ShouldBindQuery(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	page, valid := server.parsePage(ctx, req.pageRequest)
+	if !valid {
+		return
+	}
+
+	decisions, err := server.store.ListFraudDecisions(ctx, db.ListFraudDecisionsParams{
+		CursorCreatedAt:	page.before().CreatedAt,
+		CursorID:			page.before().ID,
+		PageSize:			page.limit(),
+	})
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	n, next := page.next(len(decisions), func(i int) pageCursor {
+		return pageCursor{CreatedAt: decisions[i].CreatedAt, ID: decisions[i].ID}
+	})
+	ctx.JSON(http.StatusOK, pageResponse{Items: decisions[:n], NextCursor: next})
+}
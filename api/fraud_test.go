@@ -0,0 +1,162 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"github.com/gin-gonic/gin"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/require"
+	mockdb "github.com/techschool/simplebank/db/mock"
+	db "github.com/techschool/simplebank/db/sqlc"
+	"github.com/techschool/simplebank/fraud"
+	"github.com/techschool/simplebank/util"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// buildFraudStubs 不关心风控记录的测试使用，没有规则时所有转账都被允许
+func buildFraudStubs(store *mockdb.MockStore) {
+	store.EXPECT().
+		CreateFraudDecision(gomock.Any(), gomock.Any()).
+		AnyTimes().
+		Return(db.FraudDecision{ID: 1, Decision: string(fraud.Allow)}, nil)
+	store.EXPECT().
+		SetFraudDecisionTransfer(gomock.Any(), gomock.Any()).
+		AnyTimes()
+}
+
+// fraudCheckerFunc 测试中使用的 fraud.Checker
+type fraudCheckerFunc func(ctx context.Context, input fraud.Input) (fraud.Result, error)
+
+func (fn fraudCheckerFunc) Check(ctx context.Context, input fraud.Input) (fraud.Result, error) {
+	return fn(ctx, input)
+}
+
+func TestTransferAPIFraudScreening(t *testing.T) {
+	user1, _ := randomUser(t)
+	user2, _ := randomUser(t)
+
+	account1 := randomAccount(user1.Username)
+	account2 := randomAccount(user2.Username)
+	account1.Currency = util.USD
+	account2.Currency = util.USD
+
+	body := gin.H{
+		"from_account_id":	account1.ID,
+		"to_account_id":	account2.ID,
+		"amount":			10,
+		"currency":			util.USD,
+	}
+
+	testCases := []struct{
+		name			string
+		result			fraud.Result
+		buildStubs		func(store *mockdb.MockStore)
+		checkResponse	func(t *testing.T, recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name: "Allow",
+			result: fraud.Result{Decision: fraud.Allow},
+			buildStubs: func(store *mockdb.MockStore) {
+				decision := db.FraudDecision{ID: util.RandomInt(1, 1000), Decision: string(fraud.Allow)}
+				store.EXPECT().CreateFraudDecision(gomock.Any(), gomock.Any()).Times(1).Return(decision, nil)
+				store.EXPECT().
+					TransferTx(gomock.Any(), gomock.Any()).
+					Times(1).
+					DoAndReturn(func(_ interface{}, arg db.TransferTxParams) (db.TransferTxResult, error) {
+						require.False(t, arg.RequireApproval)
+						return db.TransferTxResult{Transfer: db.Transfer{ID: 7, Status: util.TransferCompleted}}, nil
+					})
+				store.EXPECT().
+					SetFraudDecisionTransfer(gomock.Any(), gomock.Any()).
+					Times(1).
+					DoAndReturn(func(_ interface{}, arg db.SetFraudDecisionTransferParams) (db.FraudDecision, error) {
+						require.Equal(t, decision.ID, arg.ID)
+						require.Equal(t, int64(7), arg.TransferID.Int64)
+						return decision, nil
+					})
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+			},
+		},
+		{
+			name: "Review",
+			result: fraud.Result{Decision: fraud.Review, Rule: "new_payee_large_amount"},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					CreateFraudDecision(gomock.Any(), gomock.Any()).
+					Times(1).
+					DoAndReturn(func(_ interface{}, arg db.CreateFraudDecisionParams) (db.FraudDecision, error) {
+						require.Equal(t, string(fraud.Review), arg.Decision)
+						require.Equal(t, "new_payee_large_amount", arg.Rule)
+						require.Equal(t, user1.Username, arg.Username)
+						return db.FraudDecision{ID: 1, Decision: arg.Decision}, nil
+					})
+				store.EXPECT().
+					TransferTx(gomock.Any(), gomock.Any()).
+					Times(1).
+					DoAndReturn(func(_ interface{}, arg db.TransferTxParams) (db.TransferTxResult, error) {
+						require.True(t, arg.RequireApproval)
+						return db.TransferTxResult{Transfer: db.Transfer{ID: 7, Status: util.TransferPending}}, nil
+					})
+				store.EXPECT().SetFraudDecisionTransfer(gomock.Any(), gomock.Any()).Times(1)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusAccepted, recorder.Code)
+			},
+		},
+		{
+			name: "Block",
+			result: fraud.Result{Decision: fraud.Block, Rule: "high_velocity"},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					CreateFraudDecision(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.FraudDecision{ID: 1, Decision: string(fraud.Block)}, nil)
+				store.EXPECT().TransferTx(gomock.Any(), gomock.Any()).Times(0)
+				store.EXPECT().SetFraudDecisionTransfer(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnprocessableEntity, recorder.Code)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			buildAuthStubs(store, user1.Username)
+			store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account1.ID)).Times(1).Return(account1, nil)
+			store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account2.ID)).Times(1).Return(account2, nil)
+			tc.buildStubs(store)
+
+			server := newTestServer(t, store)
+			server.fraudChecker = fraudCheckerFunc(func(ctx context.Context, input fraud.Input) (fraud.Result, error) {
+				require.Equal(t, account1.ID, input.Transfer.FromAccountID)
+				require.Equal(t, util.USD, input.Transfer.Currency)
+				require.Equal(t, user1.Username, input.Metadata.Username)
+				return tc.result, nil
+			})
+			recorder := httptest.NewRecorder()
+
+			data, err := json.Marshal(body)
+			require.NoError(t, err)
+
+			request, err := http.NewRequest(http.MethodPost, "/transfers", bytes.NewReader(data))
+			require.NoError(t, err)
+
+			addAuthorization(t, request, server.tokenMaker, authorizationTypeBearer, user1.Username, user1.Role, time.Minute)
+			server.router.ServeHTTP(recorder, request)
+			tc.checkResponse(t, recorder)
+		})
+	}
+}
@@ -0,0 +1,115 @@
+package api
+
+import (
+	"github.com/gin-gonic/gin"
+	db "github.com/techschool/simplebank/db/sqlc"
+	"math"
+	"net/http"
+	"time"
+)
+
+// listHistoryRequest 账户流水和转账记录的查询条件，结果按时间倒序返回
+type listHistoryRequest struct {
+	// StartTime 和 EndTime 使用 RFC3339 格式，包含开始时间，不包含结束时间
+	StartTime	time.Time	`form:"start_time"`
+	EndTime		time.Time	`form:"end_time" binding:"omitempty,gtfield=StartTime"`
+	Direction	string		`form:"direction" binding:"omitempty,oneof=in out"`
+	// MinAmount 和 MaxAmount 使用账户币种的最小单位，流水的金额按绝对值比较
+	MinAmount	int64		`form:"min_amount" binding:"omitempty,min=0"`
+	MaxAmount	int64		`form:"max_amount" binding:"omitempty,gtefield=MinAmount"`
+	pageRequest
+}
+
+// listAccountEntries 查询账户的流水，只有账户所有者、银行职员和管理员可以查看
+func (server *Server) listAccountEntries(ctx *gin.Context) {
+	account, filter, page, valid := server.historyRequest(ctx)
+	if !valid {
+		return
+	}
+
+	entries, err := server.store.ListEntries(ctx, db.ListEntriesParams{
+		AccountID:	account.ID,
+		Direction:	filter.Direction,
+		StartTime:	filter.StartTime,
+		EndTime:	filter.EndTime,
+		MinAmount:	filter.MinAmount,
+		MaxAmount:	filter.MaxAmount,
+		CursorCreatedAt:	page.before().CreatedAt,
+		CursorID:			page.before().ID,
+		PageSize:			page.limit(),
+	})
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	n, next := page.next(len(entries), func(i int) pageCursor {
+		return pageCursor{CreatedAt: entries[i].CreatedAt, ID: entries[i].ID}
+	})
+	ctx.JSON(http.StatusOK, pageResponse{Items: entries[:n], NextCursor: next})
+}
+
+// listAccountTransfers 查询账户转出和转入的转账，金额的范围使用该账户的币种
+func (server *Server) listAccountTransfers(ctx *gin.Context) {
+	account, filter, page, valid := server.historyRequest(ctx)
+	if !valid {
+		return
+	}
+
+	transfers, err := server.store.ListTransfers(ctx, db.ListTransfersParams{
+		AccountID:	account.ID,
+		Direction:	filter.Direction,
+		StartTime:	filter.StartTime,
+		EndTime:	filter.EndTime,
+		MinAmount:	filter.MinAmount,
+		MaxAmount:	filter.MaxAmount,
+		CursorCreatedAt:	page.before().CreatedAt,
+		CursorID:			page.before().ID,
+		PageSize:			page.limit(),
+	})
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	n, next := page.next(len(transfers), func(i int) pageCursor {
+		return pageCursor{CreatedAt: transfers[i].CreatedAt, ID: transfers[i].ID}
+	})
+	ctx.JSON(http.StatusOK, pageResponse{Items: transfers[:n], NextCursor: next})
+}
+
+// historyRequest 解析账户和查询条件，并检查当前用户能否查看该账户
+func (server *Server) historyRequest(ctx *gin.Context) (db.Account, listHistoryRequest, page, bool) {
+	var uri getAccountRequest
+	if err := ctx.ShouldBindUri(&uri); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return db.Account{}, listHistoryRequest{}, page{}, false
+	}
+
+	var req listHistoryRequest
+	if err := ctx.ShouldBindQuery(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return db.Account{}, listHistoryRequest{}, page{}, false
+	}
+	p, valid := server.parsePage(ctx, req.pageRequest)
+	if !valid {
+		return db.Account{}, listHistoryRequest{}, p, false
+	}
+
+	account, valid := server.fetchAccount(ctx, uri.ID)
+	if !valid {
+		return account, listHistoryRequest{}, p, false
+	}
+	if !server.authorizeAccount(ctx, account, readAccess) {
+		return account, listHistoryRequest{}, p, false
+	}
+
+	// 没有指定的条件使用最宽的范围
+	if req.EndTime.IsZero() {
+		req.EndTime = time.Now().Add(time.Minute)
+	}
+	if req.MaxAmount == 0 {
+		req.MaxAmount = math.MaxInt64
+	}
+	return account, req, p, true
+}
@@ -0,0 +1,314 @@
+package api
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/require"
+	mockdb "github.com/techschool/simplebank/db/mock"
+	db "github.com/techschool/simplebank/db/sqlc"
+	"github.com/techschool/simplebank/util"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+)
+
+func TestListAccountEntriesAPI(t *testing.T) {
+	user1, _ := randomUser(t)
+	user2, _ := randomUser(t)
+	banker, _ := randomUser(t)
+	banker.Role = util.BankerRole
+
+	account := randomAccount(user1.Username)
+	entries := []db.Entry{
+		{ID: 12, AccountID: account.ID, Amount: 30},
+		{ID: 11, AccountID: account.ID, Amount: -20},
+	}
+	startTime := time.Date(2021, 6, 1, 0, 0, 0, 0, time.UTC)
+	endTime := time.Date(2021, 7, 1, 0, 0, 0, 0, time.UTC)
+
+	testCases := []struct{
+		name			string
+		query			url.Values
+		user			db.User
+		buildStubs		func(store *mockdb.MockStore)
+		checkResponse	func(t *testing.T, recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name: "OK",
+			query: url.Values{"page_size": {"5"}},
+			user: user1,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account.ID)).Times(1).Return(account, nil)
+				store.EXPECT().
+					ListEntries(gomock.Any(), gomock.Any()).
+					Times(1).
+					DoAndReturn(func(_ interface{}, arg db.ListEntriesParams) ([]db.Entry, error) {
+						// 没有指定的条件使用最宽的范围
+						require.Equal(t, account.ID, arg.AccountID)
+						require.Empty(t, arg.Direction)
+						require.True(t, arg.StartTime.IsZero())
+						require.True(t, arg.EndTime.After(time.Now()))
+						require.Zero(t, arg.MinAmount)
+						require.Equal(t, int64(math.MaxInt64), arg.MaxAmount)
+						require.Equal(t, maxCursorTime, arg.CursorCreatedAt)
+						require.Equal(t, int64(math.MaxInt64), arg.CursorID)
+						require.Equal(t, int32(6), arg.PageSize)
+						return entries, nil
+					})
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+
+				var got struct {
+					Items		[]db.Entry	`json:"items"`
+					NextCursor	string		`json:"next_cursor"`
+				}
+				err := json.Unmarshal(recorder.Body.Bytes(), &got)
+				require.NoError(t, err)
+				require.Equal(t, entries, got.Items)
+				require.Empty(t, got.NextCursor)
+			},
+		},
+		{
+			name: "Filters",
+			query: url.Values{
+				"page_size":	{"10"},
+				"start_time":	{startTime.Format(time.RFC3339)},
+				"end_time":		{endTime.Format(time.RFC3339)},
+				"direction":	{util.TransferDirectionOut},
+				"min_amount":	{"10"},
+				"max_amount":	{"100"},
+				"cursor":		{encodeCursor(pageCursor{CreatedAt: endTime, ID: 13})},
+			},
+			user: banker,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account.ID)).Times(1).Return(account, nil)
+				store.EXPECT().
+					ListEntries(gomock.Any(), gomock.Any()).
+					Times(1).
+					DoAndReturn(func(_ interface{}, arg db.ListEntriesParams) ([]db.Entry, error) {
+						require.Equal(t, util.TransferDirectionOut, arg.Direction)
+						require.True(t, startTime.Equal(arg.StartTime))
+						require.True(t, endTime.Equal(arg.EndTime))
+						require.Equal(t, int64(10), arg.MinAmount)
+						require.Equal(t, int64(100), arg.MaxAmount)
+						require.True(t, endTime.Equal(arg.CursorCreatedAt))
+						require.Equal(t, int64(13), arg.CursorID)
+						return entries[1:], nil
+					})
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+			},
+		},
+		{
+			name: "NotOwner",
+			query: url.Values{"page_size": {"5"}},
+			user: user2,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account.ID)).Times(1).Return(account, nil)
+				store.EXPECT().ListEntries(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusForbidden, recorder.Code)
+			},
+		},
+		{
+			name: "AccountNotFound",
+			query: url.Values{"page_size": {"5"}},
+			user: user1,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account.ID)).Times(1).Return(db.Account{}, sql.ErrNoRows)
+				store.EXPECT().ListEntries(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusNotFound, recorder.Code)
+			},
+		},
+		{
+			name: "InvalidDirection",
+			query: url.Values{"page_size": {"5"}, "direction": {"sideways"}},
+			user: user1,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+		{
+			name: "InvalidAmountRange",
+			query: url.Values{"page_size": {"5"}, "min_amount": {"100"}, "max_amount": {"10"}},
+			user: user1,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+		{
+			name: "InvalidTimeRange",
+			query: url.Values{
+				"page_size":	{"5"},
+				"start_time":	{endTime.Format(time.RFC3339)},
+				"end_time":		{startTime.Format(time.RFC3339)},
+			},
+			user: user1,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+		{
+			name: "InvalidPageSize",
+			query: url.Values{"page_size": {"100"}},  // 超过配置的最大值
+			user: user1,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+		{
+			name: "InternalError",
+			query: url.Values{"page_size": {"5"}},
+			user: user1,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account.ID)).Times(1).Return(account, nil)
+				store.EXPECT().ListEntries(gomock.Any(), gomock.Any()).Times(1).Return(nil, sql.ErrConnDone)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusInternalServerError, recorder.Code)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			buildAuthStubs(store, tc.user.Username)
+			tc.buildStubs(store)
+
+			server := newTestServer(t, store)
+			recorder := httptest.NewRecorder()
+
+			url := fmt.Sprintf("/accounts/%d/entries?%s", account.ID, tc.query.Encode())
+			request, err := http.NewRequest(http.MethodGet, url, nil)
+			require.NoError(t, err)
+
+			addAuthorization(t, request, server.tokenMaker, authorizationTypeBearer, tc.user.Username, tc.user.Role, time.Minute)
+			server.router.ServeHTTP(recorder, request)
+			tc.checkResponse(t, recorder)
+		})
+	}
+}
+
+func TestListAccountTransfersAPI(t *testing.T) {
+	user1, _ := randomUser(t)
+	user2, _ := randomUser(t)
+
+	account := randomAccount(user1.Username)
+	transfers := []db.Transfer{
+		{ID: 2, FromAccountID: account.ID, ToAccountID: account.ID + 1, Amount: 10},
+		{ID: 1, FromAccountID: account.ID + 1, ToAccountID: account.ID, Amount: 10},
+	}
+
+	testCases := []struct{
+		name			string
+		query			url.Values
+		user			db.User
+		buildStubs		func(store *mockdb.MockStore)
+		checkResponse	func(t *testing.T, recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name: "OK",
+			query: url.Values{"page_size": {"5"}, "direction": {util.TransferDirectionIn}},
+			user: user1,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account.ID)).Times(1).Return(account, nil)
+				store.EXPECT().
+					ListTransfers(gomock.Any(), gomock.Any()).
+					Times(1).
+					DoAndReturn(func(_ interface{}, arg db.ListTransfersParams) ([]db.Transfer, error) {
+						require.Equal(t, account.ID, arg.AccountID)
+						require.Equal(t, util.TransferDirectionIn, arg.Direction)
+						require.Equal(t, int32(6), arg.PageSize)
+						return transfers[1:], nil
+					})
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+
+				var got struct {
+					Items	[]db.Transfer	`json:"items"`
+				}
+				err := json.Unmarshal(recorder.Body.Bytes(), &got)
+				require.NoError(t, err)
+				require.Len(t, got.Items, 1)
+				require.Equal(t, transfers[1].ID, got.Items[0].ID)
+			},
+		},
+		{
+			name: "NotOwner",
+			query: url.Values{"page_size": {"5"}},
+			user: user2,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account.ID)).Times(1).Return(account, nil)
+				store.EXPECT().ListTransfers(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusForbidden, recorder.Code)
+			},
+		},
+		{
+			name: "InternalError",
+			query: url.Values{"page_size": {"5"}},
+			user: user1,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account.ID)).Times(1).Return(account, nil)
+				store.EXPECT().ListTransfers(gomock.Any(), gomock.Any()).Times(1).Return(nil, sql.ErrConnDone)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusInternalServerError, recorder.Code)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			buildAuthStubs(store, tc.user.Username)
+			tc.buildStubs(store)
+
+			server := newTestServer(t, store)
+			recorder := httptest.NewRecorder()
+
+			url := fmt.Sprintf("/accounts/%d/transfers?%s", account.ID, tc.query.Encode())
+			request, err := http.NewRequest(http.MethodGet, url, nil)
+			require.NoError(t, err)
+
+			addAuthorization(t, request, server.tokenMaker, authorizationTypeBearer, tc.user.Username, tc.user.Role, time.Minute)
+			server.router.ServeHTTP(recorder, request)
+			tc.checkResponse(t, recorder)
+		})
+	}
+}
@@ -0,0 +1,233 @@
+package api
+
+import (
+	"database/sql"
+	"errors"
+	"github.com/gin-gonic/gin"
+	db "github.com/techschool/simplebank/db/sqlc"
+	"github.com/techschool/simplebank/fraud"
+	"io"
+	"net/http"
+	"time"
+)
+
+var (
+	errHoldForbidden	= errors.New("only the recipient or bank staff can capture or void the hold")
+	errCaptureReview	= errors.New("capture requires manual review by fraud screening")
+)
+
+type createHoldRequest struct {
+	AccountID	int64	`json:"account_id" binding:"required,min=1"`
+	ToAccountID	int64	`json:"to_account_id" binding:"required,min=1"`
+	Amount		int64	`json:"amount" binding:"required,gt=0"`
+	Currency	string	`json:"currency" binding:"required,currency"`
+}
+
+// createHold 预授权，冻结账户的资金，只有账户所有者可以操作
+func (server *Server) createHold(ctx *gin.Context) {
+	var req createHoldRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	account, valid := server.validAccount(ctx, req.AccountID, req.Currency)
+	if !valid {
+		return
+	}
+	if !server.authorizeAccount(ctx, account, writeAccess) {
+		return
+	}
+
+	// 预授权扣款时不做币种换算，收款账户必须使用相同的币种
+	if _, valid = server.validAccount(ctx, req.ToAccountID, req.Currency); !valid {
+		return
+	}
+
+	hold, err := server.store.CreateHoldTx(ctx, db.CreateHoldParams{
+		AccountID:		req.AccountID,
+		ToAccountID:	req.ToAccountID,
+		Amount:			req.Amount,
+		CreatedBy:		account.Owner,
+		ExpiresAt:		time.Now().Add(server.config.HoldTTL),
+	})
+	if err != nil {
+		var limitErr *db.LimitExceededError
+		if errors.As(err, &limitErr) {
+			ctx.JSON(http.StatusUnprocessableEntity, limitExceededResponse(limitErr))
+			return
+		}
+		if errors.Is(err, db.ErrInsufficientFunds) || errors.Is(err, db.ErrInvalidHold) {
+			ctx.JSON(http.StatusUnprocessableEntity, errorResponse(err))
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+	ctx.JSON(http.StatusOK, hold)
+}
+
+type holdURI struct {
+	ID	int64	`uri:"id" binding:"required,min=1"`
+}
+
+// fetchHold 查询预授权，付款账户和收款账户的所有者以及银行职员可以查看
+func (server *Server) fetchHold(ctx *gin.Context) (db.Hold, bool) {
+	var uri holdURI
+	if err := ctx.ShouldBindUri(&uri); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return db.Hold{}, false
+	}
+
+	hold, err := server.store.GetHold(ctx, uri.ID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			ctx.JSON(http.StatusNotFound, errorResponse(err))
+			return hold, false
+		}
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return hold, false
+	}
+	return hold, true
+}
+
+// authorizeHoldRecipient 只有收款账户的所有者和银行职员可以扣款或者撤销预授权
+func (server *Server) authorizeHoldRecipient(ctx *gin.Context, hold db.Hold) bool {
+	authPayload, ok := authPayloadFromContext(ctx)
+	if !ok {
+		ctx.JSON(http.StatusUnauthorized, errorResponse(errUnauthenticated))
+		return false
+	}
+	if isStaff(authPayload) {
+		return true
+	}
+
+	toAccount, valid := server.fetchAccount(ctx, hold.ToAccountID)
+	if !valid {
+		return false
+	}
+	if toAccount.Owner != authPayload.Username {
+		ctx.JSON(http.StatusForbidden, errorResponse(errHoldForbidden))
+		return false
+	}
+	return true
+}
+
+// getHold 查询预授权
+func (server *Server) getHold(ctx *gin.Context) {
+	hold, valid := server.fetchHold(ctx)
+	if !valid {
+		return
+	}
+
+	authPayload, ok := authPayloadFromContext(ctx)
+	if !ok {
+		ctx.JSON(http.StatusUnauthorized, errorResponse(errUnauthenticated))
+		return
+	}
+	if hold.CreatedBy != authPayload.Username && !isStaff(authPayload) {
+		toAccount, valid := server.fetchAccount(ctx, hold.ToAccountID)
+		if !valid {
+			return
+		}
+		if toAccount.Owner != authPayload.Username {
+			ctx.JSON(http.StatusForbidden, errorResponse(errAccountForbidden))
+			return
+		}
+	}
+	ctx.JSON(http.StatusOK, hold)
+}
+
+type captureHoldRequest struct {
+	// Amount 扣款的金额，默认扣除全部冻结的金额
+	Amount	*int64	`json:"amount" binding:"omitempty,gt=0"`
+}
+
+// captureHold 预授权扣款，可以只扣除部分金额，剩余的部分会被释放
+func (server *Server) captureHold(ctx *gin.Context) {
+	var req captureHoldRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil && err != io.EOF {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	hold, valid := server.fetchHold(ctx)
+	if !valid {
+		return
+	}
+	if !server.authorizeHoldRecipient(ctx, hold) {
+		return
+	}
+
+	amount := hold.Amount
+	if req.Amount != nil {
+		amount = *req.Amount
+	}
+
+	fromAccount, valid := server.fetchAccount(ctx, hold.AccountID)
+	if !valid {
+		return
+	}
+
+	authPayload, ok := authPayloadFromContext(ctx)
+	if !ok {
+		ctx.JSON(http.StatusUnauthorized, errorResponse(errUnauthenticated))
+		return
+	}
+
+	// 扣款时才真正转账，由扣款的用户发起风控检查
+	decision, valid := server.screenTransfer(ctx, db.TransferTxParams{
+		FromAccountID:	hold.AccountID,
+		ToAccountID:	hold.ToAccountID,
+		Amount:			amount,
+		RequestedBy:	authPayload.Username,
+	}, fromAccount.Currency)
+	if !valid {
+		return
+	}
+	// 预授权扣款没有待审批的流程，需要人工审核的扣款同样被拒绝，预授权保持冻结，可以撤销
+	if decision.Decision == string(fraud.Review) {
+		ctx.JSON(http.StatusUnprocessableEntity, gin.H{
+			"message":	errCaptureReview.Error(),
+			"rule":		decision.Rule,
+		})
+		return
+	}
+
+	result, err := server.store.CaptureHoldTx(ctx, db.CaptureHoldTxParams{
+		HoldID:	hold.ID,
+		Amount:	amount,
+	})
+	if err != nil {
+		if errors.Is(err, db.ErrInvalidHold) || errors.Is(err, db.ErrInsufficientFunds) {
+			ctx.JSON(http.StatusUnprocessableEntity, errorResponse(err))
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+	server.linkFraudDecision(ctx, decision, result.Transfer)
+	ctx.JSON(http.StatusOK, result)
+}
+
+// voidHold 撤销预授权，释放冻结的金额
+func (server *Server) voidHold(ctx *gin.Context) {
+	hold, valid := server.fetchHold(ctx)
+	if !valid {
+		return
+	}
+	if !server.authorizeHoldRecipient(ctx, hold) {
+		return
+	}
+
+	hold, err := server.store.VoidHoldTx(ctx, hold.ID)
+	if err != nil {
+		if errors.Is(err, db.ErrInvalidHold) {
+			ctx.JSON(http.StatusUnprocessableEntity, errorResponse(err))
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+	ctx.JSON(http.StatusOK, hold)
+}
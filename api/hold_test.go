@@ -0,0 +1,448 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/require"
+	mockdb "github.com/techschool/simplebank/db/mock"
+	db "github.com/techschool/simplebank/db/sqlc"
+	"github.com/techschool/simplebank/fraud"
+	"github.com/techschool/simplebank/util"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func randomHold(account, toAccount db.Account) db.Hold {
+	return db.Hold{
+		ID:				util.RandomInt(1, 1000),
+		AccountID:		account.ID,
+		ToAccountID:	toAccount.ID,
+		Amount:			100,
+		Status:			util.HoldActive,
+		CreatedBy:		account.Owner,
+		ExpiresAt:		time.Now().Add(time.Hour),
+	}
+}
+
+func TestCreateHoldAPI(t *testing.T) {
+	user1, _ := randomUser(t)
+	user2, _ := randomUser(t)
+
+	account1 := randomAccount(user1.Username)
+	account2 := randomAccount(user2.Username)
+	account1.Currency = util.USD
+	account2.Currency = util.USD
+
+	testCases := []struct{
+		name			string
+		body			gin.H
+		user			db.User
+		buildStubs		func(store *mockdb.MockStore)
+		checkResponse	func(t *testing.T, recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name: "OK",
+			body: gin.H{"account_id": account1.ID, "to_account_id": account2.ID, "amount": 100, "currency": util.USD},
+			user: user1,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account1.ID)).Times(1).Return(account1, nil)
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account2.ID)).Times(1).Return(account2, nil)
+				store.EXPECT().
+					CreateHoldTx(gomock.Any(), gomock.Any()).
+					Times(1).
+					DoAndReturn(func(_ interface{}, arg db.CreateHoldParams) (db.Hold, error) {
+						require.Equal(t, account1.ID, arg.AccountID)
+						require.Equal(t, account2.ID, arg.ToAccountID)
+						require.Equal(t, int64(100), arg.Amount)
+						require.Equal(t, user1.Username, arg.CreatedBy)
+						require.WithinDuration(t, time.Now().Add(24*time.Hour), arg.ExpiresAt, time.Second)
+						return randomHold(account1, account2), nil
+					})
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+			},
+		},
+		{
+			name: "NotOwner",
+			body: gin.H{"account_id": account1.ID, "to_account_id": account2.ID, "amount": 100, "currency": util.USD},
+			user: user2,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account1.ID)).Times(1).Return(account1, nil)
+				store.EXPECT().CreateHoldTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusForbidden, recorder.Code)
+			},
+		},
+		{
+			name: "ToAccountCurrencyMismatch",
+			body: gin.H{"account_id": account1.ID, "to_account_id": account2.ID, "amount": 100, "currency": util.USD},
+			user: user1,
+			buildStubs: func(store *mockdb.MockStore) {
+				euroAccount := account2
+				euroAccount.Currency = util.EUR
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account1.ID)).Times(1).Return(account1, nil)
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account2.ID)).Times(1).Return(euroAccount, nil)
+				store.EXPECT().CreateHoldTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+		{
+			name: "InsufficientFunds",
+			body: gin.H{"account_id": account1.ID, "to_account_id": account2.ID, "amount": 100, "currency": util.USD},
+			user: user1,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account1.ID)).Times(1).Return(account1, nil)
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account2.ID)).Times(1).Return(account2, nil)
+				store.EXPECT().
+					CreateHoldTx(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.Hold{}, db.ErrInsufficientFunds)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnprocessableEntity, recorder.Code)
+			},
+		},
+		{
+			name: "LimitExceeded",
+			body: gin.H{"account_id": account1.ID, "to_account_id": account2.ID, "amount": 100, "currency": util.USD},
+			user: user1,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account1.ID)).Times(1).Return(account1, nil)
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account2.ID)).Times(1).Return(account2, nil)
+
+				remaining := int64(50)
+				limitErr := &db.LimitExceededError{
+					LimitID:			1,
+					Scope:				db.LimitScopeAccount,
+					Period:				util.LimitDaily,
+					Currency:			util.USD,
+					RemainingAmount:	&remaining,
+				}
+				store.EXPECT().
+					CreateHoldTx(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.Hold{}, fmt.Errorf("tx err: %w", limitErr))
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnprocessableEntity, recorder.Code)
+
+				var got struct {
+					Limit	db.LimitExceededError	`json:"limit"`
+				}
+				err := json.Unmarshal(recorder.Body.Bytes(), &got)
+				require.NoError(t, err)
+				require.Equal(t, int64(50), *got.Limit.RemainingAmount)
+			},
+		},
+		{
+			name: "InvalidAmount",
+			body: gin.H{"account_id": account1.ID, "to_account_id": account2.ID, "amount": 0, "currency": util.USD},
+			user: user1,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Any()).Times(0)
+				store.EXPECT().CreateHoldTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			buildAuthStubs(store, tc.user.Username)
+			tc.buildStubs(store)
+
+			server := newTestServer(t, store)
+			recorder := httptest.NewRecorder()
+
+			data, err := json.Marshal(tc.body)
+			require.NoError(t, err)
+
+			request, err := http.NewRequest(http.MethodPost, "/holds", bytes.NewReader(data))
+			require.NoError(t, err)
+
+			addAuthorization(t, request, server.tokenMaker, authorizationTypeBearer, tc.user.Username, tc.user.Role, time.Minute)
+			server.router.ServeHTTP(recorder, request)
+			tc.checkResponse(t, recorder)
+		})
+	}
+}
+
+func TestCaptureAndVoidHoldAPI(t *testing.T) {
+	payer, _ := randomUser(t)
+	merchant, _ := randomUser(t)
+	banker, _ := randomUser(t)
+	banker.Role = util.BankerRole
+
+	account1 := randomAccount(payer.Username)
+	account2 := randomAccount(merchant.Username)
+	hold := randomHold(account1, account2)
+
+	testCases := []struct{
+		name			string
+		action			string
+		body			gin.H
+		user			db.User
+		checker			fraud.Checker
+		buildStubs		func(store *mockdb.MockStore)
+		checkResponse	func(t *testing.T, recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name: "CaptureAll",
+			action: "capture",
+			user: merchant,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetHold(gomock.Any(), gomock.Eq(hold.ID)).Times(1).Return(hold, nil)
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account2.ID)).Times(1).Return(account2, nil)
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account1.ID)).Times(1).Return(account1, nil)
+
+				// 扣款前进行风控检查，扣款的转账关联到风控记录
+				decision := db.FraudDecision{ID: util.RandomInt(1, 1000), Decision: string(fraud.Allow)}
+				store.EXPECT().
+					CreateFraudDecision(gomock.Any(), gomock.Any()).
+					Times(1).
+					DoAndReturn(func(_ interface{}, arg db.CreateFraudDecisionParams) (db.FraudDecision, error) {
+						require.Equal(t, merchant.Username, arg.Username)
+						require.Equal(t, hold.AccountID, arg.FromAccountID)
+						require.Equal(t, hold.ToAccountID, arg.ToAccountID)
+						require.Equal(t, hold.Amount, arg.Amount)
+						require.Equal(t, account1.Currency, arg.Currency)
+						return decision, nil
+					})
+
+				arg := db.CaptureHoldTxParams{HoldID: hold.ID, Amount: hold.Amount}
+				result := db.CaptureHoldTxResult{}
+				result.Transfer.ID = 7
+				store.EXPECT().CaptureHoldTx(gomock.Any(), gomock.Eq(arg)).Times(1).Return(result, nil)
+				store.EXPECT().
+					SetFraudDecisionTransfer(gomock.Any(), gomock.Eq(db.SetFraudDecisionTransferParams{
+						ID:			decision.ID,
+						TransferID:	sql.NullInt64{Int64: 7, Valid: true},
+					})).
+					Times(1)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+			},
+		},
+		{
+			name: "BankerPartialCapture",
+			action: "capture",
+			body: gin.H{"amount": 40},
+			user: banker,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetHold(gomock.Any(), gomock.Eq(hold.ID)).Times(1).Return(hold, nil)
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account2.ID)).Times(0)
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account1.ID)).Times(1).Return(account1, nil)
+				buildFraudStubs(store)
+
+				arg := db.CaptureHoldTxParams{HoldID: hold.ID, Amount: 40}
+				store.EXPECT().CaptureHoldTx(gomock.Any(), gomock.Eq(arg)).Times(1)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+			},
+		},
+		{
+			name: "PayerCannotCapture",
+			action: "capture",
+			user: payer,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetHold(gomock.Any(), gomock.Eq(hold.ID)).Times(1).Return(hold, nil)
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account2.ID)).Times(1).Return(account2, nil)
+				store.EXPECT().CaptureHoldTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusForbidden, recorder.Code)
+			},
+		},
+		{
+			name: "CaptureExceedsHold",
+			action: "capture",
+			body: gin.H{"amount": 200},
+			user: merchant,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetHold(gomock.Any(), gomock.Eq(hold.ID)).Times(1).Return(hold, nil)
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account2.ID)).Times(1).Return(account2, nil)
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account1.ID)).Times(1).Return(account1, nil)
+				buildFraudStubs(store)
+				store.EXPECT().
+					CaptureHoldTx(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.CaptureHoldTxResult{}, db.ErrInvalidHold)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnprocessableEntity, recorder.Code)
+			},
+		},
+		{
+			name: "CaptureBlocked",
+			action: "capture",
+			user: merchant,
+			checker: fraudCheckerFunc(func(ctx context.Context, input fraud.Input) (fraud.Result, error) {
+				return fraud.Result{Decision: fraud.Block, Rule: "large_amount"}, nil
+			}),
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetHold(gomock.Any(), gomock.Eq(hold.ID)).Times(1).Return(hold, nil)
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account2.ID)).Times(1).Return(account2, nil)
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account1.ID)).Times(1).Return(account1, nil)
+				store.EXPECT().
+					CreateFraudDecision(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.FraudDecision{ID: 1, Decision: string(fraud.Block), Rule: "large_amount"}, nil)
+				store.EXPECT().CaptureHoldTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnprocessableEntity, recorder.Code)
+			},
+		},
+		{
+			name: "CaptureNeedsReview",  // 预授权扣款没有待审批的流程，需要人工审核时同样被拒绝
+			action: "capture",
+			user: merchant,
+			checker: fraudCheckerFunc(func(ctx context.Context, input fraud.Input) (fraud.Result, error) {
+				return fraud.Result{Decision: fraud.Review, Rule: "new_payee"}, nil
+			}),
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetHold(gomock.Any(), gomock.Eq(hold.ID)).Times(1).Return(hold, nil)
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account2.ID)).Times(1).Return(account2, nil)
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account1.ID)).Times(1).Return(account1, nil)
+				store.EXPECT().
+					CreateFraudDecision(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.FraudDecision{ID: 1, Decision: string(fraud.Review), Rule: "new_payee"}, nil)
+				store.EXPECT().CaptureHoldTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnprocessableEntity, recorder.Code)
+
+				var got gin.H
+				err := json.Unmarshal(recorder.Body.Bytes(), &got)
+				require.NoError(t, err)
+				require.Equal(t, "new_payee", got["rule"])
+			},
+		},
+		{
+			name: "Void",
+			action: "void",
+			user: merchant,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetHold(gomock.Any(), gomock.Eq(hold.ID)).Times(1).Return(hold, nil)
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account2.ID)).Times(1).Return(account2, nil)
+
+				voided := hold
+				voided.Status = util.HoldVoided
+				store.EXPECT().VoidHoldTx(gomock.Any(), gomock.Eq(hold.ID)).Times(1).Return(voided, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+			},
+		},
+		{
+			name: "VoidNotActive",
+			action: "void",
+			user: banker,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetHold(gomock.Any(), gomock.Eq(hold.ID)).Times(1).Return(hold, nil)
+				store.EXPECT().VoidHoldTx(gomock.Any(), gomock.Eq(hold.ID)).Times(1).Return(db.Hold{}, db.ErrInvalidHold)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnprocessableEntity, recorder.Code)
+			},
+		},
+		{
+			name: "NotFound",
+			action: "void",
+			user: merchant,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetHold(gomock.Any(), gomock.Eq(hold.ID)).Times(1).Return(db.Hold{}, sql.ErrNoRows)
+				store.EXPECT().VoidHoldTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusNotFound, recorder.Code)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			buildAuthStubs(store, tc.user.Username)
+			tc.buildStubs(store)
+
+			server := newTestServer(t, store)
+			if tc.checker != nil {
+				server.fraudChecker = tc.checker
+			}
+			recorder := httptest.NewRecorder()
+
+			var body bytes.Buffer
+			if tc.body != nil {
+				err := json.NewEncoder(&body).Encode(tc.body)
+				require.NoError(t, err)
+			}
+
+			url := fmt.Sprintf("/holds/%d/%s", hold.ID, tc.action)
+			request, err := http.NewRequest(http.MethodPost, url, &body)
+			require.NoError(t, err)
+
+			addAuthorization(t, request, server.tokenMaker, authorizationTypeBearer, tc.user.Username, tc.user.Role, time.Minute)
+			server.router.ServeHTTP(recorder, request)
+			tc.checkResponse(t, recorder)
+		})
+	}
+}
+
+func TestGetAccountBalanceAPI(t *testing.T) {
+	user, _ := randomUser(t)
+	account := randomAccount(user.Username)
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	store := mockdb.NewMockStore(ctrl)
+	buildAuthStubs(store, user.Username)
+	store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account.ID)).Times(1).Return(account, nil)
+	store.EXPECT().GetActiveHoldsAmount(gomock.Any(), gomock.Eq(account.ID)).Times(1).Return(int64(30), nil)
+
+	server := newTestServer(t, store)
+	recorder := httptest.NewRecorder()
+
+	url := fmt.Sprintf("/accounts/%d/balance", account.ID)
+	request, err := http.NewRequest(http.MethodGet, url, nil)
+	require.NoError(t, err)
+
+	addAuthorization(t, request, server.tokenMaker, authorizationTypeBearer, user.Username, user.Role, time.Minute)
+	server.router.ServeHTTP(recorder, request)
+	require.Equal(t, http.StatusOK, recorder.Code)
+
+	var balance accountBalanceResponse
+	err = json.Unmarshal(recorder.Body.Bytes(), &balance)
+	require.NoError(t, err)
+	require.Equal(t, account.Balance, balance.Balance)
+	require.Equal(t, int64(30), balance.HeldAmount)
+	require.Equal(t, account.Balance-30, balance.AvailableBalance)
+}
@@ -1,6 +1,7 @@
 package api
 
 import (
+	"fmt"
 	"github.com/gin-gonic/gin"
 	"github.com/stretchr/testify/require"
 	db "github.com/techschool/simplebank/db/sqlc"
@@ -10,10 +11,21 @@ import (
 	"time"
 )
 
+// testApprovalThreshold 测试服务器中 USD 转账需要审批的金额
+const testApprovalThreshold = int64(1000000)
+
+// testMaxPageSize 测试服务器中列表接口每页最多返回的记录数
+const testMaxPageSize = 50
+
 func newTestServer(t *testing.T, store db.Store) *Server {
 	config := util.Config{
 		TokenSymmetricKey: 	util.RandomString(32),
 		AccessTokenDuration: time.Minute,
+		RefreshTokenDuration: time.Hour,
+		IdempotencyKeyRetention: time.Hour,
+		HoldTTL: 24 * time.Hour,
+		ApprovalThresholds: fmt.Sprintf("%s:%d", util.USD, testApprovalThreshold),
+		MaxPageSize: testMaxPageSize,
 	}
 
 	server, err := NewServer(config, store)
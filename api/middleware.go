@@ -1,9 +1,11 @@
 package api
 
 import (
+	"database/sql"
 	"errors"
 	"fmt"
 	"github.com/gin-gonic/gin"
+	db "github.com/techschool/simplebank/db/sqlc"
 	"github.com/techschool/simplebank/token"
 	"net/http"
 	"strings"
@@ -15,7 +17,8 @@ const (
 	authorizationPayloadKey = "authorization_payload"
 )
 
-func authMiddleware(tokenMaker token.Maker) gin.HandlerFunc {
+// authMiddleware 校验 access token，并拒绝已被吊销的 token
+func authMiddleware(tokenMaker token.Maker, store db.Store) gin.HandlerFunc {
 	return func(ctx *gin.Context) {
 		authorizationHeader := ctx.GetHeader(authorizationHeaderKey)
 		if len(authorizationHeader) == 0 {
@@ -39,16 +42,65 @@ func authMiddleware(tokenMaker token.Maker) gin.HandlerFunc {
 		}
 
 		accessToken := fields[1]
-		payload, err := tokenMaker.VerifyToken(accessToken)
+		payload, err := tokenMaker.VerifyToken(accessToken, token.TokenTypeAccessToken)
 		if err != nil {
 			ctx.AbortWithStatusJSON(http.StatusUnauthorized, errorResponse(err))
 			return
 		}
 
+		err = checkTokenRevocation(ctx, store, payload)
+		if err != nil {
+			if err == errRevokedToken {
+				ctx.AbortWithStatusJSON(http.StatusUnauthorized, errorResponse(err))
+				return
+			}
+			ctx.AbortWithStatusJSON(http.StatusInternalServerError, errorResponse(err))
+			return
+		}
+
 		ctx.Set(authorizationHeaderKey, payload)
 		ctx.Next()
 	}
 }
 
+var errRevokedToken = errors.New("token has been revoked")
+
+// checkTokenRevocation 检查 token 是否被单独吊销（logout），
+// 或者签发时间早于用户修改密码、logout_all 的时间
+func checkTokenRevocation(ctx *gin.Context, store db.Store, payload *token.Payload) error {
+	revoked, err := store.IsTokenRevoked(ctx, payload.ID)
+	if err != nil {
+		return err
+	}
+	if revoked {
+		return errRevokedToken
+	}
+
+	user, err := store.GetUser(ctx, payload.Username)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return errRevokedToken
+		}
+		return err
+	}
+	if payload.IssuedAt.Before(user.PasswordChangeAt) || payload.IssuedAt.Before(user.TokensRevokedAt) {
+		return errRevokedToken
+	}
+	return nil
+}
 
+// authorizeRoles 只允许指定角色的用户访问，必须在 authMiddleware 之后使用
+func authorizeRoles(roles ...string) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		authPayload := ctx.MustGet(authorizationHeaderKey).(*token.Payload)
+		for _, role := range roles {
+			if authPayload.Role == role {
+				ctx.Next()
+				return
+			}
+		}
 
+		err := fmt.Errorf("role %s is not allowed to access this resource", authPayload.Role)
+		ctx.AbortWithStatusJSON(http.StatusForbidden, errorResponse(err))
+	}
+}
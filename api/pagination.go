@@ -0,0 +1,115 @@
+package api
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"math"
+	"net/http"
+	"time"
+)
+
+// defaultPageSize 没有指定 page_size 时每页返回的记录数
+const defaultPageSize = 10
+
+var errInvalidCursor = errors.New("invalid cursor")
+
+// maxCursorTime 倒序查询第一页的起点，晚于所有的记录
+var maxCursorTime = time.Date(9999, 12, 31, 0, 0, 0, 0, time.UTC)
+
+// pageRequest 所有列表接口共用的分页参数，cursor 使用上一页返回的 next_cursor，第一页不需要
+type pageRequest struct {
+	Cursor		string	`form:"cursor" binding:"omitempty,max=256"`
+	PageSize	int32	`form:"page_size" binding:"omitempty,min=1"`
+}
+
+// pageCursor 上一页最后一条记录的位置，编码后作为不透明的 cursor 返回给客户端
+type pageCursor struct {
+	CreatedAt	time.Time	`json:"created_at"`
+	ID			int64		`json:"id"`
+}
+
+// pageResponse 列表接口的响应，next_cursor 为空表示没有下一页
+type pageResponse struct {
+	Items		interface{}	`json:"items"`
+	NextCursor	string		`json:"next_cursor,omitempty"`
+}
+
+// page 解析后的分页参数，按 (created_at, id) 进行 keyset 分页
+type page struct {
+	cursor	pageCursor
+	size	int32
+}
+
+func encodeCursor(cursor pageCursor) string {
+	data, _ := json.Marshal(cursor)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func decodeCursor(value string) (pageCursor, error) {
+	var cursor pageCursor
+	data, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil {
+		return cursor, errInvalidCursor
+	}
+	if err = json.Unmarshal(data, &cursor); err != nil || cursor.ID <= 0 {
+		return cursor, errInvalidCursor
+	}
+	return cursor, nil
+}
+
+// parsePage 解析 cursor 并检查每页的数量不超过配置的最大值，参数错误时返回 400
+func (server *Server) parsePage(ctx *gin.Context, req pageRequest) (page, bool) {
+	maxPageSize := server.config.MaxPageSize
+	if maxPageSize < defaultPageSize {
+		maxPageSize = defaultPageSize
+	}
+
+	p := page{size: req.PageSize}
+	if p.size == 0 {
+		p.size = defaultPageSize
+	}
+	if p.size > maxPageSize {
+		err := fmt.Errorf("page_size must not be greater than %d", maxPageSize)
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return p, false
+	}
+
+	if len(req.Cursor) > 0 {
+		var err error
+		p.cursor, err = decodeCursor(req.Cursor)
+		if err != nil {
+			ctx.JSON(http.StatusBadRequest, errorResponse(err))
+			return p, false
+		}
+	}
+	return p, true
+}
+
+// limit 多查询一条记录，用于判断是否还有下一页
+func (p page) limit() int32 {
+	return p.size + 1
+}
+
+// after 升序查询的起点，第一页从最早的记录开始
+func (p page) after() pageCursor {
+	return p.cursor
+}
+
+// before 倒序查询的起点，第一页从最新的记录开始
+func (p page) before() pageCursor {
+	if p.cursor.ID == 0 {
+		return pageCursor{CreatedAt: maxCursorTime, ID: math.MaxInt64}
+	}
+	return p.cursor
+}
+
+// next 查询到 n 条记录时返回本页的记录数和下一页的 cursor，position 返回第 i 条记录的位置
+func (p page) next(n int, position func(i int) pageCursor) (int, string) {
+	if n <= int(p.size) {
+		return n, ""
+	}
+	return int(p.size), encodeCursor(position(int(p.size) - 1))
+}
@@ -0,0 +1,44 @@
+package api
+
+import (
+	"github.com/stretchr/testify/require"
+	"math"
+	"testing"
+	"time"
+)
+
+func TestPageCursor(t *testing.T) {
+	cursor := pageCursor{CreatedAt: time.Now().UTC().Truncate(time.Microsecond), ID: 42}
+
+	decoded, err := decodeCursor(encodeCursor(cursor))
+	require.NoError(t, err)
+	require.True(t, cursor.CreatedAt.Equal(decoded.CreatedAt))
+	require.Equal(t, cursor.ID, decoded.ID)
+
+	for _, value := range []string{"not-base64!", "bm90LWpzb24", encodeCursor(pageCursor{})} {
+		_, err = decodeCursor(value)
+		require.ErrorIs(t, err, errInvalidCursor)
+	}
+}
+
+func TestPageNext(t *testing.T) {
+	p := page{size: 3}
+	position := func(i int) pageCursor {
+		return pageCursor{ID: int64(i + 1)}
+	}
+
+	// 第一页倒序查询从最新的记录开始
+	require.Equal(t, int64(math.MaxInt64), p.before().ID)
+	require.Zero(t, p.after().ID)
+
+	n, next := p.next(3, position)
+	require.Equal(t, 3, n)
+	require.Empty(t, next)
+
+	// 多查到一条记录说明还有下一页，cursor 指向本页最后一条
+	n, next = p.next(4, position)
+	require.Equal(t, 3, n)
+	cursor, err := decodeCursor(next)
+	require.NoError(t, err)
+	require.Equal(t, int64(3), cursor.ID)
+}
@@ -0,0 +1,71 @@
+package api
+
+import (
+	"database/sql"
+	"fmt"
+	"github.com/gin-gonic/gin"
+	db "github.com/techschool/simplebank/db/sqlc"
+	"github.com/techschool/simplebank/util"
+	"net/http"
+	"strings"
+)
+
+type getRecipientRequest struct {
+	// Recipient 收款人的用户名或者邮箱
+	Recipient	string	`form:"recipient" binding:"required,max=255"`
+	Currency	string	`form:"currency" binding:"required,currency"`
+}
+
+// recipientResponse 收款人的信息，只返回打码后的姓名，用于转账前让付款人确认
+type recipientResponse struct {
+	RecipientName	string	`json:"recipient_name"`
+	Currency		string	`json:"currency"`
+}
+
+// getRecipient 按用户名或邮箱查询收款人在该币种的账户，返回打码后的姓名
+func (server *Server) getRecipient(ctx *gin.Context) {
+	var req getRecipientRequest
+	if err := ctx.ShouldBindQuery(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	account, user, valid := server.findRecipientAccount(ctx, req.Recipient, req.Currency)
+	if !valid {
+		return
+	}
+	ctx.JSON(http.StatusOK, recipientResponse{
+		RecipientName:	util.MaskName(user.FullName),
+		Currency:		account.Currency,
+	})
+}
+
+// findRecipientAccount 查询收款人在该币种的账户，收款人包含 @ 时按邮箱查询，否则按用户名查询。
+// 用户不存在和用户没有该币种的账户返回相同的 404，避免通过该接口判断用户是否存在。
+func (server *Server) findRecipientAccount(ctx *gin.Context, recipient, currency string) (db.Account, db.User, bool) {
+	var user db.User
+	var err error
+	if strings.Contains(recipient, "@") {
+		user, err = server.store.GetUserByEmail(ctx, recipient)
+	} else {
+		user, err = server.store.GetUser(ctx, recipient)
+	}
+
+	var account db.Account
+	if err == nil {
+		account, err = server.store.GetAccountByOwner(ctx, db.GetAccountByOwnerParams{
+			Owner:		user.Username,
+			Currency:	currency,
+		})
+	}
+	if err != nil {
+		if err == sql.ErrNoRows {
+			err = fmt.Errorf("recipient %s has no %s account", recipient, currency)
+			ctx.JSON(http.StatusNotFound, errorResponse(err))
+			return account, user, false
+		}
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return account, user, false
+	}
+	return account, user, true
+}
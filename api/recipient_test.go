@@ -0,0 +1,140 @@
+package api
+
+import (
+	"database/sql"
+	"encoding/json"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/require"
+	mockdb "github.com/techschool/simplebank/db/mock"
+	db "github.com/techschool/simplebank/db/sqlc"
+	"github.com/techschool/simplebank/util"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+)
+
+func TestGetRecipientAPI(t *testing.T) {
+	user1, _ := randomUser(t)
+	user2, _ := randomUser(t)
+	user2.FullName = "Ricardo Zhang"
+
+	account2 := randomAccount(user2.Username)
+	account2.Currency = util.USD
+
+	testCases := []struct{
+		name			string
+		recipient		string
+		currency		string
+		buildStubs		func(store *mockdb.MockStore)
+		checkResponse	func(t *testing.T, recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name: "ByUsername",
+			recipient: user2.Username,
+			currency: util.USD,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetUser(gomock.Any(), gomock.Eq(user2.Username)).Times(1).Return(user2, nil)
+				store.EXPECT().
+					GetAccountByOwner(gomock.Any(), gomock.Eq(db.GetAccountByOwnerParams{Owner: user2.Username, Currency: util.USD})).
+					Times(1).
+					Return(account2, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+
+				var got recipientResponse
+				err := json.Unmarshal(recorder.Body.Bytes(), &got)
+				require.NoError(t, err)
+				require.Equal(t, "R****** Z****", got.RecipientName)
+				require.Equal(t, util.USD, got.Currency)
+				require.NotContains(t, recorder.Body.String(), user2.Username)
+			},
+		},
+		{
+			name: "ByEmail",
+			recipient: user2.Email,
+			currency: util.USD,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetUserByEmail(gomock.Any(), gomock.Eq(user2.Email)).Times(1).Return(user2, nil)
+				store.EXPECT().GetAccountByOwner(gomock.Any(), gomock.Any()).Times(1).Return(account2, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+			},
+		},
+		{
+			name: "UserNotFound",
+			recipient: "nobody@email.com",
+			currency: util.USD,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetUserByEmail(gomock.Any(), gomock.Any()).Times(1).Return(db.User{}, sql.ErrNoRows)
+				store.EXPECT().GetAccountByOwner(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusNotFound, recorder.Code)
+			},
+		},
+		{
+			name: "NoAccountInCurrency",
+			recipient: user2.Username,
+			currency: util.EUR,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetUser(gomock.Any(), gomock.Eq(user2.Username)).Times(1).Return(user2, nil)
+				store.EXPECT().GetAccountByOwner(gomock.Any(), gomock.Any()).Times(1).Return(db.Account{}, sql.ErrNoRows)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusNotFound, recorder.Code)
+			},
+		},
+		{
+			name: "InternalError",
+			recipient: user2.Username,
+			currency: util.USD,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetUser(gomock.Any(), gomock.Eq(user2.Username)).Times(1).Return(db.User{}, sql.ErrConnDone)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusInternalServerError, recorder.Code)
+			},
+		},
+		{
+			name: "InvalidCurrency",
+			recipient: user2.Username,
+			currency: "XYZ",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccountByOwner(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			buildAuthStubs(store, user1.Username)
+			tc.buildStubs(store)
+
+			server := newTestServer(t, store)
+			recorder := httptest.NewRecorder()
+
+			query := url.Values{}
+			query.Set("recipient", tc.recipient)
+			query.Set("currency", tc.currency)
+			request, err := http.NewRequest(http.MethodGet, "/recipients?"+query.Encode(), nil)
+			require.NoError(t, err)
+
+			addAuthorization(t, request, server.tokenMaker, authorizationTypeBearer, user1.Username, user1.Role, time.Minute)
+			server.router.ServeHTTP(recorder, request)
+			tc.checkResponse(t, recorder)
+		})
+	}
+}
@@ -0,0 +1,105 @@
+package api
+
+import (
+	"database/sql"
+	"github.com/gin-gonic/gin"
+	db "github.com/techschool/simplebank/db/sqlc"
+	"net/http"
+)
+
+// runReconciliation 立即执行一次对账，返回对账报告和发现的差异，只有管理员可用
+func (server *Server) runReconciliation(ctx *gin.Context) {
+	result, err := server.store.ReconcileTx(ctx)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+	ctx.JSON(http.StatusOK, result)
+}
+
+type listReconciliationReportsRequest struct {
+	pageRequest
+}
+
+// listReconciliationReports 分页查询对账报告，最新的在前
+func (server *Server) listReconciliationReports(ctx *gin.Context) {
+	var req listReconciliationReportsRequest
+	if err := ctx.ShouldBindQuery(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	page, valid := server.parsePage(ctx, req.pageRequest)
+	if !valid {
+		return
+	}
+
+	reports, err := server.store.ListReconciliationReports(ctx, db.ListReconciliationReportsParams{
+		CursorCreatedAt:	page.before().CreatedAt,
+		CursorID:			page.before().ID,
+		PageSize:			page.limit(),
+	})
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	n, next := page.next(len(reports), func(i int) pageCursor {
+		return pageCursor{CreatedAt: reports[i].CreatedAt, ID: reports[i].ID}
+	})
+	ctx.JSON(http.StatusOK, pageResponse{Items: reports[:n], NextCursor: next})
+}
+
+type reconciliationReportURI struct {
+	ID	int64	`uri:"id" binding:"required,min=1"`
+}
+
+type listReconciliationDiscrepanciesRequest struct {
+	pageRequest
+}
+
+// listReconciliationDiscrepancies 分页查询一次对账发现的差异，按发现的顺序返回
+func (server *Server) listReconciliationDiscrepancies(ctx *gin.Context) {
+	var uri reconciliationReportURI
+	if err := ctx.ShouldBindUri(&uri); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	var req listReconciliationDiscrepanciesRequest
+	if err := ctx.ShouldBindQuery(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	page, valid := server.parsePage(ctx, req.pageRequest)
+	if !valid {
+		return
+	}
+
+	report, err := server.store.GetReconciliationReport(ctx, uri.ID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			ctx.JSON(http.StatusNotFound, errorResponse(err))
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	discrepancies, err := server.store.ListReconciliationDiscrepancies(ctx, db.ListReconciliationDiscrepanciesParams{
+		ReportID:			report.ID,
+		CursorCreatedAt:	page.after().CreatedAt,
+		CursorID:			page.after().ID,
+		PageSize:			page.limit(),
+	})
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	n, next := page.next(len(discrepancies), func(i int) pageCursor {
+		return pageCursor{CreatedAt: discrepancies[i].CreatedAt, ID: discrepancies[i].ID}
+	})
+	ctx.JSON(http.StatusOK, pageResponse{Items: discrepancies[:n], NextCursor: next})
+}
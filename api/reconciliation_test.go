@@ -0,0 +1,194 @@
+package api
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/require"
+	mockdb "github.com/techschool/simplebank/db/mock"
+	db "github.com/techschool/simplebank/db/sqlc"
+	"github.com/techschool/simplebank/util"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestRunReconciliationAPI(t *testing.T) {
+	admin, _ := randomUser(t)
+	admin.Role = util.AdminRole
+	depositor, _ := randomUser(t)
+
+	result := db.ReconcileTxResult{
+		Report: db.ReconciliationReport{ID: 1, AccountsChecked: 3, TransfersChecked: 5, DiscrepancyCount: 1},
+		Discrepancies: []db.ReconciliationDiscrepancy{
+			{
+				ID:			1,
+				ReportID:	1,
+				Kind:		util.DiscrepancyAccountBalance,
+				AccountID:	sql.NullInt64{Int64: 2, Valid: true},
+				Expected:	100,
+				Actual:		90,
+			},
+		},
+	}
+
+	testCases := []struct{
+		name			string
+		user			db.User
+		buildStubs		func(store *mockdb.MockStore)
+		checkResponse	func(t *testing.T, recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name: "OK",
+			user: admin,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().ReconcileTx(gomock.Any()).Times(1).Return(result, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+
+				var got db.ReconcileTxResult
+				err := json.Unmarshal(recorder.Body.Bytes(), &got)
+				require.NoError(t, err)
+				require.Equal(t, result.Report.DiscrepancyCount, got.Report.DiscrepancyCount)
+				require.Len(t, got.Discrepancies, 1)
+				require.Equal(t, util.DiscrepancyAccountBalance, got.Discrepancies[0].Kind)
+			},
+		},
+		{
+			name: "Forbidden",
+			user: depositor,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().ReconcileTx(gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusForbidden, recorder.Code)
+			},
+		},
+		{
+			name: "InternalError",
+			user: admin,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().ReconcileTx(gomock.Any()).Times(1).Return(db.ReconcileTxResult{}, sql.ErrConnDone)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusInternalServerError, recorder.Code)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			buildAuthStubs(store, tc.user.Username)
+			tc.buildStubs(store)
+
+			server := newTestServer(t, store)
+			recorder := httptest.NewRecorder()
+
+			request, err := http.NewRequest(http.MethodPost, "/reconciliation_reports", nil)
+			require.NoError(t, err)
+
+			addAuthorization(t, request, server.tokenMaker, authorizationTypeBearer, tc.user.Username, tc.user.Role, time.Minute)
+			server.router.ServeHTTP(recorder, request)
+			tc.checkResponse(t, recorder)
+		})
+	}
+}
+
+func TestListReconciliationDiscrepanciesAPI(t *testing.T) {
+	admin, _ := randomUser(t)
+	admin.Role = util.AdminRole
+
+	report := db.ReconciliationReport{ID: util.RandomInt(1, 1000), DiscrepancyCount: 2}
+	discrepancies := []db.ReconciliationDiscrepancy{
+		{ID: 1, ReportID: report.ID, Kind: util.DiscrepancyAccountBalance, Expected: 100, Actual: 90},
+		{ID: 2, ReportID: report.ID, Kind: util.DiscrepancyTransferEntries, Expected: 2, Actual: 1},
+	}
+
+	testCases := []struct{
+		name			string
+		reportID		int64
+		buildStubs		func(store *mockdb.MockStore)
+		checkResponse	func(t *testing.T, recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name: "OK",
+			reportID: report.ID,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetReconciliationReport(gomock.Any(), gomock.Eq(report.ID)).Times(1).Return(report, nil)
+				store.EXPECT().
+					ListReconciliationDiscrepancies(gomock.Any(), gomock.Eq(db.ListReconciliationDiscrepanciesParams{
+						ReportID:	report.ID,
+						PageSize:	defaultPageSize + 1,
+					})).
+					Times(1).
+					Return(discrepancies, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+
+				var got struct {
+					Items		[]db.ReconciliationDiscrepancy	`json:"items"`
+					NextCursor	string							`json:"next_cursor"`
+				}
+				err := json.Unmarshal(recorder.Body.Bytes(), &got)
+				require.NoError(t, err)
+				require.Equal(t, discrepancies, got.Items)
+				require.Empty(t, got.NextCursor)
+			},
+		},
+		{
+			name: "NotFound",
+			reportID: report.ID,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetReconciliationReport(gomock.Any(), gomock.Eq(report.ID)).Times(1).Return(db.ReconciliationReport{}, sql.ErrNoRows)
+				store.EXPECT().ListReconciliationDiscrepancies(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusNotFound, recorder.Code)
+			},
+		},
+		{
+			name: "InvalidID",
+			reportID: 0,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetReconciliationReport(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			buildAuthStubs(store, admin.Username)
+			tc.buildStubs(store)
+
+			server := newTestServer(t, store)
+			recorder := httptest.NewRecorder()
+
+			url := fmt.Sprintf("/reconciliation_reports/%d/discrepancies", tc.reportID)
+			request, err := http.NewRequest(http.MethodGet, url, nil)
+			require.NoError(t, err)
+
+			addAuthorization(t, request, server.tokenMaker, authorizationTypeBearer, admin.Username, admin.Role, time.Minute)
+			server.router.ServeHTTP(recorder, request)
+			tc.checkResponse(t, recorder)
+		})
+	}
+}
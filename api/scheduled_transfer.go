@@ -0,0 +1,352 @@
+package api
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"github.com/gin-gonic/gin"
+	db "github.com/techschool/simplebank/db/sqlc"
+	"github.com/techschool/simplebank/util"
+	"net/http"
+	"time"
+)
+
+var (
+	errScheduledTransferForbidden = errors.New("scheduled transfer doesn't belong to the authenticated user")
+	errScheduledTransferFinished  = errors.New("scheduled transfer has been completed or cancelled")
+)
+
+type scheduledTransferResponse struct {
+	ID				int64		`json:"id"`
+	Owner			string		`json:"owner"`
+	FromAccountID	int64		`json:"from_account_id"`
+	ToAccountID		int64		`json:"to_account_id"`
+	Amount			int64		`json:"amount"`
+	Schedule		string		`json:"schedule"`
+	Status			string		`json:"status"`
+	NextRunAt		time.Time	`json:"next_run_at"`
+	EndAt			*time.Time	`json:"end_at,omitempty"`
+	RetryCount		int32		`json:"retry_count"`
+	CreatedAt		time.Time	`json:"created_at"`
+	UpdatedAt		time.Time	`json:"updated_at"`
+}
+
+func newScheduledTransferResponse(scheduledTransfer db.ScheduledTransfer) scheduledTransferResponse {
+	rsp := scheduledTransferResponse{
+		ID:				scheduledTransfer.ID,
+		Owner:			scheduledTransfer.Owner,
+		FromAccountID:	scheduledTransfer.FromAccountID,
+		ToAccountID:	scheduledTransfer.ToAccountID,
+		Amount:			scheduledTransfer.Amount,
+		Schedule:		scheduledTransfer.Schedule,
+		Status:			scheduledTransfer.Status,
+		NextRunAt:		scheduledTransfer.NextRunAt,
+		RetryCount:		scheduledTransfer.RetryCount,
+		CreatedAt:		scheduledTransfer.CreatedAt,
+		UpdatedAt:		scheduledTransfer.UpdatedAt,
+	}
+	if scheduledTransfer.EndAt.Valid {
+		rsp.EndAt = &scheduledTransfer.EndAt.Time
+	}
+	return rsp
+}
+
+type createScheduledTransferRequest struct {
+	FromAccountID	int64		`json:"from_account_id" binding:"required,min=1"`
+	ToAccountID		int64		`json:"to_account_id" binding:"required,min=1"`
+	Amount			int64		`json:"amount" binding:"required,gt=0"`
+	Currency		string		`json:"currency" binding:"required,currency"`
+	// Schedule 例如 "@monthly 1" 表示每月 1 号转账
+	Schedule		string		`json:"schedule" binding:"required,schedule"`
+	// StartAt 第一次转账不早于该时间，默认为当前时间
+	StartAt			*time.Time	`json:"start_at"`
+	EndAt			*time.Time	`json:"end_at"`
+}
+
+// createScheduledTransfer 创建定时转账，只支持相同币种的账户之间转账
+func (server *Server) createScheduledTransfer(ctx *gin.Context) {
+	var req createScheduledTransferRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	now := time.Now()
+	start := now
+	if req.StartAt != nil {
+		if req.StartAt.Before(now) {
+			err := fmt.Errorf("start_at must not be in the past")
+			ctx.JSON(http.StatusBadRequest, errorResponse(err))
+			return
+		}
+		start = *req.StartAt
+	}
+
+	// 已经通过了 schedule 验证器，这里不会出错
+	schedule, _ := util.ParseSchedule(req.Schedule)
+	scheduledFor := schedule.First(start)
+	if req.EndAt != nil && scheduledFor.After(*req.EndAt) {
+		err := fmt.Errorf("end_at must be after the first run at %v", scheduledFor)
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	fromAccount, valid := server.validAccount(ctx, req.FromAccountID, req.Currency)
+	if !valid {
+		return
+	}
+
+	if !server.authorizeAccount(ctx, fromAccount, writeAccess) {
+		return
+	}
+
+	_, valid = server.validAccount(ctx, req.ToAccountID, req.Currency)
+	if !valid {
+		return
+	}
+
+	arg := db.CreateScheduledTransferParams{
+		Owner:			fromAccount.Owner,
+		FromAccountID:	req.FromAccountID,
+		ToAccountID:	req.ToAccountID,
+		Amount:			req.Amount,
+		Schedule:		req.Schedule,
+		ScheduledFor:	scheduledFor,
+		NextRunAt:		scheduledFor,
+	}
+	if req.EndAt != nil {
+		arg.EndAt = sql.NullTime{Time: *req.EndAt, Valid: true}
+	}
+
+	scheduledTransfer, err := server.store.CreateScheduledTransfer(ctx, arg)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+	ctx.JSON(http.StatusOK, newScheduledTransferResponse(scheduledTransfer))
+}
+
+type scheduledTransferURI struct {
+	ID	int64	`uri:"id" binding:"required,min=1"`
+}
+
+// fetchScheduledTransfer 查询定时转账并检查权限，检查不通过时已经写入了响应
+func (server *Server) fetchScheduledTransfer(ctx *gin.Context, access accountAccess) (db.ScheduledTransfer, bool) {
+	var uri scheduledTransferURI
+	if err := ctx.ShouldBindUri(&uri); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return db.ScheduledTransfer{}, false
+	}
+
+	scheduledTransfer, err := server.store.GetScheduledTransfer(ctx, uri.ID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			ctx.JSON(http.StatusNotFound, errorResponse(err))
+			return scheduledTransfer, false
+		}
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return scheduledTransfer, false
+	}
+
+	if !server.authorizeOwner(ctx, scheduledTransfer.Owner, access, errScheduledTransferForbidden) {
+		return scheduledTransfer, false
+	}
+	return scheduledTransfer, true
+}
+
+// getScheduledTransfer 查询定时转账，银行职员可以查看所有客户的定时转账
+func (server *Server) getScheduledTransfer(ctx *gin.Context) {
+	scheduledTransfer, ok := server.fetchScheduledTransfer(ctx, readAccess)
+	if !ok {
+		return
+	}
+	ctx.JSON(http.StatusOK, newScheduledTransferResponse(scheduledTransfer))
+}
+
+type listScheduledTransfersRequest struct {
+	pageRequest
+}
+
+// listScheduledTransfers 分页查询当前用户的定时转账
+func (server *Server) listScheduledTransfers(ctx *gin.Context) {
+	var req listScheduledTransfersRequest
+	if err := ctx.ShouldBindQuery(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	page, valid := server.parsePage(ctx, req.pageRequest)
+	if !valid {
+		return
+	}
+
+	authPayload, ok := authPayloadFromContext(ctx)
+	if !ok {
+		ctx.JSON(http.StatusUnauthorized, errorResponse(errUnauthenticated))
+		return
+	}
+
+	scheduledTransfers, err := server.store.ListScheduledTransfers(ctx, db.ListScheduledTransfersParams{
+		Owner:				authPayload.Username,
+		CursorCreatedAt:	page.after().CreatedAt,
+		CursorID:			page.after().ID,
+		PageSize:			page.limit(),
+	})
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	n, next := page.next(len(scheduledTransfers), func(i int) pageCursor {
+		return pageCursor{CreatedAt: scheduledTransfers[i].CreatedAt, ID: scheduledTransfers[i].ID}
+	})
+	rsp := make([]scheduledTransferResponse, n)
+	for i, scheduledTransfer := range scheduledTransfers[:n] {
+		rsp[i] = newScheduledTransferResponse(scheduledTransfer)
+	}
+	ctx.JSON(http.StatusOK, pageResponse{Items: rsp, NextCursor: next})
+}
+
+type updateScheduledTransferRequest struct {
+	Amount		*int64		`json:"amount" binding:"omitempty,gt=0"`
+	Schedule	*string		`json:"schedule" binding:"omitempty,schedule"`
+	EndAt		*time.Time	`json:"end_at"`
+	// Status 只能暂停或者恢复，取消使用 DELETE
+	Status		*string		`json:"status" binding:"omitempty,oneof=active paused"`
+}
+
+// updateScheduledTransfer 修改定时转账，只有所有者可以修改
+func (server *Server) updateScheduledTransfer(ctx *gin.Context) {
+	scheduledTransfer, ok := server.fetchScheduledTransfer(ctx, writeAccess)
+	if !ok {
+		return
+	}
+
+	var req updateScheduledTransferRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	if isScheduledTransferFinished(scheduledTransfer) {
+		ctx.JSON(http.StatusUnprocessableEntity, errorResponse(errScheduledTransferFinished))
+		return
+	}
+
+	arg := db.UpdateScheduledTransferParams{
+		ID:				scheduledTransfer.ID,
+		Amount:			scheduledTransfer.Amount,
+		Schedule:		scheduledTransfer.Schedule,
+		Status:			scheduledTransfer.Status,
+		ScheduledFor:	scheduledTransfer.ScheduledFor,
+		NextRunAt:		scheduledTransfer.NextRunAt,
+		EndAt:			scheduledTransfer.EndAt,
+		RetryCount:		scheduledTransfer.RetryCount,
+	}
+	if req.Amount != nil {
+		arg.Amount = *req.Amount
+	}
+	if req.Status != nil {
+		arg.Status = *req.Status
+	}
+	if req.EndAt != nil {
+		arg.EndAt = sql.NullTime{Time: *req.EndAt, Valid: true}
+	}
+	if req.Schedule != nil && *req.Schedule != scheduledTransfer.Schedule {
+		// 修改了规则，从还没有执行的时间点开始重新计算
+		now := time.Now()
+		anchor := scheduledTransfer.ScheduledFor
+		if anchor.Before(now) {
+			anchor = now
+		}
+		schedule, _ := util.ParseSchedule(*req.Schedule)
+		arg.Schedule = *req.Schedule
+		arg.ScheduledFor = schedule.First(anchor)
+		arg.NextRunAt = arg.ScheduledFor
+		arg.RetryCount = 0
+	}
+	if arg.EndAt.Valid && arg.ScheduledFor.After(arg.EndAt.Time) {
+		err := fmt.Errorf("end_at must be after the next run at %v", arg.ScheduledFor)
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	scheduledTransfer, err := server.store.UpdateScheduledTransfer(ctx, arg)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+	ctx.JSON(http.StatusOK, newScheduledTransferResponse(scheduledTransfer))
+}
+
+// cancelScheduledTransfer 取消定时转账，保留执行记录
+func (server *Server) cancelScheduledTransfer(ctx *gin.Context) {
+	scheduledTransfer, ok := server.fetchScheduledTransfer(ctx, writeAccess)
+	if !ok {
+		return
+	}
+
+	if isScheduledTransferFinished(scheduledTransfer) {
+		ctx.JSON(http.StatusUnprocessableEntity, errorResponse(errScheduledTransferFinished))
+		return
+	}
+
+	scheduledTransfer, err := server.store.UpdateScheduledTransfer(ctx, db.UpdateScheduledTransferParams{
+		ID:				scheduledTransfer.ID,
+		Amount:			scheduledTransfer.Amount,
+		Schedule:		scheduledTransfer.Schedule,
+		Status:			util.ScheduleCancelled,
+		ScheduledFor:	scheduledTransfer.ScheduledFor,
+		NextRunAt:		scheduledTransfer.NextRunAt,
+		EndAt:			scheduledTransfer.EndAt,
+		RetryCount:		scheduledTransfer.RetryCount,
+	})
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+	ctx.JSON(http.StatusOK, newScheduledTransferResponse(scheduledTransfer))
+}
+
+type listScheduledTransferRunsRequest struct {
+	pageRequest
+}
+
+// listScheduledTransferRuns 分页查询定时转账的执行记录，最近的在前
+func (server *Server) listScheduledTransferRuns(ctx *gin.Context) {
+	scheduledTransfer, ok := server.fetchScheduledTransfer(ctx, readAccess)
+	if !ok {
+		return
+	}
+
+	var req listScheduledTransferRunsRequest
+	if err := ctx.ShouldBindQuery(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	page, valid := server.parsePage(ctx, req.pageRequest)
+	if !valid {
+		return
+	}
+
+	runs, err := server.store.ListScheduledTransferRuns(ctx, db.ListScheduledTransferRunsParams{
+		ScheduledTransferID:	scheduledTransfer.ID,
+		CursorCreatedAt:		page.before().CreatedAt,
+		CursorID:				page.before().ID,
+		PageSize:				page.limit(),
+	})
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	n, next := page.next(len(runs), func(i int) pageCursor {
+		return pageCursor{CreatedAt: runs[i].CreatedAt, ID: runs[i].ID}
+	})
+	ctx.JSON(http.StatusOK, pageResponse{Items: runs[:n], NextCursor: next})
+}
+
+func isScheduledTransferFinished(scheduledTransfer db.ScheduledTransfer) bool {
+	return scheduledTransfer.Status == util.ScheduleCompleted || scheduledTransfer.Status == util.ScheduleCancelled
+}
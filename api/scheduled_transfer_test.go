@@ -0,0 +1,483 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/require"
+	mockdb "github.com/techschool/simplebank/db/mock"
+	db "github.com/techschool/simplebank/db/sqlc"
+	"github.com/techschool/simplebank/util"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestCreateScheduledTransferAPI(t *testing.T) {
+	amount := int64(10)
+
+	user1, _ := randomUser(t)
+	user2, _ := randomUser(t)
+
+	account1 := randomAccount(user1.Username)
+	account2 := randomAccount(user2.Username)
+	account1.Currency = util.USD
+	account2.Currency = util.USD
+
+	startAt := time.Now().Add(time.Hour).UTC().Truncate(time.Second)
+
+	testCases := []struct{
+		name			string
+		body			gin.H
+		user			db.User
+		buildStubs		func(store *mockdb.MockStore)
+		checkResponse	func(t *testing.T, recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name: "OK",
+			body: gin.H{
+				"from_account_id":	account1.ID,
+				"to_account_id":	account2.ID,
+				"amount":			amount,
+				"currency":			util.USD,
+				"schedule":			"@every 48h",
+				"start_at":			startAt,
+			},
+			user: user1,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account1.ID)).Times(1).Return(account1, nil)
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account2.ID)).Times(1).Return(account2, nil)
+
+				arg := db.CreateScheduledTransferParams{
+					Owner:			user1.Username,
+					FromAccountID:	account1.ID,
+					ToAccountID:	account2.ID,
+					Amount:			amount,
+					Schedule:		"@every 48h",
+					ScheduledFor:	startAt,
+					NextRunAt:		startAt,
+				}
+				store.EXPECT().
+					CreateScheduledTransfer(gomock.Any(), gomock.Any()).
+					Times(1).
+					DoAndReturn(func(_ context.Context, got db.CreateScheduledTransferParams) (db.ScheduledTransfer, error) {
+						require.True(t, arg.ScheduledFor.Equal(got.ScheduledFor))
+						got.ScheduledFor = arg.ScheduledFor
+						got.NextRunAt = arg.NextRunAt
+						require.Equal(t, arg, got)
+						return db.ScheduledTransfer{ID: 1, Owner: got.Owner, Status: util.ScheduleActive}, nil
+					})
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+			},
+		},
+		{
+			name: "InvalidSchedule",
+			body: gin.H{
+				"from_account_id":	account1.ID,
+				"to_account_id":	account2.ID,
+				"amount":			amount,
+				"currency":			util.USD,
+				"schedule":			"0 0 1 * *",
+			},
+			user: user1,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Any()).Times(0)
+				store.EXPECT().CreateScheduledTransfer(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+		{
+			name: "StartInThePast",
+			body: gin.H{
+				"from_account_id":	account1.ID,
+				"to_account_id":	account2.ID,
+				"amount":			amount,
+				"currency":			util.USD,
+				"schedule":			"@daily",
+				"start_at":			time.Now().Add(-time.Hour),
+			},
+			user: user1,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().CreateScheduledTransfer(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+		{
+			name: "EndBeforeFirstRun",
+			body: gin.H{
+				"from_account_id":	account1.ID,
+				"to_account_id":	account2.ID,
+				"amount":			amount,
+				"currency":			util.USD,
+				"schedule":			"@daily",
+				"start_at":			startAt,
+				"end_at":			startAt.Add(-time.Minute),
+			},
+			user: user1,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().CreateScheduledTransfer(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+		{
+			name: "UnauthorizedUser",
+			body: gin.H{
+				"from_account_id":	account1.ID,
+				"to_account_id":	account2.ID,
+				"amount":			amount,
+				"currency":			util.USD,
+				"schedule":			"@daily",
+			},
+			user: user2,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account1.ID)).Times(1).Return(account1, nil)
+				store.EXPECT().CreateScheduledTransfer(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusForbidden, recorder.Code)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			buildAuthStubs(store, tc.user.Username)
+			tc.buildStubs(store)
+
+			server := newTestServer(t, store)
+			recorder := httptest.NewRecorder()
+
+			data, err := json.Marshal(tc.body)
+			require.NoError(t, err)
+
+			request, err := http.NewRequest(http.MethodPost, "/transfers/scheduled", bytes.NewReader(data))
+			require.NoError(t, err)
+
+			addAuthorization(t, request, server.tokenMaker, authorizationTypeBearer, tc.user.Username, tc.user.Role, time.Minute)
+			server.router.ServeHTTP(recorder, request)
+			tc.checkResponse(t, recorder)
+		})
+	}
+}
+
+func TestGetScheduledTransferAPI(t *testing.T) {
+	user1, _ := randomUser(t)
+	user2, _ := randomUser(t)
+	banker, _ := randomUser(t)
+	banker.Role = util.BankerRole
+
+	scheduledTransfer := randomScheduledTransfer(user1.Username)
+
+	testCases := []struct{
+		name			string
+		user			db.User
+		buildStubs		func(store *mockdb.MockStore)
+		checkResponse	func(t *testing.T, recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name: "OK",
+			user: user1,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetScheduledTransfer(gomock.Any(), gomock.Eq(scheduledTransfer.ID)).Times(1).Return(scheduledTransfer, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+
+				var rsp scheduledTransferResponse
+				err := json.Unmarshal(recorder.Body.Bytes(), &rsp)
+				require.NoError(t, err)
+				require.Equal(t, scheduledTransfer.ID, rsp.ID)
+				require.Equal(t, scheduledTransfer.Schedule, rsp.Schedule)
+				require.Nil(t, rsp.EndAt)
+			},
+		},
+		{
+			name: "BankerCanRead",
+			user: banker,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetScheduledTransfer(gomock.Any(), gomock.Eq(scheduledTransfer.ID)).Times(1).Return(scheduledTransfer, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+			},
+		},
+		{
+			name: "UnauthorizedUser",
+			user: user2,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetScheduledTransfer(gomock.Any(), gomock.Eq(scheduledTransfer.ID)).Times(1).Return(scheduledTransfer, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusForbidden, recorder.Code)
+			},
+		},
+		{
+			name: "NotFound",
+			user: user1,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetScheduledTransfer(gomock.Any(), gomock.Any()).Times(1).Return(db.ScheduledTransfer{}, sql.ErrNoRows)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusNotFound, recorder.Code)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			buildAuthStubs(store, tc.user.Username)
+			tc.buildStubs(store)
+
+			server := newTestServer(t, store)
+			recorder := httptest.NewRecorder()
+
+			url := fmt.Sprintf("/transfers/scheduled/%d", scheduledTransfer.ID)
+			request, err := http.NewRequest(http.MethodGet, url, nil)
+			require.NoError(t, err)
+
+			addAuthorization(t, request, server.tokenMaker, authorizationTypeBearer, tc.user.Username, tc.user.Role, time.Minute)
+			server.router.ServeHTTP(recorder, request)
+			tc.checkResponse(t, recorder)
+		})
+	}
+}
+
+func TestUpdateScheduledTransferAPI(t *testing.T) {
+	user1, _ := randomUser(t)
+	banker, _ := randomUser(t)
+	banker.Role = util.BankerRole
+
+	scheduledTransfer := randomScheduledTransfer(user1.Username)
+	cancelled := scheduledTransfer
+	cancelled.Status = util.ScheduleCancelled
+
+	testCases := []struct{
+		name			string
+		method			string
+		body			gin.H
+		user			db.User
+		buildStubs		func(store *mockdb.MockStore)
+		checkResponse	func(t *testing.T, recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name: "Pause",
+			method: http.MethodPatch,
+			body: gin.H{"status": util.SchedulePaused, "amount": 20},
+			user: user1,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetScheduledTransfer(gomock.Any(), gomock.Eq(scheduledTransfer.ID)).Times(1).Return(scheduledTransfer, nil)
+				store.EXPECT().
+					UpdateScheduledTransfer(gomock.Any(), gomock.Any()).
+					Times(1).
+					DoAndReturn(func(_ context.Context, arg db.UpdateScheduledTransferParams) (db.ScheduledTransfer, error) {
+						require.Equal(t, util.SchedulePaused, arg.Status)
+						require.Equal(t, int64(20), arg.Amount)
+						require.Equal(t, scheduledTransfer.Schedule, arg.Schedule)
+						require.Equal(t, scheduledTransfer.NextRunAt, arg.NextRunAt)
+						return scheduledTransfer, nil
+					})
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+			},
+		},
+		{
+			name: "ChangeSchedule",
+			method: http.MethodPatch,
+			body: gin.H{"schedule": "@monthly 1"},
+			user: user1,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetScheduledTransfer(gomock.Any(), gomock.Eq(scheduledTransfer.ID)).Times(1).Return(scheduledTransfer, nil)
+				store.EXPECT().
+					UpdateScheduledTransfer(gomock.Any(), gomock.Any()).
+					Times(1).
+					DoAndReturn(func(_ context.Context, arg db.UpdateScheduledTransferParams) (db.ScheduledTransfer, error) {
+						require.Equal(t, "@monthly 1", arg.Schedule)
+						require.Equal(t, 1, arg.ScheduledFor.Day())
+						require.False(t, arg.ScheduledFor.Before(scheduledTransfer.ScheduledFor))
+						require.Equal(t, arg.ScheduledFor, arg.NextRunAt)
+						return scheduledTransfer, nil
+					})
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+			},
+		},
+		{
+			name: "InvalidStatus",
+			method: http.MethodPatch,
+			body: gin.H{"status": util.ScheduleCompleted},
+			user: user1,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetScheduledTransfer(gomock.Any(), gomock.Eq(scheduledTransfer.ID)).Times(1).Return(scheduledTransfer, nil)
+				store.EXPECT().UpdateScheduledTransfer(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+		{
+			name: "BankerCannotUpdate",
+			method: http.MethodPatch,
+			body: gin.H{"status": util.SchedulePaused},
+			user: banker,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetScheduledTransfer(gomock.Any(), gomock.Eq(scheduledTransfer.ID)).Times(1).Return(scheduledTransfer, nil)
+				store.EXPECT().UpdateScheduledTransfer(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusForbidden, recorder.Code)
+			},
+		},
+		{
+			name: "Cancel",
+			method: http.MethodDelete,
+			user: user1,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetScheduledTransfer(gomock.Any(), gomock.Eq(scheduledTransfer.ID)).Times(1).Return(scheduledTransfer, nil)
+				store.EXPECT().
+					UpdateScheduledTransfer(gomock.Any(), gomock.Any()).
+					Times(1).
+					DoAndReturn(func(_ context.Context, arg db.UpdateScheduledTransferParams) (db.ScheduledTransfer, error) {
+						require.Equal(t, util.ScheduleCancelled, arg.Status)
+						return cancelled, nil
+					})
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+			},
+		},
+		{
+			name: "AlreadyCancelled",
+			method: http.MethodDelete,
+			user: user1,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetScheduledTransfer(gomock.Any(), gomock.Eq(scheduledTransfer.ID)).Times(1).Return(cancelled, nil)
+				store.EXPECT().UpdateScheduledTransfer(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnprocessableEntity, recorder.Code)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			buildAuthStubs(store, tc.user.Username)
+			tc.buildStubs(store)
+
+			server := newTestServer(t, store)
+			recorder := httptest.NewRecorder()
+
+			data, err := json.Marshal(tc.body)
+			require.NoError(t, err)
+
+			url := fmt.Sprintf("/transfers/scheduled/%d", scheduledTransfer.ID)
+			request, err := http.NewRequest(tc.method, url, bytes.NewReader(data))
+			require.NoError(t, err)
+
+			addAuthorization(t, request, server.tokenMaker, authorizationTypeBearer, tc.user.Username, tc.user.Role, time.Minute)
+			server.router.ServeHTTP(recorder, request)
+			tc.checkResponse(t, recorder)
+		})
+	}
+}
+
+func TestListScheduledTransferRunsAPI(t *testing.T) {
+	user, _ := randomUser(t)
+	scheduledTransfer := randomScheduledTransfer(user.Username)
+
+	n := 5
+	runs := make([]db.ScheduledTransferRun, n)
+	for i := range runs {
+		runs[i] = db.ScheduledTransferRun{
+			ID:						int64(i + 1),
+			ScheduledTransferID:	scheduledTransfer.ID,
+			Attempt:				1,
+			Status:					"succeeded",
+		}
+	}
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	store := mockdb.NewMockStore(ctrl)
+	buildAuthStubs(store, user.Username)
+	store.EXPECT().GetScheduledTransfer(gomock.Any(), gomock.Eq(scheduledTransfer.ID)).Times(1).Return(scheduledTransfer, nil)
+	store.EXPECT().
+		ListScheduledTransferRuns(gomock.Any(), gomock.Eq(db.ListScheduledTransferRunsParams{
+			ScheduledTransferID:	scheduledTransfer.ID,
+			CursorCreatedAt:		maxCursorTime,
+			CursorID:				math.MaxInt64,
+			PageSize:				int32(n),
+		})).
+		Times(1).
+		Return(runs, nil)
+
+	server := newTestServer(t, store)
+	recorder := httptest.NewRecorder()
+
+	url := fmt.Sprintf("/transfers/scheduled/%d/runs?page_size=%d", scheduledTransfer.ID, n-1)
+	request, err := http.NewRequest(http.MethodGet, url, nil)
+	require.NoError(t, err)
+
+	addAuthorization(t, request, server.tokenMaker, authorizationTypeBearer, user.Username, user.Role, time.Minute)
+	server.router.ServeHTTP(recorder, request)
+	require.Equal(t, http.StatusOK, recorder.Code)
+
+	var got struct {
+		Items		[]db.ScheduledTransferRun	`json:"items"`
+		NextCursor	string						`json:"next_cursor"`
+	}
+	err = json.Unmarshal(recorder.Body.Bytes(), &got)
+	require.NoError(t, err)
+	require.Len(t, got.Items, n-1)
+	require.NotEmpty(t, got.NextCursor)
+}
+
+func randomScheduledTransfer(owner string) db.ScheduledTransfer {
+	nextRunAt := time.Now().Add(time.Hour).UTC().Truncate(time.Second)
+	return db.ScheduledTransfer{
+		ID:				util.RandomInt(1, 1000),
+		Owner:			owner,
+		FromAccountID:	util.RandomInt(1, 1000),
+		ToAccountID:	util.RandomInt(1, 1000),
+		Amount:			util.RandomMoney(),
+		Schedule:		"@daily",
+		Status:			util.ScheduleActive,
+		ScheduledFor:	nextRunAt,
+		NextRunAt:		nextRunAt,
+	}
+}
@@ -6,6 +6,7 @@ import (
 	"github.com/gin-gonic/gin/binding"
 	"github.com/go-playground/validator/v10"
 	db "github.com/techschool/simplebank/db/sqlc"
+	"github.com/techschool/simplebank/fraud"
 	"github.com/techschool/simplebank/token"
 	"github.com/techschool/simplebank/util"
 	"log"
@@ -16,20 +17,36 @@ type Server struct {
 	config		util.Config
 	store 		db.Store
 	tokenMaker 	token.Maker
+	// approvalThresholds 各币种单笔转账不需要审批的最大金额
+	approvalThresholds	map[string]int64
+	// fraudChecker 转账前的风控检查
+	fraudChecker	fraud.Checker
 	router 		*gin.Engine  // 初始化时，并不传入这个参数，在gin.Default()得到*gin.Engine后传入
 }
 
 // NewServer create a new HTTP server and setup router.
 func NewServer(config util.Config, store db.Store) (*Server, error) {
-	tokenMaker, err := token.NewPasetoMaker(config.TokenSymmetricKey)
+	tokenMaker, err := newTokenMaker(config)
 	if err != nil {
 		return nil, fmt.Errorf("cannot create token maker, err: %v", err)
 	}
 
+	approvalThresholds, err := util.ParseCurrencyAmounts(config.ApprovalThresholds)
+	if err != nil {
+		return nil, fmt.Errorf("cannot parse approval thresholds, err: %v", err)
+	}
+
+	fraudChecker, err := fraud.LoadRuleEngine(config.FraudRulesPath)
+	if err != nil {
+		return nil, fmt.Errorf("cannot load fraud rules, err: %v", err)
+	}
+
 	server := &Server{
 		config: config,
 		store: store,
 		tokenMaker: tokenMaker,
+		approvalThresholds: approvalThresholds,
+		fraudChecker: fraudChecker,
 	}
 
 	// currency注册验证器
@@ -38,23 +55,100 @@ func NewServer(config util.Config, store db.Store) (*Server, error) {
 		if err != nil {
 			log.Fatalf("failed register currency validator, err: %v", err)
 		}
+		err = v.RegisterValidation("role", validRole)
+		if err != nil {
+			log.Fatalf("failed register role validator, err: %v", err)
+		}
+		err = v.RegisterValidation("exchange_rate", validExchangeRate)
+		if err != nil {
+			log.Fatalf("failed register exchange_rate validator, err: %v", err)
+		}
+		err = v.RegisterValidation("schedule", validSchedule)
+		if err != nil {
+			log.Fatalf("failed register schedule validator, err: %v", err)
+		}
+		err = v.RegisterValidation("limit_period", validLimitPeriod)
+		if err != nil {
+			log.Fatalf("failed register limit_period validator, err: %v", err)
+		}
+		err = v.RegisterValidation("metadata", validMetadata)
+		if err != nil {
+			log.Fatalf("failed register metadata validator, err: %v", err)
+		}
 	}
 
 	server.setupRouter()
 	return server, nil
 }
 
+// newTokenMaker 配置了私钥时使用非对称的 PASETO v2.public，否则使用对称的 v2.local
+func newTokenMaker(config util.Config) (token.Maker, error) {
+	if len(config.TokenPrivateKey) == 0 {
+		return token.NewPasetoMaker(config.TokenSymmetricKey)
+	}
+
+	privateKey, err := token.ParsePrivateKey(config.TokenPrivateKey)
+	if err != nil {
+		return nil, err
+	}
+	verificationKeys, err := token.ParsePublicKeys(config.TokenVerificationKeys)
+	if err != nil {
+		return nil, err
+	}
+	return token.NewPasetoPublicMaker(config.TokenKeyID, privateKey, verificationKeys)
+}
+
 func (server *Server) setupRouter()  {
 	router := gin.Default()
 
-	authRouter := router.Group("/").Use(authMiddleware(server.tokenMaker))
-	authRouter.POST("/accounts", server.createAccount)
+	authRouter := router.Group("/").Use(authMiddleware(server.tokenMaker, server.store))
+	authRouter.POST("/accounts", authorizeRoles(util.DepositorRole), server.createAccount)
 	authRouter.GET("/accounts/:id", server.getAccount)
 	authRouter.GET("/accounts", server.listAccount)
-	authRouter.POST("/transfers", server.createTransfer)
+	authRouter.GET("/accounts/:id/balance", server.getAccountBalance)
+	authRouter.GET("/accounts/:id/balance/daily", server.listDailyBalances)
+	authRouter.GET("/accounts/:id/statements", server.listStatements)
+	authRouter.GET("/accounts/:id/statements/:statement_id", server.getStatement)
+	authRouter.GET("/accounts/:id/entries", server.listAccountEntries)
+	authRouter.GET("/accounts/:id/transfers", server.listAccountTransfers)
+	authRouter.POST("/transfers", authorizeRoles(util.DepositorRole), server.createTransfer)
+	authRouter.GET("/transfers", server.listTransfers)
+	authRouter.GET("/recipients", server.getRecipient)
+	authRouter.POST("/transfers/batch", authorizeRoles(util.DepositorRole), server.createBatchTransfer)
+	authRouter.POST("/transfers/:id/reverse", server.reverseTransfer)
+	authRouter.POST("/transfers/:id/approve", authorizeRoles(util.BankerRole, util.AdminRole), server.approveTransfer)
+	authRouter.POST("/transfers/:id/reject", authorizeRoles(util.BankerRole, util.AdminRole), server.rejectTransfer)
+	authRouter.POST("/transfers/scheduled", authorizeRoles(util.DepositorRole), server.createScheduledTransfer)
+	authRouter.GET("/transfers/scheduled", server.listScheduledTransfers)
+	authRouter.GET("/transfers/scheduled/:id", server.getScheduledTransfer)
+	authRouter.PATCH("/transfers/scheduled/:id", server.updateScheduledTransfer)
+	authRouter.DELETE("/transfers/scheduled/:id", server.cancelScheduledTransfer)
+	authRouter.GET("/transfers/scheduled/:id/runs", server.listScheduledTransferRuns)
+	authRouter.POST("/deposits", authorizeRoles(util.BankerRole, util.AdminRole), server.createDeposit)
+	authRouter.POST("/withdrawals", authorizeRoles(util.BankerRole, util.AdminRole), server.createWithdrawal)
+	authRouter.POST("/holds", authorizeRoles(util.DepositorRole), server.createHold)
+	authRouter.GET("/holds/:id", server.getHold)
+	authRouter.POST("/holds/:id/capture", server.captureHold)
+	authRouter.POST("/holds/:id/void", server.voidHold)
+	authRouter.POST("/users/logout", server.logoutUser)
+	authRouter.POST("/users/logout_all", server.logoutAllUser)
+	authRouter.PATCH("/users/:username/role", authorizeRoles(util.AdminRole), server.updateUserRole)
+	authRouter.POST("/exchange_rates", authorizeRoles(util.AdminRole), server.createExchangeRate)
+	authRouter.GET("/exchange_rates", server.listExchangeRates)
+	authRouter.GET("/fraud_decisions", authorizeRoles(util.BankerRole, util.AdminRole), server.listFraudDecisions)
+	authRouter.PUT("/transfer_limits", authorizeRoles(util.AdminRole), server.setTransferLimit)
+	authRouter.GET("/transfer_limits", authorizeRoles(util.BankerRole, util.AdminRole), server.listTransferLimits)
+	authRouter.DELETE("/transfer_limits/:id", authorizeRoles(util.AdminRole), server.deleteTransferLimit)
+	authRouter.POST("/reconciliation_reports", authorizeRoles(util.AdminRole), server.runReconciliation)
+	authRouter.GET("/reconciliation_reports", authorizeRoles(util.AdminRole), server.listReconciliationReports)
+	authRouter.GET("/reconciliation_reports/:id/discrepancies", authorizeRoles(util.AdminRole), server.listReconciliationDiscrepancies)
+	authRouter.GET("/entries/verify", authorizeRoles(util.AdminRole), server.verifyEntryChain)
 
 	router.POST("/users", server.createUser)
 	router.POST("/users/login", server.loginUser)
+	router.POST("/tokens/renew_access", server.renewAccessToken)
+	router.GET("/tokens/public_keys", server.listPublicKeys)
+	router.GET("/currencies", server.listCurrencies)
 
 	server.router = router
 }
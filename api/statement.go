@@ -0,0 +1,128 @@
+package api
+
+import (
+	"bytes"
+	"database/sql"
+	"errors"
+	"fmt"
+	"github.com/gin-gonic/gin"
+	db "github.com/techschool/simplebank/db/sqlc"
+	"github.com/techschool/simplebank/statement"
+	"net/http"
+)
+
+var errStatementNotFound = errors.New("statement not found")
+
+type listStatementsRequest struct {
+	pageRequest
+}
+
+// listStatements 分页查询账户的对账单，不包含流水明细，最新生成的在前
+func (server *Server) listStatements(ctx *gin.Context) {
+	var uri getAccountRequest
+	if err := ctx.ShouldBindUri(&uri); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+	var req listStatementsRequest
+	if err := ctx.ShouldBindQuery(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	page, valid := server.parsePage(ctx, req.pageRequest)
+	if !valid {
+		return
+	}
+
+	account, valid := server.fetchAccount(ctx, uri.ID)
+	if !valid {
+		return
+	}
+	if !server.authorizeAccount(ctx, account, readAccess) {
+		return
+	}
+
+	statements, err := server.store.ListStatements(ctx, db.ListStatementsParams{
+		AccountID:			account.ID,
+		CursorCreatedAt:	page.before().CreatedAt,
+		CursorID:			page.before().ID,
+		PageSize:			page.limit(),
+	})
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	n, next := page.next(len(statements), func(i int) pageCursor {
+		return pageCursor{CreatedAt: statements[i].CreatedAt, ID: statements[i].ID}
+	})
+	ctx.JSON(http.StatusOK, pageResponse{Items: statements[:n], NextCursor: next})
+}
+
+type getStatementURI struct {
+	AccountID	int64	`uri:"id" binding:"required,min=1"`
+	StatementID	int64	`uri:"statement_id" binding:"required,min=1"`
+}
+
+type getStatementRequest struct {
+	// Format 默认返回 JSON，csv 和 text 作为附件下载
+	Format	string	`form:"format" binding:"omitempty,oneof=json csv text"`
+}
+
+// getStatement 按指定的格式返回对账单
+func (server *Server) getStatement(ctx *gin.Context) {
+	var uri getStatementURI
+	if err := ctx.ShouldBindUri(&uri); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+	var req getStatementRequest
+	if err := ctx.ShouldBindQuery(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+	format := statement.JSON
+	if req.Format != "" {
+		format = statement.Format(req.Format)
+	}
+
+	account, valid := server.fetchAccount(ctx, uri.AccountID)
+	if !valid {
+		return
+	}
+	if !server.authorizeAccount(ctx, account, readAccess) {
+		return
+	}
+
+	saved, err := server.store.GetStatement(ctx, uri.StatementID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			ctx.JSON(http.StatusNotFound, errorResponse(errStatementNotFound))
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+	// 对账单不属于该账户时按不存在处理
+	if saved.AccountID != account.ID {
+		ctx.JSON(http.StatusNotFound, errorResponse(errStatementNotFound))
+		return
+	}
+
+	doc, err := statement.New(saved)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+	var buf bytes.Buffer
+	if err := statement.Write(&buf, format, doc); err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	if format != statement.JSON {
+		ctx.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", doc.FileName(format)))
+	}
+	ctx.Data(http.StatusOK, format.ContentType(), buf.Bytes())
+}
@@ -0,0 +1,207 @@
+package api
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/require"
+	mockdb "github.com/techschool/simplebank/db/mock"
+	db "github.com/techschool/simplebank/db/sqlc"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func randomStatement(t *testing.T, account db.Account) db.Statement {
+	start := time.Date(2021, time.March, 1, 0, 0, 0, 0, time.UTC)
+	lines, err := json.Marshal([]db.StatementLine{
+		{EntryID: 1, PostedAt: start.Add(time.Hour), Kind: "deposit", Amount: 100, Balance: 100},
+	})
+	require.NoError(t, err)
+
+	return db.Statement{
+		ID:				7,
+		AccountID:		account.ID,
+		Currency:		account.Currency,
+		PeriodStart:	start,
+		PeriodEnd:		start.AddDate(0, 1, 0),
+		ClosingBalance:	100,
+		TotalCredits:	100,
+		EntryCount:		1,
+		Lines:			lines,
+		CreatedAt:		start.AddDate(0, 1, 0),
+	}
+}
+
+func TestListStatementsAPI(t *testing.T) {
+	user, _ := randomUser(t)
+	account := randomAccount(user.Username)
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	store := mockdb.NewMockStore(ctrl)
+	buildAuthStubs(store, user.Username)
+	store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account.ID)).Times(1).Return(account, nil)
+	store.EXPECT().
+		ListStatements(gomock.Any(), gomock.Any()).
+		Times(1).
+		DoAndReturn(func(_ interface{}, arg db.ListStatementsParams) ([]db.ListStatementsRow, error) {
+			require.Equal(t, account.ID, arg.AccountID)
+			return []db.ListStatementsRow{{ID: 7, AccountID: account.ID, ClosingBalance: 100}}, nil
+		})
+
+	server := newTestServer(t, store)
+	recorder := httptest.NewRecorder()
+
+	request, err := http.NewRequest(http.MethodGet, fmt.Sprintf("/accounts/%d/statements", account.ID), nil)
+	require.NoError(t, err)
+
+	addAuthorization(t, request, server.tokenMaker, authorizationTypeBearer, user.Username, user.Role, time.Minute)
+	server.router.ServeHTTP(recorder, request)
+	require.Equal(t, http.StatusOK, recorder.Code)
+
+	var got struct {
+		Items	[]db.ListStatementsRow	`json:"items"`
+	}
+	require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &got))
+	require.Len(t, got.Items, 1)
+	require.Equal(t, int64(100), got.Items[0].ClosingBalance)
+}
+
+func TestGetStatementAPI(t *testing.T) {
+	user, _ := randomUser(t)
+	account := randomAccount(user.Username)
+	other, _ := randomUser(t)
+	saved := randomStatement(t, account)
+
+	testCases := []struct{
+		name			string
+		query			string
+		username		string
+		buildStubs		func(store *mockdb.MockStore)
+		checkResponse	func(t *testing.T, recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name: "JSON",
+			username: user.Username,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account.ID)).Times(1).Return(account, nil)
+				store.EXPECT().GetStatement(gomock.Any(), gomock.Eq(saved.ID)).Times(1).Return(saved, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+				require.Contains(t, recorder.Header().Get("Content-Type"), "application/json")
+
+				var got struct {
+					ClosingBalance	int64				`json:"closing_balance"`
+					Lines			[]db.StatementLine	`json:"lines"`
+				}
+				require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &got))
+				require.Equal(t, int64(100), got.ClosingBalance)
+				require.Len(t, got.Lines, 1)
+			},
+		},
+		{
+			name: "CSV",
+			query: "?format=csv",
+			username: user.Username,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account.ID)).Times(1).Return(account, nil)
+				store.EXPECT().GetStatement(gomock.Any(), gomock.Eq(saved.ID)).Times(1).Return(saved, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+				require.Contains(t, recorder.Header().Get("Content-Type"), "text/csv")
+				require.Contains(t, recorder.Header().Get("Content-Disposition"), ".csv")
+				require.Len(t, strings.Split(strings.TrimSpace(recorder.Body.String()), "\n"), 4)
+			},
+		},
+		{
+			name: "Text",
+			query: "?format=text",
+			username: user.Username,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account.ID)).Times(1).Return(account, nil)
+				store.EXPECT().GetStatement(gomock.Any(), gomock.Eq(saved.ID)).Times(1).Return(saved, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+				require.Contains(t, recorder.Body.String(), "Closing balance")
+			},
+		},
+		{
+			name: "InvalidFormat",
+			query: "?format=pdf",
+			username: user.Username,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetStatement(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+		{
+			name: "OtherAccount",
+			username: user.Username,
+			buildStubs: func(store *mockdb.MockStore) {
+				statement := saved
+				statement.AccountID = account.ID + 1
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account.ID)).Times(1).Return(account, nil)
+				store.EXPECT().GetStatement(gomock.Any(), gomock.Eq(saved.ID)).Times(1).Return(statement, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusNotFound, recorder.Code)
+			},
+		},
+		{
+			name: "NotFound",
+			username: user.Username,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account.ID)).Times(1).Return(account, nil)
+				store.EXPECT().GetStatement(gomock.Any(), gomock.Eq(saved.ID)).Times(1).Return(db.Statement{}, sql.ErrNoRows)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusNotFound, recorder.Code)
+			},
+		},
+		{
+			name: "Forbidden",
+			username: other.Username,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account.ID)).Times(1).Return(account, nil)
+				store.EXPECT().GetStatement(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusForbidden, recorder.Code)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			buildAuthStubs(store, tc.username)
+			tc.buildStubs(store)
+
+			server := newTestServer(t, store)
+			recorder := httptest.NewRecorder()
+
+			url := fmt.Sprintf("/accounts/%d/statements/%d%s", account.ID, saved.ID, tc.query)
+			request, err := http.NewRequest(http.MethodGet, url, nil)
+			require.NoError(t, err)
+
+			addAuthorization(t, request, server.tokenMaker, authorizationTypeBearer, tc.username, user.Role, time.Minute)
+			server.router.ServeHTTP(recorder, request)
+			tc.checkResponse(t, recorder)
+		})
+	}
+}
@@ -0,0 +1,135 @@
+package api
+
+import (
+	"database/sql"
+	"encoding/base64"
+	"errors"
+	"github.com/gin-gonic/gin"
+	"github.com/techschool/simplebank/token"
+	"net/http"
+	"sort"
+	"time"
+)
+
+type renewAccessTokenRequest struct {
+	RefreshToken	string	`json:"refresh_token" binding:"required"`
+}
+
+type renewAccessTokenResponse struct {
+	AccessToken				string		`json:"access_token"`
+	AccessTokenExpiresAt	time.Time	`json:"access_token_expires_at"`
+}
+
+// renewAccessToken 使用 refresh token 换取新的 access token
+func (server *Server) renewAccessToken(ctx *gin.Context) {
+	var req renewAccessTokenRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	refreshPayload, err := server.tokenMaker.VerifyToken(req.RefreshToken, token.TokenTypeRefreshToken)
+	if err != nil {
+		ctx.JSON(http.StatusUnauthorized, errorResponse(err))
+		return
+	}
+
+	// 修改密码或者 logout_all 之前签发的 refresh token 不能再换取 access token
+	err = checkTokenRevocation(ctx, server.store, refreshPayload)
+	if err != nil {
+		if err == errRevokedToken {
+			ctx.JSON(http.StatusUnauthorized, errorResponse(err))
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	session, err := server.store.GetSession(ctx, refreshPayload.ID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			ctx.JSON(http.StatusNotFound, errorResponse(err))
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	// session 被封禁后，该 refresh token 不能再使用
+	if session.IsBlocked {
+		err := errors.New("blocked session")
+		ctx.JSON(http.StatusUnauthorized, errorResponse(err))
+		return
+	}
+
+	if session.Username != refreshPayload.Username {
+		err := errors.New("incorrect session user")
+		ctx.JSON(http.StatusUnauthorized, errorResponse(err))
+		return
+	}
+
+	if session.RefreshToken != req.RefreshToken {
+		err := errors.New("mismatched session token")
+		ctx.JSON(http.StatusUnauthorized, errorResponse(err))
+		return
+	}
+
+	if time.Now().After(session.ExpiresAt) {
+		err := errors.New("expired session")
+		ctx.JSON(http.StatusUnauthorized, errorResponse(err))
+		return
+	}
+
+	accessToken, accessPayload, err := server.tokenMaker.CreateToken(
+		refreshPayload.Username,
+		refreshPayload.Role,
+		token.TokenTypeAccessToken,
+		server.config.AccessTokenDuration,
+	)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	rsp := renewAccessTokenResponse{
+		AccessToken:			accessToken,
+		AccessTokenExpiresAt:	accessPayload.ExpiredAt,
+	}
+	ctx.JSON(http.StatusOK, rsp)
+}
+
+type publicKeyResponse struct {
+	KeyID		string	`json:"kid"`
+	Algorithm	string	`json:"alg"`
+	PublicKey	string	`json:"public_key"`
+}
+
+type listPublicKeysResponse struct {
+	Keys	[]publicKeyResponse	`json:"keys"`
+}
+
+// listPublicKeys 返回用于验证 token 的公钥，供下游服务自行校验 token
+func (server *Server) listPublicKeys(ctx *gin.Context) {
+	provider, ok := server.tokenMaker.(token.PublicKeyProvider)
+	if !ok {
+		err := errors.New("token maker doesn't use public keys")
+		ctx.JSON(http.StatusNotFound, errorResponse(err))
+		return
+	}
+
+	publicKeys := provider.PublicKeys()
+	rsp := listPublicKeysResponse{
+		Keys: make([]publicKeyResponse, 0, len(publicKeys)),
+	}
+	for keyID, publicKey := range publicKeys {
+		rsp.Keys = append(rsp.Keys, publicKeyResponse{
+			KeyID:		keyID,
+			Algorithm:	"EdDSA",
+			PublicKey:	base64.StdEncoding.EncodeToString(publicKey),
+		})
+	}
+	sort.Slice(rsp.Keys, func(i, j int) bool {
+		return rsp.Keys[i].KeyID < rsp.Keys[j].KeyID
+	})
+	ctx.JSON(http.StatusOK, rsp)
+}
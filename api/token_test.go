@@ -0,0 +1,313 @@
+package api
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"crypto/rand"
+	"database/sql"
+	"encoding/base64"
+	"encoding/json"
+	"github.com/gin-gonic/gin"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/require"
+	mockdb "github.com/techschool/simplebank/db/mock"
+	db "github.com/techschool/simplebank/db/sqlc"
+	"github.com/techschool/simplebank/token"
+	"github.com/techschool/simplebank/util"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// TestRenewAccessTokenAPI 使用 refresh token 换取 access token 的测试
+func TestRenewAccessTokenAPI(t *testing.T) {
+	username := util.RandomUserName()
+
+	testCases := []struct{
+		name			string
+		buildSession	func(refreshToken string, payload *token.Payload) db.Session
+		sessionErr		error
+		checkResponse	func(t *testing.T, recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name: "OK",
+			buildSession: func(refreshToken string, payload *token.Payload) db.Session {
+				return newSession(refreshToken, payload)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+
+				var rsp renewAccessTokenResponse
+				err := json.Unmarshal(recorder.Body.Bytes(), &rsp)
+				require.NoError(t, err)
+				require.NotEmpty(t, rsp.AccessToken)
+			},
+		},
+		{
+			name: "SessionNotFound",
+			buildSession: func(refreshToken string, payload *token.Payload) db.Session {
+				return db.Session{}
+			},
+			sessionErr: sql.ErrNoRows,
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusNotFound, recorder.Code)
+			},
+		},
+		{
+			name: "InternalError",
+			buildSession: func(refreshToken string, payload *token.Payload) db.Session {
+				return db.Session{}
+			},
+			sessionErr: sql.ErrConnDone,
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusInternalServerError, recorder.Code)
+			},
+		},
+		{
+			name: "BlockedSession",
+			buildSession: func(refreshToken string, payload *token.Payload) db.Session {
+				session := newSession(refreshToken, payload)
+				session.IsBlocked = true
+				return session
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+			},
+		},
+		{
+			name: "IncorrectSessionUser",
+			buildSession: func(refreshToken string, payload *token.Payload) db.Session {
+				session := newSession(refreshToken, payload)
+				session.Username = util.RandomUserName()
+				return session
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+			},
+		},
+		{
+			name: "MismatchedSessionToken",
+			buildSession: func(refreshToken string, payload *token.Payload) db.Session {
+				session := newSession(refreshToken, payload)
+				session.RefreshToken = util.RandomString(32)
+				return session
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+			},
+		},
+		{
+			name: "ExpiredSession",
+			buildSession: func(refreshToken string, payload *token.Payload) db.Session {
+				session := newSession(refreshToken, payload)
+				session.ExpiresAt = time.Now().Add(-time.Minute)
+				return session
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			buildAuthStubs(store, username)
+			server := newTestServer(t, store)
+
+			refreshToken, payload, err := server.tokenMaker.CreateToken(username, util.DepositorRole, token.TokenTypeRefreshToken, time.Hour)
+			require.NoError(t, err)
+
+			session := tc.buildSession(refreshToken, payload)
+			store.EXPECT().
+				GetSession(gomock.Any(), gomock.Eq(payload.ID)).
+				Times(1).
+				Return(session, tc.sessionErr)
+
+			recorder := httptest.NewRecorder()
+			data, err := json.Marshal(gin.H{"refresh_token": refreshToken})
+			require.NoError(t, err)
+
+			url := "/tokens/renew_access"
+			request, err := http.NewRequest(http.MethodPost, url, bytes.NewReader(data))
+			require.NoError(t, err)
+
+			server.router.ServeHTTP(recorder, request)
+			tc.checkResponse(t, recorder)
+		})
+	}
+}
+
+func TestRenewAccessTokenInvalidToken(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	store := mockdb.NewMockStore(ctrl)
+	store.EXPECT().
+		GetSession(gomock.Any(), gomock.Any()).
+		Times(0)
+
+	server := newTestServer(t, store)
+	recorder := httptest.NewRecorder()
+
+	data, err := json.Marshal(gin.H{"refresh_token": "invalid"})
+	require.NoError(t, err)
+
+	request, err := http.NewRequest(http.MethodPost, "/tokens/renew_access", bytes.NewReader(data))
+	require.NoError(t, err)
+
+	server.router.ServeHTTP(recorder, request)
+	require.Equal(t, http.StatusUnauthorized, recorder.Code)
+}
+
+func TestRenewAccessTokenRevoked(t *testing.T) {
+	username := util.RandomUserName()
+
+	testCases := []struct{
+		name		string
+		buildStubs	func(store *mockdb.MockStore)
+		wantCode	int
+	}{
+		{
+			name: "RevokedToken",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().IsTokenRevoked(gomock.Any(), gomock.Any()).Times(1).Return(true, nil)
+			},
+			wantCode: http.StatusUnauthorized,
+		},
+		{
+			name: "IssuedBeforePasswordChange",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().IsTokenRevoked(gomock.Any(), gomock.Any()).Times(1).Return(false, nil)
+				store.EXPECT().
+					GetUser(gomock.Any(), gomock.Eq(username)).
+					Times(1).
+					Return(db.User{Username: username, PasswordChangeAt: time.Now().Add(time.Second)}, nil)
+			},
+			wantCode: http.StatusUnauthorized,
+		},
+		{
+			name: "IssuedBeforeLogoutAll",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().IsTokenRevoked(gomock.Any(), gomock.Any()).Times(1).Return(false, nil)
+				store.EXPECT().
+					GetUser(gomock.Any(), gomock.Eq(username)).
+					Times(1).
+					Return(db.User{Username: username, TokensRevokedAt: time.Now().Add(time.Second)}, nil)
+			},
+			wantCode: http.StatusUnauthorized,
+		},
+		{
+			name: "InternalError",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().IsTokenRevoked(gomock.Any(), gomock.Any()).Times(1).Return(false, sql.ErrConnDone)
+			},
+			wantCode: http.StatusInternalServerError,
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+			store.EXPECT().GetSession(gomock.Any(), gomock.Any()).Times(0)
+
+			server := newTestServer(t, store)
+			recorder := httptest.NewRecorder()
+
+			refreshToken, _, err := server.tokenMaker.CreateToken(username, util.DepositorRole, token.TokenTypeRefreshToken, time.Hour)
+			require.NoError(t, err)
+			data, err := json.Marshal(gin.H{"refresh_token": refreshToken})
+			require.NoError(t, err)
+
+			request, err := http.NewRequest(http.MethodPost, "/tokens/renew_access", bytes.NewReader(data))
+			require.NoError(t, err)
+
+			server.router.ServeHTTP(recorder, request)
+			require.Equal(t, tc.wantCode, recorder.Code)
+		})
+	}
+}
+
+func TestRenewAccessTokenWithAccessToken(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	store := mockdb.NewMockStore(ctrl)
+	store.EXPECT().
+		GetSession(gomock.Any(), gomock.Any()).
+		Times(0)
+
+	server := newTestServer(t, store)
+	recorder := httptest.NewRecorder()
+
+	// access token 不能用来换取新的 access token
+	accessToken, _, err := server.tokenMaker.CreateToken(util.RandomUserName(), util.DepositorRole, token.TokenTypeAccessToken, time.Hour)
+	require.NoError(t, err)
+	data, err := json.Marshal(gin.H{"refresh_token": accessToken})
+	require.NoError(t, err)
+
+	request, err := http.NewRequest(http.MethodPost, "/tokens/renew_access", bytes.NewReader(data))
+	require.NoError(t, err)
+
+	server.router.ServeHTTP(recorder, request)
+	require.Equal(t, http.StatusUnauthorized, recorder.Code)
+}
+
+func TestListPublicKeysAPI(t *testing.T) {
+	// 默认使用对称密钥，没有可以公开的公钥
+	server := newTestServer(t, nil)
+	recorder := httptest.NewRecorder()
+
+	request, err := http.NewRequest(http.MethodGet, "/tokens/public_keys", nil)
+	require.NoError(t, err)
+
+	server.router.ServeHTTP(recorder, request)
+	require.Equal(t, http.StatusNotFound, recorder.Code)
+
+	_, privateKey, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+
+	config := util.Config{
+		TokenKeyID:				"key1",
+		TokenPrivateKey:		base64.StdEncoding.EncodeToString(privateKey.Seed()),
+		AccessTokenDuration:	time.Minute,
+	}
+	server, err = NewServer(config, nil)
+	require.NoError(t, err)
+
+	recorder = httptest.NewRecorder()
+	server.router.ServeHTTP(recorder, request)
+	require.Equal(t, http.StatusOK, recorder.Code)
+
+	var rsp listPublicKeysResponse
+	err = json.Unmarshal(recorder.Body.Bytes(), &rsp)
+	require.NoError(t, err)
+	require.Len(t, rsp.Keys, 1)
+	require.Equal(t, "key1", rsp.Keys[0].KeyID)
+
+	publicKey, err := base64.StdEncoding.DecodeString(rsp.Keys[0].PublicKey)
+	require.NoError(t, err)
+	require.Equal(t, []byte(privateKey.Public().(ed25519.PublicKey)), publicKey)
+}
+
+// newSession 根据 refresh token 构造一个有效的 session
+func newSession(refreshToken string, payload *token.Payload) db.Session {
+	return db.Session{
+		ID:				payload.ID,
+		Username:		payload.Username,
+		RefreshToken:	refreshToken,
+		ExpiresAt:		payload.ExpiredAt,
+	}
+}
@@ -1,61 +1,332 @@
 package api
 
 import (
+	"crypto/sha256"
 	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"github.com/gin-gonic/gin"
 	db "github.com/techschool/simplebank/db/sqlc"
+	"github.com/techschool/simplebank/fraud"
+	"github.com/techschool/simplebank/util"
+	"io"
 	"net/http"
+	"time"
 )
 
+const (
+	// idempotencyKeyHeader 客户端重试转账时带上相同的 key，转账只会执行一次
+	idempotencyKeyHeader	= "Idempotency-Key"
+	maxIdempotencyKeyLength	= 255
+)
+
+var errTransferRecipient = errors.New("exactly one of to_account_id and recipient is required")
+
+var errSystemAccount = errors.New("system accounts cannot be used by customer operations")
+
+var errSelfTransfer = errors.New("cannot transfer to the same account")
+
 type transferRequest struct {
 	FromAccountID int64 `json:"from_account_id" binding:"required,min=1"`
-	ToAccountID int64 `json:"to_account_id" binding:"required,min=1"`
+	ToAccountID int64 `json:"to_account_id,omitempty" binding:"omitempty,min=1"`
+	// Recipient 收款人的用户名或者邮箱，与 to_account_id 二选一，转入收款人 to_currency 币种的账户，默认与转出账户的币种相同
+	Recipient string `json:"recipient,omitempty" binding:"omitempty,max=255"`
+	ToCurrency string `json:"to_currency,omitempty" binding:"omitempty,currency"`
 	Amount int64 `json:"amount" binding:"required,gt=0"`
 	Currency string `json:"currency" binding:"required,currency"`
+	Memo string `json:"memo,omitempty" binding:"max=255"`
+	// ExternalReference 客户端的参考号，同一个转出账户不能重复使用
+	ExternalReference string `json:"external_reference,omitempty" binding:"max=128"`
+	// Metadata 任意的 JSON 对象，不超过 4KB
+	Metadata json.RawMessage `json:"metadata,omitempty" binding:"omitempty,metadata"`
+}
+
+// transferResponse 转账的结果，按收款人转账时带上打码后的收款人姓名
+type transferResponse struct {
+	db.TransferTxResult
+	RecipientName	string	`json:"recipient_name,omitempty"`
 }
 
 func (server *Server) createTransfer(ctx *gin.Context) {
 	var req transferRequest
-	if err := ctx.ShouldBind(&req); err != nil {
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+	if (req.ToAccountID == 0) == (req.Recipient == "") {
+		ctx.JSON(http.StatusBadRequest, errorResponse(errTransferRecipient))
+		return
+	}
+
+	idempotencyKey := ctx.GetHeader(idempotencyKeyHeader)
+	if len(idempotencyKey) > maxIdempotencyKeyLength {
+		err := fmt.Errorf("%s must not be longer than %d characters", idempotencyKeyHeader, maxIdempotencyKeyLength)
 		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
 	}
 
-	_, valid := server.validAccount(ctx, req.FromAccountID, req.Currency)
+	fromAccount, valid := server.validAccount(ctx, req.FromAccountID, req.Currency)
 	if !valid {
 		return
 	}
 
-	_, valid = server.validAccount(ctx, req.ToAccountID, req.Currency)
-	if !valid {
+	// 只有账户所有者可以从该账户转出资金
+	if !server.authorizeAccount(ctx, fromAccount, writeAccess) {
+		return
+	}
+
+	// 转入账户可以是其他币种，按最新的汇率换算
+	var toAccount db.Account
+	var response transferResponse
+	if req.Recipient != "" {
+		toCurrency := req.ToCurrency
+		if toCurrency == "" {
+			toCurrency = req.Currency
+		}
+		var recipient db.User
+		toAccount, recipient, valid = server.findRecipientAccount(ctx, req.Recipient, toCurrency)
+		if !valid {
+			return
+		}
+		response.RecipientName = util.MaskName(recipient.FullName)
+	} else {
+		toAccount, valid = server.fetchAccount(ctx, req.ToAccountID)
+		if !valid {
+			return
+		}
+		if toAccount.SystemCode != "" {
+			ctx.JSON(http.StatusUnprocessableEntity, errorResponse(errSystemAccount))
+			return
+		}
+	}
+	// 收款人可能解析到转出账户本身
+	if toAccount.ID == fromAccount.ID {
+		ctx.JSON(http.StatusBadRequest, errorResponse(errSelfTransfer))
 		return
 	}
 
 	// 整理参数，去数据库中进行查询
+	authPayload, _ := authPayloadFromContext(ctx)
 	arg := db.TransferTxParams{
 		FromAccountID: req.FromAccountID,
-		ToAccountID: req.ToAccountID,
+		ToAccountID: toAccount.ID,
 		Amount: req.Amount,
+		RequestedBy: authPayload.Username,
+		RequireApproval: server.requireApproval(req.Amount, fromAccount.Currency),
+		Memo: req.Memo,
+		ExternalReference: req.ExternalReference,
+		Metadata: req.Metadata,
+	}
+
+	if toAccount.Currency != fromAccount.Currency {
+		exchangeRate, err := server.store.GetLatestExchangeRate(ctx, db.GetLatestExchangeRateParams{
+			BaseCurrency:	fromAccount.Currency,
+			QuoteCurrency:	toAccount.Currency,
+		})
+		if err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				err = fmt.Errorf("no exchange rate from %s to %s", fromAccount.Currency, toAccount.Currency)
+				ctx.JSON(http.StatusUnprocessableEntity, errorResponse(err))
+				return
+			}
+			ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+			return
+		}
+		arg.ExchangeRateID = exchangeRate.ID
+	}
+
+	// 重放已经完成的请求时直接返回第一次的结果，不再进行风控检查
+	if len(idempotencyKey) != 0 {
+		result, found, err := server.findIdempotentTransfer(ctx, authPayload.Username, idempotencyKey, transferRequestHash(req))
+		if err != nil {
+			if errors.Is(err, db.ErrIdempotencyKeyReused) {
+				ctx.JSON(http.StatusUnprocessableEntity, errorResponse(err))
+				return
+			}
+			ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+			return
+		}
+		if found {
+			response.TransferTxResult = result
+			writeTransferResponse(ctx, response)
+			return
+		}
 	}
 
-	result, err := server.store.TransferTx(ctx, arg)
+	// 风控检查，需要人工审核的转账和超过审批金额的转账一样先创建为待审批
+	fraudDecision, valid := server.screenTransfer(ctx, arg, fromAccount.Currency)
+	if !valid {
+		return
+	}
+	if fraudDecision.Decision == string(fraud.Review) {
+		arg.RequireApproval = true
+	}
+
+	var result db.TransferTxResult
+	var err error
+	if len(idempotencyKey) == 0 {
+		result, err = server.store.TransferTx(ctx, arg)
+	} else {
+		result, err = server.store.IdempotentTransferTx(ctx, db.IdempotentTransferTxParams{
+			TransferTxParams:	arg,
+			Username:			authPayload.Username,
+			IdempotencyKey:		idempotencyKey,
+			RequestHash:		transferRequestHash(req),
+			ExpiresAt:			time.Now().Add(server.config.IdempotencyKeyRetention),
+		})
+	}
 	if err != nil {
+		var limitErr *db.LimitExceededError
+		if errors.As(err, &limitErr) {
+			ctx.JSON(http.StatusUnprocessableEntity, limitExceededResponse(limitErr))
+			return
+		}
+		if errors.Is(err, db.ErrInsufficientFunds) || errors.Is(err, db.ErrIdempotencyKeyReused) ||
+			errors.Is(err, db.ErrInvalidExchange) || errors.Is(err, db.ErrSystemAccount) {
+			ctx.JSON(http.StatusUnprocessableEntity, errorResponse(err))
+			return
+		}
+		if errors.Is(err, db.ErrDuplicateReference) {
+			ctx.JSON(http.StatusConflict, errorResponse(err))
+			return
+		}
 		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
 		return
 	}
-	ctx.JSON(http.StatusOK, result)
+
+	// 并发的相同请求在事务中等待后重放，风控记录不关联到第一次的转账上
+	if !result.Replayed {
+		server.linkFraudDecision(ctx, fraudDecision, result.Transfer)
+	}
+
+	response.TransferTxResult = result
+	writeTransferResponse(ctx, response)
 }
 
-func (server *Server) validAccount(ctx *gin.Context, accountID int64, currency string) (db.Account, bool) {
+// writeTransferResponse 超过审批金额的转账只创建了待审批的记录，还没有结算
+func writeTransferResponse(ctx *gin.Context, response transferResponse) {
+	if response.Transfer.Status == util.TransferPending {
+		ctx.JSON(http.StatusAccepted, response)
+		return
+	}
+	ctx.JSON(http.StatusOK, response)
+}
+
+// findIdempotentTransfer 查询 idempotency key 对应的已经完成的转账，key 不存在或者已经过期时返回 false，
+// key 用于不同的请求时返回 ErrIdempotencyKeyReused
+func (server *Server) findIdempotentTransfer(ctx *gin.Context, username, key, requestHash string) (db.TransferTxResult, bool, error) {
+	var result db.TransferTxResult
+	saved, err := server.store.GetIdempotencyKey(ctx, db.GetIdempotencyKeyParams{
+		Username:		username,
+		IdempotencyKey:	key,
+	})
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return result, false, nil
+		}
+		return result, false, err
+	}
+	if !saved.ExpiresAt.After(time.Now()) {
+		return result, false, nil
+	}
+	if saved.RequestHash != requestHash {
+		return result, false, db.ErrIdempotencyKeyReused
+	}
+
+	err = json.Unmarshal(saved.Response, &result)
+	result.Replayed = true
+	return result, true, err
+}
+
+type listTransfersRequest struct {
+	Reference		string	`form:"reference" binding:"required,max=128"`
+	// FromAccountID 只查询该账户转出的转账，银行职员可以查询客户的账户，默认查询当前用户所有的账户
+	FromAccountID	int64	`form:"from_account_id" binding:"omitempty,min=1"`
+}
+
+// listTransfers 按 external_reference 查询转账，用于客户端对账
+func (server *Server) listTransfers(ctx *gin.Context) {
+	var req listTransfersRequest
+	if err := ctx.ShouldBindQuery(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	authPayload, ok := authPayloadFromContext(ctx)
+	if !ok {
+		ctx.JSON(http.StatusUnauthorized, errorResponse(errUnauthenticated))
+		return
+	}
+
+	transfers := []db.Transfer{}
+	if req.FromAccountID != 0 {
+		account, valid := server.fetchAccount(ctx, req.FromAccountID)
+		if !valid {
+			return
+		}
+		if !server.authorizeAccount(ctx, account, readAccess) {
+			return
+		}
+
+		transfer, err := server.store.GetTransferByReference(ctx, db.GetTransferByReferenceParams{
+			FromAccountID:		account.ID,
+			ExternalReference:	req.Reference,
+		})
+		if err != nil && !errors.Is(err, sql.ErrNoRows) {
+			ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+			return
+		}
+		if err == nil {
+			transfers = append(transfers, transfer)
+		}
+		ctx.JSON(http.StatusOK, transfers)
+		return
+	}
+
+	result, err := server.store.ListTransfersByReference(ctx, db.ListTransfersByReferenceParams{
+		ExternalReference:	req.Reference,
+		Owner:				authPayload.Username,
+	})
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+	ctx.JSON(http.StatusOK, append(transfers, result...))
+}
+
+// requireApproval 转账金额超过该币种的审批金额时需要审批，没有配置的币种不需要审批
+func (server *Server) requireApproval(amount int64, currency string) bool {
+	threshold, ok := server.approvalThresholds[currency]
+	return ok && amount > threshold
+}
+
+// fetchAccount 查询账户，不存在时返回 404
+func (server *Server) fetchAccount(ctx *gin.Context, accountID int64) (db.Account, bool) {
 	account, err := server.store.GetAccount(ctx, accountID)
 	if err != nil {
-		if err == sql.ErrNoRows {
+		if errors.Is(err, sql.ErrNoRows) {
 			ctx.JSON(http.StatusNotFound, errorResponse(err))
 			return account, false
 		}
 		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
 		return account, false
 	}
+	return account, true
+}
+
+// validAccount 查询账户，并检查账户币种与请求中的币种一致，系统内部账户不能用于客户的操作
+func (server *Server) validAccount(ctx *gin.Context, accountID int64, currency string) (db.Account, bool) {
+	account, valid := server.fetchAccount(ctx, accountID)
+	if !valid {
+		return account, false
+	}
+
+	if account.SystemCode != "" {
+		ctx.JSON(http.StatusUnprocessableEntity, errorResponse(errSystemAccount))
+		return account, false
+	}
 
 	if account.Currency != currency {
 		err := fmt.Errorf("account [%d] currency mismatch: %v VS %v", account.ID, account.Currency, currency)
@@ -65,4 +336,147 @@ func (server *Server) validAccount(ctx *gin.Context, accountID int64, currency s
 	return account, true
 }
 
+// transferRequestHash 计算转账请求的指纹，用于判断同一个 idempotency key 是否被用于不同的请求
+func transferRequestHash(req transferRequest) string {
+	data, _ := json.Marshal(req)
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+var errTransferForbidden = errors.New("only the recipient or bank staff can reverse the transfer")
+
+type reverseTransferURI struct {
+	ID	int64	`uri:"id" binding:"required,min=1"`
+}
+
+type reverseTransferRequest struct {
+	// Amount 部分退款的金额，使用原转账转出账户的币种，默认退回全部剩余的金额
+	Amount	*int64	`json:"amount" binding:"omitempty,gt=0"`
+}
+
+// reverseTransfer 撤销转账或者部分退款，只有收款人和银行职员可以操作，
+// 资金只退回原来的转出账户，不做风控检查
+func (server *Server) reverseTransfer(ctx *gin.Context) {
+	var uri reverseTransferURI
+	if err := ctx.ShouldBindUri(&uri); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	var req reverseTransferRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil && err != io.EOF {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	transfer, err := server.store.GetTransfers(ctx, uri.ID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			ctx.JSON(http.StatusNotFound, errorResponse(err))
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	authPayload, ok := authPayloadFromContext(ctx)
+	if !ok {
+		ctx.JSON(http.StatusUnauthorized, errorResponse(errUnauthenticated))
+		return
+	}
+	if !isStaff(authPayload) {
+		toAccount, valid := server.fetchAccount(ctx, transfer.ToAccountID)
+		if !valid {
+			return
+		}
+		if toAccount.Owner != authPayload.Username {
+			ctx.JSON(http.StatusForbidden, errorResponse(errTransferForbidden))
+			return
+		}
+	}
+
+	amount := transfer.Amount - transfer.ReversedAmount
+	if req.Amount != nil {
+		amount = *req.Amount
+	}
+
+	result, err := server.store.ReverseTransferTx(ctx, db.ReverseTransferTxParams{
+		TransferID:		transfer.ID,
+		Amount:			amount,
+		RequestedBy:	authPayload.Username,
+	})
+	if err != nil {
+		if errors.Is(err, db.ErrInvalidReversal) || errors.Is(err, db.ErrInsufficientFunds) {
+			ctx.JSON(http.StatusUnprocessableEntity, errorResponse(err))
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+	ctx.JSON(http.StatusOK, result)
+}
+
+type decideTransferURI struct {
+	ID	int64	`uri:"id" binding:"required,min=1"`
+}
+
+// approveTransfer 审批通过待审批的转账并完成结算，只有银行职员和管理员可以操作，且不能审批自己发起的转账
+func (server *Server) approveTransfer(ctx *gin.Context) {
+	arg, ok := server.decideTransferParams(ctx)
+	if !ok {
+		return
+	}
 
+	result, err := server.store.ApproveTransferTx(ctx, arg)
+	if err != nil {
+		server.decideTransferError(ctx, err)
+		return
+	}
+	ctx.JSON(http.StatusOK, result)
+}
+
+// rejectTransfer 拒绝待审批的转账，不会有资金变动
+func (server *Server) rejectTransfer(ctx *gin.Context) {
+	arg, ok := server.decideTransferParams(ctx)
+	if !ok {
+		return
+	}
+
+	transfer, err := server.store.RejectTransferTx(ctx, arg)
+	if err != nil {
+		server.decideTransferError(ctx, err)
+		return
+	}
+	ctx.JSON(http.StatusOK, transfer)
+}
+
+func (server *Server) decideTransferParams(ctx *gin.Context) (db.DecideTransferTxParams, bool) {
+	var uri decideTransferURI
+	if err := ctx.ShouldBindUri(&uri); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return db.DecideTransferTxParams{}, false
+	}
+
+	authPayload, ok := authPayloadFromContext(ctx)
+	if !ok {
+		ctx.JSON(http.StatusUnauthorized, errorResponse(errUnauthenticated))
+		return db.DecideTransferTxParams{}, false
+	}
+	return db.DecideTransferTxParams{
+		TransferID:	uri.ID,
+		Approver:	authPayload.Username,
+	}, true
+}
+
+func (server *Server) decideTransferError(ctx *gin.Context, err error) {
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		ctx.JSON(http.StatusNotFound, errorResponse(err))
+	case errors.Is(err, db.ErrSelfApproval):
+		ctx.JSON(http.StatusForbidden, errorResponse(err))
+	case errors.Is(err, db.ErrInvalidTransferStatus) || errors.Is(err, db.ErrInsufficientFunds):
+		ctx.JSON(http.StatusUnprocessableEntity, errorResponse(err))
+	default:
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+	}
+}
@@ -0,0 +1,183 @@
+package api
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"github.com/gin-gonic/gin"
+	db "github.com/techschool/simplebank/db/sqlc"
+	"net/http"
+	"time"
+)
+
+type transferLimitResponse struct {
+	ID			int64		`json:"id"`
+	Scope		string		`json:"scope"`
+	Owner		string		`json:"owner,omitempty"`
+	AccountID	int64		`json:"account_id,omitempty"`
+	Currency	string		`json:"currency"`
+	Period		string		`json:"period"`
+	MaxCount	int32		`json:"max_count"`
+	MaxAmount	int64		`json:"max_amount"`
+	UpdatedAt	time.Time	`json:"updated_at"`
+}
+
+func newTransferLimitResponse(limit db.TransferLimit) transferLimitResponse {
+	return transferLimitResponse{
+		ID:			limit.ID,
+		Scope:		db.LimitScope(limit),
+		Owner:		limit.Owner.String,
+		AccountID:	limit.AccountID.Int64,
+		Currency:	limit.Currency,
+		Period:		limit.Period,
+		MaxCount:	limit.MaxCount,
+		MaxAmount:	limit.MaxAmount,
+		UpdatedAt:	limit.UpdatedAt,
+	}
+}
+
+type setTransferLimitRequest struct {
+	// Owner 和 AccountID 都为空时设置默认限额，账户限额的币种就是账户的币种
+	Owner		string	`json:"owner" binding:"omitempty,alphanum"`
+	AccountID	int64	`json:"account_id" binding:"omitempty,min=1"`
+	Currency	string	`json:"currency" binding:"required_without=AccountID,omitempty,currency"`
+	Period		string	`json:"period" binding:"required,limit_period"`
+	// MaxCount 和 MaxAmount 为 0 表示不限制
+	MaxCount	int32	`json:"max_count" binding:"min=0"`
+	MaxAmount	int64	`json:"max_amount" binding:"min=0"`
+}
+
+// setTransferLimit 设置转账限额，同一范围和周期的限额已经存在时更新，仅管理员可用
+func (server *Server) setTransferLimit(ctx *gin.Context) {
+	var req setTransferLimitRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+	if req.Owner != "" && req.AccountID != 0 {
+		err := errors.New("owner and account_id cannot be set at the same time")
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+	if req.MaxCount == 0 && req.MaxAmount == 0 {
+		err := errors.New("at least one of max_count and max_amount must be positive")
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	arg := db.UpsertTransferLimitParams{
+		Currency:	req.Currency,
+		Period:		req.Period,
+		MaxCount:	req.MaxCount,
+		MaxAmount:	req.MaxAmount,
+	}
+
+	if req.AccountID != 0 {
+		account, valid := server.fetchAccount(ctx, req.AccountID)
+		if !valid {
+			return
+		}
+		if req.Currency != "" && req.Currency != account.Currency {
+			err := fmt.Errorf("account [%d] currency mismatch: %v VS %v", account.ID, account.Currency, req.Currency)
+			ctx.JSON(http.StatusBadRequest, errorResponse(err))
+			return
+		}
+		arg.AccountID = sql.NullInt64{Int64: account.ID, Valid: true}
+		arg.Currency = account.Currency
+	}
+
+	if req.Owner != "" {
+		_, err := server.store.GetUser(ctx, req.Owner)
+		if err != nil {
+			if err == sql.ErrNoRows {
+				ctx.JSON(http.StatusNotFound, errorResponse(err))
+				return
+			}
+			ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+			return
+		}
+		arg.Owner = sql.NullString{String: req.Owner, Valid: true}
+	}
+
+	limit, err := server.store.UpsertTransferLimit(ctx, arg)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+	ctx.JSON(http.StatusOK, newTransferLimitResponse(limit))
+}
+
+type listTransferLimitsRequest struct {
+	pageRequest
+}
+
+// listTransferLimits 分页查询所有的转账限额，银行职员和管理员可用
+func (server *Server) listTransferLimits(ctx *gin.Context) {
+	var req listTransferLimitsRequest
+	if err := ctx.ShouldBindQuery(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	page, valid := server.parsePage(ctx, req.pageRequest)
+	if !valid {
+		return
+	}
+
+	limits, err := server.store.ListTransferLimits(ctx, db.ListTransferLimitsParams{
+		CursorCreatedAt:	page.after().CreatedAt,
+		CursorID:			page.after().ID,
+		PageSize:			page.limit(),
+	})
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	n, next := page.next(len(limits), func(i int) pageCursor {
+		return pageCursor{CreatedAt: limits[i].CreatedAt, ID: limits[i].ID}
+	})
+	rsp := make([]transferLimitResponse, n)
+	for i, limit := range limits[:n] {
+		rsp[i] = newTransferLimitResponse(limit)
+	}
+	ctx.JSON(http.StatusOK, pageResponse{Items: rsp, NextCursor: next})
+}
+
+type deleteTransferLimitURI struct {
+	ID	int64	`uri:"id" binding:"required,min=1"`
+}
+
+// deleteTransferLimit 删除转账限额，仅管理员可用
+func (server *Server) deleteTransferLimit(ctx *gin.Context) {
+	var uri deleteTransferLimitURI
+	if err := ctx.ShouldBindUri(&uri); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	_, err := server.store.GetTransferLimit(ctx, uri.ID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			ctx.JSON(http.StatusNotFound, errorResponse(err))
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	err = server.store.DeleteTransferLimit(ctx, uri.ID)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+	ctx.JSON(http.StatusOK, gin.H{})
+}
+
+// limitExceededResponse 超出限额时返回剩余的额度
+func limitExceededResponse(err *db.LimitExceededError) gin.H {
+	return gin.H{
+		"message":	err.Error(),
+		"limit":	err,
+	}
+}
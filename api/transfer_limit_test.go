@@ -0,0 +1,174 @@
+package api
+
+import (
+	"bytes"
+	"database/sql"
+	"encoding/json"
+	"github.com/gin-gonic/gin"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/require"
+	mockdb "github.com/techschool/simplebank/db/mock"
+	db "github.com/techschool/simplebank/db/sqlc"
+	"github.com/techschool/simplebank/util"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestSetTransferLimitAPI(t *testing.T) {
+	admin, _ := randomUser(t)
+	admin.Role = util.AdminRole
+	depositor, _ := randomUser(t)
+	account := randomAccount(depositor.Username)
+	account.Currency = util.USD
+
+	testCases := []struct{
+		name			string
+		body			gin.H
+		user			db.User
+		buildStubs		func(store *mockdb.MockStore)
+		checkResponse	func(t *testing.T, recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name: "Default",
+			body: gin.H{"currency": util.EUR, "period": util.LimitDaily, "max_amount": 100000},
+			user: admin,
+			buildStubs: func(store *mockdb.MockStore) {
+				arg := db.UpsertTransferLimitParams{Currency: util.EUR, Period: util.LimitDaily, MaxAmount: 100000}
+				store.EXPECT().
+					UpsertTransferLimit(gomock.Any(), gomock.Eq(arg)).
+					Times(1).
+					Return(db.TransferLimit{ID: 1, Currency: util.EUR, Period: util.LimitDaily, MaxAmount: 100000}, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+
+				var got transferLimitResponse
+				err := json.Unmarshal(recorder.Body.Bytes(), &got)
+				require.NoError(t, err)
+				require.Equal(t, db.LimitScopeDefault, got.Scope)
+				require.Equal(t, int64(100000), got.MaxAmount)
+			},
+		},
+		{
+			name: "Account",
+			body: gin.H{"account_id": account.ID, "period": util.LimitMonthly, "max_count": 10},
+			user: admin,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account.ID)).Times(1).Return(account, nil)
+
+				arg := db.UpsertTransferLimitParams{
+					AccountID:	sql.NullInt64{Int64: account.ID, Valid: true},
+					Currency:	util.USD,
+					Period:		util.LimitMonthly,
+					MaxCount:	10,
+				}
+				store.EXPECT().UpsertTransferLimit(gomock.Any(), gomock.Eq(arg)).Times(1)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+			},
+		},
+		{
+			name: "User",
+			body: gin.H{"owner": depositor.Username, "currency": util.USD, "period": util.LimitDaily, "max_count": 5},
+			user: admin,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetUser(gomock.Any(), gomock.Eq(depositor.Username)).Times(1).Return(depositor, nil)
+
+				arg := db.UpsertTransferLimitParams{
+					Owner:		sql.NullString{String: depositor.Username, Valid: true},
+					Currency:	util.USD,
+					Period:		util.LimitDaily,
+					MaxCount:	5,
+				}
+				store.EXPECT().UpsertTransferLimit(gomock.Any(), gomock.Eq(arg)).Times(1)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+			},
+		},
+		{
+			name: "NotAdmin",
+			body: gin.H{"currency": util.USD, "period": util.LimitDaily, "max_count": 5},
+			user: depositor,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().UpsertTransferLimit(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusForbidden, recorder.Code)
+			},
+		},
+		{
+			name: "OwnerAndAccount",
+			body: gin.H{"owner": depositor.Username, "account_id": account.ID, "period": util.LimitDaily, "max_count": 5},
+			user: admin,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().UpsertTransferLimit(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+		{
+			name: "MissingCurrency",
+			body: gin.H{"period": util.LimitDaily, "max_count": 5},
+			user: admin,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().UpsertTransferLimit(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+		{
+			name: "InvalidPeriod",
+			body: gin.H{"currency": util.USD, "period": "weekly", "max_count": 5},
+			user: admin,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().UpsertTransferLimit(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+		{
+			name: "NoRestriction",
+			body: gin.H{"currency": util.USD, "period": util.LimitDaily},
+			user: admin,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().UpsertTransferLimit(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			buildAuthStubs(store, tc.user.Username)
+			tc.buildStubs(store)
+
+			server := newTestServer(t, store)
+			recorder := httptest.NewRecorder()
+
+			data, err := json.Marshal(tc.body)
+			require.NoError(t, err)
+
+			request, err := http.NewRequest(http.MethodPut, "/transfer_limits", bytes.NewReader(data))
+			require.NoError(t, err)
+
+			addAuthorization(t, request, server.tokenMaker, authorizationTypeBearer, tc.user.Username, tc.user.Role, time.Minute)
+			server.router.ServeHTTP(recorder, request)
+			tc.checkResponse(t, recorder)
+		})
+	}
+}
@@ -1,8 +1,1245 @@
 package api
 
-//func TestTransferAPI(t *testing.T) {
-//	amount := int64(10)
-//
-//}
+import (
+	"bytes"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/require"
+	mockdb "github.com/techschool/simplebank/db/mock"
+	db "github.com/techschool/simplebank/db/sqlc"
+	"github.com/techschool/simplebank/fraud"
+	"github.com/techschool/simplebank/token"
+	"github.com/techschool/simplebank/util"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
 
+func TestTransferAPI(t *testing.T) {
+	amount := int64(10)
 
+	user1, _ := randomUser(t)
+	user2, _ := randomUser(t)
+	user3, _ := randomUser(t)
+
+	account1 := randomAccount(user1.Username)
+	account2 := randomAccount(user2.Username)
+	account3 := randomAccount(user3.Username)
+
+	account1.Currency = util.USD
+	account2.Currency = util.USD
+	account3.Currency = util.EUR
+
+	testCases := []struct{
+		name			string
+		body			gin.H
+		setupAuth		func(t *testing.T, request *http.Request, tokenMaker token.Maker)
+		buildStubs		func(store *mockdb.MockStore)
+		checkResponse	func(t *testing.T, recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name: "OK",
+			body: gin.H{
+				"from_account_id":	account1.ID,
+				"to_account_id":	account2.ID,
+				"amount":			amount,
+				"currency":			util.USD,
+			},
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, user1.Username, user1.Role, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account1.ID)).Times(1).Return(account1, nil)
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account2.ID)).Times(1).Return(account2, nil)
+
+				arg := db.TransferTxParams{
+					FromAccountID:	account1.ID,
+					ToAccountID:	account2.ID,
+					Amount:			amount,
+					RequestedBy:	user1.Username,
+				}
+				store.EXPECT().TransferTx(gomock.Any(), gomock.Eq(arg)).Times(1)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+			},
+		},
+		{
+			name: "UnauthorizedUser",  // 不能从别人的账户转出资金
+			body: gin.H{
+				"from_account_id":	account1.ID,
+				"to_account_id":	account2.ID,
+				"amount":			amount,
+				"currency":			util.USD,
+			},
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, user2.Username, user2.Role, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account1.ID)).Times(1).Return(account1, nil)
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account2.ID)).Times(0)
+				store.EXPECT().TransferTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusForbidden, recorder.Code)
+			},
+		},
+		{
+			name: "NoAuthorization",
+			body: gin.H{
+				"from_account_id":	account1.ID,
+				"to_account_id":	account2.ID,
+				"amount":			amount,
+				"currency":			util.USD,
+			},
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Any()).Times(0)
+				store.EXPECT().TransferTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+			},
+		},
+		{
+			name: "FromAccountNotFound",
+			body: gin.H{
+				"from_account_id":	account1.ID,
+				"to_account_id":	account2.ID,
+				"amount":			amount,
+				"currency":			util.USD,
+			},
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, user1.Username, user1.Role, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account1.ID)).Times(1).Return(db.Account{}, sql.ErrNoRows)
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account2.ID)).Times(0)
+				store.EXPECT().TransferTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusNotFound, recorder.Code)
+			},
+		},
+		{
+			name: "ToAccountNotFound",
+			body: gin.H{
+				"from_account_id":	account1.ID,
+				"to_account_id":	account2.ID,
+				"amount":			amount,
+				"currency":			util.USD,
+			},
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, user1.Username, user1.Role, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account1.ID)).Times(1).Return(account1, nil)
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account2.ID)).Times(1).Return(db.Account{}, sql.ErrNoRows)
+				store.EXPECT().TransferTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusNotFound, recorder.Code)
+			},
+		},
+		{
+			name: "CrossCurrency",  // 转入账户是其他币种时使用最新的汇率
+			body: gin.H{
+				"from_account_id":	account1.ID,
+				"to_account_id":	account3.ID,
+				"amount":			amount,
+				"currency":			util.USD,
+			},
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, user1.Username, user1.Role, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account1.ID)).Times(1).Return(account1, nil)
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account3.ID)).Times(1).Return(account3, nil)
+
+				exchangeRate := randomExchangeRate(util.USD, util.EUR)
+				store.EXPECT().
+					GetLatestExchangeRate(gomock.Any(), gomock.Eq(db.GetLatestExchangeRateParams{
+						BaseCurrency:	util.USD,
+						QuoteCurrency:	util.EUR,
+					})).
+					Times(1).
+					Return(exchangeRate, nil)
+
+				arg := db.TransferTxParams{
+					FromAccountID:	account1.ID,
+					ToAccountID:	account3.ID,
+					Amount:			amount,
+					ExchangeRateID:	exchangeRate.ID,
+					RequestedBy:	user1.Username,
+				}
+				store.EXPECT().TransferTx(gomock.Any(), gomock.Eq(arg)).Times(1)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+			},
+		},
+		{
+			name: "NoExchangeRate",
+			body: gin.H{
+				"from_account_id":	account1.ID,
+				"to_account_id":	account3.ID,
+				"amount":			amount,
+				"currency":			util.USD,
+			},
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, user1.Username, user1.Role, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account1.ID)).Times(1).Return(account1, nil)
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account3.ID)).Times(1).Return(account3, nil)
+				store.EXPECT().GetLatestExchangeRate(gomock.Any(), gomock.Any()).Times(1).Return(db.ExchangeRate{}, sql.ErrNoRows)
+				store.EXPECT().TransferTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnprocessableEntity, recorder.Code)
+			},
+		},
+		{
+			name: "InvalidExchange",
+			body: gin.H{
+				"from_account_id":	account1.ID,
+				"to_account_id":	account3.ID,
+				"amount":			amount,
+				"currency":			util.USD,
+			},
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, user1.Username, user1.Role, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account1.ID)).Times(1).Return(account1, nil)
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account3.ID)).Times(1).Return(account3, nil)
+				store.EXPECT().GetLatestExchangeRate(gomock.Any(), gomock.Any()).Times(1).Return(randomExchangeRate(util.USD, util.EUR), nil)
+				store.EXPECT().TransferTx(gomock.Any(), gomock.Any()).Times(1).Return(db.TransferTxResult{}, db.ErrInvalidExchange)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnprocessableEntity, recorder.Code)
+			},
+		},
+		{
+			name: "FromAccountCurrencyMismatch",
+			body: gin.H{
+				"from_account_id":	account1.ID,
+				"to_account_id":	account2.ID,
+				"amount":			amount,
+				"currency":			util.EUR,
+			},
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, user1.Username, user1.Role, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account1.ID)).Times(1).Return(account1, nil)
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account2.ID)).Times(0)
+				store.EXPECT().TransferTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+		{
+			name: "NegativeAmount",
+			body: gin.H{
+				"from_account_id":	account1.ID,
+				"to_account_id":	account2.ID,
+				"amount":			-amount,
+				"currency":			util.USD,
+			},
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, user1.Username, user1.Role, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Any()).Times(0)
+				store.EXPECT().TransferTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+		{
+			name: "InsufficientFunds",
+			body: gin.H{
+				"from_account_id":	account1.ID,
+				"to_account_id":	account2.ID,
+				"amount":			amount,
+				"currency":			util.USD,
+			},
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, user1.Username, user1.Role, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account1.ID)).Times(1).Return(account1, nil)
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account2.ID)).Times(1).Return(account2, nil)
+				store.EXPECT().TransferTx(gomock.Any(), gomock.Any()).Times(1).Return(db.TransferTxResult{}, db.ErrInsufficientFunds)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnprocessableEntity, recorder.Code)
+			},
+		},
+		{
+			name: "ApprovalRequired",
+			body: gin.H{
+				"from_account_id":	account1.ID,
+				"to_account_id":	account2.ID,
+				"amount":			testApprovalThreshold + 1,
+				"currency":			util.USD,
+			},
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, user1.Username, user1.Role, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account1.ID)).Times(1).Return(account1, nil)
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account2.ID)).Times(1).Return(account2, nil)
+
+				arg := db.TransferTxParams{
+					FromAccountID:		account1.ID,
+					ToAccountID:		account2.ID,
+					Amount:				testApprovalThreshold + 1,
+					RequestedBy:		user1.Username,
+					RequireApproval:	true,
+				}
+				store.EXPECT().
+					TransferTx(gomock.Any(), gomock.Eq(arg)).
+					Times(1).
+					Return(db.TransferTxResult{Transfer: db.Transfer{Status: util.TransferPending}}, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusAccepted, recorder.Code)
+			},
+		},
+		{
+			name: "LimitExceeded",
+			body: gin.H{
+				"from_account_id":	account1.ID,
+				"to_account_id":	account2.ID,
+				"amount":			amount,
+				"currency":			util.USD,
+			},
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, user1.Username, user1.Role, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account1.ID)).Times(1).Return(account1, nil)
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account2.ID)).Times(1).Return(account2, nil)
+
+				remaining := int64(5)
+				limitErr := &db.LimitExceededError{
+					LimitID:			1,
+					Scope:				db.LimitScopeAccount,
+					Period:				util.LimitDaily,
+					Currency:			util.USD,
+					RemainingAmount:	&remaining,
+				}
+				store.EXPECT().
+					TransferTx(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.TransferTxResult{}, fmt.Errorf("tx err: %w", limitErr))
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnprocessableEntity, recorder.Code)
+
+				var got struct {
+					Limit	db.LimitExceededError	`json:"limit"`
+				}
+				err := json.Unmarshal(recorder.Body.Bytes(), &got)
+				require.NoError(t, err)
+				require.Equal(t, util.LimitDaily, got.Limit.Period)
+				require.Nil(t, got.Limit.RemainingCount)
+				require.Equal(t, int64(5), *got.Limit.RemainingAmount)
+			},
+		},
+		{
+			name: "ToRecipient",  // 按收款人的邮箱转账，返回打码后的收款人姓名
+			body: gin.H{
+				"from_account_id":	account1.ID,
+				"recipient":		user2.Email,
+				"amount":			amount,
+				"currency":			util.USD,
+			},
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, user1.Username, user1.Role, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account1.ID)).Times(1).Return(account1, nil)
+				store.EXPECT().GetUserByEmail(gomock.Any(), gomock.Eq(user2.Email)).Times(1).Return(user2, nil)
+				store.EXPECT().
+					GetAccountByOwner(gomock.Any(), gomock.Eq(db.GetAccountByOwnerParams{Owner: user2.Username, Currency: util.USD})).
+					Times(1).
+					Return(account2, nil)
+
+				arg := db.TransferTxParams{
+					FromAccountID:	account1.ID,
+					ToAccountID:	account2.ID,
+					Amount:			amount,
+					RequestedBy:	user1.Username,
+				}
+				store.EXPECT().TransferTx(gomock.Any(), gomock.Eq(arg)).Times(1)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+
+				var got transferResponse
+				err := json.Unmarshal(recorder.Body.Bytes(), &got)
+				require.NoError(t, err)
+				require.Equal(t, util.MaskName(user2.FullName), got.RecipientName)
+			},
+		},
+		{
+			name: "ToSelfByAccountID",
+			body: gin.H{
+				"from_account_id":	account1.ID,
+				"to_account_id":	account1.ID,
+				"amount":			amount,
+				"currency":			util.USD,
+			},
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, user1.Username, user1.Role, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account1.ID)).Times(2).Return(account1, nil)
+				store.EXPECT().TransferTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+		{
+			name: "ToSelfByRecipient",  // 收款人是自己，解析到的账户就是转出账户
+			body: gin.H{
+				"from_account_id":	account1.ID,
+				"recipient":		user1.Username,
+				"amount":			amount,
+				"currency":			util.USD,
+			},
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, user1.Username, user1.Role, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account1.ID)).Times(1).Return(account1, nil)
+				store.EXPECT().GetUser(gomock.Any(), gomock.Eq(user1.Username)).AnyTimes().Return(user1, nil)
+				store.EXPECT().
+					GetAccountByOwner(gomock.Any(), gomock.Eq(db.GetAccountByOwnerParams{Owner: user1.Username, Currency: util.USD})).
+					Times(1).
+					Return(account1, nil)
+				store.EXPECT().TransferTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+		{
+			name: "RecipientAccountNotFound",  // 收款人没有该币种的账户
+			body: gin.H{
+				"from_account_id":	account1.ID,
+				"recipient":		user3.Username,
+				"amount":			amount,
+				"currency":			util.USD,
+			},
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, user1.Username, user1.Role, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account1.ID)).Times(1).Return(account1, nil)
+				store.EXPECT().GetUser(gomock.Any(), gomock.Eq(user3.Username)).Times(1).Return(user3, nil)
+				store.EXPECT().
+					GetAccountByOwner(gomock.Any(), gomock.Eq(db.GetAccountByOwnerParams{Owner: user3.Username, Currency: util.USD})).
+					Times(1).
+					Return(db.Account{}, sql.ErrNoRows)
+				store.EXPECT().TransferTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusNotFound, recorder.Code)
+			},
+		},
+		{
+			name: "RecipientAndAccountID",  // 收款人和转入账户只能指定一个
+			body: gin.H{
+				"from_account_id":	account1.ID,
+				"to_account_id":	account2.ID,
+				"recipient":		user2.Username,
+				"amount":			amount,
+				"currency":			util.USD,
+			},
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, user1.Username, user1.Role, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Any()).Times(0)
+				store.EXPECT().TransferTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+		{
+			name: "NoRecipient",
+			body: gin.H{
+				"from_account_id":	account1.ID,
+				"amount":			amount,
+				"currency":			util.USD,
+			},
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, user1.Username, user1.Role, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Any()).Times(0)
+				store.EXPECT().TransferTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+		{
+			name: "WithReference",  // 备注、参考号和 metadata 保存到转账记录中
+			body: gin.H{
+				"from_account_id":		account1.ID,
+				"to_account_id":		account2.ID,
+				"amount":				amount,
+				"currency":				util.USD,
+				"memo":					"rent for May",
+				"external_reference":	"INV-1001",
+				"metadata":				gin.H{"invoice": "INV-1001"},
+			},
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, user1.Username, user1.Role, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account1.ID)).Times(1).Return(account1, nil)
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account2.ID)).Times(1).Return(account2, nil)
+
+				arg := db.TransferTxParams{
+					FromAccountID:		account1.ID,
+					ToAccountID:		account2.ID,
+					Amount:				amount,
+					RequestedBy:		user1.Username,
+					Memo:				"rent for May",
+					ExternalReference:	"INV-1001",
+					Metadata:			json.RawMessage(`{"invoice":"INV-1001"}`),
+				}
+				store.EXPECT().TransferTx(gomock.Any(), gomock.Eq(arg)).Times(1)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+			},
+		},
+		{
+			name: "DuplicateReference",
+			body: gin.H{
+				"from_account_id":		account1.ID,
+				"to_account_id":		account2.ID,
+				"amount":				amount,
+				"currency":				util.USD,
+				"external_reference":	"INV-1001",
+			},
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, user1.Username, user1.Role, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account1.ID)).Times(1).Return(account1, nil)
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account2.ID)).Times(1).Return(account2, nil)
+				store.EXPECT().
+					TransferTx(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.TransferTxResult{}, fmt.Errorf("%w: INV-1001", db.ErrDuplicateReference))
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusConflict, recorder.Code)
+			},
+		},
+		{
+			name: "InvalidMetadata",  // metadata 必须是 JSON 对象
+			body: gin.H{
+				"from_account_id":	account1.ID,
+				"to_account_id":	account2.ID,
+				"amount":			amount,
+				"currency":			util.USD,
+				"metadata":			[]string{"INV-1001"},
+			},
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, user1.Username, user1.Role, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Any()).Times(0)
+				store.EXPECT().TransferTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+		{
+			name: "TransferTxError",
+			body: gin.H{
+				"from_account_id":	account1.ID,
+				"to_account_id":	account2.ID,
+				"amount":			amount,
+				"currency":			util.USD,
+			},
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, user1.Username, user1.Role, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account1.ID)).Times(1).Return(account1, nil)
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account2.ID)).Times(1).Return(account2, nil)
+				store.EXPECT().TransferTx(gomock.Any(), gomock.Any()).Times(1).Return(db.TransferTxResult{}, sql.ErrTxDone)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusInternalServerError, recorder.Code)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			buildAuthStubs(store, user1.Username)
+			buildAuthStubs(store, user2.Username)
+			buildFraudStubs(store)
+			tc.buildStubs(store)
+
+			server := newTestServer(t, store)
+			recorder := httptest.NewRecorder()
+
+			data, err := json.Marshal(tc.body)
+			require.NoError(t, err)
+
+			url := "/transfers"
+			request, err := http.NewRequest(http.MethodPost, url, bytes.NewReader(data))
+			require.NoError(t, err)
+
+			tc.setupAuth(t, request, server.tokenMaker)
+			server.router.ServeHTTP(recorder, request)
+			tc.checkResponse(t, recorder)
+		})
+	}
+}
+
+func TestListTransfersByReferenceAPI(t *testing.T) {
+	user1, _ := randomUser(t)
+	user2, _ := randomUser(t)
+	banker, _ := randomUser(t)
+	banker.Role = util.BankerRole
+
+	account1 := randomAccount(user1.Username)
+	transfer := db.Transfer{
+		ID:					util.RandomInt(1, 1000),
+		FromAccountID:		account1.ID,
+		ExternalReference:	"INV-1001",
+	}
+
+	testCases := []struct{
+		name			string
+		query			string
+		user			db.User
+		buildStubs		func(store *mockdb.MockStore)
+		checkResponse	func(t *testing.T, recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name: "OwnTransfers",
+			query: "reference=INV-1001",
+			user: user1,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					ListTransfersByReference(gomock.Any(), gomock.Eq(db.ListTransfersByReferenceParams{
+						ExternalReference:	"INV-1001",
+						Owner:				user1.Username,
+					})).
+					Times(1).
+					Return([]db.Transfer{transfer}, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+
+				var got []db.Transfer
+				err := json.Unmarshal(recorder.Body.Bytes(), &got)
+				require.NoError(t, err)
+				require.Len(t, got, 1)
+				require.Equal(t, transfer.ID, got[0].ID)
+			},
+		},
+		{
+			name: "NoTransfer",
+			query: "reference=INV-1002",
+			user: user1,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().ListTransfersByReference(gomock.Any(), gomock.Any()).Times(1).Return(nil, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+				require.JSONEq(t, "[]", recorder.Body.String())
+			},
+		},
+		{
+			name: "BankerByAccount",
+			query: fmt.Sprintf("reference=INV-1001&from_account_id=%d", account1.ID),
+			user: banker,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account1.ID)).Times(1).Return(account1, nil)
+				store.EXPECT().
+					GetTransferByReference(gomock.Any(), gomock.Eq(db.GetTransferByReferenceParams{
+						FromAccountID:		account1.ID,
+						ExternalReference:	"INV-1001",
+					})).
+					Times(1).
+					Return(transfer, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+			},
+		},
+		{
+			name: "OtherUsersAccount",
+			query: fmt.Sprintf("reference=INV-1001&from_account_id=%d", account1.ID),
+			user: user2,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account1.ID)).Times(1).Return(account1, nil)
+				store.EXPECT().GetTransferByReference(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusForbidden, recorder.Code)
+			},
+		},
+		{
+			name: "NoReference",
+			query: "",
+			user: user1,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().ListTransfersByReference(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			buildAuthStubs(store, tc.user.Username)
+			tc.buildStubs(store)
+
+			server := newTestServer(t, store)
+			recorder := httptest.NewRecorder()
+
+			request, err := http.NewRequest(http.MethodGet, "/transfers?"+tc.query, nil)
+			require.NoError(t, err)
+
+			addAuthorization(t, request, server.tokenMaker, authorizationTypeBearer, tc.user.Username, tc.user.Role, time.Minute)
+			server.router.ServeHTTP(recorder, request)
+			tc.checkResponse(t, recorder)
+		})
+	}
+}
+
+func TestTransferAPIIdempotencyKey(t *testing.T) {
+	amount := int64(10)
+
+	user1, _ := randomUser(t)
+	user2, _ := randomUser(t)
+
+	account1 := randomAccount(user1.Username)
+	account2 := randomAccount(user2.Username)
+	account1.Currency = util.USD
+	account2.Currency = util.USD
+
+	req := transferRequest{
+		FromAccountID:	account1.ID,
+		ToAccountID:	account2.ID,
+		Amount:			amount,
+		Currency:		util.USD,
+	}
+	idempotencyKey := util.RandomString(32)
+
+	testCases := []struct{
+		name			string
+		idempotencyKey	string
+		buildStubs		func(store *mockdb.MockStore)
+		checkResponse	func(t *testing.T, recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name: "OK",
+			idempotencyKey: idempotencyKey,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account1.ID)).Times(1).Return(account1, nil)
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account2.ID)).Times(1).Return(account2, nil)
+				buildFraudStubs(store)
+				store.EXPECT().
+					GetIdempotencyKey(gomock.Any(), gomock.Eq(db.GetIdempotencyKeyParams{Username: user1.Username, IdempotencyKey: idempotencyKey})).
+					Times(1).
+					Return(db.IdempotencyKey{}, sql.ErrNoRows)
+				store.EXPECT().TransferTx(gomock.Any(), gomock.Any()).Times(0)
+				store.EXPECT().
+					IdempotentTransferTx(gomock.Any(), gomock.Any()).
+					Times(1).
+					DoAndReturn(func(_ interface{}, arg db.IdempotentTransferTxParams) (db.TransferTxResult, error) {
+						require.Equal(t, account1.ID, arg.FromAccountID)
+						require.Equal(t, account2.ID, arg.ToAccountID)
+						require.Equal(t, amount, arg.Amount)
+						require.Equal(t, user1.Username, arg.Username)
+						require.Equal(t, idempotencyKey, arg.IdempotencyKey)
+						require.Equal(t, transferRequestHash(req), arg.RequestHash)
+						require.WithinDuration(t, time.Now().Add(time.Hour), arg.ExpiresAt, time.Second)
+						return db.TransferTxResult{}, nil
+					})
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+			},
+		},
+		{
+			name: "KeyReused",
+			idempotencyKey: idempotencyKey,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account1.ID)).Times(1).Return(account1, nil)
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account2.ID)).Times(1).Return(account2, nil)
+				buildFraudStubs(store)
+				store.EXPECT().
+					GetIdempotencyKey(gomock.Any(), gomock.Eq(db.GetIdempotencyKeyParams{Username: user1.Username, IdempotencyKey: idempotencyKey})).
+					Times(1).
+					Return(db.IdempotencyKey{}, sql.ErrNoRows)
+				store.EXPECT().
+					IdempotentTransferTx(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.TransferTxResult{}, db.ErrIdempotencyKeyReused)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnprocessableEntity, recorder.Code)
+			},
+		},
+		{
+			name: "Replay",
+			idempotencyKey: idempotencyKey,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account1.ID)).Times(1).Return(account1, nil)
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account2.ID)).Times(1).Return(account2, nil)
+				store.EXPECT().
+					GetIdempotencyKey(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.IdempotencyKey{
+						RequestHash:	transferRequestHash(req),
+						Response:		json.RawMessage(`{"transfer": {"id": 42, "status": "completed"}}`),
+						ExpiresAt:		time.Now().Add(time.Hour),
+					}, nil)
+				// 重放时不再进行风控检查，也不会再次转账
+				store.EXPECT().CreateFraudDecision(gomock.Any(), gomock.Any()).Times(0)
+				store.EXPECT().SetFraudDecisionTransfer(gomock.Any(), gomock.Any()).Times(0)
+				store.EXPECT().IdempotentTransferTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+
+				var got transferResponse
+				err := json.Unmarshal(recorder.Body.Bytes(), &got)
+				require.NoError(t, err)
+				require.Equal(t, int64(42), got.Transfer.ID)
+			},
+		},
+		{
+			name: "ReplayDifferentRequest",
+			idempotencyKey: idempotencyKey,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account1.ID)).Times(1).Return(account1, nil)
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account2.ID)).Times(1).Return(account2, nil)
+				store.EXPECT().
+					GetIdempotencyKey(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.IdempotencyKey{RequestHash: "other", ExpiresAt: time.Now().Add(time.Hour)}, nil)
+				store.EXPECT().CreateFraudDecision(gomock.Any(), gomock.Any()).Times(0)
+				store.EXPECT().IdempotentTransferTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnprocessableEntity, recorder.Code)
+			},
+		},
+		{
+			name: "ExpiredKey",
+			idempotencyKey: idempotencyKey,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account1.ID)).Times(1).Return(account1, nil)
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account2.ID)).Times(1).Return(account2, nil)
+				store.EXPECT().
+					GetIdempotencyKey(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.IdempotencyKey{RequestHash: "other", ExpiresAt: time.Now().Add(-time.Minute)}, nil)
+				buildFraudStubs(store)
+				store.EXPECT().IdempotentTransferTx(gomock.Any(), gomock.Any()).Times(1).Return(db.TransferTxResult{}, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+			},
+		},
+		{
+			name: "ConcurrentReplay",
+			idempotencyKey: idempotencyKey,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account1.ID)).Times(1).Return(account1, nil)
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account2.ID)).Times(1).Return(account2, nil)
+				store.EXPECT().GetIdempotencyKey(gomock.Any(), gomock.Any()).Times(1).Return(db.IdempotencyKey{}, sql.ErrNoRows)
+				store.EXPECT().
+					CreateFraudDecision(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.FraudDecision{ID: 1, Decision: string(fraud.Allow)}, nil)
+				// 事务中等待第一个请求提交后重放，风控记录不关联到第一次的转账上
+				store.EXPECT().
+					IdempotentTransferTx(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.TransferTxResult{Transfer: db.Transfer{ID: 42}, Replayed: true}, nil)
+				store.EXPECT().SetFraudDecisionTransfer(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+			},
+		},
+		{
+			name: "KeyTooLong",
+			idempotencyKey: util.RandomString(maxIdempotencyKeyLength + 1),
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Any()).Times(0)
+				store.EXPECT().IdempotentTransferTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			buildAuthStubs(store, user1.Username)
+			tc.buildStubs(store)
+
+			server := newTestServer(t, store)
+			recorder := httptest.NewRecorder()
+
+			data, err := json.Marshal(req)
+			require.NoError(t, err)
+
+			request, err := http.NewRequest(http.MethodPost, "/transfers", bytes.NewReader(data))
+			require.NoError(t, err)
+			request.Header.Set(idempotencyKeyHeader, tc.idempotencyKey)
+
+			addAuthorization(t, request, server.tokenMaker, authorizationTypeBearer, user1.Username, user1.Role, time.Minute)
+			server.router.ServeHTTP(recorder, request)
+			tc.checkResponse(t, recorder)
+		})
+	}
+}
+
+func TestReverseTransferAPI(t *testing.T) {
+	sender, _ := randomUser(t)
+	recipient, _ := randomUser(t)
+	banker, _ := randomUser(t)
+	banker.Role = util.BankerRole
+
+	account1 := randomAccount(sender.Username)
+	account2 := randomAccount(recipient.Username)
+
+	transfer := db.Transfer{
+		ID:				util.RandomInt(1, 1000),
+		FromAccountID:	account1.ID,
+		ToAccountID:	account2.ID,
+		Amount:			100,
+		ToAmount:		100,
+		ReversedAmount:	30,
+		Status:			util.TransferPartiallyReversed,
+	}
+
+	testCases := []struct{
+		name			string
+		body			gin.H
+		user			db.User
+		buildStubs		func(store *mockdb.MockStore)
+		checkResponse	func(t *testing.T, recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name: "RecipientRefundsRemaining",
+			user: recipient,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetTransfers(gomock.Any(), gomock.Eq(transfer.ID)).Times(1).Return(transfer, nil)
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account2.ID)).Times(1).Return(account2, nil)
+
+				// 资金退回原来的转出账户，不做风控检查
+				store.EXPECT().CreateFraudDecision(gomock.Any(), gomock.Any()).Times(0)
+
+				arg := db.ReverseTransferTxParams{TransferID: transfer.ID, Amount: 70, RequestedBy: recipient.Username}
+				store.EXPECT().ReverseTransferTx(gomock.Any(), gomock.Eq(arg)).Times(1)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+			},
+		},
+		{
+			name: "BankerPartialReversal",
+			body: gin.H{"amount": 20},
+			user: banker,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetTransfers(gomock.Any(), gomock.Eq(transfer.ID)).Times(1).Return(transfer, nil)
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Any()).Times(0)
+
+				arg := db.ReverseTransferTxParams{TransferID: transfer.ID, Amount: 20, RequestedBy: banker.Username}
+				store.EXPECT().ReverseTransferTx(gomock.Any(), gomock.Eq(arg)).Times(1)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+			},
+		},
+		{
+			name: "SenderCannotReverse",
+			user: sender,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetTransfers(gomock.Any(), gomock.Eq(transfer.ID)).Times(1).Return(transfer, nil)
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account2.ID)).Times(1).Return(account2, nil)
+				store.EXPECT().ReverseTransferTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusForbidden, recorder.Code)
+			},
+		},
+		{
+			name: "ExceedsAmount",
+			body: gin.H{"amount": 80},
+			user: recipient,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetTransfers(gomock.Any(), gomock.Eq(transfer.ID)).Times(1).Return(transfer, nil)
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account2.ID)).Times(1).Return(account2, nil)
+				store.EXPECT().
+					ReverseTransferTx(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.ReverseTransferTxResult{}, db.ErrInvalidReversal)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnprocessableEntity, recorder.Code)
+			},
+		},
+		{
+			name: "InvalidAmount",
+			body: gin.H{"amount": -1},
+			user: recipient,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetTransfers(gomock.Any(), gomock.Any()).Times(0)
+				store.EXPECT().ReverseTransferTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+		{
+			name: "TransferNotFound",
+			user: recipient,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetTransfers(gomock.Any(), gomock.Any()).Times(1).Return(db.Transfer{}, sql.ErrNoRows)
+				store.EXPECT().ReverseTransferTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusNotFound, recorder.Code)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			buildAuthStubs(store, tc.user.Username)
+			tc.buildStubs(store)
+
+			server := newTestServer(t, store)
+			recorder := httptest.NewRecorder()
+
+			var body bytes.Buffer
+			if tc.body != nil {
+				err := json.NewEncoder(&body).Encode(tc.body)
+				require.NoError(t, err)
+			}
+
+			url := fmt.Sprintf("/transfers/%d/reverse", transfer.ID)
+			request, err := http.NewRequest(http.MethodPost, url, &body)
+			require.NoError(t, err)
+
+			addAuthorization(t, request, server.tokenMaker, authorizationTypeBearer, tc.user.Username, tc.user.Role, time.Minute)
+			server.router.ServeHTTP(recorder, request)
+			tc.checkResponse(t, recorder)
+		})
+	}
+}
+
+func TestDecideTransferAPI(t *testing.T) {
+	requester, _ := randomUser(t)
+	banker, _ := randomUser(t)
+	banker.Role = util.BankerRole
+	transferID := util.RandomInt(1, 1000)
+
+	testCases := []struct{
+		name			string
+		action			string
+		user			db.User
+		buildStubs		func(store *mockdb.MockStore)
+		checkResponse	func(t *testing.T, recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name: "Approve",
+			action: "approve",
+			user: banker,
+			buildStubs: func(store *mockdb.MockStore) {
+				// 转账在创建时已经做过风控检查
+				store.EXPECT().CreateFraudDecision(gomock.Any(), gomock.Any()).Times(0)
+
+				arg := db.DecideTransferTxParams{TransferID: transferID, Approver: banker.Username}
+				store.EXPECT().
+					ApproveTransferTx(gomock.Any(), gomock.Eq(arg)).
+					Times(1).
+					Return(db.TransferTxResult{Transfer: db.Transfer{ID: transferID, Status: util.TransferCompleted}}, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+			},
+		},
+		{
+			name: "Reject",
+			action: "reject",
+			user: banker,
+			buildStubs: func(store *mockdb.MockStore) {
+				arg := db.DecideTransferTxParams{TransferID: transferID, Approver: banker.Username}
+				store.EXPECT().
+					RejectTransferTx(gomock.Any(), gomock.Eq(arg)).
+					Times(1).
+					Return(db.Transfer{ID: transferID, Status: util.TransferRejected}, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+			},
+		},
+		{
+			name: "DepositorForbidden",
+			action: "approve",
+			user: requester,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().ApproveTransferTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusForbidden, recorder.Code)
+			},
+		},
+		{
+			name: "SelfApproval",
+			action: "approve",
+			user: banker,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					ApproveTransferTx(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.TransferTxResult{}, db.ErrSelfApproval)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusForbidden, recorder.Code)
+			},
+		},
+		{
+			name: "NotPending",
+			action: "reject",
+			user: banker,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					RejectTransferTx(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.Transfer{}, db.ErrInvalidTransferStatus)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnprocessableEntity, recorder.Code)
+			},
+		},
+		{
+			name: "InsufficientFunds",
+			action: "approve",
+			user: banker,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					ApproveTransferTx(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.TransferTxResult{}, db.ErrInsufficientFunds)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnprocessableEntity, recorder.Code)
+			},
+		},
+		{
+			name: "NotFound",
+			action: "approve",
+			user: banker,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					ApproveTransferTx(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.TransferTxResult{}, sql.ErrNoRows)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusNotFound, recorder.Code)
+			},
+		},
+		{
+			name: "RejectNotFoundWrapped",
+			action: "reject",
+			user: banker,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					RejectTransferTx(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.Transfer{}, fmt.Errorf("cannot lock transfer: %w", sql.ErrNoRows))
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusNotFound, recorder.Code)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			buildAuthStubs(store, tc.user.Username)
+			tc.buildStubs(store)
+
+			server := newTestServer(t, store)
+			recorder := httptest.NewRecorder()
+
+			url := fmt.Sprintf("/transfers/%d/%s", transferID, tc.action)
+			request, err := http.NewRequest(http.MethodPost, url, nil)
+			require.NoError(t, err)
+
+			addAuthorization(t, request, server.tokenMaker, authorizationTypeBearer, tc.user.Username, tc.user.Role, time.Minute)
+			server.router.ServeHTTP(recorder, request)
+			tc.checkResponse(t, recorder)
+		})
+	}
+}
@@ -1,10 +1,15 @@
 package api
 
 import (
+	"database/sql"
+	"errors"
 	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
 	"github.com/lib/pq"
 	db "github.com/techschool/simplebank/db/sqlc"
+	"github.com/techschool/simplebank/token"
 	"github.com/techschool/simplebank/util"
+	"io"
 	"net/http"
 	"time"
 )
@@ -12,7 +17,7 @@ import (
 type createUserRequest struct {
 	// 需要对参数进行验证
 	Username	string	`json:"username" binding:"required,alphanum"`
-	Password	string	`json:"hashed_password" binding:"required,min=6"`
+	Password	string	`json:"password" binding:"required,min=6"`
 	FullName	string	`json:"full_name" binding:"required"`
 	Email		string	`json:"email" binding:"required,email"`
 }
@@ -21,6 +26,7 @@ type userResponse struct {
 	Username         string    `json:"username"`
 	FullName         string    `json:"full_name"`
 	Email            string    `json:"email"`
+	Role             string    `json:"role"`
 	PasswordChangeAt time.Time `json:"password_change_at"`
 	CreatedAt        time.Time `json:"created_at"`
 }
@@ -31,6 +37,7 @@ func newUserResponse(user db.User) userResponse {
 		Username:         user.Username,
 		FullName:         user.FullName,
 		Email:            user.Email,
+		Role:             user.Role,
 		PasswordChangeAt: user.PasswordChangeAt,
 		CreatedAt:        user.CreatedAt,
 	}
@@ -39,7 +46,7 @@ func newUserResponse(user db.User) userResponse {
 // createUser POST请求创建User
 func (server *Server) createUser(ctx *gin.Context) {
 	var req createUserRequest
-	if err := ctx.ShouldBind(&req); err != nil {
+	if err := ctx.ShouldBindJSON(&req); err != nil {
 		// 提交参数验证错误，返回400 请求错误
 		ctx.JSON(http.StatusBadRequest, errorResponse(err))
 		return
@@ -58,6 +65,7 @@ func (server *Server) createUser(ctx *gin.Context) {
 		HashedPassword:	hashPassword,
 		FullName: 		req.FullName,
 		Email: 			req.Email,
+		Role:			util.DepositorRole,
 	}
 
 	user, err := server.store.CreateUser(ctx, arg)
@@ -73,6 +81,7 @@ func (server *Server) createUser(ctx *gin.Context) {
 		}
 		// 其他情况下返回500 归结为服务器端的错误
 		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
 	}
 	rsp := newUserResponse(user)
 	ctx.JSON(http.StatusOK, rsp)
@@ -80,24 +89,32 @@ func (server *Server) createUser(ctx *gin.Context) {
 
 type loginUserRequest struct {
 	UserName	string	`json:"username" binding:"required,alphanum"`
-	Password	string	`json:"password" binding:"requires,min=6"`
+	Password	string	`json:"password" binding:"required,min=6"`
 }
 
 type loginUserResponse struct {
-	AccessToken	string			`json:"access_token"`
-	User		userResponse	`json:"user"`
+	SessionID				uuid.UUID		`json:"session_id"`
+	AccessToken				string			`json:"access_token"`
+	AccessTokenExpiresAt	time.Time		`json:"access_token_expires_at"`
+	RefreshToken			string			`json:"refresh_token"`
+	RefreshTokenExpiresAt	time.Time		`json:"refresh_token_expires_at"`
+	User					userResponse	`json:"user"`
 }
 
 func (server *Server) loginUser(ctx *gin.Context) {
 	var req loginUserRequest
-	if err := ctx.ShouldBind(&req); err != nil {
+	if err := ctx.ShouldBindJSON(&req); err != nil {
 		ctx.JSON(http.StatusBadRequest, errorResponse(err))
 		return
 	}
 
 	user, err := server.store.GetUser(ctx, req.UserName)
 	if err != nil {
-		ctx.JSON(http.StatusNotFound, errorResponse(err))
+		if err == sql.ErrNoRows {
+			ctx.JSON(http.StatusNotFound, errorResponse(err))
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
 		return
 	}
 
@@ -108,18 +125,160 @@ func (server *Server) loginUser(ctx *gin.Context) {
 		return
 	}
 
-	accessToken, err := server.tokenMaker.CreateToken(req.UserName, server.config.AccessTokenDuration)
+	accessToken, accessPayload, err := server.tokenMaker.CreateToken(user.Username, user.Role, token.TokenTypeAccessToken, server.config.AccessTokenDuration)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	// refresh token 有效期较长，用于在 access token 过期后换取新的 access token
+	refreshToken, refreshPayload, err := server.tokenMaker.CreateToken(user.Username, user.Role, token.TokenTypeRefreshToken, server.config.RefreshTokenDuration)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	// 以 refresh token 的 ID 作为 session 的主键保存到数据库中
+	session, err := server.store.CreateSession(ctx, db.CreateSessionParams{
+		ID:				refreshPayload.ID,
+		Username:		user.Username,
+		RefreshToken:	refreshToken,
+		UserAgent:		ctx.Request.UserAgent(),
+		ClientIp:		ctx.ClientIP(),
+		IsBlocked:		false,
+		ExpiresAt:		refreshPayload.ExpiredAt,
+	})
 	if err != nil {
 		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
 		return
 	}
 
 	rsp := loginUserResponse{
-		AccessToken: accessToken,
-		User: newUserResponse(user),
+		SessionID:				session.ID,
+		AccessToken:			accessToken,
+		AccessTokenExpiresAt:	accessPayload.ExpiredAt,
+		RefreshToken:			refreshToken,
+		RefreshTokenExpiresAt:	refreshPayload.ExpiredAt,
+		User:					newUserResponse(user),
 	}
 	ctx.JSON(http.StatusOK, rsp)
 }
 
+type logoutUserRequest struct {
+	RefreshToken	string	`json:"refresh_token"`
+}
+
+// logoutUser 吊销当前的 access token，如果提供了 refresh token，同时封禁对应的 session
+func (server *Server) logoutUser(ctx *gin.Context) {
+	var req logoutUserRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil && err != io.EOF {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	authPayload := ctx.MustGet(authorizationHeaderKey).(*token.Payload)
+
+	if req.RefreshToken != "" {
+		refreshPayload, err := server.tokenMaker.VerifyToken(req.RefreshToken, token.TokenTypeRefreshToken)
+		if err != nil {
+			ctx.JSON(http.StatusUnauthorized, errorResponse(err))
+			return
+		}
+		if refreshPayload.Username != authPayload.Username {
+			err := errors.New("refresh token doesn't belong to the authenticated user")
+			ctx.JSON(http.StatusUnauthorized, errorResponse(err))
+			return
+		}
+
+		err = server.store.BlockSession(ctx, refreshPayload.ID)
+		if err != nil {
+			ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+			return
+		}
+	}
+
+	err := server.store.CreateRevokedToken(ctx, db.CreateRevokedTokenParams{
+		ID:			authPayload.ID,
+		Username:	authPayload.Username,
+		ExpiresAt:	authPayload.ExpiredAt,
+	})
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+	ctx.JSON(http.StatusOK, gin.H{})
+}
+
+// logoutAllUser 让该用户此前签发的所有 token 失效，并封禁所有 session
+func (server *Server) logoutAllUser(ctx *gin.Context) {
+	authPayload := ctx.MustGet(authorizationHeaderKey).(*token.Payload)
+
+	_, err := server.store.RevokeUserTokens(ctx, db.RevokeUserTokensParams{
+		RevokedAt:	time.Now(),
+		Username:	authPayload.Username,
+	})
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	err = server.store.BlockUserSessions(ctx, authPayload.Username)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+	ctx.JSON(http.StatusOK, gin.H{})
+}
+
+type updateUserRoleURI struct {
+	Username	string	`uri:"username" binding:"required,alphanum"`
+}
 
+type updateUserRoleRequest struct {
+	Role	string	`json:"role" binding:"required,role"`
+}
 
+// updateUserRole 修改用户角色，仅管理员可用
+func (server *Server) updateUserRole(ctx *gin.Context) {
+	var uri updateUserRoleURI
+	if err := ctx.ShouldBindUri(&uri); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	var req updateUserRoleRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	user, err := server.store.UpdateUserRole(ctx, db.UpdateUserRoleParams{
+		Role:		req.Role,
+		Username:	uri.Username,
+	})
+	if err != nil {
+		if err == sql.ErrNoRows {
+			ctx.JSON(http.StatusNotFound, errorResponse(err))
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	// 已签发的 token 中仍然是旧角色，需要让用户重新登录
+	user, err = server.store.RevokeUserTokens(ctx, db.RevokeUserTokensParams{
+		RevokedAt:	time.Now(),
+		Username:	user.Username,
+	})
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	err = server.store.BlockUserSessions(ctx, user.Username)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+	ctx.JSON(http.StatusOK, newUserResponse(user))
+}
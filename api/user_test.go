@@ -11,12 +11,14 @@ import (
 	"github.com/stretchr/testify/require"
 	mockdb "github.com/techschool/simplebank/db/mock"
 	db "github.com/techschool/simplebank/db/sqlc"
+	"github.com/techschool/simplebank/token"
 	"github.com/techschool/simplebank/util"
 	"io/ioutil"
 	"net/http"
 	"net/http/httptest"
 	"reflect"
 	"testing"
+	"time"
 )
 
 type eqCreateUserParamsMatcher struct {
@@ -70,6 +72,7 @@ func TestCreateUserAPI(t *testing.T) {
 					Username: user.Username,
 					FullName: user.FullName,
 					Email: user.Email,
+					Role: util.DepositorRole,
 				}
 				store.EXPECT().
 					CreateUser(gomock.Any(), EqCreateUserParams(arg, password)).
@@ -219,6 +222,9 @@ func TestLoginUserAPI(t *testing.T) {
 					GetUser(gomock.Any(), gomock.Eq(user.Username)).
 					Times(1).
 					Return(user, nil)
+				store.EXPECT().
+					CreateSession(gomock.Any(), gomock.Any()).
+					Times(1)
 			},
 			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
 				require.Equal(t, http.StatusOK, recorder.Code)
@@ -229,13 +235,16 @@ func TestLoginUserAPI(t *testing.T) {
 			name: "IncorrectPassword",
 			body: gin.H{
 				"username": user.Username,
-				"password": password,
+				"password": "incorrect",
 			},
 			buildStubs: func(store *mockdb.MockStore) {
 				store.EXPECT().
 					GetUser(gomock.Any(), gomock.Eq(user.Username)).
 					Times(1).
 					Return(user, nil)
+				store.EXPECT().
+					CreateSession(gomock.Any(), gomock.Any()).
+					Times(0)
 			},
 			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
 				require.Equal(t, http.StatusUnauthorized, recorder.Code)
@@ -252,7 +261,7 @@ func TestLoginUserAPI(t *testing.T) {
 				store.EXPECT().
 					GetUser(gomock.Any(), gomock.Any()).
 					Times(1).
-					Return(user, nil)
+					Return(db.User{}, sql.ErrNoRows)
 			},
 			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
 				require.Equal(t, http.StatusNotFound, recorder.Code)
@@ -269,7 +278,28 @@ func TestLoginUserAPI(t *testing.T) {
 				store.EXPECT().
 					GetUser(gomock.Any(), gomock.Any()).
 					Times(1).
+					Return(db.User{}, sql.ErrConnDone)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusInternalServerError, recorder.Code)
+			},
+		},
+		// 创建 session 失败
+		{
+			name: "CreateSessionError",
+			body: gin.H{
+				"username": user.Username,
+				"password": password,
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetUser(gomock.Any(), gomock.Eq(user.Username)).
+					Times(1).
 					Return(user, nil)
+				store.EXPECT().
+					CreateSession(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.Session{}, sql.ErrConnDone)
 			},
 			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
 				require.Equal(t, http.StatusInternalServerError, recorder.Code)
@@ -285,8 +315,7 @@ func TestLoginUserAPI(t *testing.T) {
 			buildStubs: func(store *mockdb.MockStore) {
 				store.EXPECT().
 					GetUser(gomock.Any(), gomock.Any()).
-					Times(1).
-					Return(user, nil)
+					Times(0)
 			},
 			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
 				require.Equal(t, http.StatusBadRequest, recorder.Code)
@@ -321,6 +350,297 @@ func TestLoginUserAPI(t *testing.T) {
 	}
 }
 
+// TestLogoutUserAPI 用户登出测试
+func TestLogoutUserAPI(t *testing.T) {
+	user, _ := randomUser(t)
+
+	testCases := []struct{
+		name			string
+		buildBody		func(t *testing.T, tokenMaker token.Maker) gin.H
+		buildStubs		func(store *mockdb.MockStore)
+		checkResponse	func(t *testing.T, recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name: "OK",
+			buildBody: func(t *testing.T, tokenMaker token.Maker) gin.H {
+				return gin.H{}
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					BlockSession(gomock.Any(), gomock.Any()).
+					Times(0)
+				store.EXPECT().
+					CreateRevokedToken(gomock.Any(), gomock.Any()).
+					Times(1)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+			},
+		},
+		{
+			name: "WithRefreshToken",
+			buildBody: func(t *testing.T, tokenMaker token.Maker) gin.H {
+				refreshToken, _, err := tokenMaker.CreateToken(user.Username, user.Role, token.TokenTypeRefreshToken, time.Hour)
+				require.NoError(t, err)
+				return gin.H{"refresh_token": refreshToken}
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					BlockSession(gomock.Any(), gomock.Any()).
+					Times(1)
+				store.EXPECT().
+					CreateRevokedToken(gomock.Any(), gomock.Any()).
+					Times(1)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+			},
+		},
+		{
+			name: "OtherUserRefreshToken",
+			buildBody: func(t *testing.T, tokenMaker token.Maker) gin.H {
+				refreshToken, _, err := tokenMaker.CreateToken(util.RandomUserName(), util.DepositorRole, token.TokenTypeRefreshToken, time.Hour)
+				require.NoError(t, err)
+				return gin.H{"refresh_token": refreshToken}
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					BlockSession(gomock.Any(), gomock.Any()).
+					Times(0)
+				store.EXPECT().
+					CreateRevokedToken(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+			},
+		},
+		{
+			name: "InternalError",
+			buildBody: func(t *testing.T, tokenMaker token.Maker) gin.H {
+				return gin.H{}
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					CreateRevokedToken(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(sql.ErrConnDone)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusInternalServerError, recorder.Code)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			buildAuthStubs(store, user.Username)
+			tc.buildStubs(store)
+
+			server := newTestServer(t, store)
+			recorder := httptest.NewRecorder()
+
+			data, err := json.Marshal(tc.buildBody(t, server.tokenMaker))
+			require.NoError(t, err)
+
+			url := "/users/logout"
+			request, err := http.NewRequest(http.MethodPost, url, bytes.NewReader(data))
+			require.NoError(t, err)
+
+			addAuthorization(t, request, server.tokenMaker, authorizationTypeBearer, user.Username, user.Role, time.Minute)
+			server.router.ServeHTTP(recorder, request)
+			tc.checkResponse(t, recorder)
+		})
+	}
+}
+
+// TestLogoutAllUserAPI 登出所有设备测试
+func TestLogoutAllUserAPI(t *testing.T) {
+	user, _ := randomUser(t)
+
+	testCases := []struct{
+		name			string
+		buildStubs		func(store *mockdb.MockStore)
+		checkResponse	func(t *testing.T, recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name: "OK",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					RevokeUserTokens(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(user, nil)
+				store.EXPECT().
+					BlockUserSessions(gomock.Any(), gomock.Eq(user.Username)).
+					Times(1)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+			},
+		},
+		{
+			name: "InternalError",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					RevokeUserTokens(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.User{}, sql.ErrConnDone)
+				store.EXPECT().
+					BlockUserSessions(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusInternalServerError, recorder.Code)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			buildAuthStubs(store, user.Username)
+			tc.buildStubs(store)
+
+			server := newTestServer(t, store)
+			recorder := httptest.NewRecorder()
+
+			url := "/users/logout_all"
+			request, err := http.NewRequest(http.MethodPost, url, nil)
+			require.NoError(t, err)
+
+			addAuthorization(t, request, server.tokenMaker, authorizationTypeBearer, user.Username, user.Role, time.Minute)
+			server.router.ServeHTTP(recorder, request)
+			tc.checkResponse(t, recorder)
+		})
+	}
+}
+
+// TestUpdateUserRoleAPI 修改用户角色测试
+func TestUpdateUserRoleAPI(t *testing.T) {
+	admin, _ := randomUser(t)
+	admin.Role = util.AdminRole
+	user, _ := randomUser(t)
+
+	testCases := []struct{
+		name			string
+		role			string
+		body			gin.H
+		buildStubs		func(store *mockdb.MockStore)
+		checkResponse	func(t *testing.T, recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name: "OK",
+			role: util.AdminRole,
+			body: gin.H{"role": util.BankerRole},
+			buildStubs: func(store *mockdb.MockStore) {
+				arg := db.UpdateUserRoleParams{
+					Role:		util.BankerRole,
+					Username:	user.Username,
+				}
+				banker := user
+				banker.Role = util.BankerRole
+				store.EXPECT().
+					UpdateUserRole(gomock.Any(), gomock.Eq(arg)).
+					Times(1).
+					Return(banker, nil)
+				store.EXPECT().
+					RevokeUserTokens(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(banker, nil)
+				store.EXPECT().
+					BlockUserSessions(gomock.Any(), gomock.Eq(user.Username)).
+					Times(1)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+
+				var rsp userResponse
+				err := json.Unmarshal(recorder.Body.Bytes(), &rsp)
+				require.NoError(t, err)
+				require.Equal(t, util.BankerRole, rsp.Role)
+			},
+		},
+		{
+			name: "NotAdmin",
+			role: util.BankerRole,
+			body: gin.H{"role": util.AdminRole},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					UpdateUserRole(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusForbidden, recorder.Code)
+			},
+		},
+		{
+			name: "InvalidRole",
+			role: util.AdminRole,
+			body: gin.H{"role": "invalid"},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					UpdateUserRole(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+		{
+			name: "UserNotFound",
+			role: util.AdminRole,
+			body: gin.H{"role": util.BankerRole},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					UpdateUserRole(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.User{}, sql.ErrNoRows)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusNotFound, recorder.Code)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			buildAuthStubs(store, admin.Username)
+			tc.buildStubs(store)
+
+			server := newTestServer(t, store)
+			recorder := httptest.NewRecorder()
+
+			data, err := json.Marshal(tc.body)
+			require.NoError(t, err)
+
+			url := fmt.Sprintf("/users/%s/role", user.Username)
+			request, err := http.NewRequest(http.MethodPatch, url, bytes.NewReader(data))
+			require.NoError(t, err)
+
+			addAuthorization(t, request, server.tokenMaker, authorizationTypeBearer, admin.Username, tc.role, time.Minute)
+			server.router.ServeHTTP(recorder, request)
+			tc.checkResponse(t, recorder)
+		})
+	}
+}
+
 // randomUser 随机生成一个User
 // 此处返回的password是明文密码，而在user中的是经过hash后的密码
 func randomUser(t *testing.T) (user db.User, password string) {
@@ -333,6 +653,7 @@ func randomUser(t *testing.T) (user db.User, password string) {
 		HashedPassword: hashPassword,
 		FullName: 		util.RandomFullName(),
 		Email: 			util.RandomEmail(),
+		Role:			util.DepositorRole,
 	}
 	return
 }
@@ -349,5 +670,7 @@ func requireBodyMatchUser(t *testing.T, body *bytes.Buffer, user db.User) {
 	require.Equal(t, user.Username, gotUser.Username)
 	require.Equal(t, user.FullName, gotUser.FullName)
 	require.Equal(t, user.Email, gotUser.Email)
-	require.NotEmpty(t, gotUser.HashedPassword)
+	require.Equal(t, user.Role, gotUser.Role)
+	// 返回的数据中不能包含密码
+	require.Empty(t, gotUser.HashedPassword)
 }
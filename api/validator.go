@@ -1,6 +1,7 @@
 package api
 
 import (
+	"encoding/json"
 	"github.com/go-playground/validator/v10"
 	"github.com/techschool/simplebank/util"
 )
@@ -12,3 +13,37 @@ var validCurrency validator.Func = func(fieldLevel validator.FieldLevel) bool {
 	return false
 }
 
+var validRole validator.Func = func(fieldLevel validator.FieldLevel) bool {
+	if role, ok := fieldLevel.Field().Interface().(string); ok {
+		return util.IsSupportRole(role)
+	}
+	return false
+}
+
+var validExchangeRate validator.Func = func(fieldLevel validator.FieldLevel) bool {
+	if rate, ok := fieldLevel.Field().Interface().(string); ok {
+		return util.IsValidExchangeRate(rate)
+	}
+	return false
+}
+
+var validSchedule validator.Func = func(fieldLevel validator.FieldLevel) bool {
+	if schedule, ok := fieldLevel.Field().Interface().(string); ok {
+		return util.IsValidSchedule(schedule)
+	}
+	return false
+}
+
+var validLimitPeriod validator.Func = func(fieldLevel validator.FieldLevel) bool {
+	if period, ok := fieldLevel.Field().Interface().(string); ok {
+		return util.IsSupportLimitPeriod(period)
+	}
+	return false
+}
+
+var validMetadata validator.Func = func(fieldLevel validator.FieldLevel) bool {
+	if metadata, ok := fieldLevel.Field().Interface().(json.RawMessage); ok {
+		return util.IsValidMetadata(metadata)
+	}
+	return false
+}
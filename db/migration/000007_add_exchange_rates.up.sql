@@ -0,0 +1,26 @@
+CREATE TABLE "exchange_rates" (
+    "id" bigserial PRIMARY KEY,
+    "base_currency" varchar NOT NULL,
+    "quote_currency" varchar NOT NULL,
+    "rate" numeric(20, 10) NOT NULL,
+    "created_by" varchar NOT NULL,
+    "created_at" timestamptz NOT NULL DEFAULT (now())
+);
+
+ALTER TABLE "exchange_rates" ADD FOREIGN KEY ("created_by") REFERENCES "users" ("username");
+
+CREATE INDEX ON "exchange_rates" ("base_currency", "quote_currency", "created_at");
+
+COMMENT ON COLUMN "exchange_rates"."rate" IS '1 unit of base currency = rate units of quote currency';
+
+-- 跨币种转账时，转入账户收到 to_amount，使用的汇率记录在转账上用于审计
+ALTER TABLE "transfers" ADD COLUMN "to_amount" bigint;
+UPDATE "transfers" SET "to_amount" = "amount";
+ALTER TABLE "transfers" ALTER COLUMN "to_amount" SET NOT NULL;
+
+ALTER TABLE "transfers" ADD COLUMN "exchange_rate" numeric(20, 10) NOT NULL DEFAULT (1);
+ALTER TABLE "transfers" ADD COLUMN "exchange_rate_id" bigint;
+
+ALTER TABLE "transfers" ADD FOREIGN KEY ("exchange_rate_id") REFERENCES "exchange_rates" ("id");
+
+COMMENT ON COLUMN "transfers"."to_amount" IS 'amount credited to the to account, in its currency';
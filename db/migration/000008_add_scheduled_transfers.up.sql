@@ -0,0 +1,44 @@
+CREATE TABLE "scheduled_transfers" (
+    "id" bigserial PRIMARY KEY,
+    "owner" varchar NOT NULL,
+    "from_account_id" bigint NOT NULL,
+    "to_account_id" bigint NOT NULL,
+    "amount" bigint NOT NULL,
+    "schedule" varchar NOT NULL,
+    "status" varchar NOT NULL DEFAULT 'active',
+    "scheduled_for" timestamptz NOT NULL,
+    "next_run_at" timestamptz NOT NULL,
+    "end_at" timestamptz,
+    "retry_count" int NOT NULL DEFAULT 0,
+    "created_at" timestamptz NOT NULL DEFAULT (now()),
+    "updated_at" timestamptz NOT NULL DEFAULT (now())
+);
+
+ALTER TABLE "scheduled_transfers" ADD FOREIGN KEY ("owner") REFERENCES "users" ("username");
+ALTER TABLE "scheduled_transfers" ADD FOREIGN KEY ("from_account_id") REFERENCES "account" ("id");
+ALTER TABLE "scheduled_transfers" ADD FOREIGN KEY ("to_account_id") REFERENCES "account" ("id");
+
+CREATE INDEX ON "scheduled_transfers" ("owner");
+CREATE INDEX ON "scheduled_transfers" ("status", "next_run_at");
+
+COMMENT ON COLUMN "scheduled_transfers"."schedule" IS '@once, @daily, @weekly, @every <duration> or @monthly <day>';
+COMMENT ON COLUMN "scheduled_transfers"."status" IS 'active, paused, completed or cancelled';
+COMMENT ON COLUMN "scheduled_transfers"."scheduled_for" IS 'the pending occurrence, next_run_at is later than it when the run is retried';
+
+CREATE TABLE "scheduled_transfer_runs" (
+    "id" bigserial PRIMARY KEY,
+    "scheduled_transfer_id" bigint NOT NULL,
+    "scheduled_for" timestamptz NOT NULL,
+    "attempt" int NOT NULL,
+    "status" varchar NOT NULL,
+    "transfer_id" bigint,
+    "error" varchar NOT NULL DEFAULT '',
+    "created_at" timestamptz NOT NULL DEFAULT (now())
+);
+
+ALTER TABLE "scheduled_transfer_runs" ADD FOREIGN KEY ("scheduled_transfer_id") REFERENCES "scheduled_transfers" ("id");
+ALTER TABLE "scheduled_transfer_runs" ADD FOREIGN KEY ("transfer_id") REFERENCES "transfers" ("id");
+
+CREATE INDEX ON "scheduled_transfer_runs" ("scheduled_transfer_id");
+
+COMMENT ON COLUMN "scheduled_transfer_runs"."status" IS 'succeeded or failed';
@@ -0,0 +1,3 @@
+ALTER TABLE IF EXISTS "transfers" DROP COLUMN IF EXISTS "reversal_of";
+ALTER TABLE IF EXISTS "transfers" DROP COLUMN IF EXISTS "reversed_amount";
+ALTER TABLE IF EXISTS "transfers" DROP COLUMN IF EXISTS "status";
@@ -0,0 +1,11 @@
+ALTER TABLE "transfers" ADD COLUMN "status" varchar NOT NULL DEFAULT 'completed';
+ALTER TABLE "transfers" ADD COLUMN "reversed_amount" bigint NOT NULL DEFAULT 0;
+ALTER TABLE "transfers" ADD COLUMN "reversal_of" bigint;
+
+ALTER TABLE "transfers" ADD FOREIGN KEY ("reversal_of") REFERENCES "transfers" ("id");
+
+CREATE INDEX ON "transfers" ("reversal_of");
+
+COMMENT ON COLUMN "transfers"."status" IS 'completed, partially_reversed or reversed';
+COMMENT ON COLUMN "transfers"."reversed_amount" IS 'part of amount that has been reversed, in the currency of the from account';
+COMMENT ON COLUMN "transfers"."reversal_of" IS 'the original transfer if this transfer is a reversal';
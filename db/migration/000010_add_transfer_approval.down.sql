@@ -0,0 +1,7 @@
+DROP INDEX IF EXISTS "transfers_status_idx";
+
+ALTER TABLE IF EXISTS "transfers" DROP COLUMN IF EXISTS "decided_at";
+ALTER TABLE IF EXISTS "transfers" DROP COLUMN IF EXISTS "approver";
+ALTER TABLE IF EXISTS "transfers" DROP COLUMN IF EXISTS "requested_by";
+
+COMMENT ON COLUMN "transfers"."status" IS 'completed, partially_reversed or reversed';
@@ -0,0 +1,12 @@
+-- 超过审批金额的转账先创建为 pending，由另一个有审批权限的用户批准后才结算
+ALTER TABLE "transfers" ADD COLUMN "requested_by" varchar;
+ALTER TABLE "transfers" ADD COLUMN "approver" varchar;
+ALTER TABLE "transfers" ADD COLUMN "decided_at" timestamptz;
+
+ALTER TABLE "transfers" ADD FOREIGN KEY ("requested_by") REFERENCES "users" ("username");
+ALTER TABLE "transfers" ADD FOREIGN KEY ("approver") REFERENCES "users" ("username");
+
+CREATE INDEX ON "transfers" ("status");
+
+COMMENT ON COLUMN "transfers"."status" IS 'pending, rejected, completed, partially_reversed or reversed';
+COMMENT ON COLUMN "transfers"."approver" IS 'the user who approved or rejected the pending transfer';
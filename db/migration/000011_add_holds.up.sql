@@ -0,0 +1,26 @@
+-- 预授权：先冻结账户的资金，之后全部或部分扣款(capture)，或者撤销(void)
+CREATE TABLE "holds" (
+    "id" bigserial PRIMARY KEY,
+    "account_id" bigint NOT NULL,
+    "to_account_id" bigint NOT NULL,
+    "amount" bigint NOT NULL,
+    "captured_amount" bigint NOT NULL DEFAULT 0,
+    "status" varchar NOT NULL DEFAULT 'active',
+    "created_by" varchar NOT NULL,
+    "transfer_id" bigint,
+    "expires_at" timestamptz NOT NULL,
+    "created_at" timestamptz NOT NULL DEFAULT (now()),
+    "updated_at" timestamptz NOT NULL DEFAULT (now())
+);
+
+ALTER TABLE "holds" ADD FOREIGN KEY ("account_id") REFERENCES "account" ("id");
+ALTER TABLE "holds" ADD FOREIGN KEY ("to_account_id") REFERENCES "account" ("id");
+ALTER TABLE "holds" ADD FOREIGN KEY ("created_by") REFERENCES "users" ("username");
+ALTER TABLE "holds" ADD FOREIGN KEY ("transfer_id") REFERENCES "transfers" ("id");
+
+CREATE INDEX ON "holds" ("account_id", "status");
+CREATE INDEX ON "holds" ("status", "expires_at");
+
+COMMENT ON COLUMN "holds"."amount" IS 'must be positive';
+COMMENT ON COLUMN "holds"."status" IS 'active, captured, voided or expired';
+COMMENT ON COLUMN "holds"."transfer_id" IS 'the transfer that settled the captured amount';
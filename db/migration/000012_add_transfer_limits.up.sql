@@ -0,0 +1,28 @@
+-- 转账限额：account_id 不为空时限制单个账户，owner 不为空时限制用户该币种的所有账户，
+-- 两者都为空时是每个账户的默认限额
+CREATE TABLE "transfer_limits" (
+    "id" bigserial PRIMARY KEY,
+    "owner" varchar,
+    "account_id" bigint,
+    "currency" varchar NOT NULL,
+    "period" varchar NOT NULL,
+    "max_count" int NOT NULL DEFAULT 0,
+    "max_amount" bigint NOT NULL DEFAULT 0,
+    "created_at" timestamptz NOT NULL DEFAULT (now()),
+    "updated_at" timestamptz NOT NULL DEFAULT (now()),
+    CHECK ("owner" IS NULL OR "account_id" IS NULL)
+);
+
+ALTER TABLE "transfer_limits" ADD FOREIGN KEY ("owner") REFERENCES "users" ("username");
+ALTER TABLE "transfer_limits" ADD FOREIGN KEY ("account_id") REFERENCES "account" ("id");
+
+CREATE UNIQUE INDEX "transfer_limits_scope_idx" ON "transfer_limits" (
+    (COALESCE("owner", '')), (COALESCE("account_id", 0)), "currency", "period"
+);
+
+COMMENT ON COLUMN "transfer_limits"."period" IS 'daily (rolling 24 hours) or monthly (rolling 30 days)';
+COMMENT ON COLUMN "transfer_limits"."max_count" IS '0 means no limit on the number of transfers';
+COMMENT ON COLUMN "transfer_limits"."max_amount" IS 'in minor units of the currency, 0 means no limit on the amount';
+
+-- 限额按转出账户和时间统计转账记录
+CREATE INDEX ON "transfers" ("from_account_id", "created_at");
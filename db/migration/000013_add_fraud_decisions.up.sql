@@ -0,0 +1,26 @@
+-- 每次转账前的风控结果都会记录下来，被拒绝的转账没有 transfer_id
+CREATE TABLE "fraud_decisions" (
+    "id" bigserial PRIMARY KEY,
+    "username" varchar NOT NULL,
+    "from_account_id" bigint NOT NULL,
+    "to_account_id" bigint NOT NULL,
+    "amount" bigint NOT NULL,
+    "currency" varchar NOT NULL,
+    "decision" varchar NOT NULL,
+    "rule" varchar NOT NULL DEFAULT '',
+    "reason" varchar NOT NULL DEFAULT '',
+    "metadata" jsonb NOT NULL DEFAULT '{}',
+    "transfer_id" bigint,
+    "created_at" timestamptz NOT NULL DEFAULT (now())
+);
+
+ALTER TABLE "fraud_decisions" ADD FOREIGN KEY ("username") REFERENCES "users" ("username");
+ALTER TABLE "fraud_decisions" ADD FOREIGN KEY ("from_account_id") REFERENCES "account" ("id");
+ALTER TABLE "fraud_decisions" ADD FOREIGN KEY ("to_account_id") REFERENCES "account" ("id");
+ALTER TABLE "fraud_decisions" ADD FOREIGN KEY ("transfer_id") REFERENCES "transfers" ("id");
+
+CREATE INDEX ON "fraud_decisions" ("from_account_id", "created_at");
+CREATE INDEX ON "fraud_decisions" ("transfer_id");
+
+COMMENT ON COLUMN "fraud_decisions"."decision" IS 'allow, review or block';
+COMMENT ON COLUMN "fraud_decisions"."rule" IS 'the matched rule, empty if no rule matches';
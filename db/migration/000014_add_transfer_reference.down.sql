@@ -0,0 +1,5 @@
+DROP INDEX IF EXISTS "transfers_external_reference_key";
+
+ALTER TABLE "transfers" DROP COLUMN IF EXISTS "metadata";
+ALTER TABLE "transfers" DROP COLUMN IF EXISTS "external_reference";
+ALTER TABLE "transfers" DROP COLUMN IF EXISTS "memo";
@@ -0,0 +1,10 @@
+ALTER TABLE "transfers" ADD COLUMN "memo" varchar NOT NULL DEFAULT '';
+ALTER TABLE "transfers" ADD COLUMN "external_reference" varchar NOT NULL DEFAULT '';
+ALTER TABLE "transfers" ADD COLUMN "metadata" jsonb NOT NULL DEFAULT '{}';
+
+-- 同一个转出账户的 external_reference 不能重复，没有提供的转账不受限制
+CREATE UNIQUE INDEX "transfers_external_reference_key" ON "transfers" ("from_account_id", "external_reference")
+    WHERE "external_reference" <> '';
+
+COMMENT ON COLUMN "transfers"."memo" IS 'free-text memo of the sender';
+COMMENT ON COLUMN "transfers"."external_reference" IS 'client-supplied reference, unique per from account if not empty';
@@ -0,0 +1,6 @@
+DROP INDEX IF EXISTS "account_owner_created_at_id_idx";
+DROP INDEX IF EXISTS "entries_account_id_created_at_id_idx";
+DROP INDEX IF EXISTS "transfers_to_account_id_created_at_id_idx";
+DROP INDEX IF EXISTS "exchange_rates_created_at_id_idx";
+DROP INDEX IF EXISTS "fraud_decisions_created_at_id_idx";
+DROP INDEX IF EXISTS "scheduled_transfer_runs_scheduled_transfer_id_created_at_id_idx";
@@ -0,0 +1,6 @@
+CREATE INDEX ON "account" ("owner", "created_at", "id");
+CREATE INDEX ON "entries" ("account_id", "created_at", "id");
+CREATE INDEX ON "transfers" ("to_account_id", "created_at", "id");
+CREATE INDEX ON "exchange_rates" ("created_at", "id");
+CREATE INDEX ON "fraud_decisions" ("created_at", "id");
+CREATE INDEX ON "scheduled_transfer_runs" ("scheduled_transfer_id", "created_at", "id");
@@ -0,0 +1,4 @@
+DROP TABLE IF EXISTS "reconciliation_discrepancies";
+DROP TABLE IF EXISTS "reconciliation_reports";
+
+ALTER TABLE "entries" DROP COLUMN IF EXISTS "transfer_id";
@@ -0,0 +1,51 @@
+-- 每条流水记录所属的转账，用于核对转账和流水是否一致
+ALTER TABLE "entries" ADD COLUMN "transfer_id" bigint;
+ALTER TABLE "entries" ADD FOREIGN KEY ("transfer_id") REFERENCES "transfers" ("id");
+CREATE INDEX ON "entries" ("transfer_id");
+
+-- 回填历史流水：按账户和金额匹配在流水之前创建的、时间最接近的已结算转账，匹配不上的保留为 NULL，由对账任务报告
+UPDATE "entries" SET "transfer_id" = "matched"."transfer_id"
+FROM (
+    SELECT DISTINCT ON (e."id") e."id" AS "entry_id", t."id" AS "transfer_id"
+    FROM "entries" e
+    JOIN "transfers" t ON t."status" NOT IN ('pending', 'rejected')
+        AND e."created_at" >= t."created_at"
+        AND ((e."account_id" = t."from_account_id" AND e."amount" = -t."amount")
+            OR (e."account_id" = t."to_account_id" AND e."amount" = t."to_amount"))
+    ORDER BY e."id", e."created_at" - t."created_at", t."id"
+) "matched"
+WHERE "entries"."id" = "matched"."entry_id";
+
+-- 对账报告：每次对账记录一条，发现的差异记录在 reconciliation_discrepancies 中
+CREATE TABLE "reconciliation_reports" (
+    "id" bigserial PRIMARY KEY,
+    "accounts_checked" bigint NOT NULL,
+    "transfers_checked" bigint NOT NULL,
+    "discrepancy_count" bigint NOT NULL,
+    "started_at" timestamptz NOT NULL,
+    "created_at" timestamptz NOT NULL DEFAULT (now())
+);
+
+CREATE TABLE "reconciliation_discrepancies" (
+    "id" bigserial PRIMARY KEY,
+    "report_id" bigint NOT NULL,
+    "kind" varchar NOT NULL,
+    "account_id" bigint,
+    "transfer_id" bigint,
+    "expected" bigint NOT NULL,
+    "actual" bigint NOT NULL,
+    "detail" varchar NOT NULL DEFAULT '',
+    "created_at" timestamptz NOT NULL DEFAULT (now())
+);
+
+ALTER TABLE "reconciliation_discrepancies" ADD FOREIGN KEY ("report_id") REFERENCES "reconciliation_reports" ("id");
+ALTER TABLE "reconciliation_discrepancies" ADD FOREIGN KEY ("account_id") REFERENCES "account" ("id");
+ALTER TABLE "reconciliation_discrepancies" ADD FOREIGN KEY ("transfer_id") REFERENCES "transfers" ("id");
+
+CREATE INDEX ON "reconciliation_reports" ("created_at", "id");
+CREATE INDEX ON "reconciliation_discrepancies" ("report_id");
+
+COMMENT ON COLUMN "entries"."transfer_id" IS 'the transfer that created the entry';
+COMMENT ON COLUMN "reconciliation_discrepancies"."kind" IS 'account_balance or transfer_entries';
+COMMENT ON COLUMN "reconciliation_discrepancies"."expected" IS 'account balance, or the number of entries the transfer should have';
+COMMENT ON COLUMN "reconciliation_discrepancies"."actual" IS 'sum of the account entries, or the number of matching entries of the transfer';
@@ -0,0 +1,17 @@
+-- 删除迁移时补充的兑换和期初流水，历史流水保留
+DELETE FROM "entries" WHERE "account_id" IN (SELECT "id" FROM "account" WHERE "system_code" <> '');
+DELETE FROM "entries" e USING "journals" j
+WHERE e."journal_id" = j."id" AND j."kind" = 'opening_balance' AND e."created_at" = j."created_at";
+
+ALTER TABLE "reconciliation_discrepancies" DROP COLUMN IF EXISTS "journal_id";
+ALTER TABLE "entries" DROP COLUMN IF EXISTS "journal_id";
+DROP TABLE IF EXISTS "journals";
+
+DELETE FROM "reconciliation_discrepancies" WHERE "account_id" IN (SELECT "id" FROM "account" WHERE "system_code" <> '');
+DELETE FROM "account" WHERE "system_code" <> '';
+DELETE FROM "users" WHERE "username" = 'system';
+
+DROP INDEX IF EXISTS "account_system_code_key";
+DROP INDEX IF EXISTS "owner_currency_key";
+ALTER TABLE "account" ADD CONSTRAINT "owner_currency_key" UNIQUE ("owner", "currency");
+ALTER TABLE "account" DROP COLUMN IF EXISTS "system_code";
@@ -0,0 +1,98 @@
+-- 系统内部账户：每个币种各有现金、手续费、利息支出、挂账和货币兑换账户，属于 system 用户，不能登录
+ALTER TABLE "account" ADD COLUMN "system_code" varchar NOT NULL DEFAULT '';
+
+ALTER TABLE "account" DROP CONSTRAINT "owner_currency_key";
+CREATE UNIQUE INDEX "owner_currency_key" ON "account" ("owner", "currency") WHERE "system_code" = '';
+CREATE UNIQUE INDEX "account_system_code_key" ON "account" ("system_code", "currency") WHERE "system_code" <> '';
+
+INSERT INTO "users" ("username", "hashed_password", "full_name", "email")
+VALUES ('system', '', 'Simple Bank', 'system@simplebank.internal');
+
+INSERT INTO "account" ("owner", "balance", "currency", "system_code")
+SELECT 'system', 0, "currencies"."code", "codes"."code"
+FROM (VALUES ('USD'), ('EUR'), ('CAD'), ('CNY'), ('JPY'), ('GBP')) AS "currencies" ("code")
+CROSS JOIN (VALUES ('cash'), ('fees'), ('interest_expense'), ('suspense'), ('exchange')) AS "codes" ("code");
+
+-- 每一笔资金变动是一条会计分录(journal)，各个币种的流水之和为 0
+CREATE TABLE "journals" (
+    "id" bigserial PRIMARY KEY,
+    "kind" varchar NOT NULL,
+    "memo" varchar NOT NULL DEFAULT '',
+    "transfer_id" bigint,
+    "created_by" varchar NOT NULL DEFAULT '',
+    "created_at" timestamptz NOT NULL DEFAULT (now())
+);
+
+ALTER TABLE "journals" ADD FOREIGN KEY ("transfer_id") REFERENCES "transfers" ("id");
+CREATE INDEX ON "journals" ("transfer_id");
+
+ALTER TABLE "entries" ADD COLUMN "journal_id" bigint;
+ALTER TABLE "entries" ADD FOREIGN KEY ("journal_id") REFERENCES "journals" ("id");
+CREATE INDEX ON "entries" ("journal_id");
+
+ALTER TABLE "reconciliation_discrepancies" ADD COLUMN "journal_id" bigint;
+ALTER TABLE "reconciliation_discrepancies" ADD FOREIGN KEY ("journal_id") REFERENCES "journals" ("id");
+
+-- 已结算的历史转账各补一条分录，跨币种的转账通过兑换账户在两个币种内分别平衡
+INSERT INTO "journals" ("kind", "transfer_id", "created_at")
+SELECT CASE WHEN t."reversal_of" IS NULL THEN 'transfer' ELSE 'reversal' END, t."id", t."created_at"
+FROM "transfers" t
+WHERE EXISTS (SELECT 1 FROM "entries" e WHERE e."transfer_id" = t."id");
+
+UPDATE "entries" SET "journal_id" = j."id"
+FROM "journals" j
+WHERE "entries"."transfer_id" = j."transfer_id";
+
+INSERT INTO "entries" ("account_id", "amount", "transfer_id", "journal_id", "created_at")
+SELECT x."id", t."amount", t."id", j."id", t."created_at"
+FROM "journals" j
+JOIN "transfers" t ON t."id" = j."transfer_id"
+JOIN "account" a ON a."id" = t."from_account_id"
+JOIN "account" x ON x."system_code" = 'exchange' AND x."currency" = a."currency"
+WHERE t."exchange_rate_id" IS NOT NULL;
+
+INSERT INTO "entries" ("account_id", "amount", "transfer_id", "journal_id", "created_at")
+SELECT x."id", -t."to_amount", t."id", j."id", t."created_at"
+FROM "journals" j
+JOIN "transfers" t ON t."id" = j."transfer_id"
+JOIN "account" a ON a."id" = t."to_account_id"
+JOIN "account" x ON x."system_code" = 'exchange' AND x."currency" = a."currency"
+WHERE t."exchange_rate_id" IS NOT NULL;
+
+-- 期初余额：直接设置的余额和没有对应转账的流水都计入同一条期初分录，对方账户为各币种的现金账户
+INSERT INTO "journals" ("kind", "memo")
+SELECT 'opening_balance', 'balances before the general ledger'
+WHERE EXISTS (SELECT 1 FROM "entries" WHERE "journal_id" IS NULL)
+   OR EXISTS (
+    SELECT 1 FROM "account" a
+    WHERE a."system_code" = '' AND a."balance" <> (SELECT COALESCE(SUM(e."amount"), 0) FROM "entries" e WHERE e."account_id" = a."id")
+);
+
+UPDATE "entries" SET "journal_id" = (SELECT "id" FROM "journals" WHERE "kind" = 'opening_balance')
+WHERE "journal_id" IS NULL;
+
+INSERT INTO "entries" ("account_id", "amount", "journal_id")
+SELECT a."id", a."balance" - COALESCE(SUM(e."amount"), 0), (SELECT "id" FROM "journals" WHERE "kind" = 'opening_balance')
+FROM "account" a
+LEFT JOIN "entries" e ON e."account_id" = a."id"
+WHERE a."system_code" = ''
+GROUP BY a."id"
+HAVING a."balance" <> COALESCE(SUM(e."amount"), 0);
+
+INSERT INTO "entries" ("account_id", "amount", "journal_id")
+SELECT c."id", -SUM(e."amount"), e."journal_id"
+FROM "entries" e
+JOIN "account" a ON a."id" = e."account_id"
+JOIN "account" c ON c."system_code" = 'cash' AND c."currency" = a."currency"
+WHERE e."journal_id" = (SELECT "id" FROM "journals" WHERE "kind" = 'opening_balance')
+GROUP BY c."id", e."journal_id"
+HAVING SUM(e."amount") <> 0;
+
+UPDATE "account" SET "balance" = (SELECT COALESCE(SUM(e."amount"), 0) FROM "entries" e WHERE e."account_id" = "account"."id")
+WHERE "system_code" <> '';
+
+COMMENT ON COLUMN "account"."system_code" IS 'cash, fees, interest_expense, suspense or exchange for system accounts, empty for customer accounts';
+COMMENT ON COLUMN "journals"."kind" IS 'transfer, reversal, deposit, withdrawal or opening_balance';
+COMMENT ON COLUMN "reconciliation_discrepancies"."kind" IS 'account_balance, transfer_entries, journal_balance or currency_total';
+COMMENT ON COLUMN "reconciliation_discrepancies"."expected" IS 'account balance, expected number of transfer entries, or zero';
+COMMENT ON COLUMN "reconciliation_discrepancies"."actual" IS 'sum of the account entries, number of transfer entries, or sum of the journal legs or balances';
@@ -0,0 +1,38 @@
+-- 每条流水保存内容的哈希，并与同一个账户的上一条流水的哈希串联，直接修改、插入或删除流水都会使链条断开
+ALTER TABLE "entries" ADD COLUMN "prev_hash" varchar NOT NULL DEFAULT '';
+ALTER TABLE "entries" ADD COLUMN "hash" varchar NOT NULL DEFAULT '';
+-- 账户最后一条流水的哈希，用于发现末尾的流水被删除
+ALTER TABLE "account" ADD COLUMN "entry_hash" varchar NOT NULL DEFAULT '';
+
+CREATE INDEX ON "entries" ("account_id", "id");
+
+-- 回填已有的流水，哈希的内容与 util.EntryHash 一致：
+-- account_id|amount|transfer_id|journal_id|created_at 的微秒数|prev_hash，没有的 id 记为 0
+WITH RECURSIVE "ordered" AS (
+    SELECT e."id", e."account_id", e."amount", e."created_at",
+        COALESCE(e."transfer_id", 0) AS "transfer_id", COALESCE(e."journal_id", 0) AS "journal_id",
+        row_number() OVER (PARTITION BY e."account_id" ORDER BY e."id") AS "n"
+    FROM "entries" e
+), "chain" AS (
+    SELECT o."id", o."account_id", o."n", ''::varchar AS "prev_hash",
+        encode(sha256(convert_to(concat_ws('|', o."account_id", o."amount", o."transfer_id", o."journal_id",
+            (extract(epoch FROM o."created_at") * 1000000)::bigint, ''), 'UTF8')), 'hex')::varchar AS "hash"
+    FROM "ordered" o
+    WHERE o."n" = 1
+    UNION ALL
+    SELECT o."id", o."account_id", o."n", c."hash",
+        encode(sha256(convert_to(concat_ws('|', o."account_id", o."amount", o."transfer_id", o."journal_id",
+            (extract(epoch FROM o."created_at") * 1000000)::bigint, c."hash"), 'UTF8')), 'hex')::varchar
+    FROM "chain" c
+    JOIN "ordered" o ON o."account_id" = c."account_id" AND o."n" = c."n" + 1
+)
+UPDATE "entries" SET "prev_hash" = "chain"."prev_hash", "hash" = "chain"."hash"
+FROM "chain"
+WHERE "entries"."id" = "chain"."id";
+
+UPDATE "account" SET "entry_hash" = COALESCE((
+    SELECT e."hash" FROM "entries" e WHERE e."account_id" = "account"."id" ORDER BY e."id" DESC LIMIT 1
+), '');
+
+COMMENT ON COLUMN "entries"."hash" IS 'sha256 of the entry content chained to prev_hash, see util.EntryHash';
+COMMENT ON COLUMN "account"."entry_hash" IS 'hash of the last entry of the account';
@@ -0,0 +1,14 @@
+-- 账户在 snapshot_at 时刻之前所有流水的金额之和，查询历史余额时只需要累加快照之后的流水
+CREATE TABLE "balance_snapshots" (
+    "account_id" bigint NOT NULL,
+    "snapshot_at" timestamptz NOT NULL,
+    "balance" bigint NOT NULL,
+    "created_at" timestamptz NOT NULL DEFAULT (now()),
+    PRIMARY KEY ("account_id", "snapshot_at")
+);
+
+ALTER TABLE "balance_snapshots" ADD FOREIGN KEY ("account_id") REFERENCES "account" ("id");
+
+CREATE INDEX ON "entries" ("account_id", "created_at");
+
+COMMENT ON COLUMN "balance_snapshots"."balance" IS 'sum of the entries of the account created before snapshot_at';
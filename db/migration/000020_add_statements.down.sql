@@ -0,0 +1,3 @@
+DROP TRIGGER IF EXISTS "statements_immutable" ON "statements";
+DROP FUNCTION IF EXISTS "reject_statement_change";
+DROP TABLE IF EXISTS "statements";
@@ -0,0 +1,38 @@
+-- 账户对账单，生成后不能修改，流水明细保存为 JSON 快照
+CREATE TABLE "statements" (
+    "id" bigserial PRIMARY KEY,
+    "account_id" bigint NOT NULL,
+    "currency" varchar NOT NULL,
+    "period_start" timestamptz NOT NULL,
+    "period_end" timestamptz NOT NULL,
+    "opening_balance" bigint NOT NULL,
+    "closing_balance" bigint NOT NULL,
+    "total_credits" bigint NOT NULL,
+    "total_debits" bigint NOT NULL,
+    "entry_count" bigint NOT NULL,
+    "lines" jsonb NOT NULL,
+    "created_at" timestamptz NOT NULL DEFAULT (now()),
+    CHECK ("period_start" < "period_end")
+);
+
+ALTER TABLE "statements" ADD FOREIGN KEY ("account_id") REFERENCES "account" ("id");
+
+CREATE UNIQUE INDEX ON "statements" ("account_id", "period_start", "period_end");
+
+CREATE INDEX ON "statements" ("account_id", "created_at", "id");
+
+CREATE FUNCTION "reject_statement_change"() RETURNS trigger AS $$
+BEGIN
+    RAISE EXCEPTION 'statements are immutable';
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER "statements_immutable"
+    BEFORE UPDATE OR DELETE ON "statements"
+    FOR EACH ROW EXECUTE PROCEDURE "reject_statement_change"();
+
+COMMENT ON COLUMN "statements"."period_end" IS 'exclusive, entries created in [period_start, period_end) are included';
+
+COMMENT ON COLUMN "statements"."total_debits" IS 'sum of the negative entries, as a positive amount';
+
+COMMENT ON COLUMN "statements"."lines" IS 'the entries of the period with the counterparty and the running balance';
@@ -7,8 +7,10 @@ package mockdb
 import (
 	context "context"
 	reflect "reflect"
+	time "time"
 
 	gomock "github.com/golang/mock/gomock"
+	uuid "github.com/google/uuid"
 	db "github.com/techschool/simplebank/db/sqlc"
 )
 
@@ -35,198 +37,1394 @@ func (m *MockStore) EXPECT() *MockStoreMockRecorder {
 	return m.recorder
 }
 
+// AddAccountLedgerBalance mocks base method.
+func (m *MockStore) AddAccountLedgerBalance(arg0 context.Context, arg1 db.AddAccountLedgerBalanceParams) (db.Account, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "AddAccountLedgerBalance", arg0, arg1)
+	ret0, _ := ret[0].(db.Account)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// AddAccountLedgerBalance indicates an expected call of AddAccountLedgerBalance.
+func (mr *MockStoreMockRecorder) AddAccountLedgerBalance(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AddAccountLedgerBalance", reflect.TypeOf((*MockStore)(nil).AddAccountLedgerBalance), arg0, arg1)
+}
+
 // AddaAccountBalance mocks base method.
 func (m *MockStore) AddaAccountBalance(arg0 context.Context, arg1 db.AddaAccountBalanceParams) (db.Account, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "AddaAccountBalance", arg0, arg1)
-	ret0, _ := ret[0].(db.Account)
+	ret := m.ctrl.Call(m, "AddaAccountBalance", arg0, arg1)
+	ret0, _ := ret[0].(db.Account)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// AddaAccountBalance indicates an expected call of AddaAccountBalance.
+func (mr *MockStoreMockRecorder) AddaAccountBalance(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AddaAccountBalance", reflect.TypeOf((*MockStore)(nil).AddaAccountBalance), arg0, arg1)
+}
+
+// AdvanceScheduledTransfer mocks base method.
+func (m *MockStore) AdvanceScheduledTransfer(arg0 context.Context, arg1 db.AdvanceScheduledTransferParams) (db.ScheduledTransfer, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "AdvanceScheduledTransfer", arg0, arg1)
+	ret0, _ := ret[0].(db.ScheduledTransfer)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// AdvanceScheduledTransfer indicates an expected call of AdvanceScheduledTransfer.
+func (mr *MockStoreMockRecorder) AdvanceScheduledTransfer(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AdvanceScheduledTransfer", reflect.TypeOf((*MockStore)(nil).AdvanceScheduledTransfer), arg0, arg1)
+}
+
+// ApproveTransferTx mocks base method.
+func (m *MockStore) ApproveTransferTx(arg0 context.Context, arg1 db.DecideTransferTxParams) (db.TransferTxResult, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ApproveTransferTx", arg0, arg1)
+	ret0, _ := ret[0].(db.TransferTxResult)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ApproveTransferTx indicates an expected call of ApproveTransferTx.
+func (mr *MockStoreMockRecorder) ApproveTransferTx(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ApproveTransferTx", reflect.TypeOf((*MockStore)(nil).ApproveTransferTx), arg0, arg1)
+}
+
+// BatchTransferTx mocks base method.
+func (m *MockStore) BatchTransferTx(arg0 context.Context, arg1 db.BatchTransferTxParams) (db.BatchTransferTxResult, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "BatchTransferTx", arg0, arg1)
+	ret0, _ := ret[0].(db.BatchTransferTxResult)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// BatchTransferTx indicates an expected call of BatchTransferTx.
+func (mr *MockStoreMockRecorder) BatchTransferTx(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "BatchTransferTx", reflect.TypeOf((*MockStore)(nil).BatchTransferTx), arg0, arg1)
+}
+
+// BlockSession mocks base method.
+func (m *MockStore) BlockSession(arg0 context.Context, arg1 uuid.UUID) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "BlockSession", arg0, arg1)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// BlockSession indicates an expected call of BlockSession.
+func (mr *MockStoreMockRecorder) BlockSession(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "BlockSession", reflect.TypeOf((*MockStore)(nil).BlockSession), arg0, arg1)
+}
+
+// BlockUserSessions mocks base method.
+func (m *MockStore) BlockUserSessions(arg0 context.Context, arg1 string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "BlockUserSessions", arg0, arg1)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// BlockUserSessions indicates an expected call of BlockUserSessions.
+func (mr *MockStoreMockRecorder) BlockUserSessions(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "BlockUserSessions", reflect.TypeOf((*MockStore)(nil).BlockUserSessions), arg0, arg1)
+}
+
+// CaptureHoldTx mocks base method.
+func (m *MockStore) CaptureHoldTx(arg0 context.Context, arg1 db.CaptureHoldTxParams) (db.CaptureHoldTxResult, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CaptureHoldTx", arg0, arg1)
+	ret0, _ := ret[0].(db.CaptureHoldTxResult)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CaptureHoldTx indicates an expected call of CaptureHoldTx.
+func (mr *MockStoreMockRecorder) CaptureHoldTx(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CaptureHoldTx", reflect.TypeOf((*MockStore)(nil).CaptureHoldTx), arg0, arg1)
+}
+
+// CountAccounts mocks base method.
+func (m *MockStore) CountAccounts(arg0 context.Context) (int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CountAccounts", arg0)
+	ret0, _ := ret[0].(int64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CountAccounts indicates an expected call of CountAccounts.
+func (mr *MockStoreMockRecorder) CountAccounts(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CountAccounts", reflect.TypeOf((*MockStore)(nil).CountAccounts), arg0)
+}
+
+// CountPayeeTransfers mocks base method.
+func (m *MockStore) CountPayeeTransfers(arg0 context.Context, arg1 db.CountPayeeTransfersParams) (int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CountPayeeTransfers", arg0, arg1)
+	ret0, _ := ret[0].(int64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CountPayeeTransfers indicates an expected call of CountPayeeTransfers.
+func (mr *MockStoreMockRecorder) CountPayeeTransfers(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CountPayeeTransfers", reflect.TypeOf((*MockStore)(nil).CountPayeeTransfers), arg0, arg1)
+}
+
+// CountTransfers mocks base method.
+func (m *MockStore) CountTransfers(arg0 context.Context) (int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CountTransfers", arg0)
+	ret0, _ := ret[0].(int64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CountTransfers indicates an expected call of CountTransfers.
+func (mr *MockStoreMockRecorder) CountTransfers(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CountTransfers", reflect.TypeOf((*MockStore)(nil).CountTransfers), arg0)
+}
+
+// CountTransfersSince mocks base method.
+func (m *MockStore) CountTransfersSince(arg0 context.Context, arg1 db.CountTransfersSinceParams) (int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CountTransfersSince", arg0, arg1)
+	ret0, _ := ret[0].(int64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CountTransfersSince indicates an expected call of CountTransfersSince.
+func (mr *MockStoreMockRecorder) CountTransfersSince(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CountTransfersSince", reflect.TypeOf((*MockStore)(nil).CountTransfersSince), arg0, arg1)
+}
+
+// CreateAccount mocks base method.
+func (m *MockStore) CreateAccount(arg0 context.Context, arg1 db.CreateAccountParams) (db.Account, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateAccount", arg0, arg1)
+	ret0, _ := ret[0].(db.Account)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CreateAccount indicates an expected call of CreateAccount.
+func (mr *MockStoreMockRecorder) CreateAccount(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateAccount", reflect.TypeOf((*MockStore)(nil).CreateAccount), arg0, arg1)
+}
+
+// CreateBalanceSnapshots mocks base method.
+func (m *MockStore) CreateBalanceSnapshots(arg0 context.Context, arg1 time.Time) (int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateBalanceSnapshots", arg0, arg1)
+	ret0, _ := ret[0].(int64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CreateBalanceSnapshots indicates an expected call of CreateBalanceSnapshots.
+func (mr *MockStoreMockRecorder) CreateBalanceSnapshots(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateBalanceSnapshots", reflect.TypeOf((*MockStore)(nil).CreateBalanceSnapshots), arg0, arg1)
+}
+
+// CreateChainedEntry mocks base method.
+func (m *MockStore) CreateChainedEntry(arg0 context.Context, arg1 db.CreateChainedEntryParams) (db.Entry, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateChainedEntry", arg0, arg1)
+	ret0, _ := ret[0].(db.Entry)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CreateChainedEntry indicates an expected call of CreateChainedEntry.
+func (mr *MockStoreMockRecorder) CreateChainedEntry(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateChainedEntry", reflect.TypeOf((*MockStore)(nil).CreateChainedEntry), arg0, arg1)
+}
+
+// CreateEntry mocks base method.
+func (m *MockStore) CreateEntry(arg0 context.Context, arg1 db.CreateEntryParams) (db.Entry, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateEntry", arg0, arg1)
+	ret0, _ := ret[0].(db.Entry)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CreateEntry indicates an expected call of CreateEntry.
+func (mr *MockStoreMockRecorder) CreateEntry(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateEntry", reflect.TypeOf((*MockStore)(nil).CreateEntry), arg0, arg1)
+}
+
+// CreateExchangeRate mocks base method.
+func (m *MockStore) CreateExchangeRate(arg0 context.Context, arg1 db.CreateExchangeRateParams) (db.ExchangeRate, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateExchangeRate", arg0, arg1)
+	ret0, _ := ret[0].(db.ExchangeRate)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CreateExchangeRate indicates an expected call of CreateExchangeRate.
+func (mr *MockStoreMockRecorder) CreateExchangeRate(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateExchangeRate", reflect.TypeOf((*MockStore)(nil).CreateExchangeRate), arg0, arg1)
+}
+
+// CreateFraudDecision mocks base method.
+func (m *MockStore) CreateFraudDecision(arg0 context.Context, arg1 db.CreateFraudDecisionParams) (db.FraudDecision, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateFraudDecision", arg0, arg1)
+	ret0, _ := ret[0].(db.FraudDecision)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CreateFraudDecision indicates an expected call of CreateFraudDecision.
+func (mr *MockStoreMockRecorder) CreateFraudDecision(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateFraudDecision", reflect.TypeOf((*MockStore)(nil).CreateFraudDecision), arg0, arg1)
+}
+
+// CreateHold mocks base method.
+func (m *MockStore) CreateHold(arg0 context.Context, arg1 db.CreateHoldParams) (db.Hold, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateHold", arg0, arg1)
+	ret0, _ := ret[0].(db.Hold)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CreateHold indicates an expected call of CreateHold.
+func (mr *MockStoreMockRecorder) CreateHold(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateHold", reflect.TypeOf((*MockStore)(nil).CreateHold), arg0, arg1)
+}
+
+// CreateHoldTx mocks base method.
+func (m *MockStore) CreateHoldTx(arg0 context.Context, arg1 db.CreateHoldParams) (db.Hold, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateHoldTx", arg0, arg1)
+	ret0, _ := ret[0].(db.Hold)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CreateHoldTx indicates an expected call of CreateHoldTx.
+func (mr *MockStoreMockRecorder) CreateHoldTx(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateHoldTx", reflect.TypeOf((*MockStore)(nil).CreateHoldTx), arg0, arg1)
+}
+
+// CreateIdempotencyKey mocks base method.
+func (m *MockStore) CreateIdempotencyKey(arg0 context.Context, arg1 db.CreateIdempotencyKeyParams) (db.IdempotencyKey, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateIdempotencyKey", arg0, arg1)
+	ret0, _ := ret[0].(db.IdempotencyKey)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CreateIdempotencyKey indicates an expected call of CreateIdempotencyKey.
+func (mr *MockStoreMockRecorder) CreateIdempotencyKey(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateIdempotencyKey", reflect.TypeOf((*MockStore)(nil).CreateIdempotencyKey), arg0, arg1)
+}
+
+// CreateJournal mocks base method.
+func (m *MockStore) CreateJournal(arg0 context.Context, arg1 db.CreateJournalParams) (db.Journal, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateJournal", arg0, arg1)
+	ret0, _ := ret[0].(db.Journal)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CreateJournal indicates an expected call of CreateJournal.
+func (mr *MockStoreMockRecorder) CreateJournal(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateJournal", reflect.TypeOf((*MockStore)(nil).CreateJournal), arg0, arg1)
+}
+
+// CreateReconciliationDiscrepancy mocks base method.
+func (m *MockStore) CreateReconciliationDiscrepancy(arg0 context.Context, arg1 db.CreateReconciliationDiscrepancyParams) (db.ReconciliationDiscrepancy, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateReconciliationDiscrepancy", arg0, arg1)
+	ret0, _ := ret[0].(db.ReconciliationDiscrepancy)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CreateReconciliationDiscrepancy indicates an expected call of CreateReconciliationDiscrepancy.
+func (mr *MockStoreMockRecorder) CreateReconciliationDiscrepancy(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateReconciliationDiscrepancy", reflect.TypeOf((*MockStore)(nil).CreateReconciliationDiscrepancy), arg0, arg1)
+}
+
+// CreateReconciliationReport mocks base method.
+func (m *MockStore) CreateReconciliationReport(arg0 context.Context, arg1 db.CreateReconciliationReportParams) (db.ReconciliationReport, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateReconciliationReport", arg0, arg1)
+	ret0, _ := ret[0].(db.ReconciliationReport)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CreateReconciliationReport indicates an expected call of CreateReconciliationReport.
+func (mr *MockStoreMockRecorder) CreateReconciliationReport(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateReconciliationReport", reflect.TypeOf((*MockStore)(nil).CreateReconciliationReport), arg0, arg1)
+}
+
+// CreateRevokedToken mocks base method.
+func (m *MockStore) CreateRevokedToken(arg0 context.Context, arg1 db.CreateRevokedTokenParams) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateRevokedToken", arg0, arg1)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// CreateRevokedToken indicates an expected call of CreateRevokedToken.
+func (mr *MockStoreMockRecorder) CreateRevokedToken(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateRevokedToken", reflect.TypeOf((*MockStore)(nil).CreateRevokedToken), arg0, arg1)
+}
+
+// CreateScheduledTransfer mocks base method.
+func (m *MockStore) CreateScheduledTransfer(arg0 context.Context, arg1 db.CreateScheduledTransferParams) (db.ScheduledTransfer, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateScheduledTransfer", arg0, arg1)
+	ret0, _ := ret[0].(db.ScheduledTransfer)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CreateScheduledTransfer indicates an expected call of CreateScheduledTransfer.
+func (mr *MockStoreMockRecorder) CreateScheduledTransfer(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateScheduledTransfer", reflect.TypeOf((*MockStore)(nil).CreateScheduledTransfer), arg0, arg1)
+}
+
+// CreateScheduledTransferRun mocks base method.
+func (m *MockStore) CreateScheduledTransferRun(arg0 context.Context, arg1 db.CreateScheduledTransferRunParams) (db.ScheduledTransferRun, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateScheduledTransferRun", arg0, arg1)
+	ret0, _ := ret[0].(db.ScheduledTransferRun)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CreateScheduledTransferRun indicates an expected call of CreateScheduledTransferRun.
+func (mr *MockStoreMockRecorder) CreateScheduledTransferRun(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateScheduledTransferRun", reflect.TypeOf((*MockStore)(nil).CreateScheduledTransferRun), arg0, arg1)
+}
+
+// CreateSession mocks base method.
+func (m *MockStore) CreateSession(arg0 context.Context, arg1 db.CreateSessionParams) (db.Session, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateSession", arg0, arg1)
+	ret0, _ := ret[0].(db.Session)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CreateSession indicates an expected call of CreateSession.
+func (mr *MockStoreMockRecorder) CreateSession(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateSession", reflect.TypeOf((*MockStore)(nil).CreateSession), arg0, arg1)
+}
+
+// CreateStatement mocks base method.
+func (m *MockStore) CreateStatement(arg0 context.Context, arg1 db.CreateStatementParams) (db.Statement, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateStatement", arg0, arg1)
+	ret0, _ := ret[0].(db.Statement)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CreateStatement indicates an expected call of CreateStatement.
+func (mr *MockStoreMockRecorder) CreateStatement(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateStatement", reflect.TypeOf((*MockStore)(nil).CreateStatement), arg0, arg1)
+}
+
+// CreateStatementTx mocks base method.
+func (m *MockStore) CreateStatementTx(arg0 context.Context, arg1 db.CreateStatementTxParams) (db.Statement, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateStatementTx", arg0, arg1)
+	ret0, _ := ret[0].(db.Statement)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CreateStatementTx indicates an expected call of CreateStatementTx.
+func (mr *MockStoreMockRecorder) CreateStatementTx(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateStatementTx", reflect.TypeOf((*MockStore)(nil).CreateStatementTx), arg0, arg1)
+}
+
+// CreateSystemAccount mocks base method.
+func (m *MockStore) CreateSystemAccount(arg0 context.Context, arg1 db.CreateSystemAccountParams) (int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateSystemAccount", arg0, arg1)
+	ret0, _ := ret[0].(int64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CreateSystemAccount indicates an expected call of CreateSystemAccount.
+func (mr *MockStoreMockRecorder) CreateSystemAccount(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateSystemAccount", reflect.TypeOf((*MockStore)(nil).CreateSystemAccount), arg0, arg1)
+}
+
+// CreateTransfers mocks base method.
+func (m *MockStore) CreateTransfers(arg0 context.Context, arg1 db.CreateTransfersParams) (db.Transfer, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateTransfers", arg0, arg1)
+	ret0, _ := ret[0].(db.Transfer)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CreateTransfers indicates an expected call of CreateTransfers.
+func (mr *MockStoreMockRecorder) CreateTransfers(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateTransfers", reflect.TypeOf((*MockStore)(nil).CreateTransfers), arg0, arg1)
+}
+
+// CreateUser mocks base method.
+func (m *MockStore) CreateUser(arg0 context.Context, arg1 db.CreateUserParams) (db.User, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateUser", arg0, arg1)
+	ret0, _ := ret[0].(db.User)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CreateUser indicates an expected call of CreateUser.
+func (mr *MockStoreMockRecorder) CreateUser(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateUser", reflect.TypeOf((*MockStore)(nil).CreateUser), arg0, arg1)
+}
+
+// DeleteAccount mocks base method.
+func (m *MockStore) DeleteAccount(arg0 context.Context, arg1 int64) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteAccount", arg0, arg1)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DeleteAccount indicates an expected call of DeleteAccount.
+func (mr *MockStoreMockRecorder) DeleteAccount(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteAccount", reflect.TypeOf((*MockStore)(nil).DeleteAccount), arg0, arg1)
+}
+
+// DeleteExpiredIdempotencyKeys mocks base method.
+func (m *MockStore) DeleteExpiredIdempotencyKeys(arg0 context.Context, arg1 time.Time) (int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteExpiredIdempotencyKeys", arg0, arg1)
+	ret0, _ := ret[0].(int64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// DeleteExpiredIdempotencyKeys indicates an expected call of DeleteExpiredIdempotencyKeys.
+func (mr *MockStoreMockRecorder) DeleteExpiredIdempotencyKeys(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteExpiredIdempotencyKeys", reflect.TypeOf((*MockStore)(nil).DeleteExpiredIdempotencyKeys), arg0, arg1)
+}
+
+// DeleteExpiredRevokedTokens mocks base method.
+func (m *MockStore) DeleteExpiredRevokedTokens(arg0 context.Context, arg1 time.Time) (int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteExpiredRevokedTokens", arg0, arg1)
+	ret0, _ := ret[0].(int64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// DeleteExpiredRevokedTokens indicates an expected call of DeleteExpiredRevokedTokens.
+func (mr *MockStoreMockRecorder) DeleteExpiredRevokedTokens(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteExpiredRevokedTokens", reflect.TypeOf((*MockStore)(nil).DeleteExpiredRevokedTokens), arg0, arg1)
+}
+
+// DeleteTransferLimit mocks base method.
+func (m *MockStore) DeleteTransferLimit(arg0 context.Context, arg1 int64) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteTransferLimit", arg0, arg1)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DeleteTransferLimit indicates an expected call of DeleteTransferLimit.
+func (mr *MockStoreMockRecorder) DeleteTransferLimit(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteTransferLimit", reflect.TypeOf((*MockStore)(nil).DeleteTransferLimit), arg0, arg1)
+}
+
+// DepositTx mocks base method.
+func (m *MockStore) DepositTx(arg0 context.Context, arg1 db.CashTxParams) (db.CashTxResult, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DepositTx", arg0, arg1)
+	ret0, _ := ret[0].(db.CashTxResult)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// DepositTx indicates an expected call of DepositTx.
+func (mr *MockStoreMockRecorder) DepositTx(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DepositTx", reflect.TypeOf((*MockStore)(nil).DepositTx), arg0, arg1)
+}
+
+// EnsureSystemAccounts mocks base method.
+func (m *MockStore) EnsureSystemAccounts(arg0 context.Context) (int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "EnsureSystemAccounts", arg0)
+	ret0, _ := ret[0].(int64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// EnsureSystemAccounts indicates an expected call of EnsureSystemAccounts.
+func (mr *MockStoreMockRecorder) EnsureSystemAccounts(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "EnsureSystemAccounts", reflect.TypeOf((*MockStore)(nil).EnsureSystemAccounts), arg0)
+}
+
+// ExpireHolds mocks base method.
+func (m *MockStore) ExpireHolds(arg0 context.Context, arg1 time.Time) (int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ExpireHolds", arg0, arg1)
+	ret0, _ := ret[0].(int64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ExpireHolds indicates an expected call of ExpireHolds.
+func (mr *MockStoreMockRecorder) ExpireHolds(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ExpireHolds", reflect.TypeOf((*MockStore)(nil).ExpireHolds), arg0, arg1)
+}
+
+// GetAccount mocks base method.
+func (m *MockStore) GetAccount(arg0 context.Context, arg1 int64) (db.Account, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetAccount", arg0, arg1)
+	ret0, _ := ret[0].(db.Account)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetAccount indicates an expected call of GetAccount.
+func (mr *MockStoreMockRecorder) GetAccount(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetAccount", reflect.TypeOf((*MockStore)(nil).GetAccount), arg0, arg1)
+}
+
+// GetAccountByOwner mocks base method.
+func (m *MockStore) GetAccountByOwner(arg0 context.Context, arg1 db.GetAccountByOwnerParams) (db.Account, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetAccountByOwner", arg0, arg1)
+	ret0, _ := ret[0].(db.Account)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetAccountByOwner indicates an expected call of GetAccountByOwner.
+func (mr *MockStoreMockRecorder) GetAccountByOwner(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetAccountByOwner", reflect.TypeOf((*MockStore)(nil).GetAccountByOwner), arg0, arg1)
+}
+
+// GetAccountForUpdate mocks base method.
+func (m *MockStore) GetAccountForUpdate(arg0 context.Context, arg1 int64) (db.Account, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetAccountForUpdate", arg0, arg1)
+	ret0, _ := ret[0].(db.Account)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetAccountForUpdate indicates an expected call of GetAccountForUpdate.
+func (mr *MockStoreMockRecorder) GetAccountForUpdate(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetAccountForUpdate", reflect.TypeOf((*MockStore)(nil).GetAccountForUpdate), arg0, arg1)
+}
+
+// GetAccountTransferUsage mocks base method.
+func (m *MockStore) GetAccountTransferUsage(arg0 context.Context, arg1 db.GetAccountTransferUsageParams) (db.GetAccountTransferUsageRow, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetAccountTransferUsage", arg0, arg1)
+	ret0, _ := ret[0].(db.GetAccountTransferUsageRow)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetAccountTransferUsage indicates an expected call of GetAccountTransferUsage.
+func (mr *MockStoreMockRecorder) GetAccountTransferUsage(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetAccountTransferUsage", reflect.TypeOf((*MockStore)(nil).GetAccountTransferUsage), arg0, arg1)
+}
+
+// GetActiveHoldsAmount mocks base method.
+func (m *MockStore) GetActiveHoldsAmount(arg0 context.Context, arg1 int64) (int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetActiveHoldsAmount", arg0, arg1)
+	ret0, _ := ret[0].(int64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetActiveHoldsAmount indicates an expected call of GetActiveHoldsAmount.
+func (mr *MockStoreMockRecorder) GetActiveHoldsAmount(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetActiveHoldsAmount", reflect.TypeOf((*MockStore)(nil).GetActiveHoldsAmount), arg0, arg1)
+}
+
+// GetBalanceAt mocks base method.
+func (m *MockStore) GetBalanceAt(arg0 context.Context, arg1 db.GetBalanceAtParams) (int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetBalanceAt", arg0, arg1)
+	ret0, _ := ret[0].(int64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetBalanceAt indicates an expected call of GetBalanceAt.
+func (mr *MockStoreMockRecorder) GetBalanceAt(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetBalanceAt", reflect.TypeOf((*MockStore)(nil).GetBalanceAt), arg0, arg1)
+}
+
+// GetEntry mocks base method.
+func (m *MockStore) GetEntry(arg0 context.Context, arg1 int64) (db.Entry, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetEntry", arg0, arg1)
+	ret0, _ := ret[0].(db.Entry)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetEntry indicates an expected call of GetEntry.
+func (mr *MockStoreMockRecorder) GetEntry(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetEntry", reflect.TypeOf((*MockStore)(nil).GetEntry), arg0, arg1)
+}
+
+// GetExchangeRate mocks base method.
+func (m *MockStore) GetExchangeRate(arg0 context.Context, arg1 int64) (db.ExchangeRate, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetExchangeRate", arg0, arg1)
+	ret0, _ := ret[0].(db.ExchangeRate)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetExchangeRate indicates an expected call of GetExchangeRate.
+func (mr *MockStoreMockRecorder) GetExchangeRate(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetExchangeRate", reflect.TypeOf((*MockStore)(nil).GetExchangeRate), arg0, arg1)
+}
+
+// GetHold mocks base method.
+func (m *MockStore) GetHold(arg0 context.Context, arg1 int64) (db.Hold, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetHold", arg0, arg1)
+	ret0, _ := ret[0].(db.Hold)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetHold indicates an expected call of GetHold.
+func (mr *MockStoreMockRecorder) GetHold(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetHold", reflect.TypeOf((*MockStore)(nil).GetHold), arg0, arg1)
+}
+
+// GetHoldForUpdate mocks base method.
+func (m *MockStore) GetHoldForUpdate(arg0 context.Context, arg1 int64) (db.Hold, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetHoldForUpdate", arg0, arg1)
+	ret0, _ := ret[0].(db.Hold)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetHoldForUpdate indicates an expected call of GetHoldForUpdate.
+func (mr *MockStoreMockRecorder) GetHoldForUpdate(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetHoldForUpdate", reflect.TypeOf((*MockStore)(nil).GetHoldForUpdate), arg0, arg1)
+}
+
+// GetIdempotencyKey mocks base method.
+func (m *MockStore) GetIdempotencyKey(arg0 context.Context, arg1 db.GetIdempotencyKeyParams) (db.IdempotencyKey, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetIdempotencyKey", arg0, arg1)
+	ret0, _ := ret[0].(db.IdempotencyKey)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetIdempotencyKey indicates an expected call of GetIdempotencyKey.
+func (mr *MockStoreMockRecorder) GetIdempotencyKey(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetIdempotencyKey", reflect.TypeOf((*MockStore)(nil).GetIdempotencyKey), arg0, arg1)
+}
+
+// GetJournal mocks base method.
+func (m *MockStore) GetJournal(arg0 context.Context, arg1 int64) (db.Journal, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetJournal", arg0, arg1)
+	ret0, _ := ret[0].(db.Journal)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetJournal indicates an expected call of GetJournal.
+func (mr *MockStoreMockRecorder) GetJournal(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetJournal", reflect.TypeOf((*MockStore)(nil).GetJournal), arg0, arg1)
+}
+
+// GetLatestBalanceSnapshot mocks base method.
+func (m *MockStore) GetLatestBalanceSnapshot(arg0 context.Context, arg1 db.GetLatestBalanceSnapshotParams) (db.BalanceSnapshot, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetLatestBalanceSnapshot", arg0, arg1)
+	ret0, _ := ret[0].(db.BalanceSnapshot)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetLatestBalanceSnapshot indicates an expected call of GetLatestBalanceSnapshot.
+func (mr *MockStoreMockRecorder) GetLatestBalanceSnapshot(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetLatestBalanceSnapshot", reflect.TypeOf((*MockStore)(nil).GetLatestBalanceSnapshot), arg0, arg1)
+}
+
+// GetLatestExchangeRate mocks base method.
+func (m *MockStore) GetLatestExchangeRate(arg0 context.Context, arg1 db.GetLatestExchangeRateParams) (db.ExchangeRate, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetLatestExchangeRate", arg0, arg1)
+	ret0, _ := ret[0].(db.ExchangeRate)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetLatestExchangeRate indicates an expected call of GetLatestExchangeRate.
+func (mr *MockStoreMockRecorder) GetLatestExchangeRate(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetLatestExchangeRate", reflect.TypeOf((*MockStore)(nil).GetLatestExchangeRate), arg0, arg1)
+}
+
+// GetReconciliationReport mocks base method.
+func (m *MockStore) GetReconciliationReport(arg0 context.Context, arg1 int64) (db.ReconciliationReport, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetReconciliationReport", arg0, arg1)
+	ret0, _ := ret[0].(db.ReconciliationReport)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetReconciliationReport indicates an expected call of GetReconciliationReport.
+func (mr *MockStoreMockRecorder) GetReconciliationReport(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetReconciliationReport", reflect.TypeOf((*MockStore)(nil).GetReconciliationReport), arg0, arg1)
+}
+
+// GetScheduledTransfer mocks base method.
+func (m *MockStore) GetScheduledTransfer(arg0 context.Context, arg1 int64) (db.ScheduledTransfer, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetScheduledTransfer", arg0, arg1)
+	ret0, _ := ret[0].(db.ScheduledTransfer)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetScheduledTransfer indicates an expected call of GetScheduledTransfer.
+func (mr *MockStoreMockRecorder) GetScheduledTransfer(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetScheduledTransfer", reflect.TypeOf((*MockStore)(nil).GetScheduledTransfer), arg0, arg1)
+}
+
+// GetSession mocks base method.
+func (m *MockStore) GetSession(arg0 context.Context, arg1 uuid.UUID) (db.Session, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetSession", arg0, arg1)
+	ret0, _ := ret[0].(db.Session)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetSession indicates an expected call of GetSession.
+func (mr *MockStoreMockRecorder) GetSession(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetSession", reflect.TypeOf((*MockStore)(nil).GetSession), arg0, arg1)
+}
+
+// GetStatement mocks base method.
+func (m *MockStore) GetStatement(arg0 context.Context, arg1 int64) (db.Statement, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetStatement", arg0, arg1)
+	ret0, _ := ret[0].(db.Statement)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetStatement indicates an expected call of GetStatement.
+func (mr *MockStoreMockRecorder) GetStatement(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetStatement", reflect.TypeOf((*MockStore)(nil).GetStatement), arg0, arg1)
+}
+
+// GetStatementByPeriod mocks base method.
+func (m *MockStore) GetStatementByPeriod(arg0 context.Context, arg1 db.GetStatementByPeriodParams) (db.Statement, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetStatementByPeriod", arg0, arg1)
+	ret0, _ := ret[0].(db.Statement)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetStatementByPeriod indicates an expected call of GetStatementByPeriod.
+func (mr *MockStoreMockRecorder) GetStatementByPeriod(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetStatementByPeriod", reflect.TypeOf((*MockStore)(nil).GetStatementByPeriod), arg0, arg1)
+}
+
+// GetSystemAccount mocks base method.
+func (m *MockStore) GetSystemAccount(arg0 context.Context, arg1 db.GetSystemAccountParams) (db.Account, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetSystemAccount", arg0, arg1)
+	ret0, _ := ret[0].(db.Account)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetSystemAccount indicates an expected call of GetSystemAccount.
+func (mr *MockStoreMockRecorder) GetSystemAccount(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetSystemAccount", reflect.TypeOf((*MockStore)(nil).GetSystemAccount), arg0, arg1)
+}
+
+// GetTransferByReference mocks base method.
+func (m *MockStore) GetTransferByReference(arg0 context.Context, arg1 db.GetTransferByReferenceParams) (db.Transfer, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetTransferByReference", arg0, arg1)
+	ret0, _ := ret[0].(db.Transfer)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetTransferByReference indicates an expected call of GetTransferByReference.
+func (mr *MockStoreMockRecorder) GetTransferByReference(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetTransferByReference", reflect.TypeOf((*MockStore)(nil).GetTransferByReference), arg0, arg1)
+}
+
+// GetTransferForUpdate mocks base method.
+func (m *MockStore) GetTransferForUpdate(arg0 context.Context, arg1 int64) (db.Transfer, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetTransferForUpdate", arg0, arg1)
+	ret0, _ := ret[0].(db.Transfer)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetTransferForUpdate indicates an expected call of GetTransferForUpdate.
+func (mr *MockStoreMockRecorder) GetTransferForUpdate(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetTransferForUpdate", reflect.TypeOf((*MockStore)(nil).GetTransferForUpdate), arg0, arg1)
+}
+
+// GetTransferLimit mocks base method.
+func (m *MockStore) GetTransferLimit(arg0 context.Context, arg1 int64) (db.TransferLimit, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetTransferLimit", arg0, arg1)
+	ret0, _ := ret[0].(db.TransferLimit)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetTransferLimit indicates an expected call of GetTransferLimit.
+func (mr *MockStoreMockRecorder) GetTransferLimit(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetTransferLimit", reflect.TypeOf((*MockStore)(nil).GetTransferLimit), arg0, arg1)
+}
+
+// GetTransfers mocks base method.
+func (m *MockStore) GetTransfers(arg0 context.Context, arg1 int64) (db.Transfer, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetTransfers", arg0, arg1)
+	ret0, _ := ret[0].(db.Transfer)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetTransfers indicates an expected call of GetTransfers.
+func (mr *MockStoreMockRecorder) GetTransfers(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetTransfers", reflect.TypeOf((*MockStore)(nil).GetTransfers), arg0, arg1)
+}
+
+// GetUser mocks base method.
+func (m *MockStore) GetUser(arg0 context.Context, arg1 string) (db.User, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetUser", arg0, arg1)
+	ret0, _ := ret[0].(db.User)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetUser indicates an expected call of GetUser.
+func (mr *MockStoreMockRecorder) GetUser(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetUser", reflect.TypeOf((*MockStore)(nil).GetUser), arg0, arg1)
+}
+
+// GetUserByEmail mocks base method.
+func (m *MockStore) GetUserByEmail(arg0 context.Context, arg1 string) (db.User, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetUserByEmail", arg0, arg1)
+	ret0, _ := ret[0].(db.User)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetUserByEmail indicates an expected call of GetUserByEmail.
+func (mr *MockStoreMockRecorder) GetUserByEmail(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetUserByEmail", reflect.TypeOf((*MockStore)(nil).GetUserByEmail), arg0, arg1)
+}
+
+// GetUserForUpdate mocks base method.
+func (m *MockStore) GetUserForUpdate(arg0 context.Context, arg1 string) (db.User, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetUserForUpdate", arg0, arg1)
+	ret0, _ := ret[0].(db.User)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetUserForUpdate indicates an expected call of GetUserForUpdate.
+func (mr *MockStoreMockRecorder) GetUserForUpdate(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetUserForUpdate", reflect.TypeOf((*MockStore)(nil).GetUserForUpdate), arg0, arg1)
+}
+
+// GetUserTransferUsage mocks base method.
+func (m *MockStore) GetUserTransferUsage(arg0 context.Context, arg1 db.GetUserTransferUsageParams) (db.GetUserTransferUsageRow, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetUserTransferUsage", arg0, arg1)
+	ret0, _ := ret[0].(db.GetUserTransferUsageRow)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetUserTransferUsage indicates an expected call of GetUserTransferUsage.
+func (mr *MockStoreMockRecorder) GetUserTransferUsage(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetUserTransferUsage", reflect.TypeOf((*MockStore)(nil).GetUserTransferUsage), arg0, arg1)
+}
+
+// IdempotentTransferTx mocks base method.
+func (m *MockStore) IdempotentTransferTx(arg0 context.Context, arg1 db.IdempotentTransferTxParams) (db.TransferTxResult, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "IdempotentTransferTx", arg0, arg1)
+	ret0, _ := ret[0].(db.TransferTxResult)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// IdempotentTransferTx indicates an expected call of IdempotentTransferTx.
+func (mr *MockStoreMockRecorder) IdempotentTransferTx(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "IdempotentTransferTx", reflect.TypeOf((*MockStore)(nil).IdempotentTransferTx), arg0, arg1)
+}
+
+// IsTokenRevoked mocks base method.
+func (m *MockStore) IsTokenRevoked(arg0 context.Context, arg1 uuid.UUID) (bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "IsTokenRevoked", arg0, arg1)
+	ret0, _ := ret[0].(bool)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// IsTokenRevoked indicates an expected call of IsTokenRevoked.
+func (mr *MockStoreMockRecorder) IsTokenRevoked(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "IsTokenRevoked", reflect.TypeOf((*MockStore)(nil).IsTokenRevoked), arg0, arg1)
+}
+
+// ListAccountBalanceMismatches mocks base method.
+func (m *MockStore) ListAccountBalanceMismatches(arg0 context.Context) ([]db.ListAccountBalanceMismatchesRow, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListAccountBalanceMismatches", arg0)
+	ret0, _ := ret[0].([]db.ListAccountBalanceMismatchesRow)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListAccountBalanceMismatches indicates an expected call of ListAccountBalanceMismatches.
+func (mr *MockStoreMockRecorder) ListAccountBalanceMismatches(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListAccountBalanceMismatches", reflect.TypeOf((*MockStore)(nil).ListAccountBalanceMismatches), arg0)
+}
+
+// ListAccountEntryChain mocks base method.
+func (m *MockStore) ListAccountEntryChain(arg0 context.Context, arg1 db.ListAccountEntryChainParams) ([]db.Entry, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListAccountEntryChain", arg0, arg1)
+	ret0, _ := ret[0].([]db.Entry)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListAccountEntryChain indicates an expected call of ListAccountEntryChain.
+func (mr *MockStoreMockRecorder) ListAccountEntryChain(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListAccountEntryChain", reflect.TypeOf((*MockStore)(nil).ListAccountEntryChain), arg0, arg1)
+}
+
+// ListAccounts mocks base method.
+func (m *MockStore) ListAccounts(arg0 context.Context, arg1 db.ListAccountsParams) ([]db.Account, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListAccounts", arg0, arg1)
+	ret0, _ := ret[0].([]db.Account)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListAccounts indicates an expected call of ListAccounts.
+func (mr *MockStoreMockRecorder) ListAccounts(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListAccounts", reflect.TypeOf((*MockStore)(nil).ListAccounts), arg0, arg1)
+}
+
+// ListAccountsAfter mocks base method.
+func (m *MockStore) ListAccountsAfter(arg0 context.Context, arg1 db.ListAccountsAfterParams) ([]db.Account, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListAccountsAfter", arg0, arg1)
+	ret0, _ := ret[0].([]db.Account)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListAccountsAfter indicates an expected call of ListAccountsAfter.
+func (mr *MockStoreMockRecorder) ListAccountsAfter(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListAccountsAfter", reflect.TypeOf((*MockStore)(nil).ListAccountsAfter), arg0, arg1)
+}
+
+// ListApplicableTransferLimits mocks base method.
+func (m *MockStore) ListApplicableTransferLimits(arg0 context.Context, arg1 db.ListApplicableTransferLimitsParams) ([]db.TransferLimit, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListApplicableTransferLimits", arg0, arg1)
+	ret0, _ := ret[0].([]db.TransferLimit)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListApplicableTransferLimits indicates an expected call of ListApplicableTransferLimits.
+func (mr *MockStoreMockRecorder) ListApplicableTransferLimits(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListApplicableTransferLimits", reflect.TypeOf((*MockStore)(nil).ListApplicableTransferLimits), arg0, arg1)
+}
+
+// ListCurrencyImbalances mocks base method.
+func (m *MockStore) ListCurrencyImbalances(arg0 context.Context) ([]db.ListCurrencyImbalancesRow, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListCurrencyImbalances", arg0)
+	ret0, _ := ret[0].([]db.ListCurrencyImbalancesRow)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListCurrencyImbalances indicates an expected call of ListCurrencyImbalances.
+func (mr *MockStoreMockRecorder) ListCurrencyImbalances(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListCurrencyImbalances", reflect.TypeOf((*MockStore)(nil).ListCurrencyImbalances), arg0)
+}
+
+// ListDailyBalances mocks base method.
+func (m *MockStore) ListDailyBalances(arg0 context.Context, arg1 db.ListDailyBalancesParams) ([]db.DailyBalance, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListDailyBalances", arg0, arg1)
+	ret0, _ := ret[0].([]db.DailyBalance)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListDailyBalances indicates an expected call of ListDailyBalances.
+func (mr *MockStoreMockRecorder) ListDailyBalances(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListDailyBalances", reflect.TypeOf((*MockStore)(nil).ListDailyBalances), arg0, arg1)
+}
+
+// ListDailyEntryTotals mocks base method.
+func (m *MockStore) ListDailyEntryTotals(arg0 context.Context, arg1 db.ListDailyEntryTotalsParams) ([]db.ListDailyEntryTotalsRow, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListDailyEntryTotals", arg0, arg1)
+	ret0, _ := ret[0].([]db.ListDailyEntryTotalsRow)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListDailyEntryTotals indicates an expected call of ListDailyEntryTotals.
+func (mr *MockStoreMockRecorder) ListDailyEntryTotals(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListDailyEntryTotals", reflect.TypeOf((*MockStore)(nil).ListDailyEntryTotals), arg0, arg1)
+}
+
+// ListDueScheduledTransfers mocks base method.
+func (m *MockStore) ListDueScheduledTransfers(arg0 context.Context, arg1 db.ListDueScheduledTransfersParams) ([]db.ScheduledTransfer, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListDueScheduledTransfers", arg0, arg1)
+	ret0, _ := ret[0].([]db.ScheduledTransfer)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListDueScheduledTransfers indicates an expected call of ListDueScheduledTransfers.
+func (mr *MockStoreMockRecorder) ListDueScheduledTransfers(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListDueScheduledTransfers", reflect.TypeOf((*MockStore)(nil).ListDueScheduledTransfers), arg0, arg1)
+}
+
+// ListEntries mocks base method.
+func (m *MockStore) ListEntries(arg0 context.Context, arg1 db.ListEntriesParams) ([]db.Entry, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListEntries", arg0, arg1)
+	ret0, _ := ret[0].([]db.Entry)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListEntries indicates an expected call of ListEntries.
+func (mr *MockStoreMockRecorder) ListEntries(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListEntries", reflect.TypeOf((*MockStore)(nil).ListEntries), arg0, arg1)
+}
+
+// ListExchangeRates mocks base method.
+func (m *MockStore) ListExchangeRates(arg0 context.Context, arg1 db.ListExchangeRatesParams) ([]db.ExchangeRate, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListExchangeRates", arg0, arg1)
+	ret0, _ := ret[0].([]db.ExchangeRate)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
-// AddaAccountBalance indicates an expected call of AddaAccountBalance.
-func (mr *MockStoreMockRecorder) AddaAccountBalance(arg0, arg1 interface{}) *gomock.Call {
+// ListExchangeRates indicates an expected call of ListExchangeRates.
+func (mr *MockStoreMockRecorder) ListExchangeRates(arg0, arg1 interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AddaAccountBalance", reflect.TypeOf((*MockStore)(nil).AddaAccountBalance), arg0, arg1)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListExchangeRates", reflect.TypeOf((*MockStore)(nil).ListExchangeRates), arg0, arg1)
 }
 
-// CreateAccount mocks base method.
-func (m *MockStore) CreateAccount(arg0 context.Context, arg1 db.CreateAccountParams) (db.Account, error) {
+// ListFraudDecisions mocks base method.
+func (m *MockStore) ListFraudDecisions(arg0 context.Context, arg1 db.ListFraudDecisionsParams) ([]db.FraudDecision, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "CreateAccount", arg0, arg1)
-	ret0, _ := ret[0].(db.Account)
+	ret := m.ctrl.Call(m, "ListFraudDecisions", arg0, arg1)
+	ret0, _ := ret[0].([]db.FraudDecision)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
-// CreateAccount indicates an expected call of CreateAccount.
-func (mr *MockStoreMockRecorder) CreateAccount(arg0, arg1 interface{}) *gomock.Call {
+// ListFraudDecisions indicates an expected call of ListFraudDecisions.
+func (mr *MockStoreMockRecorder) ListFraudDecisions(arg0, arg1 interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateAccount", reflect.TypeOf((*MockStore)(nil).CreateAccount), arg0, arg1)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListFraudDecisions", reflect.TypeOf((*MockStore)(nil).ListFraudDecisions), arg0, arg1)
 }
 
-// CreateEntry mocks base method.
-func (m *MockStore) CreateEntry(arg0 context.Context, arg1 db.CreateEntryParams) (db.Entry, error) {
+// ListHolds mocks base method.
+func (m *MockStore) ListHolds(arg0 context.Context, arg1 db.ListHoldsParams) ([]db.Hold, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "CreateEntry", arg0, arg1)
-	ret0, _ := ret[0].(db.Entry)
+	ret := m.ctrl.Call(m, "ListHolds", arg0, arg1)
+	ret0, _ := ret[0].([]db.Hold)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
-// CreateEntry indicates an expected call of CreateEntry.
-func (mr *MockStoreMockRecorder) CreateEntry(arg0, arg1 interface{}) *gomock.Call {
+// ListHolds indicates an expected call of ListHolds.
+func (mr *MockStoreMockRecorder) ListHolds(arg0, arg1 interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateEntry", reflect.TypeOf((*MockStore)(nil).CreateEntry), arg0, arg1)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListHolds", reflect.TypeOf((*MockStore)(nil).ListHolds), arg0, arg1)
 }
 
-// CreateTransfers mocks base method.
-func (m *MockStore) CreateTransfers(arg0 context.Context, arg1 db.CreateTransfersParams) (db.Transfer, error) {
+// ListJournalEntries mocks base method.
+func (m *MockStore) ListJournalEntries(arg0 context.Context, arg1 int64) ([]db.Entry, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "CreateTransfers", arg0, arg1)
-	ret0, _ := ret[0].(db.Transfer)
+	ret := m.ctrl.Call(m, "ListJournalEntries", arg0, arg1)
+	ret0, _ := ret[0].([]db.Entry)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
-// CreateTransfers indicates an expected call of CreateTransfers.
-func (mr *MockStoreMockRecorder) CreateTransfers(arg0, arg1 interface{}) *gomock.Call {
+// ListJournalEntries indicates an expected call of ListJournalEntries.
+func (mr *MockStoreMockRecorder) ListJournalEntries(arg0, arg1 interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateTransfers", reflect.TypeOf((*MockStore)(nil).CreateTransfers), arg0, arg1)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListJournalEntries", reflect.TypeOf((*MockStore)(nil).ListJournalEntries), arg0, arg1)
 }
 
-// CreateUser mocks base method.
-func (m *MockStore) CreateUser(arg0 context.Context, arg1 db.CreateUserParams) (db.User, error) {
+// ListReconciliationDiscrepancies mocks base method.
+func (m *MockStore) ListReconciliationDiscrepancies(arg0 context.Context, arg1 db.ListReconciliationDiscrepanciesParams) ([]db.ReconciliationDiscrepancy, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "CreateUser", arg0, arg1)
-	ret0, _ := ret[0].(db.User)
+	ret := m.ctrl.Call(m, "ListReconciliationDiscrepancies", arg0, arg1)
+	ret0, _ := ret[0].([]db.ReconciliationDiscrepancy)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
-// CreateUser indicates an expected call of CreateUser.
-func (mr *MockStoreMockRecorder) CreateUser(arg0, arg1 interface{}) *gomock.Call {
+// ListReconciliationDiscrepancies indicates an expected call of ListReconciliationDiscrepancies.
+func (mr *MockStoreMockRecorder) ListReconciliationDiscrepancies(arg0, arg1 interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateUser", reflect.TypeOf((*MockStore)(nil).CreateUser), arg0, arg1)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListReconciliationDiscrepancies", reflect.TypeOf((*MockStore)(nil).ListReconciliationDiscrepancies), arg0, arg1)
 }
 
-// DeleteAccount mocks base method.
-func (m *MockStore) DeleteAccount(arg0 context.Context, arg1 int64) error {
+// ListReconciliationReports mocks base method.
+func (m *MockStore) ListReconciliationReports(arg0 context.Context, arg1 db.ListReconciliationReportsParams) ([]db.ReconciliationReport, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "DeleteAccount", arg0, arg1)
-	ret0, _ := ret[0].(error)
-	return ret0
+	ret := m.ctrl.Call(m, "ListReconciliationReports", arg0, arg1)
+	ret0, _ := ret[0].([]db.ReconciliationReport)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
 }
 
-// DeleteAccount indicates an expected call of DeleteAccount.
-func (mr *MockStoreMockRecorder) DeleteAccount(arg0, arg1 interface{}) *gomock.Call {
+// ListReconciliationReports indicates an expected call of ListReconciliationReports.
+func (mr *MockStoreMockRecorder) ListReconciliationReports(arg0, arg1 interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteAccount", reflect.TypeOf((*MockStore)(nil).DeleteAccount), arg0, arg1)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListReconciliationReports", reflect.TypeOf((*MockStore)(nil).ListReconciliationReports), arg0, arg1)
 }
 
-// GetAccount mocks base method.
-func (m *MockStore) GetAccount(arg0 context.Context, arg1 int64) (db.Account, error) {
+// ListScheduledTransferRuns mocks base method.
+func (m *MockStore) ListScheduledTransferRuns(arg0 context.Context, arg1 db.ListScheduledTransferRunsParams) ([]db.ScheduledTransferRun, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "GetAccount", arg0, arg1)
-	ret0, _ := ret[0].(db.Account)
+	ret := m.ctrl.Call(m, "ListScheduledTransferRuns", arg0, arg1)
+	ret0, _ := ret[0].([]db.ScheduledTransferRun)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
-// GetAccount indicates an expected call of GetAccount.
-func (mr *MockStoreMockRecorder) GetAccount(arg0, arg1 interface{}) *gomock.Call {
+// ListScheduledTransferRuns indicates an expected call of ListScheduledTransferRuns.
+func (mr *MockStoreMockRecorder) ListScheduledTransferRuns(arg0, arg1 interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetAccount", reflect.TypeOf((*MockStore)(nil).GetAccount), arg0, arg1)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListScheduledTransferRuns", reflect.TypeOf((*MockStore)(nil).ListScheduledTransferRuns), arg0, arg1)
 }
 
-// GetAccountForUpdate mocks base method.
-func (m *MockStore) GetAccountForUpdate(arg0 context.Context, arg1 int64) (db.Account, error) {
+// ListScheduledTransfers mocks base method.
+func (m *MockStore) ListScheduledTransfers(arg0 context.Context, arg1 db.ListScheduledTransfersParams) ([]db.ScheduledTransfer, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "GetAccountForUpdate", arg0, arg1)
-	ret0, _ := ret[0].(db.Account)
+	ret := m.ctrl.Call(m, "ListScheduledTransfers", arg0, arg1)
+	ret0, _ := ret[0].([]db.ScheduledTransfer)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
-// GetAccountForUpdate indicates an expected call of GetAccountForUpdate.
-func (mr *MockStoreMockRecorder) GetAccountForUpdate(arg0, arg1 interface{}) *gomock.Call {
+// ListScheduledTransfers indicates an expected call of ListScheduledTransfers.
+func (mr *MockStoreMockRecorder) ListScheduledTransfers(arg0, arg1 interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetAccountForUpdate", reflect.TypeOf((*MockStore)(nil).GetAccountForUpdate), arg0, arg1)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListScheduledTransfers", reflect.TypeOf((*MockStore)(nil).ListScheduledTransfers), arg0, arg1)
 }
 
-// GetEntry mocks base method.
-func (m *MockStore) GetEntry(arg0 context.Context, arg1 int64) (db.Entry, error) {
+// ListStatementAccounts mocks base method.
+func (m *MockStore) ListStatementAccounts(arg0 context.Context, arg1 db.ListStatementAccountsParams) ([]db.Account, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "GetEntry", arg0, arg1)
-	ret0, _ := ret[0].(db.Entry)
+	ret := m.ctrl.Call(m, "ListStatementAccounts", arg0, arg1)
+	ret0, _ := ret[0].([]db.Account)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
-// GetEntry indicates an expected call of GetEntry.
-func (mr *MockStoreMockRecorder) GetEntry(arg0, arg1 interface{}) *gomock.Call {
+// ListStatementAccounts indicates an expected call of ListStatementAccounts.
+func (mr *MockStoreMockRecorder) ListStatementAccounts(arg0, arg1 interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetEntry", reflect.TypeOf((*MockStore)(nil).GetEntry), arg0, arg1)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListStatementAccounts", reflect.TypeOf((*MockStore)(nil).ListStatementAccounts), arg0, arg1)
 }
 
-// GetTransfers mocks base method.
-func (m *MockStore) GetTransfers(arg0 context.Context, arg1 int64) (db.Transfer, error) {
+// ListStatementEntries mocks base method.
+func (m *MockStore) ListStatementEntries(arg0 context.Context, arg1 db.ListStatementEntriesParams) ([]db.ListStatementEntriesRow, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "GetTransfers", arg0, arg1)
-	ret0, _ := ret[0].(db.Transfer)
+	ret := m.ctrl.Call(m, "ListStatementEntries", arg0, arg1)
+	ret0, _ := ret[0].([]db.ListStatementEntriesRow)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
-// GetTransfers indicates an expected call of GetTransfers.
-func (mr *MockStoreMockRecorder) GetTransfers(arg0, arg1 interface{}) *gomock.Call {
+// ListStatementEntries indicates an expected call of ListStatementEntries.
+func (mr *MockStoreMockRecorder) ListStatementEntries(arg0, arg1 interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetTransfers", reflect.TypeOf((*MockStore)(nil).GetTransfers), arg0, arg1)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListStatementEntries", reflect.TypeOf((*MockStore)(nil).ListStatementEntries), arg0, arg1)
 }
 
-// GetUser mocks base method.
-func (m *MockStore) GetUser(arg0 context.Context, arg1 string) (db.User, error) {
+// ListStatements mocks base method.
+func (m *MockStore) ListStatements(arg0 context.Context, arg1 db.ListStatementsParams) ([]db.ListStatementsRow, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "GetUser", arg0, arg1)
-	ret0, _ := ret[0].(db.User)
+	ret := m.ctrl.Call(m, "ListStatements", arg0, arg1)
+	ret0, _ := ret[0].([]db.ListStatementsRow)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
-// GetUser indicates an expected call of GetUser.
-func (mr *MockStoreMockRecorder) GetUser(arg0, arg1 interface{}) *gomock.Call {
+// ListStatements indicates an expected call of ListStatements.
+func (mr *MockStoreMockRecorder) ListStatements(arg0, arg1 interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetUser", reflect.TypeOf((*MockStore)(nil).GetUser), arg0, arg1)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListStatements", reflect.TypeOf((*MockStore)(nil).ListStatements), arg0, arg1)
 }
 
-// ListAccounts mocks base method.
-func (m *MockStore) ListAccounts(arg0 context.Context, arg1 db.ListAccountsParams) ([]db.Account, error) {
+// ListTransferEntryMismatches mocks base method.
+func (m *MockStore) ListTransferEntryMismatches(arg0 context.Context, arg1 []string) ([]db.ListTransferEntryMismatchesRow, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "ListAccounts", arg0, arg1)
-	ret0, _ := ret[0].([]db.Account)
+	ret := m.ctrl.Call(m, "ListTransferEntryMismatches", arg0, arg1)
+	ret0, _ := ret[0].([]db.ListTransferEntryMismatchesRow)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
-// ListAccounts indicates an expected call of ListAccounts.
-func (mr *MockStoreMockRecorder) ListAccounts(arg0, arg1 interface{}) *gomock.Call {
+// ListTransferEntryMismatches indicates an expected call of ListTransferEntryMismatches.
+func (mr *MockStoreMockRecorder) ListTransferEntryMismatches(arg0, arg1 interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListAccounts", reflect.TypeOf((*MockStore)(nil).ListAccounts), arg0, arg1)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListTransferEntryMismatches", reflect.TypeOf((*MockStore)(nil).ListTransferEntryMismatches), arg0, arg1)
 }
 
-// ListEntries mocks base method.
-func (m *MockStore) ListEntries(arg0 context.Context, arg1 db.ListEntriesParams) ([]db.Entry, error) {
+// ListTransferLimits mocks base method.
+func (m *MockStore) ListTransferLimits(arg0 context.Context, arg1 db.ListTransferLimitsParams) ([]db.TransferLimit, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "ListEntries", arg0, arg1)
-	ret0, _ := ret[0].([]db.Entry)
+	ret := m.ctrl.Call(m, "ListTransferLimits", arg0, arg1)
+	ret0, _ := ret[0].([]db.TransferLimit)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
-// ListEntries indicates an expected call of ListEntries.
-func (mr *MockStoreMockRecorder) ListEntries(arg0, arg1 interface{}) *gomock.Call {
+// ListTransferLimits indicates an expected call of ListTransferLimits.
+func (mr *MockStoreMockRecorder) ListTransferLimits(arg0, arg1 interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListEntries", reflect.TypeOf((*MockStore)(nil).ListEntries), arg0, arg1)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListTransferLimits", reflect.TypeOf((*MockStore)(nil).ListTransferLimits), arg0, arg1)
 }
 
 // ListTransfers mocks base method.
@@ -244,6 +1442,141 @@ func (mr *MockStoreMockRecorder) ListTransfers(arg0, arg1 interface{}) *gomock.C
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListTransfers", reflect.TypeOf((*MockStore)(nil).ListTransfers), arg0, arg1)
 }
 
+// ListTransfersByReference mocks base method.
+func (m *MockStore) ListTransfersByReference(arg0 context.Context, arg1 db.ListTransfersByReferenceParams) ([]db.Transfer, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListTransfersByReference", arg0, arg1)
+	ret0, _ := ret[0].([]db.Transfer)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListTransfersByReference indicates an expected call of ListTransfersByReference.
+func (mr *MockStoreMockRecorder) ListTransfersByReference(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListTransfersByReference", reflect.TypeOf((*MockStore)(nil).ListTransfersByReference), arg0, arg1)
+}
+
+// ListUnbalancedJournals mocks base method.
+func (m *MockStore) ListUnbalancedJournals(arg0 context.Context) ([]db.ListUnbalancedJournalsRow, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListUnbalancedJournals", arg0)
+	ret0, _ := ret[0].([]db.ListUnbalancedJournalsRow)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListUnbalancedJournals indicates an expected call of ListUnbalancedJournals.
+func (mr *MockStoreMockRecorder) ListUnbalancedJournals(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListUnbalancedJournals", reflect.TypeOf((*MockStore)(nil).ListUnbalancedJournals), arg0)
+}
+
+// PostJournalTx mocks base method.
+func (m *MockStore) PostJournalTx(arg0 context.Context, arg1 db.PostJournalTxParams) (db.PostJournalTxResult, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "PostJournalTx", arg0, arg1)
+	ret0, _ := ret[0].(db.PostJournalTxResult)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// PostJournalTx indicates an expected call of PostJournalTx.
+func (mr *MockStoreMockRecorder) PostJournalTx(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "PostJournalTx", reflect.TypeOf((*MockStore)(nil).PostJournalTx), arg0, arg1)
+}
+
+// ReconcileTx mocks base method.
+func (m *MockStore) ReconcileTx(arg0 context.Context) (db.ReconcileTxResult, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ReconcileTx", arg0)
+	ret0, _ := ret[0].(db.ReconcileTxResult)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ReconcileTx indicates an expected call of ReconcileTx.
+func (mr *MockStoreMockRecorder) ReconcileTx(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ReconcileTx", reflect.TypeOf((*MockStore)(nil).ReconcileTx), arg0)
+}
+
+// RejectTransferTx mocks base method.
+func (m *MockStore) RejectTransferTx(arg0 context.Context, arg1 db.DecideTransferTxParams) (db.Transfer, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RejectTransferTx", arg0, arg1)
+	ret0, _ := ret[0].(db.Transfer)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// RejectTransferTx indicates an expected call of RejectTransferTx.
+func (mr *MockStoreMockRecorder) RejectTransferTx(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RejectTransferTx", reflect.TypeOf((*MockStore)(nil).RejectTransferTx), arg0, arg1)
+}
+
+// ReverseTransferTx mocks base method.
+func (m *MockStore) ReverseTransferTx(arg0 context.Context, arg1 db.ReverseTransferTxParams) (db.ReverseTransferTxResult, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ReverseTransferTx", arg0, arg1)
+	ret0, _ := ret[0].(db.ReverseTransferTxResult)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ReverseTransferTx indicates an expected call of ReverseTransferTx.
+func (mr *MockStoreMockRecorder) ReverseTransferTx(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ReverseTransferTx", reflect.TypeOf((*MockStore)(nil).ReverseTransferTx), arg0, arg1)
+}
+
+// RevokeUserTokens mocks base method.
+func (m *MockStore) RevokeUserTokens(arg0 context.Context, arg1 db.RevokeUserTokensParams) (db.User, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RevokeUserTokens", arg0, arg1)
+	ret0, _ := ret[0].(db.User)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// RevokeUserTokens indicates an expected call of RevokeUserTokens.
+func (mr *MockStoreMockRecorder) RevokeUserTokens(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RevokeUserTokens", reflect.TypeOf((*MockStore)(nil).RevokeUserTokens), arg0, arg1)
+}
+
+// SetFraudDecisionTransfer mocks base method.
+func (m *MockStore) SetFraudDecisionTransfer(arg0 context.Context, arg1 db.SetFraudDecisionTransferParams) (db.FraudDecision, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SetFraudDecisionTransfer", arg0, arg1)
+	ret0, _ := ret[0].(db.FraudDecision)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// SetFraudDecisionTransfer indicates an expected call of SetFraudDecisionTransfer.
+func (mr *MockStoreMockRecorder) SetFraudDecisionTransfer(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetFraudDecisionTransfer", reflect.TypeOf((*MockStore)(nil).SetFraudDecisionTransfer), arg0, arg1)
+}
+
+// SumAccountEntries mocks base method.
+func (m *MockStore) SumAccountEntries(arg0 context.Context, arg1 db.SumAccountEntriesParams) (int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SumAccountEntries", arg0, arg1)
+	ret0, _ := ret[0].(int64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// SumAccountEntries indicates an expected call of SumAccountEntries.
+func (mr *MockStoreMockRecorder) SumAccountEntries(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SumAccountEntries", reflect.TypeOf((*MockStore)(nil).SumAccountEntries), arg0, arg1)
+}
+
 // TransferTx mocks base method.
 func (m *MockStore) TransferTx(arg0 context.Context, arg1 db.TransferTxParams) (db.TransferTxResult, error) {
 	m.ctrl.T.Helper()
@@ -273,3 +1606,152 @@ func (mr *MockStoreMockRecorder) UpdateAccount(arg0, arg1 interface{}) *gomock.C
 	mr.mock.ctrl.T.Helper()
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateAccount", reflect.TypeOf((*MockStore)(nil).UpdateAccount), arg0, arg1)
 }
+
+// UpdateHoldStatus mocks base method.
+func (m *MockStore) UpdateHoldStatus(arg0 context.Context, arg1 db.UpdateHoldStatusParams) (db.Hold, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdateHoldStatus", arg0, arg1)
+	ret0, _ := ret[0].(db.Hold)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// UpdateHoldStatus indicates an expected call of UpdateHoldStatus.
+func (mr *MockStoreMockRecorder) UpdateHoldStatus(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateHoldStatus", reflect.TypeOf((*MockStore)(nil).UpdateHoldStatus), arg0, arg1)
+}
+
+// UpdateIdempotencyKeyResponse mocks base method.
+func (m *MockStore) UpdateIdempotencyKeyResponse(arg0 context.Context, arg1 db.UpdateIdempotencyKeyResponseParams) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdateIdempotencyKeyResponse", arg0, arg1)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// UpdateIdempotencyKeyResponse indicates an expected call of UpdateIdempotencyKeyResponse.
+func (mr *MockStoreMockRecorder) UpdateIdempotencyKeyResponse(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateIdempotencyKeyResponse", reflect.TypeOf((*MockStore)(nil).UpdateIdempotencyKeyResponse), arg0, arg1)
+}
+
+// UpdateScheduledTransfer mocks base method.
+func (m *MockStore) UpdateScheduledTransfer(arg0 context.Context, arg1 db.UpdateScheduledTransferParams) (db.ScheduledTransfer, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdateScheduledTransfer", arg0, arg1)
+	ret0, _ := ret[0].(db.ScheduledTransfer)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// UpdateScheduledTransfer indicates an expected call of UpdateScheduledTransfer.
+func (mr *MockStoreMockRecorder) UpdateScheduledTransfer(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateScheduledTransfer", reflect.TypeOf((*MockStore)(nil).UpdateScheduledTransfer), arg0, arg1)
+}
+
+// UpdateTransferDecision mocks base method.
+func (m *MockStore) UpdateTransferDecision(arg0 context.Context, arg1 db.UpdateTransferDecisionParams) (db.Transfer, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdateTransferDecision", arg0, arg1)
+	ret0, _ := ret[0].(db.Transfer)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// UpdateTransferDecision indicates an expected call of UpdateTransferDecision.
+func (mr *MockStoreMockRecorder) UpdateTransferDecision(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateTransferDecision", reflect.TypeOf((*MockStore)(nil).UpdateTransferDecision), arg0, arg1)
+}
+
+// UpdateTransferReversal mocks base method.
+func (m *MockStore) UpdateTransferReversal(arg0 context.Context, arg1 db.UpdateTransferReversalParams) (db.Transfer, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdateTransferReversal", arg0, arg1)
+	ret0, _ := ret[0].(db.Transfer)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// UpdateTransferReversal indicates an expected call of UpdateTransferReversal.
+func (mr *MockStoreMockRecorder) UpdateTransferReversal(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateTransferReversal", reflect.TypeOf((*MockStore)(nil).UpdateTransferReversal), arg0, arg1)
+}
+
+// UpdateUserRole mocks base method.
+func (m *MockStore) UpdateUserRole(arg0 context.Context, arg1 db.UpdateUserRoleParams) (db.User, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdateUserRole", arg0, arg1)
+	ret0, _ := ret[0].(db.User)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// UpdateUserRole indicates an expected call of UpdateUserRole.
+func (mr *MockStoreMockRecorder) UpdateUserRole(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateUserRole", reflect.TypeOf((*MockStore)(nil).UpdateUserRole), arg0, arg1)
+}
+
+// UpsertTransferLimit mocks base method.
+func (m *MockStore) UpsertTransferLimit(arg0 context.Context, arg1 db.UpsertTransferLimitParams) (db.TransferLimit, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpsertTransferLimit", arg0, arg1)
+	ret0, _ := ret[0].(db.TransferLimit)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// UpsertTransferLimit indicates an expected call of UpsertTransferLimit.
+func (mr *MockStoreMockRecorder) UpsertTransferLimit(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpsertTransferLimit", reflect.TypeOf((*MockStore)(nil).UpsertTransferLimit), arg0, arg1)
+}
+
+// VerifyEntryChain mocks base method.
+func (m *MockStore) VerifyEntryChain(arg0 context.Context, arg1 db.VerifyEntryChainParams) (db.VerifyEntryChainResult, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "VerifyEntryChain", arg0, arg1)
+	ret0, _ := ret[0].(db.VerifyEntryChainResult)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// VerifyEntryChain indicates an expected call of VerifyEntryChain.
+func (mr *MockStoreMockRecorder) VerifyEntryChain(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "VerifyEntryChain", reflect.TypeOf((*MockStore)(nil).VerifyEntryChain), arg0, arg1)
+}
+
+// VoidHoldTx mocks base method.
+func (m *MockStore) VoidHoldTx(arg0 context.Context, arg1 int64) (db.Hold, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "VoidHoldTx", arg0, arg1)
+	ret0, _ := ret[0].(db.Hold)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// VoidHoldTx indicates an expected call of VoidHoldTx.
+func (mr *MockStoreMockRecorder) VoidHoldTx(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "VoidHoldTx", reflect.TypeOf((*MockStore)(nil).VoidHoldTx), arg0, arg1)
+}
+
+// WithdrawTx mocks base method.
+func (m *MockStore) WithdrawTx(arg0 context.Context, arg1 db.CashTxParams) (db.CashTxResult, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "WithdrawTx", arg0, arg1)
+	ret0, _ := ret[0].(db.CashTxResult)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// WithdrawTx indicates an expected call of WithdrawTx.
+func (mr *MockStoreMockRecorder) WithdrawTx(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "WithdrawTx", reflect.TypeOf((*MockStore)(nil).WithdrawTx), arg0, arg1)
+}
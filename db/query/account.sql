@@ -16,12 +16,31 @@ SELECT * FROM account
 WHERE id = $1 LIMIT 1
 FOR NO KEY UPDATE;
 
+-- name: GetAccountByOwner :one
+SELECT * FROM account
+WHERE owner = $1 AND currency = $2 AND system_code = '' LIMIT 1;
+
+-- name: GetSystemAccount :one
+SELECT * FROM account
+WHERE system_code = $1 AND currency = $2 LIMIT 1;
+
+-- name: CreateSystemAccount :execrows
+-- 已经存在的系统账户不会重复创建
+INSERT INTO account (
+    owner,
+    balance,
+    currency,
+    system_code
+) VALUES (
+    'system', 0, sqlc.arg(currency), sqlc.arg(system_code)
+) ON CONFLICT DO NOTHING;
+
 -- name: ListAccounts :many
 SELECT * FROM account
-where owner = $1
-ORDER BY id
-LIMIT $2
-OFFSET $3;
+WHERE owner = sqlc.arg(owner)
+  AND (created_at, id) > (sqlc.arg(cursor_created_at)::timestamptz, sqlc.arg(cursor_id)::bigint)
+ORDER BY created_at, id
+LIMIT sqlc.arg(page_size);
 
 -- name: UpdateAccount :one
 UPDATE account
@@ -35,6 +54,20 @@ SET balance=balance + sqlc.arg(amount)
 WHERE id=sqlc.arg(id)
 RETURNING *;
 
+-- name: AddAccountLedgerBalance :one
+-- 记账时同时更新余额和最后一条流水的哈希
+UPDATE account
+SET balance = balance + sqlc.arg(amount),
+    entry_hash = sqlc.arg(entry_hash)
+WHERE id = sqlc.arg(id)
+RETURNING *;
+
+-- name: ListAccountsAfter :many
+SELECT * FROM account
+WHERE id > sqlc.arg(after_id)
+ORDER BY id
+LIMIT sqlc.arg(page_size);
+
 -- name: DeleteAccount :exec
 DELETE FROM account
 WHERE id=$1;
@@ -0,0 +1,39 @@
+-- name: CreateBalanceSnapshots :execrows
+-- 为上一个快照之后有流水的账户生成 snapshot_at 时刻的快照，在上一个快照的基础上只累加之后的流水
+INSERT INTO balance_snapshots (account_id, snapshot_at, balance)
+SELECT a.id, sqlc.arg(snapshot_at)::timestamptz, COALESCE(prev.balance, 0) + SUM(e.amount)
+FROM account a
+LEFT JOIN LATERAL (
+    SELECT s.snapshot_at, s.balance FROM balance_snapshots s
+    WHERE s.account_id = a.id AND s.snapshot_at < sqlc.arg(snapshot_at)::timestamptz
+    ORDER BY s.snapshot_at DESC
+    LIMIT 1
+) prev ON true
+JOIN entries e ON e.account_id = a.id
+    AND e.created_at >= COALESCE(prev.snapshot_at, '-infinity'::timestamptz)
+    AND e.created_at < sqlc.arg(snapshot_at)::timestamptz
+GROUP BY a.id, prev.balance
+ON CONFLICT (account_id, snapshot_at) DO NOTHING;
+
+-- name: GetLatestBalanceSnapshot :one
+SELECT * FROM balance_snapshots
+WHERE account_id = $1 AND snapshot_at <= $2
+ORDER BY snapshot_at DESC
+LIMIT 1;
+
+-- name: SumAccountEntries :one
+-- 账户在 [start_time, end_time) 之间创建的流水的金额之和
+SELECT COALESCE(SUM(amount), 0)::bigint FROM entries
+WHERE account_id = sqlc.arg(account_id)
+  AND created_at >= sqlc.arg(start_time)
+  AND created_at < sqlc.arg(end_time);
+
+-- name: ListDailyEntryTotals :many
+-- 按 UTC 的自然日汇总账户在 [start_time, end_time) 之间的流水
+SELECT date_trunc('day', created_at AT TIME ZONE 'UTC')::timestamp AS day, SUM(amount)::bigint AS total
+FROM entries
+WHERE account_id = sqlc.arg(account_id)
+  AND created_at >= sqlc.arg(start_time)
+  AND created_at < sqlc.arg(end_time)
+GROUP BY day
+ORDER BY day;
@@ -1,9 +1,11 @@
 -- name: CreateEntry :one
 INSERT INTO entries (
     account_id,
-    amount
+    amount,
+    transfer_id,
+    journal_id
 ) VALUES (
-    $1, $2
+    $1, $2, $3, $4
 ) RETURNING *;
 
 -- name: GetEntry :one
@@ -11,8 +13,36 @@ SELECT * FROM entries
 WHERE id=$1 LIMIT 1;
 
 -- name: ListEntries :many
+-- 按 (created_at, id) 倒序的 keyset 分页，direction 为 in 时只返回入账，为 out 时只返回出账，金额的范围按绝对值计算
 SELECT * FROM entries
-WHERE account_id=$1
+WHERE account_id = sqlc.arg(account_id)
+  AND (sqlc.arg(direction)::varchar = ''
+    OR (sqlc.arg(direction)::varchar = 'in' AND amount > 0)
+    OR (sqlc.arg(direction)::varchar = 'out' AND amount < 0))
+  AND created_at >= sqlc.arg(start_time)
+  AND created_at < sqlc.arg(end_time)
+  AND ABS(amount) BETWEEN sqlc.arg(min_amount)::bigint AND sqlc.arg(max_amount)::bigint
+  AND (created_at, id) < (sqlc.arg(cursor_created_at)::timestamptz, sqlc.arg(cursor_id)::bigint)
+ORDER BY created_at DESC, id DESC
+LIMIT sqlc.arg(page_size);
+
+-- name: CreateChainedEntry :one
+INSERT INTO entries (
+    account_id,
+    amount,
+    transfer_id,
+    journal_id,
+    prev_hash,
+    hash,
+    created_at
+) VALUES (
+    $1, $2, $3, $4, $5, $6, $7
+) RETURNING *;
+
+-- name: ListAccountEntryChain :many
+-- 按 id 顺序遍历账户的流水，校验哈希链
+SELECT * FROM entries
+WHERE account_id = sqlc.arg(account_id)
+  AND id > sqlc.arg(after_id)
 ORDER BY id
-LIMIT $2
-OFFSET $3;
+LIMIT sqlc.arg(page_size);
@@ -0,0 +1,25 @@
+-- name: CreateExchangeRate :one
+INSERT INTO exchange_rates (
+    base_currency,
+    quote_currency,
+    rate,
+    created_by
+) VALUES (
+    $1, $2, $3, $4
+) RETURNING *;
+
+-- name: GetExchangeRate :one
+SELECT * FROM exchange_rates
+WHERE id = $1 LIMIT 1;
+
+-- name: GetLatestExchangeRate :one
+SELECT * FROM exchange_rates
+WHERE base_currency = $1 AND quote_currency = $2
+ORDER BY created_at DESC, id DESC
+LIMIT 1;
+
+-- name: ListExchangeRates :many
+SELECT * FROM exchange_rates
+WHERE (created_at, id) < (sqlc.arg(cursor_created_at)::timestamptz, sqlc.arg(cursor_id)::bigint)
+ORDER BY created_at DESC, id DESC
+LIMIT sqlc.arg(page_size);
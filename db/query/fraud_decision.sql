@@ -0,0 +1,26 @@
+-- name: CreateFraudDecision :one
+INSERT INTO fraud_decisions (
+    username,
+    from_account_id,
+    to_account_id,
+    amount,
+    currency,
+    decision,
+    rule,
+    reason,
+    metadata
+) VALUES (
+    $1, $2, $3, $4, $5, $6, $7, $8, $9
+) RETURNING *;
+
+-- name: SetFraudDecisionTransfer :one
+UPDATE fraud_decisions
+SET transfer_id = $2
+WHERE id = $1
+RETURNING *;
+
+-- name: ListFraudDecisions :many
+SELECT * FROM fraud_decisions
+WHERE (created_at, id) < (sqlc.arg(cursor_created_at)::timestamptz, sqlc.arg(cursor_id)::bigint)
+ORDER BY created_at DESC, id DESC
+LIMIT sqlc.arg(page_size);
@@ -0,0 +1,45 @@
+-- name: CreateHold :one
+INSERT INTO holds (
+    account_id,
+    to_account_id,
+    amount,
+    created_by,
+    expires_at
+) VALUES (
+    $1, $2, $3, $4, $5
+) RETURNING *;
+
+-- name: GetHold :one
+SELECT * FROM holds
+WHERE id = $1 LIMIT 1;
+
+-- name: GetHoldForUpdate :one
+SELECT * FROM holds
+WHERE id = $1 LIMIT 1
+FOR NO KEY UPDATE;
+
+-- name: ListHolds :many
+SELECT * FROM holds
+WHERE account_id = sqlc.arg(account_id)
+  AND (created_at, id) > (sqlc.arg(cursor_created_at)::timestamptz, sqlc.arg(cursor_id)::bigint)
+ORDER BY created_at, id
+LIMIT sqlc.arg(page_size);
+
+-- name: GetActiveHoldsAmount :one
+SELECT COALESCE(SUM(amount), 0)::bigint FROM holds
+WHERE account_id = $1 AND status = 'active' AND expires_at > now();
+
+-- name: UpdateHoldStatus :one
+UPDATE holds
+SET status = $2,
+    captured_amount = $3,
+    transfer_id = $4,
+    updated_at = now()
+WHERE id = $1
+RETURNING *;
+
+-- name: ExpireHolds :execrows
+UPDATE holds
+SET status = 'expired',
+    updated_at = now()
+WHERE status = 'active' AND expires_at <= $1;
@@ -0,0 +1,30 @@
+-- name: CreateIdempotencyKey :one
+-- 同一个用户的 key 已经存在且没有过期时不返回任何行，过期的 key 可以重新使用
+INSERT INTO idempotency_keys (
+    username,
+    idempotency_key,
+    request_hash,
+    expires_at
+) VALUES (
+    $1, $2, $3, $4
+) ON CONFLICT (username, idempotency_key) DO UPDATE
+SET request_hash = EXCLUDED.request_hash,
+    response = '{}',
+    created_at = now(),
+    expires_at = EXCLUDED.expires_at
+WHERE idempotency_keys.expires_at <= now()
+RETURNING *;
+
+-- name: GetIdempotencyKey :one
+SELECT * FROM idempotency_keys
+WHERE username = $1 AND idempotency_key = $2 LIMIT 1;
+
+-- name: UpdateIdempotencyKeyResponse :exec
+UPDATE idempotency_keys
+SET response = $3
+WHERE username = $1 AND idempotency_key = $2;
+
+-- name: DeleteExpiredIdempotencyKeys :execrows
+-- 过期的 key 已经可以重新使用，删除后不影响重放
+DELETE FROM idempotency_keys
+WHERE expires_at <= $1;
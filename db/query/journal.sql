@@ -0,0 +1,18 @@
+-- name: CreateJournal :one
+INSERT INTO journals (
+    kind,
+    memo,
+    transfer_id,
+    created_by
+) VALUES (
+    $1, $2, $3, $4
+) RETURNING *;
+
+-- name: GetJournal :one
+SELECT * FROM journals
+WHERE id = $1 LIMIT 1;
+
+-- name: ListJournalEntries :many
+SELECT * FROM entries
+WHERE journal_id = sqlc.arg(journal_id)::bigint
+ORDER BY id;
@@ -0,0 +1,89 @@
+-- name: ListAccountBalanceMismatches :many
+-- 账户余额应该等于该账户所有流水的金额之和
+SELECT a.id, a.balance, COALESCE(SUM(e.amount), 0)::bigint AS entries_total
+FROM account a
+LEFT JOIN entries e ON e.account_id = a.id
+GROUP BY a.id
+HAVING a.balance <> COALESCE(SUM(e.amount), 0)
+ORDER BY a.id;
+
+-- name: ListTransferEntryMismatches :many
+-- 已结算的转账应该恰好有一条转出账户的出账和一条转入账户的入账，跨币种的转账另外还有两条兑换账户的流水，
+-- 未结算的转账没有流水
+SELECT t.id, t.status, (t.exchange_rate_id IS NOT NULL)::boolean AS exchanged, COUNT(e.id) AS entry_count,
+    COALESCE(SUM(CASE WHEN e.account_id = t.from_account_id AND e.amount = -t.amount THEN 1 ELSE 0 END), 0)::bigint AS from_entries,
+    COALESCE(SUM(CASE WHEN e.account_id = t.to_account_id AND e.amount = t.to_amount THEN 1 ELSE 0 END), 0)::bigint AS to_entries
+FROM transfers t
+LEFT JOIN entries e ON e.transfer_id = t.id
+GROUP BY t.id
+HAVING CASE WHEN t.status = ANY(sqlc.arg(unsettled_statuses)::varchar[]) THEN COUNT(e.id) <> 0
+    ELSE COUNT(e.id) <> CASE WHEN t.exchange_rate_id IS NULL THEN 2 ELSE 4 END
+        OR COALESCE(SUM(CASE WHEN e.account_id = t.from_account_id AND e.amount = -t.amount THEN 1 ELSE 0 END), 0) <> 1
+        OR COALESCE(SUM(CASE WHEN e.account_id = t.to_account_id AND e.amount = t.to_amount THEN 1 ELSE 0 END), 0) <> 1
+    END
+ORDER BY t.id;
+
+-- name: ListUnbalancedJournals :many
+-- 每条分录在各个币种内的流水之和都应该为 0
+SELECT e.journal_id::bigint AS journal_id, a.currency, SUM(e.amount)::bigint AS total
+FROM entries e
+JOIN account a ON a.id = e.account_id
+WHERE e.journal_id IS NOT NULL
+GROUP BY e.journal_id, a.currency
+HAVING SUM(e.amount) <> 0
+ORDER BY e.journal_id, a.currency;
+
+-- name: ListCurrencyImbalances :many
+-- 包括系统账户在内，各个币种所有账户的余额之和应该为 0
+SELECT currency, SUM(balance)::bigint AS total
+FROM account
+GROUP BY currency
+HAVING SUM(balance) <> 0
+ORDER BY currency;
+
+-- name: CountAccounts :one
+SELECT COUNT(*) FROM account;
+
+-- name: CountTransfers :one
+SELECT COUNT(*) FROM transfers;
+
+-- name: CreateReconciliationReport :one
+INSERT INTO reconciliation_reports (
+    accounts_checked,
+    transfers_checked,
+    discrepancy_count,
+    started_at
+) VALUES (
+    $1, $2, $3, $4
+) RETURNING *;
+
+-- name: GetReconciliationReport :one
+SELECT * FROM reconciliation_reports
+WHERE id = $1 LIMIT 1;
+
+-- name: ListReconciliationReports :many
+SELECT * FROM reconciliation_reports
+WHERE (created_at, id) < (sqlc.arg(cursor_created_at)::timestamptz, sqlc.arg(cursor_id)::bigint)
+ORDER BY created_at DESC, id DESC
+LIMIT sqlc.arg(page_size);
+
+-- name: CreateReconciliationDiscrepancy :one
+INSERT INTO reconciliation_discrepancies (
+    report_id,
+    kind,
+    account_id,
+    transfer_id,
+    journal_id,
+    expected,
+    actual,
+    detail
+) VALUES (
+    $1, $2, $3, $4, $5, $6, $7, $8
+) RETURNING *;
+
+-- name: ListReconciliationDiscrepancies :many
+SELECT * FROM reconciliation_discrepancies
+WHERE report_id = sqlc.arg(report_id)
+  AND (created_at, id) > (sqlc.arg(cursor_created_at)::timestamptz, sqlc.arg(cursor_id)::bigint)
+ORDER BY created_at, id
+LIMIT sqlc.arg(page_size);
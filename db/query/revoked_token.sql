@@ -0,0 +1,19 @@
+-- name: CreateRevokedToken :exec
+INSERT INTO revoked_tokens (
+    id,
+    username,
+    expires_at
+) VALUES (
+    $1, $2, $3
+) ON CONFLICT (id) DO NOTHING;
+
+-- name: IsTokenRevoked :one
+SELECT EXISTS (
+    SELECT 1 FROM revoked_tokens
+    WHERE id = $1
+) AS revoked;
+
+-- name: DeleteExpiredRevokedTokens :execrows
+-- 过期的 token 已经无法通过验证，不再需要吊销记录
+DELETE FROM revoked_tokens
+WHERE expires_at <= $1;
@@ -0,0 +1,75 @@
+-- name: CreateScheduledTransfer :one
+INSERT INTO scheduled_transfers (
+    owner,
+    from_account_id,
+    to_account_id,
+    amount,
+    schedule,
+    scheduled_for,
+    next_run_at,
+    end_at
+) VALUES (
+    $1, $2, $3, $4, $5, $6, $7, $8
+) RETURNING *;
+
+-- name: GetScheduledTransfer :one
+SELECT * FROM scheduled_transfers
+WHERE id = $1 LIMIT 1;
+
+-- name: ListScheduledTransfers :many
+SELECT * FROM scheduled_transfers
+WHERE owner = sqlc.arg(owner)
+  AND (created_at, id) > (sqlc.arg(cursor_created_at)::timestamptz, sqlc.arg(cursor_id)::bigint)
+ORDER BY created_at, id
+LIMIT sqlc.arg(page_size);
+
+-- name: UpdateScheduledTransfer :one
+UPDATE scheduled_transfers
+SET amount = $2,
+    schedule = $3,
+    status = $4,
+    scheduled_for = $5,
+    next_run_at = $6,
+    end_at = $7,
+    retry_count = $8,
+    updated_at = now()
+WHERE id = $1
+RETURNING *;
+
+-- name: AdvanceScheduledTransfer :one
+-- 只修改执行进度，列出后被修改、取消或者被其他进程执行过的定时转账不会被更新
+UPDATE scheduled_transfers
+SET scheduled_for = sqlc.arg(scheduled_for),
+    next_run_at = sqlc.arg(next_run_at),
+    retry_count = sqlc.arg(retry_count),
+    status = CASE WHEN sqlc.arg(completed)::bool THEN 'completed' ELSE status END,
+    updated_at = now()
+WHERE id = sqlc.arg(id)
+  AND status = 'active'
+  AND updated_at = sqlc.arg(updated_at)
+RETURNING *;
+
+-- name: ListDueScheduledTransfers :many
+SELECT * FROM scheduled_transfers
+WHERE status = 'active' AND next_run_at <= $1
+ORDER BY next_run_at
+LIMIT $2;
+
+-- name: CreateScheduledTransferRun :one
+INSERT INTO scheduled_transfer_runs (
+    scheduled_transfer_id,
+    scheduled_for,
+    attempt,
+    status,
+    transfer_id,
+    error
+) VALUES (
+    $1, $2, $3, $4, $5, $6
+) RETURNING *;
+
+-- name: ListScheduledTransferRuns :many
+SELECT * FROM scheduled_transfer_runs
+WHERE scheduled_transfer_id = sqlc.arg(scheduled_transfer_id)
+  AND (created_at, id) < (sqlc.arg(cursor_created_at)::timestamptz, sqlc.arg(cursor_id)::bigint)
+ORDER BY created_at DESC, id DESC
+LIMIT sqlc.arg(page_size);
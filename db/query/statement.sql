@@ -0,0 +1,65 @@
+-- name: CreateStatement :one
+INSERT INTO statements (
+    account_id,
+    currency,
+    period_start,
+    period_end,
+    opening_balance,
+    closing_balance,
+    total_credits,
+    total_debits,
+    entry_count,
+    lines
+) VALUES (
+    $1, $2, $3, $4, $5, $6, $7, $8, $9, $10
+) RETURNING *;
+
+-- name: GetStatement :one
+SELECT * FROM statements
+WHERE id = $1 LIMIT 1;
+
+-- name: GetStatementByPeriod :one
+SELECT * FROM statements
+WHERE account_id = $1 AND period_start = $2 AND period_end = $3
+LIMIT 1;
+
+-- name: ListStatements :many
+-- 列表中不返回流水明细，按 (created_at, id) 倒序的 keyset 分页
+SELECT id, account_id, currency, period_start, period_end, opening_balance, closing_balance,
+       total_credits, total_debits, entry_count, created_at
+FROM statements
+WHERE account_id = sqlc.arg(account_id)
+  AND (created_at, id) < (sqlc.arg(cursor_created_at)::timestamptz, sqlc.arg(cursor_id)::bigint)
+ORDER BY created_at DESC, id DESC
+LIMIT sqlc.arg(page_size);
+
+-- name: ListStatementEntries :many
+-- 账户在 [start_time, end_time) 之间的流水，转账的对方账户从 transfers 中查询
+SELECT e.id, e.amount, e.created_at, COALESCE(e.transfer_id, 0)::bigint AS transfer_id,
+       COALESCE(j.kind, '')::varchar AS kind,
+       COALESCE(NULLIF(t.memo, ''), j.memo, '')::varchar AS memo,
+       COALESCE(c.id, 0)::bigint AS counterparty_account_id,
+       COALESCE(c.owner, '')::varchar AS counterparty_owner
+FROM entries e
+LEFT JOIN journals j ON j.id = e.journal_id
+LEFT JOIN transfers t ON t.id = e.transfer_id
+LEFT JOIN account c ON c.id = CASE WHEN t.from_account_id = e.account_id THEN t.to_account_id ELSE t.from_account_id END
+WHERE e.account_id = sqlc.arg(account_id)
+  AND e.created_at >= sqlc.arg(start_time)
+  AND e.created_at < sqlc.arg(end_time)
+ORDER BY e.created_at, e.id;
+
+-- name: ListStatementAccounts :many
+-- 需要生成对账单的客户账户：账户在期末之前已经开立，且还没有该期间的对账单，按 id 分页
+SELECT a.* FROM account a
+WHERE a.system_code = ''
+  AND a.created_at < sqlc.arg(period_end)::timestamptz
+  AND a.id > sqlc.arg(after_id)::bigint
+  AND NOT EXISTS (
+    SELECT 1 FROM statements s
+    WHERE s.account_id = a.id
+      AND s.period_start = sqlc.arg(period_start)::timestamptz
+      AND s.period_end = sqlc.arg(period_end)::timestamptz
+  )
+ORDER BY a.id
+LIMIT sqlc.arg(page_size);
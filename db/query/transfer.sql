@@ -2,21 +2,80 @@
 INSERT INTO transfers (
     from_account_id,
     to_account_id,
-    amount
+    amount,
+    to_amount,
+    exchange_rate,
+    exchange_rate_id,
+    reversal_of,
+    status,
+    requested_by,
+    memo,
+    external_reference,
+    metadata
 ) VALUES (
-    $1, $2, $3
+    $1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12
 ) RETURNING *;
 
 -- name: GetTransfers :one
 SELECT * FROM transfers
 WHERE id=$1 LIMIT 1;
 
+-- name: GetTransferForUpdate :one
+SELECT * FROM transfers
+WHERE id = $1 LIMIT 1
+FOR NO KEY UPDATE;
+
+-- name: UpdateTransferReversal :one
+UPDATE transfers
+SET reversed_amount = $2,
+    status = $3
+WHERE id = $1
+RETURNING *;
+
+-- name: UpdateTransferDecision :one
+UPDATE transfers
+SET status = $2,
+    approver = $3,
+    decided_at = $4
+WHERE id = $1
+RETURNING *;
+
+-- name: ListTransfersByReference :many
+SELECT transfers.* FROM transfers
+JOIN account ON account.id = transfers.from_account_id
+WHERE transfers.external_reference = sqlc.arg(external_reference)
+  AND account.owner = sqlc.arg(owner)
+ORDER BY transfers.id;
+
+-- name: GetTransferByReference :one
+SELECT * FROM transfers
+WHERE from_account_id = $1
+  AND external_reference = $2
+LIMIT 1;
+
 -- name: ListTransfers :many
+-- 账户转出和转入的转账，按 (created_at, id) 倒序的 keyset 分页，金额使用该账户的币种
 SELECT * FROM transfers
-WHERE
-    from_account_id = $1 OR
-    to_account_id = $2
-ORDER BY id
-LIMIT $3
-OFFSET $4;
+WHERE ((from_account_id = sqlc.arg(account_id) AND sqlc.arg(direction)::varchar <> 'in')
+    OR (to_account_id = sqlc.arg(account_id) AND sqlc.arg(direction)::varchar <> 'out'))
+  AND created_at >= sqlc.arg(start_time)
+  AND created_at < sqlc.arg(end_time)
+  AND (CASE WHEN from_account_id = sqlc.arg(account_id) THEN amount ELSE to_amount END)
+    BETWEEN sqlc.arg(min_amount)::bigint AND sqlc.arg(max_amount)::bigint
+  AND (created_at, id) < (sqlc.arg(cursor_created_at)::timestamptz, sqlc.arg(cursor_id)::bigint)
+ORDER BY created_at DESC, id DESC
+LIMIT sqlc.arg(page_size);
+
+-- name: CountPayeeTransfers :one
+SELECT COUNT(*) FROM transfers
+WHERE from_account_id = $1
+  AND to_account_id = $2
+  AND reversal_of IS NULL
+  AND status <> 'rejected';
+
+-- name: CountTransfersSince :one
+SELECT COUNT(*) FROM transfers
+WHERE from_account_id = sqlc.arg(from_account_id)
+  AND created_at > sqlc.arg(since)
+  AND reversal_of IS NULL;
 
@@ -0,0 +1,71 @@
+-- name: UpsertTransferLimit :one
+INSERT INTO transfer_limits (
+    owner,
+    account_id,
+    currency,
+    period,
+    max_count,
+    max_amount
+) VALUES (
+    $1, $2, $3, $4, $5, $6
+)
+ON CONFLICT ((COALESCE(owner, '')), (COALESCE(account_id, 0)), currency, period) DO UPDATE
+SET max_count = EXCLUDED.max_count,
+    max_amount = EXCLUDED.max_amount,
+    updated_at = now()
+RETURNING *;
+
+-- name: GetTransferLimit :one
+SELECT * FROM transfer_limits
+WHERE id = $1 LIMIT 1;
+
+-- name: ListTransferLimits :many
+SELECT * FROM transfer_limits
+WHERE (created_at, id) > (sqlc.arg(cursor_created_at)::timestamptz, sqlc.arg(cursor_id)::bigint)
+ORDER BY created_at, id
+LIMIT sqlc.arg(page_size);
+
+-- name: DeleteTransferLimit :exec
+DELETE FROM transfer_limits
+WHERE id = $1;
+
+-- name: ListApplicableTransferLimits :many
+SELECT * FROM transfer_limits
+WHERE account_id = sqlc.arg(account_id)
+   OR (owner = sqlc.arg(owner) AND currency = sqlc.arg(currency))
+   OR (owner IS NULL AND account_id IS NULL AND currency = sqlc.arg(currency))
+ORDER BY id;
+
+-- name: GetAccountTransferUsage :one
+-- 未释放的预授权也占用转账限额，扣款后由对应的转账计入
+SELECT COUNT(*) AS count, COALESCE(SUM(amount), 0)::bigint AS amount
+FROM (
+    SELECT t.amount FROM transfers t
+    WHERE t.from_account_id = sqlc.arg(account_id)
+      AND t.created_at > sqlc.arg(since)
+      AND t.reversal_of IS NULL
+      AND t.status <> 'rejected'
+    UNION ALL
+    SELECT h.amount FROM holds h
+    WHERE h.account_id = sqlc.arg(account_id)
+      AND h.created_at > sqlc.arg(since)
+      AND h.status = 'active'
+      AND h.expires_at > now()
+) usage;
+
+-- name: GetUserTransferUsage :one
+SELECT COUNT(*) AS count, COALESCE(SUM(usage.amount), 0)::bigint AS amount
+FROM (
+    SELECT t.from_account_id AS account_id, t.amount FROM transfers t
+    WHERE t.created_at > sqlc.arg(since)
+      AND t.reversal_of IS NULL
+      AND t.status <> 'rejected'
+    UNION ALL
+    SELECT h.account_id, h.amount FROM holds h
+    WHERE h.created_at > sqlc.arg(since)
+      AND h.status = 'active'
+      AND h.expires_at > now()
+) usage
+JOIN account a ON a.id = usage.account_id
+WHERE a.owner = sqlc.arg(owner)
+  AND a.currency = sqlc.arg(currency);
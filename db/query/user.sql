@@ -3,14 +3,33 @@ INSERT INTO users (
     username,
     hashed_password,
     full_name,
-    email
+    email,
+    role
 ) VALUES (
-    $1, $2, $3, $4
+    $1, $2, $3, $4, $5
 ) RETURNING *;
 
 -- name: GetUser :one
 SELECT * FROM users
 WHERE username = $1 LIMIT 1;
 
+-- name: RevokeUserTokens :one
+UPDATE users
+SET tokens_revoked_at = sqlc.arg(revoked_at)
+WHERE username = sqlc.arg(username)
+RETURNING *;
 
+-- name: UpdateUserRole :one
+UPDATE users
+SET role = sqlc.arg(role)
+WHERE username = sqlc.arg(username)
+RETURNING *;
 
+-- name: GetUserForUpdate :one
+SELECT * FROM users
+WHERE username = $1 LIMIT 1
+FOR NO KEY UPDATE;
+
+-- name: GetUserByEmail :one
+SELECT * FROM users
+WHERE email = $1 LIMIT 1;
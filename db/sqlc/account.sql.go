@@ -5,13 +5,44 @@ package db
 
 import (
 	"context"
+	"time"
 )
 
+const addAccountLedgerBalance = `-- name: AddAccountLedgerBalance :one
+UPDATE account
+SET balance = balance + $1,
+    entry_hash = $2
+WHERE id = $3
+RETURNING id, owner, balance, currency, created_at, system_code, entry_hash
+`
+
+type AddAccountLedgerBalanceParams struct {
+	Amount    int64  `json:"amount"`
+	EntryHash string `json:"entry_hash"`
+	ID        int64  `json:"id"`
+}
+
+// 记账时同时更新余额和最后一条流水的哈希
+func (q *Queries) AddAccountLedgerBalance(ctx context.Context, arg AddAccountLedgerBalanceParams) (Account, error) {
+	row := q.db.QueryRowContext(ctx, addAccountLedgerBalance, arg.Amount, arg.EntryHash, arg.ID)
+	var i Account
+	err := row.Scan(
+		&i.ID,
+		&i.Owner,
+		&i.Balance,
+		&i.Currency,
+		&i.CreatedAt,
+		&i.SystemCode,
+		&i.EntryHash,
+	)
+	return i, err
+}
+
 const addaAccountBalance = `-- name: AddaAccountBalance :one
 UPDATE account
 SET balance=balance + $1
 WHERE id=$2
-RETURNING id, owner, balance, currency, created_at
+RETURNING id, owner, balance, currency, created_at, system_code, entry_hash
 `
 
 type AddaAccountBalanceParams struct {
@@ -28,6 +59,8 @@ func (q *Queries) AddaAccountBalance(ctx context.Context, arg AddaAccountBalance
 		&i.Balance,
 		&i.Currency,
 		&i.CreatedAt,
+		&i.SystemCode,
+		&i.EntryHash,
 	)
 	return i, err
 }
@@ -39,7 +72,7 @@ INSERT INTO account (
     currency
 ) VALUES (
     $1, $2, $3
-) RETURNING id, owner, balance, currency, created_at
+) RETURNING id, owner, balance, currency, created_at, system_code, entry_hash
 `
 
 type CreateAccountParams struct {
@@ -57,10 +90,37 @@ func (q *Queries) CreateAccount(ctx context.Context, arg CreateAccountParams) (A
 		&i.Balance,
 		&i.Currency,
 		&i.CreatedAt,
+		&i.SystemCode,
+		&i.EntryHash,
 	)
 	return i, err
 }
 
+const createSystemAccount = `-- name: CreateSystemAccount :execrows
+INSERT INTO account (
+    owner,
+    balance,
+    currency,
+    system_code
+) VALUES (
+    'system', 0, $1, $2
+) ON CONFLICT DO NOTHING
+`
+
+type CreateSystemAccountParams struct {
+	Currency   string `json:"currency"`
+	SystemCode string `json:"system_code"`
+}
+
+// 已经存在的系统账户不会重复创建
+func (q *Queries) CreateSystemAccount(ctx context.Context, arg CreateSystemAccountParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, createSystemAccount, arg.Currency, arg.SystemCode)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
 const deleteAccount = `-- name: DeleteAccount :exec
 DELETE FROM account
 WHERE id=$1
@@ -72,7 +132,7 @@ func (q *Queries) DeleteAccount(ctx context.Context, id int64) error {
 }
 
 const getAccount = `-- name: GetAccount :one
-SELECT id, owner, balance, currency, created_at FROM account
+SELECT id, owner, balance, currency, created_at, system_code, entry_hash FROM account
 WHERE id = $1 LIMIT 1
 `
 
@@ -85,12 +145,39 @@ func (q *Queries) GetAccount(ctx context.Context, id int64) (Account, error) {
 		&i.Balance,
 		&i.Currency,
 		&i.CreatedAt,
+		&i.SystemCode,
+		&i.EntryHash,
+	)
+	return i, err
+}
+
+const getAccountByOwner = `-- name: GetAccountByOwner :one
+SELECT id, owner, balance, currency, created_at, system_code, entry_hash FROM account
+WHERE owner = $1 AND currency = $2 AND system_code = '' LIMIT 1
+`
+
+type GetAccountByOwnerParams struct {
+	Owner    string `json:"owner"`
+	Currency string `json:"currency"`
+}
+
+func (q *Queries) GetAccountByOwner(ctx context.Context, arg GetAccountByOwnerParams) (Account, error) {
+	row := q.db.QueryRowContext(ctx, getAccountByOwner, arg.Owner, arg.Currency)
+	var i Account
+	err := row.Scan(
+		&i.ID,
+		&i.Owner,
+		&i.Balance,
+		&i.Currency,
+		&i.CreatedAt,
+		&i.SystemCode,
+		&i.EntryHash,
 	)
 	return i, err
 }
 
 const getAccountForUpdate = `-- name: GetAccountForUpdate :one
-SELECT id, owner, balance, currency, created_at FROM account
+SELECT id, owner, balance, currency, created_at, system_code, entry_hash FROM account
 WHERE id = $1 LIMIT 1
 FOR NO KEY UPDATE
 `
@@ -104,26 +191,102 @@ func (q *Queries) GetAccountForUpdate(ctx context.Context, id int64) (Account, e
 		&i.Balance,
 		&i.Currency,
 		&i.CreatedAt,
+		&i.SystemCode,
+		&i.EntryHash,
+	)
+	return i, err
+}
+
+const getSystemAccount = `-- name: GetSystemAccount :one
+SELECT id, owner, balance, currency, created_at, system_code, entry_hash FROM account
+WHERE system_code = $1 AND currency = $2 LIMIT 1
+`
+
+type GetSystemAccountParams struct {
+	SystemCode string `json:"system_code"`
+	Currency   string `json:"currency"`
+}
+
+func (q *Queries) GetSystemAccount(ctx context.Context, arg GetSystemAccountParams) (Account, error) {
+	row := q.db.QueryRowContext(ctx, getSystemAccount, arg.SystemCode, arg.Currency)
+	var i Account
+	err := row.Scan(
+		&i.ID,
+		&i.Owner,
+		&i.Balance,
+		&i.Currency,
+		&i.CreatedAt,
+		&i.SystemCode,
+		&i.EntryHash,
 	)
 	return i, err
 }
 
 const listAccounts = `-- name: ListAccounts :many
-SELECT id, owner, balance, currency, created_at FROM account
-where owner = $1
-ORDER BY id
-LIMIT $2
-OFFSET $3
+SELECT id, owner, balance, currency, created_at, system_code, entry_hash FROM account
+WHERE owner = $1
+  AND (created_at, id) > ($2::timestamptz, $3::bigint)
+ORDER BY created_at, id
+LIMIT $4
 `
 
 type ListAccountsParams struct {
-	Owner  string `json:"owner"`
-	Limit  int32  `json:"limit"`
-	Offset int32  `json:"offset"`
+	Owner           string    `json:"owner"`
+	CursorCreatedAt time.Time `json:"cursor_created_at"`
+	CursorID        int64     `json:"cursor_id"`
+	PageSize        int32     `json:"page_size"`
 }
 
 func (q *Queries) ListAccounts(ctx context.Context, arg ListAccountsParams) ([]Account, error) {
-	rows, err := q.db.QueryContext(ctx, listAccounts, arg.Owner, arg.Limit, arg.Offset)
+	rows, err := q.db.QueryContext(ctx, listAccounts,
+		arg.Owner,
+		arg.CursorCreatedAt,
+		arg.CursorID,
+		arg.PageSize,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []Account{}
+	for rows.Next() {
+		var i Account
+		if err := rows.Scan(
+			&i.ID,
+			&i.Owner,
+			&i.Balance,
+			&i.Currency,
+			&i.CreatedAt,
+			&i.SystemCode,
+			&i.EntryHash,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listAccountsAfter = `-- name: ListAccountsAfter :many
+SELECT id, owner, balance, currency, created_at, system_code, entry_hash FROM account
+WHERE id > $1
+ORDER BY id
+LIMIT $2
+`
+
+type ListAccountsAfterParams struct {
+	AfterID  int64 `json:"after_id"`
+	PageSize int32 `json:"page_size"`
+}
+
+func (q *Queries) ListAccountsAfter(ctx context.Context, arg ListAccountsAfterParams) ([]Account, error) {
+	rows, err := q.db.QueryContext(ctx, listAccountsAfter, arg.AfterID, arg.PageSize)
 	if err != nil {
 		return nil, err
 	}
@@ -137,6 +300,8 @@ func (q *Queries) ListAccounts(ctx context.Context, arg ListAccountsParams) ([]A
 			&i.Balance,
 			&i.Currency,
 			&i.CreatedAt,
+			&i.SystemCode,
+			&i.EntryHash,
 		); err != nil {
 			return nil, err
 		}
@@ -155,7 +320,7 @@ const updateAccount = `-- name: UpdateAccount :one
 UPDATE account
 SET balance=$2
 WHERE id=$1
-RETURNING id, owner, balance, currency, created_at
+RETURNING id, owner, balance, currency, created_at, system_code, entry_hash
 `
 
 type UpdateAccountParams struct {
@@ -172,6 +337,8 @@ func (q *Queries) UpdateAccount(ctx context.Context, arg UpdateAccountParams) (A
 		&i.Balance,
 		&i.Currency,
 		&i.CreatedAt,
+		&i.SystemCode,
+		&i.EntryHash,
 	)
 	return i, err
 }
@@ -47,6 +47,27 @@ func TestGetAccount(t *testing.T) {
 	require.WithinDuration(t, account2.CreatedAt, account1.CreatedAt, time.Second)
 }
 
+func TestGetAccountByOwner(t *testing.T) {
+	account1 := createRandomAccount(t)
+	account2, err := testQueries.GetAccountByOwner(context.Background(), GetAccountByOwnerParams{
+		Owner: account1.Owner,
+		Currency: account1.Currency,
+	})
+	require.NoError(t, err)
+	require.Equal(t, account1.ID, account2.ID)
+
+	// 用户没有该币种的账户
+	currency := util.EUR
+	if account1.Currency == util.EUR {
+		currency = util.USD
+	}
+	_, err = testQueries.GetAccountByOwner(context.Background(), GetAccountByOwnerParams{
+		Owner: account1.Owner,
+		Currency: currency,
+	})
+	require.ErrorIs(t, err, sql.ErrNoRows)
+}
+
 func TestUpdateAccount(t *testing.T) {
 	account1 := createRandomAccount(t)
 
@@ -77,20 +98,30 @@ func TestDeleteAccount(t *testing.T) {
 }
 
 func TestListAccounts(t *testing.T) {
+	var lastAccount Account
 	for i:=0; i<10; i++ {
-		_ = createRandomAccount(t)
+		lastAccount = createRandomAccount(t)
 	}
 	arg := ListAccountsParams{
-		Limit: 5,
-		Offset: 5,
+		Owner: lastAccount.Owner,
+		PageSize: 5,
 	}
 
 	accounts, err := testQueries.ListAccounts(context.Background(), arg)
 	require.NoError(t, err)
-	require.Equal(t, len(accounts), 5)
+	require.NotEmpty(t, accounts)
 
 	for _, account := range accounts {
 		require.NotEmpty(t, account)
+		require.Equal(t, lastAccount.Owner, account.Owner)
 	}
+
+	// 从最后一条记录之后开始没有更多的账户
+	last := accounts[len(accounts)-1]
+	arg.CursorCreatedAt = last.CreatedAt
+	arg.CursorID = last.ID
+	accounts, err = testQueries.ListAccounts(context.Background(), arg)
+	require.NoError(t, err)
+	require.Empty(t, accounts)
 }
 
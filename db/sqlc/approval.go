@@ -0,0 +1,86 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"github.com/techschool/simplebank/util"
+	"time"
+)
+
+var (
+	// ErrInvalidTransferStatus is returned when a transfer is not in the status required by the operation.
+	ErrInvalidTransferStatus = errors.New("invalid transfer status")
+	// ErrSelfApproval is returned when the user who requested a transfer tries to approve or reject it.
+	ErrSelfApproval = errors.New("transfer cannot be decided by its requester")
+)
+
+// DecideTransferTxParams contains the input parameters of approve and reject transfer translations.
+type DecideTransferTxParams struct {
+	TransferID	int64	`json:"transfer_id"`
+	Approver	string	`json:"approver"`
+}
+
+// ApproveTransferTx approves a pending transfer and settles it within a single translation.
+// The approver must be a different user from the one who requested the transfer.
+func (store *SQLStore) ApproveTransferTx(ctx context.Context, arg DecideTransferTxParams) (TransferTxResult, error) {
+	var result TransferTxResult
+	err := store.execTx(ctx, func(q *Queries) error {
+		transfer, err := lockPendingTransfer(ctx, q, arg)
+		if err != nil {
+			return err
+		}
+
+		result, err = store.settleTransfer(ctx, q, transfer)
+		if err != nil {
+			return err
+		}
+
+		result.Transfer, err = q.UpdateTransferDecision(ctx, UpdateTransferDecisionParams{
+			ID:			transfer.ID,
+			Status:		util.TransferCompleted,
+			Approver:	sql.NullString{String: arg.Approver, Valid: true},
+			DecidedAt:	sql.NullTime{Time: time.Now(), Valid: true},
+		})
+		return err
+	})
+	return result, err
+}
+
+// RejectTransferTx rejects a pending transfer, no money is moved.
+func (store *SQLStore) RejectTransferTx(ctx context.Context, arg DecideTransferTxParams) (Transfer, error) {
+	var result Transfer
+	err := store.execTx(ctx, func(q *Queries) error {
+		transfer, err := lockPendingTransfer(ctx, q, arg)
+		if err != nil {
+			return err
+		}
+
+		result, err = q.UpdateTransferDecision(ctx, UpdateTransferDecisionParams{
+			ID:			transfer.ID,
+			Status:		util.TransferRejected,
+			Approver:	sql.NullString{String: arg.Approver, Valid: true},
+			DecidedAt:	sql.NullTime{Time: time.Now(), Valid: true},
+		})
+		return err
+	})
+	return result, err
+}
+
+// lockPendingTransfer 锁定待审批的转账，并发的审批只有一个能成功
+func lockPendingTransfer(ctx context.Context, q *Queries, arg DecideTransferTxParams) (Transfer, error) {
+	transfer, err := q.GetTransferForUpdate(ctx, arg.TransferID)
+	if err != nil {
+		return transfer, err
+	}
+
+	if transfer.Status != util.TransferPending {
+		return transfer, fmt.Errorf("%w: transfer [%d] is %s", ErrInvalidTransferStatus, transfer.ID, transfer.Status)
+	}
+	// maker-checker: 发起人不能审批自己的转账
+	if transfer.RequestedBy.Valid && transfer.RequestedBy.String == arg.Approver {
+		return transfer, fmt.Errorf("%w: transfer [%d] is requested by %s", ErrSelfApproval, transfer.ID, arg.Approver)
+	}
+	return transfer, nil
+}
@@ -0,0 +1,128 @@
+package db
+
+import (
+	"context"
+	"github.com/stretchr/testify/require"
+	"github.com/techschool/simplebank/util"
+	"testing"
+)
+
+func createPendingTransfer(t *testing.T, store Store, account1, account2 Account, amount int64) Transfer {
+	result, err := store.TransferTx(context.Background(), TransferTxParams{
+		FromAccountID: account1.ID,
+		ToAccountID: account2.ID,
+		Amount: amount,
+		RequestedBy: account1.Owner,
+		RequireApproval: true,
+	})
+	require.NoError(t, err)
+	require.Equal(t, util.TransferPending, result.Transfer.Status)
+	require.Equal(t, account1.Owner, result.Transfer.RequestedBy.String)
+	require.False(t, result.Transfer.Approver.Valid)
+	require.Zero(t, result.FromEntry.ID)
+	require.Zero(t, result.ToEntry.ID)
+	return result.Transfer
+}
+
+func TestApproveTransferTx(t *testing.T) {
+	store := NewStore(testDB)
+
+	currency := util.RandomCurrency()
+	account1 := createAccountWithCurrency(t, currency)
+	account2 := createAccountWithCurrency(t, currency)
+	approver := createRandomUser(t)
+
+	transfer := createPendingTransfer(t, store, account1, account2, 10)
+
+	// 待审批的转账不会改变余额，也不能撤销
+	pendingAccount, err := store.GetAccount(context.Background(), account1.ID)
+	require.NoError(t, err)
+	require.Equal(t, account1.Balance, pendingAccount.Balance)
+
+	_, err = store.ReverseTransferTx(context.Background(), ReverseTransferTxParams{TransferID: transfer.ID, Amount: 10})
+	require.ErrorIs(t, err, ErrInvalidReversal)
+
+	// 发起人不能审批自己的转账
+	_, err = store.ApproveTransferTx(context.Background(), DecideTransferTxParams{
+		TransferID: transfer.ID,
+		Approver: account1.Owner,
+	})
+	require.ErrorIs(t, err, ErrSelfApproval)
+
+	result, err := store.ApproveTransferTx(context.Background(), DecideTransferTxParams{
+		TransferID: transfer.ID,
+		Approver: approver.Username,
+	})
+	require.NoError(t, err)
+	require.Equal(t, util.TransferCompleted, result.Transfer.Status)
+	require.Equal(t, approver.Username, result.Transfer.Approver.String)
+	require.True(t, result.Transfer.DecidedAt.Valid)
+	require.Equal(t, int64(-10), result.FromEntry.Amount)
+	require.Equal(t, int64(10), result.ToEntry.Amount)
+	require.Equal(t, account1.Balance-10, result.FromAccount.Balance)
+	require.Equal(t, account2.Balance+10, result.ToAccount.Balance)
+
+	// 已经审批的转账不能再次审批或者拒绝
+	_, err = store.ApproveTransferTx(context.Background(), DecideTransferTxParams{
+		TransferID: transfer.ID,
+		Approver: approver.Username,
+	})
+	require.ErrorIs(t, err, ErrInvalidTransferStatus)
+
+	_, err = store.RejectTransferTx(context.Background(), DecideTransferTxParams{
+		TransferID: transfer.ID,
+		Approver: approver.Username,
+	})
+	require.ErrorIs(t, err, ErrInvalidTransferStatus)
+}
+
+func TestRejectTransferTx(t *testing.T) {
+	store := NewStore(testDB)
+
+	currency := util.RandomCurrency()
+	account1 := createAccountWithCurrency(t, currency)
+	account2 := createAccountWithCurrency(t, currency)
+	approver := createRandomUser(t)
+
+	transfer := createPendingTransfer(t, store, account1, account2, 10)
+
+	rejected, err := store.RejectTransferTx(context.Background(), DecideTransferTxParams{
+		TransferID: transfer.ID,
+		Approver: approver.Username,
+	})
+	require.NoError(t, err)
+	require.Equal(t, util.TransferRejected, rejected.Status)
+	require.Equal(t, approver.Username, rejected.Approver.String)
+
+	account, err := store.GetAccount(context.Background(), account1.ID)
+	require.NoError(t, err)
+	require.Equal(t, account1.Balance, account.Balance)
+
+	_, err = store.ApproveTransferTx(context.Background(), DecideTransferTxParams{
+		TransferID: transfer.ID,
+		Approver: approver.Username,
+	})
+	require.ErrorIs(t, err, ErrInvalidTransferStatus)
+}
+
+func TestApproveTransferTxInsufficientFunds(t *testing.T) {
+	store := NewStore(testDB)
+
+	currency := util.RandomCurrency()
+	account1 := setAccountBalance(t, createAccountWithCurrency(t, currency), 10)
+	account2 := createAccountWithCurrency(t, currency)
+	approver := createRandomUser(t)
+
+	// 创建时不检查余额，审批时余额不足则审批失败，转账仍然是待审批状态
+	transfer := createPendingTransfer(t, store, account1, account2, 20)
+
+	_, err := store.ApproveTransferTx(context.Background(), DecideTransferTxParams{
+		TransferID: transfer.ID,
+		Approver: approver.Username,
+	})
+	require.ErrorIs(t, err, ErrInsufficientFunds)
+
+	transfer, err = store.GetTransfers(context.Background(), transfer.ID)
+	require.NoError(t, err)
+	require.Equal(t, util.TransferPending, transfer.Status)
+}
@@ -0,0 +1,91 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"time"
+)
+
+// GetBalanceAtParams contains the input parameters of GetBalanceAt.
+type GetBalanceAtParams struct {
+	AccountID	int64		`json:"account_id"`
+	At			time.Time	`json:"at"`
+}
+
+// GetBalanceAt returns the balance of the account at a point in time, including the entries created at that time.
+// It starts from the latest balance snapshot and only sums the entries created after it.
+func (store *SQLStore) GetBalanceAt(ctx context.Context, arg GetBalanceAtParams) (int64, error) {
+	// 数据库中的时间精确到微秒，created_at <= at 等价于 created_at < at + 1µs
+	return balanceBefore(ctx, store.Queries, arg.AccountID, arg.At.Add(time.Microsecond))
+}
+
+// ListDailyBalancesParams contains the input parameters of ListDailyBalances, From and To are UTC dates.
+type ListDailyBalancesParams struct {
+	AccountID	int64		`json:"account_id"`
+	From		time.Time	`json:"from"`
+	To			time.Time	`json:"to"`
+}
+
+// DailyBalance is the balance of an account at the end of a UTC day.
+type DailyBalance struct {
+	Date	time.Time	`json:"date"`
+	Balance	int64		`json:"balance"`
+}
+
+// ListDailyBalances returns the balance at the end of every day between From and To, both inclusive.
+func (store *SQLStore) ListDailyBalances(ctx context.Context, arg ListDailyBalancesParams) ([]DailyBalance, error) {
+	from := truncateDay(arg.From)
+	end := truncateDay(arg.To).AddDate(0, 0, 1)
+
+	balance, err := balanceBefore(ctx, store.Queries, arg.AccountID, from)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := store.ListDailyEntryTotals(ctx, ListDailyEntryTotalsParams{
+		AccountID:	arg.AccountID,
+		StartTime:	from,
+		EndTime:	end,
+	})
+	if err != nil {
+		return nil, err
+	}
+	totals := make(map[time.Time]int64, len(rows))
+	for _, row := range rows {
+		totals[truncateDay(row.Day)] = row.Total
+	}
+
+	var balances []DailyBalance
+	for day := from; day.Before(end); day = day.AddDate(0, 0, 1) {
+		balance += totals[day]
+		balances = append(balances, DailyBalance{Date: day, Balance: balance})
+	}
+	return balances, nil
+}
+
+// balanceBefore 账户在 end 之前创建的所有流水之和，从 end 之前最近的快照开始累加
+func balanceBefore(ctx context.Context, q *Queries, accountID int64, end time.Time) (int64, error) {
+	snapshot, err := q.GetLatestBalanceSnapshot(ctx, GetLatestBalanceSnapshotParams{
+		AccountID:	accountID,
+		SnapshotAt:	end,
+	})
+	if err != nil && err != sql.ErrNoRows {
+		return 0, err
+	}
+
+	sum, err := q.SumAccountEntries(ctx, SumAccountEntriesParams{
+		AccountID:	accountID,
+		StartTime:	snapshot.SnapshotAt,
+		EndTime:	end,
+	})
+	if err != nil {
+		return 0, err
+	}
+	return snapshot.Balance + sum, nil
+}
+
+// truncateDay 返回 UTC 自然日的零点
+func truncateDay(t time.Time) time.Time {
+	year, month, day := t.UTC().Date()
+	return time.Date(year, month, day, 0, 0, 0, 0, time.UTC)
+}
@@ -0,0 +1,125 @@
+// Code generated by sqlc. DO NOT EDIT.
+// source: balance_snapshot.sql
+
+package db
+
+import (
+	"context"
+	"time"
+)
+
+const createBalanceSnapshots = `-- name: CreateBalanceSnapshots :execrows
+INSERT INTO balance_snapshots (account_id, snapshot_at, balance)
+SELECT a.id, $1::timestamptz, COALESCE(prev.balance, 0) + SUM(e.amount)
+FROM account a
+LEFT JOIN LATERAL (
+    SELECT s.snapshot_at, s.balance FROM balance_snapshots s
+    WHERE s.account_id = a.id AND s.snapshot_at < $1::timestamptz
+    ORDER BY s.snapshot_at DESC
+    LIMIT 1
+) prev ON true
+JOIN entries e ON e.account_id = a.id
+    AND e.created_at >= COALESCE(prev.snapshot_at, '-infinity'::timestamptz)
+    AND e.created_at < $1::timestamptz
+GROUP BY a.id, prev.balance
+ON CONFLICT (account_id, snapshot_at) DO NOTHING
+`
+
+// 为上一个快照之后有流水的账户生成 snapshot_at 时刻的快照，在上一个快照的基础上只累加之后的流水
+func (q *Queries) CreateBalanceSnapshots(ctx context.Context, snapshotAt time.Time) (int64, error) {
+	result, err := q.db.ExecContext(ctx, createBalanceSnapshots, snapshotAt)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const getLatestBalanceSnapshot = `-- name: GetLatestBalanceSnapshot :one
+SELECT account_id, snapshot_at, balance, created_at FROM balance_snapshots
+WHERE account_id = $1 AND snapshot_at <= $2
+ORDER BY snapshot_at DESC
+LIMIT 1
+`
+
+type GetLatestBalanceSnapshotParams struct {
+	AccountID  int64     `json:"account_id"`
+	SnapshotAt time.Time `json:"snapshot_at"`
+}
+
+func (q *Queries) GetLatestBalanceSnapshot(ctx context.Context, arg GetLatestBalanceSnapshotParams) (BalanceSnapshot, error) {
+	row := q.db.QueryRowContext(ctx, getLatestBalanceSnapshot, arg.AccountID, arg.SnapshotAt)
+	var i BalanceSnapshot
+	err := row.Scan(
+		&i.AccountID,
+		&i.SnapshotAt,
+		&i.Balance,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
+const listDailyEntryTotals = `-- name: ListDailyEntryTotals :many
+SELECT date_trunc('day', created_at AT TIME ZONE 'UTC')::timestamp AS day, SUM(amount)::bigint AS total
+FROM entries
+WHERE account_id = $1
+  AND created_at >= $2
+  AND created_at < $3
+GROUP BY day
+ORDER BY day
+`
+
+type ListDailyEntryTotalsParams struct {
+	AccountID int64     `json:"account_id"`
+	StartTime time.Time `json:"start_time"`
+	EndTime   time.Time `json:"end_time"`
+}
+
+type ListDailyEntryTotalsRow struct {
+	Day   time.Time `json:"day"`
+	Total int64     `json:"total"`
+}
+
+// 按 UTC 的自然日汇总账户在 [start_time, end_time) 之间的流水
+func (q *Queries) ListDailyEntryTotals(ctx context.Context, arg ListDailyEntryTotalsParams) ([]ListDailyEntryTotalsRow, error) {
+	rows, err := q.db.QueryContext(ctx, listDailyEntryTotals, arg.AccountID, arg.StartTime, arg.EndTime)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []ListDailyEntryTotalsRow{}
+	for rows.Next() {
+		var i ListDailyEntryTotalsRow
+		if err := rows.Scan(&i.Day, &i.Total); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const sumAccountEntries = `-- name: SumAccountEntries :one
+SELECT COALESCE(SUM(amount), 0)::bigint FROM entries
+WHERE account_id = $1
+  AND created_at >= $2
+  AND created_at < $3
+`
+
+type SumAccountEntriesParams struct {
+	AccountID int64     `json:"account_id"`
+	StartTime time.Time `json:"start_time"`
+	EndTime   time.Time `json:"end_time"`
+}
+
+// 账户在 [start_time, end_time) 之间创建的流水的金额之和
+func (q *Queries) SumAccountEntries(ctx context.Context, arg SumAccountEntriesParams) (int64, error) {
+	row := q.db.QueryRowContext(ctx, sumAccountEntries, arg.AccountID, arg.StartTime, arg.EndTime)
+	var column_1 int64
+	err := row.Scan(&column_1)
+	return column_1, err
+}
@@ -0,0 +1,69 @@
+package db
+
+import (
+	"context"
+	"github.com/stretchr/testify/require"
+	"github.com/techschool/simplebank/util"
+	"testing"
+	"time"
+)
+
+func depositAmount(t *testing.T, store Store, account Account, amount int64) Entry {
+	result, err := store.DepositTx(context.Background(), CashTxParams{AccountID: account.ID, Amount: amount})
+	require.NoError(t, err)
+	return result.Entry
+}
+
+func TestGetBalanceAt(t *testing.T) {
+	store := NewStore(testDB)
+	account := setAccountBalance(t, createAccountWithCurrency(t, util.USD), 0)
+
+	first := depositAmount(t, store, account, 100)
+	second := depositAmount(t, store, account, 50)
+
+	balanceAt := func(at time.Time) int64 {
+		balance, err := store.GetBalanceAt(context.Background(), GetBalanceAtParams{AccountID: account.ID, At: at})
+		require.NoError(t, err)
+		return balance
+	}
+	require.Zero(t, balanceAt(first.CreatedAt.Add(-time.Microsecond)))
+	// 包含查询时刻创建的流水
+	require.Equal(t, int64(100), balanceAt(first.CreatedAt))
+	require.Equal(t, int64(150), balanceAt(second.CreatedAt))
+
+	// 快照之后的余额从快照开始累加，结果不变
+	snapshotAt := time.Now()
+	count, err := testQueries.CreateBalanceSnapshots(context.Background(), snapshotAt)
+	require.NoError(t, err)
+	require.NotZero(t, count)
+
+	snapshot, err := testQueries.GetLatestBalanceSnapshot(context.Background(), GetLatestBalanceSnapshotParams{
+		AccountID:	account.ID,
+		SnapshotAt:	snapshotAt,
+	})
+	require.NoError(t, err)
+	require.Equal(t, int64(150), snapshot.Balance)
+
+	third := depositAmount(t, store, account, 25)
+	require.Equal(t, int64(150), balanceAt(snapshotAt))
+	require.Equal(t, int64(175), balanceAt(third.CreatedAt))
+	require.Equal(t, int64(100), balanceAt(first.CreatedAt))
+}
+
+func TestListDailyBalances(t *testing.T) {
+	store := NewStore(testDB)
+	account := setAccountBalance(t, createAccountWithCurrency(t, util.USD), 0)
+	depositAmount(t, store, account, 100)
+	entry := depositAmount(t, store, account, 50)
+
+	today := entry.CreatedAt.UTC().Truncate(24 * time.Hour)
+	balances, err := store.ListDailyBalances(context.Background(), ListDailyBalancesParams{
+		AccountID:	account.ID,
+		From:		today.AddDate(0, 0, -2),
+		To:			today.AddDate(0, 0, 1),
+	})
+	require.NoError(t, err)
+	require.Len(t, balances, 4)
+	require.Equal(t, []int64{0, 0, 150, 150}, []int64{balances[0].Balance, balances[1].Balance, balances[2].Balance, balances[3].Balance})
+	require.True(t, today.Equal(balances[2].Date))
+}
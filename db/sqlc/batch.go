@@ -0,0 +1,208 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+)
+
+// ErrBatchFailed is returned by an all-or-nothing batch when any item fails, no transfer of the batch is made.
+var ErrBatchFailed = errors.New("batch transfer failed")
+
+// 批量转账中每一项的结果
+const (
+	BatchItemSucceeded	= "succeeded"
+	BatchItemFailed		= "failed"
+	// BatchItemNotApplied 全部成功或全部失败的批量转账中，其他项失败导致该项被回滚或没有执行
+	BatchItemNotApplied	= "not_applied"
+)
+
+// BatchTransferItem is one payee of a batch transfer.
+type BatchTransferItem struct {
+	ToAccountID			int64			`json:"to_account_id"`
+	Amount				int64			`json:"amount"`
+	RequireApproval		bool			`json:"require_approval"`
+	Memo				string			`json:"memo"`
+	ExternalReference	string			`json:"external_reference"`
+	Metadata			json.RawMessage	`json:"metadata"`
+}
+
+// BatchTransferTxParams contains the input parameters of the batch transfer translation.
+type BatchTransferTxParams struct {
+	FromAccountID	int64				`json:"from_account_id"`
+	Items			[]BatchTransferItem	`json:"items"`
+	RequestedBy		string				`json:"requested_by"`
+	// AllOrNothing 为 true 时任意一项失败则所有的转账都回滚，否则每一项单独成功或失败
+	AllOrNothing	bool				`json:"all_or_nothing"`
+}
+
+// BatchTransferItemResult is the result of one item of the batch transfer.
+type BatchTransferItemResult struct {
+	ToAccountID	int64		`json:"to_account_id"`
+	Amount		int64		`json:"amount"`
+	Status		string		`json:"status"`
+	Transfer	*Transfer	`json:"transfer,omitempty"`
+	Error		string		`json:"error,omitempty"`
+	// Err 失败的原因，用于 errors.Is 判断错误的类型
+	Err			error		`json:"-"`
+}
+
+// BatchTransferTxResult is the result of the batch transfer translation.
+type BatchTransferTxResult struct {
+	FromAccount	Account						`json:"from_account"`
+	Items		[]BatchTransferItemResult	`json:"items"`
+	Succeeded	int							`json:"succeeded"`
+	Failed		int							`json:"failed"`
+}
+
+// BatchTransferTx performs transfers from one account to many payees within a single translation.
+// All accounts are locked in the order of their IDs before any money moves, so concurrent transfers cannot deadlock.
+// Each item runs in its own savepoint: a failed item is rolled back alone, and an all-or-nothing batch
+// with any failed item is rolled back as a whole and returns ErrBatchFailed with the results.
+// Payees in other currencies are converted with the latest exchange rate.
+func (store *SQLStore) BatchTransferTx(ctx context.Context, arg BatchTransferTxParams) (BatchTransferTxResult, error) {
+	var result BatchTransferTxResult
+	err := store.execTx(ctx, func(q *Queries) error {
+		result = BatchTransferTxResult{Items: make([]BatchTransferItemResult, len(arg.Items))}
+
+		accounts, err := lockBatchAccounts(ctx, q, arg)
+		if err != nil {
+			return err
+		}
+		fromAccount := accounts[arg.FromAccountID]
+
+		for i, item := range arg.Items {
+			itemResult := &result.Items[i]
+			itemResult.ToAccountID = item.ToAccountID
+			itemResult.Amount = item.Amount
+
+			var transferResult TransferTxResult
+			itemErr, err := withSavepoint(ctx, q, func() error {
+				toAccount, ok := accounts[item.ToAccountID]
+				if !ok {
+					return fmt.Errorf("to account [%d] is not found: %w", item.ToAccountID, sql.ErrNoRows)
+				}
+
+				transferArg := TransferTxParams{
+					FromAccountID:		arg.FromAccountID,
+					ToAccountID:		item.ToAccountID,
+					Amount:				item.Amount,
+					RequestedBy:		arg.RequestedBy,
+					RequireApproval:	item.RequireApproval,
+					Memo:				item.Memo,
+					ExternalReference:	item.ExternalReference,
+					Metadata:			item.Metadata,
+				}
+				if toAccount.Currency != fromAccount.Currency {
+					exchangeRate, err := q.GetLatestExchangeRate(ctx, GetLatestExchangeRateParams{
+						BaseCurrency:	fromAccount.Currency,
+						QuoteCurrency:	toAccount.Currency,
+					})
+					if err == sql.ErrNoRows {
+						return fmt.Errorf("%w: no exchange rate from %s to %s",
+							ErrInvalidExchange, fromAccount.Currency, toAccount.Currency)
+					}
+					if err != nil {
+						return err
+					}
+					transferArg.ExchangeRateID = exchangeRate.ID
+				}
+
+				var err error
+				transferResult, err = store.transfer(ctx, q, transferArg)
+				return err
+			})
+			if err != nil {
+				return err
+			}
+
+			if itemErr != nil {
+				itemResult.Status = BatchItemFailed
+				itemResult.Error = itemErr.Error()
+				itemResult.Err = itemErr
+				result.Failed++
+				continue
+			}
+			itemResult.Status = BatchItemSucceeded
+			itemResult.Transfer = &transferResult.Transfer
+			result.Succeeded++
+		}
+
+		if arg.AllOrNothing && result.Failed > 0 {
+			for i := range result.Items {
+				if result.Items[i].Status == BatchItemSucceeded {
+					result.Items[i].Status = BatchItemNotApplied
+					result.Items[i].Transfer = nil
+				}
+			}
+			result.Succeeded = 0
+			return fmt.Errorf("%w: %d of %d items failed", ErrBatchFailed, result.Failed, len(arg.Items))
+		}
+
+		result.FromAccount, err = q.GetAccount(ctx, arg.FromAccountID)
+		return err
+	})
+	return result, err
+}
+
+// lockBatchAccounts 先锁定转出账户的所有者，再按 ID 的顺序锁定批量转账涉及的所有账户。
+// 用户的锁与 checkTransferLimits 的加锁顺序一致，不存在的收款账户不在返回的结果中。
+func lockBatchAccounts(ctx context.Context, q *Queries, arg BatchTransferTxParams) (map[int64]Account, error) {
+	fromAccount, err := q.GetAccount(ctx, arg.FromAccountID)
+	if err != nil {
+		return nil, err
+	}
+	_, err = q.GetUserForUpdate(ctx, fromAccount.Owner)
+	if err != nil {
+		return nil, err
+	}
+
+	ids := []int64{arg.FromAccountID}
+	seen := map[int64]bool{arg.FromAccountID: true}
+	for _, item := range arg.Items {
+		if !seen[item.ToAccountID] {
+			seen[item.ToAccountID] = true
+			ids = append(ids, item.ToAccountID)
+		}
+	}
+	sort.Slice(ids, func(i, j int) bool {
+		return ids[i] < ids[j]
+	})
+
+	accounts := make(map[int64]Account, len(ids))
+	for _, id := range ids {
+		account, err := q.GetAccountForUpdate(ctx, id)
+		if err == sql.ErrNoRows {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		accounts[id] = account
+	}
+	if _, ok := accounts[arg.FromAccountID]; !ok {
+		return nil, sql.ErrNoRows
+	}
+	return accounts, nil
+}
+
+// withSavepoint runs fn in a savepoint, the changes of fn are rolled back if it fails.
+// itemErr is the error of fn, err is returned if the savepoint itself fails and the translation must be aborted.
+func withSavepoint(ctx context.Context, q *Queries, fn func() error) (itemErr error, err error) {
+	_, err = q.db.ExecContext(ctx, "SAVEPOINT batch_item")
+	if err != nil {
+		return nil, err
+	}
+
+	itemErr = fn()
+	if itemErr != nil {
+		_, err = q.db.ExecContext(ctx, "ROLLBACK TO SAVEPOINT batch_item")
+		return itemErr, err
+	}
+
+	_, err = q.db.ExecContext(ctx, "RELEASE SAVEPOINT batch_item")
+	return nil, err
+}
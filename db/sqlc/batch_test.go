@@ -0,0 +1,168 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"github.com/stretchr/testify/require"
+	"github.com/techschool/simplebank/util"
+	"testing"
+)
+
+func requireBalance(t *testing.T, account Account, balance int64) {
+	updated, err := testQueries.GetAccount(context.Background(), account.ID)
+	require.NoError(t, err)
+	require.Equal(t, balance, updated.Balance)
+}
+
+func TestBatchTransferTx(t *testing.T) {
+	store := NewStore(testDB)
+
+	account1 := setAccountBalance(t, createAccountWithCurrency(t, util.USD), 100)
+	account2 := createAccountWithCurrency(t, util.USD)
+	account3 := createAccountWithCurrency(t, util.USD)
+
+	result, err := store.BatchTransferTx(context.Background(), BatchTransferTxParams{
+		FromAccountID: account1.ID,
+		Items: []BatchTransferItem{
+			{ToAccountID: account2.ID, Amount: 30},
+			{ToAccountID: account3.ID, Amount: 20},
+		},
+		RequestedBy: account1.Owner,
+		AllOrNothing: true,
+	})
+	require.NoError(t, err)
+	require.Equal(t, 2, result.Succeeded)
+	require.Zero(t, result.Failed)
+	require.Equal(t, int64(50), result.FromAccount.Balance)
+
+	for _, item := range result.Items {
+		require.Equal(t, BatchItemSucceeded, item.Status)
+		require.NotNil(t, item.Transfer)
+		require.Equal(t, account1.ID, item.Transfer.FromAccountID)
+		require.Equal(t, item.ToAccountID, item.Transfer.ToAccountID)
+		require.Equal(t, item.Amount, item.Transfer.Amount)
+	}
+
+	requireBalance(t, account2, account2.Balance+30)
+	requireBalance(t, account3, account3.Balance+20)
+}
+
+func TestBatchTransferTxAllOrNothing(t *testing.T) {
+	store := NewStore(testDB)
+
+	account1 := setAccountBalance(t, createAccountWithCurrency(t, util.USD), 100)
+	account2 := createAccountWithCurrency(t, util.USD)
+	account3 := createAccountWithCurrency(t, util.USD)
+
+	// 第二项余额不足，第三项的收款账户不存在，所有的转账都要回滚
+	result, err := store.BatchTransferTx(context.Background(), BatchTransferTxParams{
+		FromAccountID: account1.ID,
+		Items: []BatchTransferItem{
+			{ToAccountID: account2.ID, Amount: 60},
+			{ToAccountID: account3.ID, Amount: 60},
+			{ToAccountID: account3.ID + 1000000, Amount: 10},
+		},
+		RequestedBy: account1.Owner,
+		AllOrNothing: true,
+	})
+	require.ErrorIs(t, err, ErrBatchFailed)
+	require.Zero(t, result.Succeeded)
+	require.Equal(t, 2, result.Failed)
+	require.Equal(t, BatchItemNotApplied, result.Items[0].Status)
+	require.Nil(t, result.Items[0].Transfer)
+	require.Equal(t, BatchItemFailed, result.Items[1].Status)
+	require.ErrorIs(t, result.Items[1].Err, ErrInsufficientFunds)
+	require.Equal(t, BatchItemFailed, result.Items[2].Status)
+	require.ErrorIs(t, result.Items[2].Err, sql.ErrNoRows)
+
+	requireBalance(t, account1, 100)
+	requireBalance(t, account2, account2.Balance)
+	requireBalance(t, account3, account3.Balance)
+
+	transfers := listAccountTransfers(t, account1.ID, 10)
+	require.Empty(t, transfers)
+}
+
+func TestBatchTransferTxIndependent(t *testing.T) {
+	store := NewStore(testDB)
+
+	account1 := setAccountBalance(t, createAccountWithCurrency(t, util.USD), 100)
+	account2 := createAccountWithCurrency(t, util.USD)
+	account3 := createAccountWithCurrency(t, util.USD)
+
+	// 失败的一项单独回滚，后面的转账不受影响
+	result, err := store.BatchTransferTx(context.Background(), BatchTransferTxParams{
+		FromAccountID: account1.ID,
+		Items: []BatchTransferItem{
+			{ToAccountID: account2.ID, Amount: 60},
+			{ToAccountID: account3.ID, Amount: 60},
+			{ToAccountID: account3.ID, Amount: 40},
+		},
+		RequestedBy: account1.Owner,
+	})
+	require.NoError(t, err)
+	require.Equal(t, 2, result.Succeeded)
+	require.Equal(t, 1, result.Failed)
+	require.Equal(t, BatchItemSucceeded, result.Items[0].Status)
+	require.Equal(t, BatchItemFailed, result.Items[1].Status)
+	require.ErrorIs(t, result.Items[1].Err, ErrInsufficientFunds)
+	require.Equal(t, BatchItemSucceeded, result.Items[2].Status)
+	require.Zero(t, result.FromAccount.Balance)
+
+	requireBalance(t, account2, account2.Balance+60)
+	requireBalance(t, account3, account3.Balance+40)
+}
+
+func TestBatchTransferTxDeadLock(t *testing.T) {
+	store := NewStore(testDB)
+
+	account1 := setAccountBalance(t, createAccountWithCurrency(t, util.USD), 1000)
+	account2 := setAccountBalance(t, createAccountWithCurrency(t, util.USD), 1000)
+	account3 := setAccountBalance(t, createAccountWithCurrency(t, util.USD), 1000)
+
+	// 两个方向的批量转账和普通转账并发执行，不能出现死锁
+	n := 10
+	amount := int64(5)
+	errs := make(chan error)
+
+	for i:=0; i<n; i++ {
+		fromAccountID := account1.ID
+		toAccountID := account2.ID
+		if i % 2 == 1 {
+			fromAccountID = account2.ID
+			toAccountID = account1.ID
+		}
+
+		txName := fmt.Sprintf("tx %d", i+1)
+		go func() {
+			ctx := context.WithValue(context.Background(), txKey, txName)
+			_, err := store.BatchTransferTx(ctx, BatchTransferTxParams{
+				FromAccountID: fromAccountID,
+				Items: []BatchTransferItem{
+					{ToAccountID: account3.ID, Amount: amount},
+					{ToAccountID: toAccountID, Amount: amount},
+				},
+				AllOrNothing: true,
+			})
+			if err != nil {
+				errs <- err
+				return
+			}
+			_, err = store.TransferTx(ctx, TransferTxParams{
+				FromAccountID: account3.ID,
+				ToAccountID: fromAccountID,
+				Amount: amount,
+			})
+			errs <- err
+		}()
+	}
+	for i:=0; i<n; i++ {
+		err := <-errs
+		require.NoError(t, err)
+	}
+
+	requireBalance(t, account1, account1.Balance)
+	requireBalance(t, account2, account2.Balance)
+	requireBalance(t, account3, account3.Balance)
+}
@@ -5,36 +5,99 @@ package db
 
 import (
 	"context"
+	"database/sql"
+	"time"
 )
 
+const createChainedEntry = `-- name: CreateChainedEntry :one
+INSERT INTO entries (
+    account_id,
+    amount,
+    transfer_id,
+    journal_id,
+    prev_hash,
+    hash,
+    created_at
+) VALUES (
+    $1, $2, $3, $4, $5, $6, $7
+) RETURNING id, account_id, amount, created_at, transfer_id, journal_id, prev_hash, hash
+`
+
+type CreateChainedEntryParams struct {
+	AccountID  int64         `json:"account_id"`
+	Amount     int64         `json:"amount"`
+	TransferID sql.NullInt64 `json:"transfer_id"`
+	JournalID  sql.NullInt64 `json:"journal_id"`
+	PrevHash   string        `json:"prev_hash"`
+	Hash       string        `json:"hash"`
+	CreatedAt  time.Time     `json:"created_at"`
+}
+
+func (q *Queries) CreateChainedEntry(ctx context.Context, arg CreateChainedEntryParams) (Entry, error) {
+	row := q.db.QueryRowContext(ctx, createChainedEntry,
+		arg.AccountID,
+		arg.Amount,
+		arg.TransferID,
+		arg.JournalID,
+		arg.PrevHash,
+		arg.Hash,
+		arg.CreatedAt,
+	)
+	var i Entry
+	err := row.Scan(
+		&i.ID,
+		&i.AccountID,
+		&i.Amount,
+		&i.CreatedAt,
+		&i.TransferID,
+		&i.JournalID,
+		&i.PrevHash,
+		&i.Hash,
+	)
+	return i, err
+}
+
 const createEntry = `-- name: CreateEntry :one
 INSERT INTO entries (
     account_id,
-    amount
+    amount,
+    transfer_id,
+    journal_id
 ) VALUES (
-    $1, $2
-) RETURNING id, account_id, amount, created_at
+    $1, $2, $3, $4
+) RETURNING id, account_id, amount, created_at, transfer_id, journal_id, prev_hash, hash
 `
 
 type CreateEntryParams struct {
-	AccountID int64 `json:"account_id"`
-	Amount    int64 `json:"amount"`
+	AccountID  int64         `json:"account_id"`
+	Amount     int64         `json:"amount"`
+	TransferID sql.NullInt64 `json:"transfer_id"`
+	JournalID  sql.NullInt64 `json:"journal_id"`
 }
 
 func (q *Queries) CreateEntry(ctx context.Context, arg CreateEntryParams) (Entry, error) {
-	row := q.db.QueryRowContext(ctx, createEntry, arg.AccountID, arg.Amount)
+	row := q.db.QueryRowContext(ctx, createEntry,
+		arg.AccountID,
+		arg.Amount,
+		arg.TransferID,
+		arg.JournalID,
+	)
 	var i Entry
 	err := row.Scan(
 		&i.ID,
 		&i.AccountID,
 		&i.Amount,
 		&i.CreatedAt,
+		&i.TransferID,
+		&i.JournalID,
+		&i.PrevHash,
+		&i.Hash,
 	)
 	return i, err
 }
 
 const getEntry = `-- name: GetEntry :one
-SELECT id, account_id, amount, created_at FROM entries
+SELECT id, account_id, amount, created_at, transfer_id, journal_id, prev_hash, hash FROM entries
 WHERE id=$1 LIMIT 1
 `
 
@@ -46,26 +109,100 @@ func (q *Queries) GetEntry(ctx context.Context, id int64) (Entry, error) {
 		&i.AccountID,
 		&i.Amount,
 		&i.CreatedAt,
+		&i.TransferID,
+		&i.JournalID,
+		&i.PrevHash,
+		&i.Hash,
 	)
 	return i, err
 }
 
-const listEntries = `-- name: ListEntries :many
-SELECT id, account_id, amount, created_at FROM entries
-WHERE account_id=$1
+const listAccountEntryChain = `-- name: ListAccountEntryChain :many
+SELECT id, account_id, amount, created_at, transfer_id, journal_id, prev_hash, hash FROM entries
+WHERE account_id = $1
+  AND id > $2
 ORDER BY id
-LIMIT $2
-OFFSET $3
+LIMIT $3
 `
 
-type ListEntriesParams struct {
+type ListAccountEntryChainParams struct {
 	AccountID int64 `json:"account_id"`
-	Limit     int32 `json:"limit"`
-	Offset    int32 `json:"offset"`
+	AfterID   int64 `json:"after_id"`
+	PageSize  int32 `json:"page_size"`
 }
 
+// 按 id 顺序遍历账户的流水，校验哈希链
+func (q *Queries) ListAccountEntryChain(ctx context.Context, arg ListAccountEntryChainParams) ([]Entry, error) {
+	rows, err := q.db.QueryContext(ctx, listAccountEntryChain, arg.AccountID, arg.AfterID, arg.PageSize)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []Entry{}
+	for rows.Next() {
+		var i Entry
+		if err := rows.Scan(
+			&i.ID,
+			&i.AccountID,
+			&i.Amount,
+			&i.CreatedAt,
+			&i.TransferID,
+			&i.JournalID,
+			&i.PrevHash,
+			&i.Hash,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listEntries = `-- name: ListEntries :many
+SELECT id, account_id, amount, created_at, transfer_id, journal_id, prev_hash, hash FROM entries
+WHERE account_id = $1
+  AND ($2::varchar = ''
+    OR ($2::varchar = 'in' AND amount > 0)
+    OR ($2::varchar = 'out' AND amount < 0))
+  AND created_at >= $3
+  AND created_at < $4
+  AND ABS(amount) BETWEEN $5::bigint AND $6::bigint
+  AND (created_at, id) < ($7::timestamptz, $8::bigint)
+ORDER BY created_at DESC, id DESC
+LIMIT $9
+`
+
+type ListEntriesParams struct {
+	AccountID       int64     `json:"account_id"`
+	Direction       string    `json:"direction"`
+	StartTime       time.Time `json:"start_time"`
+	EndTime         time.Time `json:"end_time"`
+	MinAmount       int64     `json:"min_amount"`
+	MaxAmount       int64     `json:"max_amount"`
+	CursorCreatedAt time.Time `json:"cursor_created_at"`
+	CursorID        int64     `json:"cursor_id"`
+	PageSize        int32     `json:"page_size"`
+}
+
+// 按 (created_at, id) 倒序的 keyset 分页，direction 为 in 时只返回入账，为 out 时只返回出账，金额的范围按绝对值计算
 func (q *Queries) ListEntries(ctx context.Context, arg ListEntriesParams) ([]Entry, error) {
-	rows, err := q.db.QueryContext(ctx, listEntries, arg.AccountID, arg.Limit, arg.Offset)
+	rows, err := q.db.QueryContext(ctx, listEntries,
+		arg.AccountID,
+		arg.Direction,
+		arg.StartTime,
+		arg.EndTime,
+		arg.MinAmount,
+		arg.MaxAmount,
+		arg.CursorCreatedAt,
+		arg.CursorID,
+		arg.PageSize,
+	)
 	if err != nil {
 		return nil, err
 	}
@@ -78,6 +215,10 @@ func (q *Queries) ListEntries(ctx context.Context, arg ListEntriesParams) ([]Ent
 			&i.AccountID,
 			&i.Amount,
 			&i.CreatedAt,
+			&i.TransferID,
+			&i.JournalID,
+			&i.PrevHash,
+			&i.Hash,
 		); err != nil {
 			return nil, err
 		}
@@ -0,0 +1,116 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"github.com/techschool/simplebank/util"
+)
+
+// entryChainPageSize 校验哈希链时每次读取的账户和流水的数量
+const entryChainPageSize = 1000
+
+// VerifyEntryChainParams contains the input parameters of VerifyEntryChain.
+type VerifyEntryChainParams struct {
+	// AccountID 只校验该账户的流水，为 0 时校验所有的账户
+	AccountID	int64	`json:"account_id"`
+}
+
+// EntryChainBreak describes the first entry whose hash doesn't match the chain.
+type EntryChainBreak struct {
+	AccountID	int64	`json:"account_id"`
+	// EntryID 为 0 表示账户记录的最后一条流水的哈希与流水不一致，例如末尾的流水被删除
+	EntryID		int64	`json:"entry_id"`
+	Reason		string	`json:"reason"`
+}
+
+// VerifyEntryChainResult is the result of VerifyEntryChain, Break is nil if every chain is intact.
+type VerifyEntryChainResult struct {
+	AccountsChecked	int64				`json:"accounts_checked"`
+	EntriesChecked	int64				`json:"entries_checked"`
+	Break			*EntryChainBreak	`json:"break"`
+}
+
+// VerifyEntryChain walks the hash chain of the entries of every account in the order of their ids and stops at the
+// first broken link. It reads a consistent snapshot, so concurrent transfers don't cause false reports.
+func (store *SQLStore) VerifyEntryChain(ctx context.Context, arg VerifyEntryChainParams) (VerifyEntryChainResult, error) {
+	var result VerifyEntryChainResult
+	opts := &sql.TxOptions{Isolation: sql.LevelRepeatableRead, ReadOnly: true}
+	err := store.execTxWithOptions(ctx, opts, func(q *Queries) error {
+		if arg.AccountID != 0 {
+			account, err := q.GetAccount(ctx, arg.AccountID)
+			if err != nil {
+				return err
+			}
+			return verifyAccountChain(ctx, q, account, &result)
+		}
+
+		var afterID int64
+		for result.Break == nil {
+			accounts, err := q.ListAccountsAfter(ctx, ListAccountsAfterParams{
+				AfterID:	afterID,
+				PageSize:	entryChainPageSize,
+			})
+			if err != nil {
+				return err
+			}
+			for _, account := range accounts {
+				if err = verifyAccountChain(ctx, q, account, &result); err != nil || result.Break != nil {
+					return err
+				}
+			}
+			if len(accounts) < entryChainPageSize {
+				return nil
+			}
+			afterID = accounts[len(accounts)-1].ID
+		}
+		return nil
+	})
+	return result, err
+}
+
+// verifyAccountChain 重新计算账户每一条流水的哈希，并检查与上一条流水串联，发现断开时记录在 result.Break 中
+func verifyAccountChain(ctx context.Context, q *Queries, account Account, result *VerifyEntryChainResult) error {
+	result.AccountsChecked++
+
+	var afterID int64
+	prevHash := ""
+	for {
+		entries, err := q.ListAccountEntryChain(ctx, ListAccountEntryChainParams{
+			AccountID:	account.ID,
+			AfterID:	afterID,
+			PageSize:	entryChainPageSize,
+		})
+		if err != nil {
+			return err
+		}
+
+		for _, entry := range entries {
+			result.EntriesChecked++
+			if entry.PrevHash != prevHash {
+				result.Break = &EntryChainBreak{AccountID: account.ID, EntryID: entry.ID,
+					Reason: fmt.Sprintf("prev_hash %q doesn't match the hash %q of the previous entry", entry.PrevHash, prevHash)}
+				return nil
+			}
+			hash := util.EntryHash(entry.AccountID, entry.Amount, entry.TransferID.Int64, entry.JournalID.Int64,
+				entry.CreatedAt, entry.PrevHash)
+			if entry.Hash != hash {
+				result.Break = &EntryChainBreak{AccountID: account.ID, EntryID: entry.ID,
+					Reason: fmt.Sprintf("hash %q doesn't match the content of the entry", entry.Hash)}
+				return nil
+			}
+			prevHash = entry.Hash
+		}
+
+		if len(entries) < entryChainPageSize {
+			break
+		}
+		afterID = entries[len(entries)-1].ID
+	}
+
+	if account.EntryHash != prevHash {
+		result.Break = &EntryChainBreak{AccountID: account.ID,
+			Reason: fmt.Sprintf("entry_hash %q of the account doesn't match the last entry %q", account.EntryHash, prevHash)}
+	}
+	return nil
+}
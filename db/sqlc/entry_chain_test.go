@@ -0,0 +1,55 @@
+package db
+
+import (
+	"context"
+	"github.com/stretchr/testify/require"
+	"github.com/techschool/simplebank/util"
+	"testing"
+)
+
+func TestVerifyEntryChain(t *testing.T) {
+	store := NewStore(testDB)
+	account1 := setAccountBalance(t, createAccountWithCurrency(t, util.USD), 1000)
+	account2 := createAccountWithCurrency(t, util.USD)
+
+	var results []TransferTxResult
+	for i := 0; i < 3; i++ {
+		result, err := store.TransferTx(context.Background(), TransferTxParams{
+			FromAccountID: account1.ID,
+			ToAccountID: account2.ID,
+			Amount: 10,
+		})
+		require.NoError(t, err)
+		results = append(results, result)
+	}
+
+	// 每条流水与同一个账户的上一条流水串联
+	require.Empty(t, results[0].FromEntry.PrevHash)
+	require.Equal(t, results[0].FromEntry.Hash, results[1].FromEntry.PrevHash)
+	require.Equal(t, results[2].ToEntry.Hash, results[2].ToAccount.EntryHash)
+
+	verified, err := store.VerifyEntryChain(context.Background(), VerifyEntryChainParams{AccountID: account1.ID})
+	require.NoError(t, err)
+	require.Nil(t, verified.Break)
+	require.Equal(t, int64(1), verified.AccountsChecked)
+	require.Equal(t, int64(3), verified.EntriesChecked)
+
+	// 直接修改流水的金额，哈希链在该流水处断开
+	_, err = testDB.Exec("UPDATE entries SET amount = amount + 1 WHERE id = $1", results[1].ToEntry.ID)
+	require.NoError(t, err)
+
+	verified, err = store.VerifyEntryChain(context.Background(), VerifyEntryChainParams{AccountID: account2.ID})
+	require.NoError(t, err)
+	require.NotNil(t, verified.Break)
+	require.Equal(t, account2.ID, verified.Break.AccountID)
+	require.Equal(t, results[1].ToEntry.ID, verified.Break.EntryID)
+
+	// 删除最后一条流水，账户记录的哈希与剩下的流水不一致
+	_, err = testDB.Exec("DELETE FROM entries WHERE id = $1", results[2].FromEntry.ID)
+	require.NoError(t, err)
+
+	verified, err = store.VerifyEntryChain(context.Background(), VerifyEntryChainParams{AccountID: account1.ID})
+	require.NoError(t, err)
+	require.NotNil(t, verified.Break)
+	require.Zero(t, verified.Break.EntryID)
+}
@@ -2,6 +2,7 @@ package db
 
 import (
 	"context"
+	"math"
 	"github.com/stretchr/testify/require"
 	"github.com/techschool/simplebank/util"
 	"testing"
@@ -51,8 +52,11 @@ func TestListEntry(t *testing.T) {
 
 	arg := ListEntriesParams{
 		AccountID: account.ID,
-		Limit: 5,
-		Offset: 5,
+		EndTime: time.Now().Add(time.Hour),
+		MaxAmount: math.MaxInt64,
+		CursorCreatedAt: time.Now().Add(time.Hour),
+		CursorID: math.MaxInt64,
+		PageSize: 5,
 	}
 
 	entries, err := testQueries.ListEntries(context.Background(), arg)
@@ -64,6 +68,31 @@ func TestListEntry(t *testing.T) {
 		require.Equal(t, arg.AccountID, entry.AccountID)
 	}
 
+	// keyset 分页，下一页从上一页最后一条记录之前开始
+	cursor := arg.CursorCreatedAt
+	arg.CursorCreatedAt = entries[4].CreatedAt
+	arg.CursorID = entries[4].ID
+	nextPage, err := testQueries.ListEntries(context.Background(), arg)
+	require.NoError(t, err)
+	require.Len(t, nextPage, 5)
+	require.Less(t, nextPage[0].ID, entries[4].ID)
+
+	// 按方向和金额过滤，金额按绝对值计算
+	arg.CursorCreatedAt = cursor
+	arg.CursorID = math.MaxInt64
+	arg.PageSize = 10
+	arg.Direction = util.TransferDirectionIn
+	arg.MinAmount = 100
+	arg.MaxAmount = 800
+	entries, err = testQueries.ListEntries(context.Background(), arg)
+	require.NoError(t, err)
+	require.Len(t, entries, 10)
+
+	arg.Direction = util.TransferDirectionOut
+	entries, err = testQueries.ListEntries(context.Background(), arg)
+	require.NoError(t, err)
+	require.Empty(t, entries)
+
 }
 
 
@@ -0,0 +1,134 @@
+// Code generated by sqlc. DO NOT EDIT.
+// source: exchange_rate.sql
+
+package db
+
+import (
+	"context"
+	"time"
+)
+
+const createExchangeRate = `-- name: CreateExchangeRate :one
+INSERT INTO exchange_rates (
+    base_currency,
+    quote_currency,
+    rate,
+    created_by
+) VALUES (
+    $1, $2, $3, $4
+) RETURNING id, base_currency, quote_currency, rate, created_by, created_at
+`
+
+type CreateExchangeRateParams struct {
+	BaseCurrency  string `json:"base_currency"`
+	QuoteCurrency string `json:"quote_currency"`
+	Rate          string `json:"rate"`
+	CreatedBy     string `json:"created_by"`
+}
+
+func (q *Queries) CreateExchangeRate(ctx context.Context, arg CreateExchangeRateParams) (ExchangeRate, error) {
+	row := q.db.QueryRowContext(ctx, createExchangeRate,
+		arg.BaseCurrency,
+		arg.QuoteCurrency,
+		arg.Rate,
+		arg.CreatedBy,
+	)
+	var i ExchangeRate
+	err := row.Scan(
+		&i.ID,
+		&i.BaseCurrency,
+		&i.QuoteCurrency,
+		&i.Rate,
+		&i.CreatedBy,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
+const getExchangeRate = `-- name: GetExchangeRate :one
+SELECT id, base_currency, quote_currency, rate, created_by, created_at FROM exchange_rates
+WHERE id = $1 LIMIT 1
+`
+
+func (q *Queries) GetExchangeRate(ctx context.Context, id int64) (ExchangeRate, error) {
+	row := q.db.QueryRowContext(ctx, getExchangeRate, id)
+	var i ExchangeRate
+	err := row.Scan(
+		&i.ID,
+		&i.BaseCurrency,
+		&i.QuoteCurrency,
+		&i.Rate,
+		&i.CreatedBy,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
+const getLatestExchangeRate = `-- name: GetLatestExchangeRate :one
+SELECT id, base_currency, quote_currency, rate, created_by, created_at FROM exchange_rates
+WHERE base_currency = $1 AND quote_currency = $2
+ORDER BY created_at DESC, id DESC
+LIMIT 1
+`
+
+type GetLatestExchangeRateParams struct {
+	BaseCurrency  string `json:"base_currency"`
+	QuoteCurrency string `json:"quote_currency"`
+}
+
+func (q *Queries) GetLatestExchangeRate(ctx context.Context, arg GetLatestExchangeRateParams) (ExchangeRate, error) {
+	row := q.db.QueryRowContext(ctx, getLatestExchangeRate, arg.BaseCurrency, arg.QuoteCurrency)
+	var i ExchangeRate
+	err := row.Scan(
+		&i.ID,
+		&i.BaseCurrency,
+		&i.QuoteCurrency,
+		&i.Rate,
+		&i.CreatedBy,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
+const listExchangeRates = `-- name: ListExchangeRates :many
+SELECT id, base_currency, quote_currency, rate, created_by, created_at FROM exchange_rates
+WHERE (created_at, id) < ($1::timestamptz, $2::bigint)
+ORDER BY created_at DESC, id DESC
+LIMIT $3
+`
+
+type ListExchangeRatesParams struct {
+	CursorCreatedAt time.Time `json:"cursor_created_at"`
+	CursorID        int64     `json:"cursor_id"`
+	PageSize        int32     `json:"page_size"`
+}
+
+func (q *Queries) ListExchangeRates(ctx context.Context, arg ListExchangeRatesParams) ([]ExchangeRate, error) {
+	rows, err := q.db.QueryContext(ctx, listExchangeRates, arg.CursorCreatedAt, arg.CursorID, arg.PageSize)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []ExchangeRate{}
+	for rows.Next() {
+		var i ExchangeRate
+		if err := rows.Scan(
+			&i.ID,
+			&i.BaseCurrency,
+			&i.QuoteCurrency,
+			&i.Rate,
+			&i.CreatedBy,
+			&i.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
@@ -0,0 +1,126 @@
+package db
+
+import (
+	"context"
+	"math"
+	"github.com/stretchr/testify/require"
+	"github.com/techschool/simplebank/util"
+	"testing"
+	"time"
+)
+
+func createRandomExchangeRate(t *testing.T, baseCurrency, quoteCurrency, rate string) ExchangeRate {
+	user := createRandomUser(t)
+
+	arg := CreateExchangeRateParams{
+		BaseCurrency:	baseCurrency,
+		QuoteCurrency:	quoteCurrency,
+		Rate:			rate,
+		CreatedBy:		user.Username,
+	}
+
+	exchangeRate, err := testQueries.CreateExchangeRate(context.Background(), arg)
+	require.NoError(t, err)
+	require.NotEmpty(t, exchangeRate)
+
+	require.Equal(t, arg.BaseCurrency, exchangeRate.BaseCurrency)
+	require.Equal(t, arg.QuoteCurrency, exchangeRate.QuoteCurrency)
+	require.Equal(t, arg.CreatedBy, exchangeRate.CreatedBy)
+	require.NotZero(t, exchangeRate.ID)
+	require.NotZero(t, exchangeRate.CreatedAt)
+	return exchangeRate
+}
+
+func createAccountWithCurrency(t *testing.T, currency string) Account {
+	user := createRandomUser(t)
+
+	account, err := testQueries.CreateAccount(context.Background(), CreateAccountParams{
+		Owner: user.Username,
+		Balance: util.RandomMoney(),
+		Currency: currency,
+	})
+	require.NoError(t, err)
+	return account
+}
+
+func TestCreateExchangeRate(t *testing.T) {
+	createRandomExchangeRate(t, util.USD, util.EUR, "0.92")
+}
+
+func TestGetLatestExchangeRate(t *testing.T) {
+	createRandomExchangeRate(t, util.USD, util.CAD, "1.35")
+	exchangeRate1 := createRandomExchangeRate(t, util.USD, util.CAD, "1.36")
+
+	exchangeRate2, err := testQueries.GetLatestExchangeRate(context.Background(), GetLatestExchangeRateParams{
+		BaseCurrency:	util.USD,
+		QuoteCurrency:	util.CAD,
+	})
+	require.NoError(t, err)
+	require.Equal(t, exchangeRate1.ID, exchangeRate2.ID)
+	require.Equal(t, exchangeRate1.Rate, exchangeRate2.Rate)
+	require.WithinDuration(t, exchangeRate1.CreatedAt, exchangeRate2.CreatedAt, time.Second)
+}
+
+func TestListExchangeRates(t *testing.T) {
+	for i:=0; i<5; i++ {
+		createRandomExchangeRate(t, util.EUR, util.USD, "1.08")
+	}
+
+	arg := ListExchangeRatesParams{
+		CursorCreatedAt: time.Now().Add(time.Hour),
+		CursorID: math.MaxInt64,
+		PageSize: 5,
+	}
+	exchangeRates, err := testQueries.ListExchangeRates(context.Background(), arg)
+	require.NoError(t, err)
+	require.Len(t, exchangeRates, 5)
+
+	// 下一页从上一页最后一条记录之前开始
+	arg.CursorCreatedAt = exchangeRates[4].CreatedAt
+	arg.CursorID = exchangeRates[4].ID
+	nextPage, err := testQueries.ListExchangeRates(context.Background(), arg)
+	require.NoError(t, err)
+	for _, exchangeRate := range nextPage {
+		require.False(t, exchangeRate.CreatedAt.After(exchangeRates[4].CreatedAt))
+		require.NotEqual(t, exchangeRates[4].ID, exchangeRate.ID)
+	}
+}
+
+func TestTransferTxExchange(t *testing.T) {
+	store := NewStore(testDB)
+
+	account1 := createAccountWithCurrency(t, util.USD)
+	account2 := createAccountWithCurrency(t, util.EUR)
+	exchangeRate := createRandomExchangeRate(t, util.USD, util.EUR, "0.9")
+
+	result, err := store.TransferTx(context.Background(), TransferTxParams{
+		FromAccountID: account1.ID,
+		ToAccountID: account2.ID,
+		Amount: 15,
+		ExchangeRateID: exchangeRate.ID,
+	})
+	require.NoError(t, err)
+
+	// 转出 15 USD，转入 13.5 EUR，小数部分舍去
+	require.Equal(t, int64(15), result.Transfer.Amount)
+	require.Equal(t, int64(13), result.Transfer.ToAmount)
+	require.Equal(t, exchangeRate.Rate, result.Transfer.ExchangeRate)
+	require.Equal(t, exchangeRate.ID, result.Transfer.ExchangeRateID.Int64)
+	require.Equal(t, int64(-15), result.FromEntry.Amount)
+	require.Equal(t, int64(13), result.ToEntry.Amount)
+	require.Equal(t, account1.Balance-15, result.FromAccount.Balance)
+	require.Equal(t, account2.Balance+13, result.ToAccount.Balance)
+
+	// 汇率的币种和账户不一致时，转账必须回滚
+	_, err = store.TransferTx(context.Background(), TransferTxParams{
+		FromAccountID: account2.ID,
+		ToAccountID: account1.ID,
+		Amount: 15,
+		ExchangeRateID: exchangeRate.ID,
+	})
+	require.ErrorIs(t, err, ErrInvalidExchange)
+
+	updateAccount1, err := testQueries.GetAccount(context.Background(), account1.ID)
+	require.NoError(t, err)
+	require.Equal(t, result.FromAccount.Balance, updateAccount1.Balance)
+}
@@ -0,0 +1,150 @@
+// Code generated by sqlc. DO NOT EDIT.
+// source: fraud_decision.sql
+
+package db
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"time"
+)
+
+const createFraudDecision = `-- name: CreateFraudDecision :one
+INSERT INTO fraud_decisions (
+    username,
+    from_account_id,
+    to_account_id,
+    amount,
+    currency,
+    decision,
+    rule,
+    reason,
+    metadata
+) VALUES (
+    $1, $2, $3, $4, $5, $6, $7, $8, $9
+) RETURNING id, username, from_account_id, to_account_id, amount, currency, decision, rule, reason, metadata, transfer_id, created_at
+`
+
+type CreateFraudDecisionParams struct {
+	Username      string          `json:"username"`
+	FromAccountID int64           `json:"from_account_id"`
+	ToAccountID   int64           `json:"to_account_id"`
+	Amount        int64           `json:"amount"`
+	Currency      string          `json:"currency"`
+	Decision      string          `json:"decision"`
+	Rule          string          `json:"rule"`
+	Reason        string          `json:"reason"`
+	Metadata      json.RawMessage `json:"metadata"`
+}
+
+func (q *Queries) CreateFraudDecision(ctx context.Context, arg CreateFraudDecisionParams) (FraudDecision, error) {
+	row := q.db.QueryRowContext(ctx, createFraudDecision,
+		arg.Username,
+		arg.FromAccountID,
+		arg.ToAccountID,
+		arg.Amount,
+		arg.Currency,
+		arg.Decision,
+		arg.Rule,
+		arg.Reason,
+		arg.Metadata,
+	)
+	var i FraudDecision
+	err := row.Scan(
+		&i.ID,
+		&i.Username,
+		&i.FromAccountID,
+		&i.ToAccountID,
+		&i.Amount,
+		&i.Currency,
+		&i.Decision,
+		&i.Rule,
+		&i.Reason,
+		&i.Metadata,
+		&i.TransferID,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
+const listFraudDecisions = `-- name: ListFraudDecisions :many
+SELECT id, username, from_account_id, to_account_id, amount, currency, decision, rule, reason, metadata, transfer_id, created_at FROM fraud_decisions
+WHERE (created_at, id) < ($1::timestamptz, $2::bigint)
+ORDER BY created_at DESC, id DESC
+LIMIT $3
+`
+
+type ListFraudDecisionsParams struct {
+	CursorCreatedAt time.Time `json:"cursor_created_at"`
+	CursorID        int64     `json:"cursor_id"`
+	PageSize        int32     `json:"page_size"`
+}
+
+func (q *Queries) ListFraudDecisions(ctx context.Context, arg ListFraudDecisionsParams) ([]FraudDecision, error) {
+	rows, err := q.db.QueryContext(ctx, listFraudDecisions, arg.CursorCreatedAt, arg.CursorID, arg.PageSize)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []FraudDecision{}
+	for rows.Next() {
+		var i FraudDecision
+		if err := rows.Scan(
+			&i.ID,
+			&i.Username,
+			&i.FromAccountID,
+			&i.ToAccountID,
+			&i.Amount,
+			&i.Currency,
+			&i.Decision,
+			&i.Rule,
+			&i.Reason,
+			&i.Metadata,
+			&i.TransferID,
+			&i.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const setFraudDecisionTransfer = `-- name: SetFraudDecisionTransfer :one
+UPDATE fraud_decisions
+SET transfer_id = $2
+WHERE id = $1
+RETURNING id, username, from_account_id, to_account_id, amount, currency, decision, rule, reason, metadata, transfer_id, created_at
+`
+
+type SetFraudDecisionTransferParams struct {
+	ID         int64         `json:"id"`
+	TransferID sql.NullInt64 `json:"transfer_id"`
+}
+
+func (q *Queries) SetFraudDecisionTransfer(ctx context.Context, arg SetFraudDecisionTransferParams) (FraudDecision, error) {
+	row := q.db.QueryRowContext(ctx, setFraudDecisionTransfer, arg.ID, arg.TransferID)
+	var i FraudDecision
+	err := row.Scan(
+		&i.ID,
+		&i.Username,
+		&i.FromAccountID,
+		&i.ToAccountID,
+		&i.Amount,
+		&i.Currency,
+		&i.Decision,
+		&i.Rule,
+		&i.Reason,
+		&i.Metadata,
+		&i.TransferID,
+		&i.CreatedAt,
+	)
+	return i, err
+}
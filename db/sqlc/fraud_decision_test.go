@@ -0,0 +1,54 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"github.com/stretchr/testify/require"
+	"testing"
+	"time"
+)
+
+func TestFraudDecision(t *testing.T) {
+	account1 := createRandomAccount(t)
+	account2 := createRandomAccount(t)
+
+	arg := CreateFraudDecisionParams{
+		Username: account1.Owner,
+		FromAccountID: account1.ID,
+		ToAccountID: account2.ID,
+		Amount: 10,
+		Currency: account1.Currency,
+		Decision: "review",
+		Rule: "new_payee_large_amount",
+		Reason: "new payee",
+		Metadata: []byte(`{"client_ip": "127.0.0.1"}`),
+	}
+	decision, err := testQueries.CreateFraudDecision(context.Background(), arg)
+	require.NoError(t, err)
+	require.Equal(t, arg.Decision, decision.Decision)
+	require.Equal(t, arg.Rule, decision.Rule)
+	require.JSONEq(t, string(arg.Metadata), string(decision.Metadata))
+	require.False(t, decision.TransferID.Valid)
+
+	transfer := createOneTransfer(t, account1, account2)
+	decision, err = testQueries.SetFraudDecisionTransfer(context.Background(), SetFraudDecisionTransferParams{
+		ID: decision.ID,
+		TransferID: sql.NullInt64{Int64: transfer.ID, Valid: true},
+	})
+	require.NoError(t, err)
+	require.Equal(t, transfer.ID, decision.TransferID.Int64)
+
+	count, err := testQueries.CountPayeeTransfers(context.Background(), CountPayeeTransfersParams{
+		FromAccountID: account1.ID,
+		ToAccountID: account2.ID,
+	})
+	require.NoError(t, err)
+	require.Equal(t, int64(1), count)
+
+	count, err = testQueries.CountTransfersSince(context.Background(), CountTransfersSinceParams{
+		FromAccountID: account1.ID,
+		Since: transfer.CreatedAt.Add(-time.Minute),
+	})
+	require.NoError(t, err)
+	require.Equal(t, int64(1), count)
+}
@@ -0,0 +1,144 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"github.com/techschool/simplebank/util"
+	"time"
+)
+
+// ErrInvalidHold is returned when a hold cannot be created, captured or voided with the request.
+var ErrInvalidHold = errors.New("invalid hold")
+
+// CaptureHoldTxParams contains the input parameters of capture hold translation.
+type CaptureHoldTxParams struct {
+	HoldID	int64	`json:"hold_id"`
+	// Amount 扣款的金额，不能超过冻结的金额，剩余的部分会被释放
+	Amount	int64	`json:"amount"`
+}
+
+// CaptureHoldTxResult is the result of the capture hold translation.
+type CaptureHoldTxResult struct {
+	Hold	Hold	`json:"hold"`
+	TransferTxResult
+}
+
+// CreateHoldTx reserves funds of an account, the held amount is not available for other transfers
+// and counts toward the transfer limits of the account until the hold is captured or released.
+func (store *SQLStore) CreateHoldTx(ctx context.Context, arg CreateHoldParams) (Hold, error) {
+	var result Hold
+	if arg.Amount <= 0 {
+		return result, fmt.Errorf("%w: amount %d must be positive", ErrInvalidHold, arg.Amount)
+	}
+
+	err := store.execTx(ctx, func(q *Queries) error {
+		// 预授权与转账一样占用转账限额，先于账户加锁，与转账的加锁顺序一致
+		err := checkTransferLimits(ctx, q, TransferTxParams{
+			FromAccountID:	arg.AccountID,
+			ToAccountID:	arg.ToAccountID,
+			Amount:			arg.Amount,
+		})
+		if err != nil {
+			return err
+		}
+
+		// 锁定账户，与转账的余额检查互斥
+		account, err := q.GetAccountForUpdate(ctx, arg.AccountID)
+		if err != nil {
+			return err
+		}
+
+		result, err = q.CreateHold(ctx, arg)
+		if err != nil {
+			return err
+		}
+		return store.checkBalance(ctx, q, account)
+	})
+	return result, err
+}
+
+// CaptureHoldTx settles all or part of an active hold through a transfer to the recipient account,
+// the rest of the held amount is released.
+func (store *SQLStore) CaptureHoldTx(ctx context.Context, arg CaptureHoldTxParams) (CaptureHoldTxResult, error) {
+	var result CaptureHoldTxResult
+	err := store.execTx(ctx, func(q *Queries) error {
+		hold, err := lockActiveHold(ctx, q, arg.HoldID)
+		if err != nil {
+			return err
+		}
+		if arg.Amount <= 0 || arg.Amount > hold.Amount {
+			return fmt.Errorf("%w: amount %d exceeds the held amount %d of hold [%d]",
+				ErrInvalidHold, arg.Amount, hold.Amount, hold.ID)
+		}
+
+		// 先释放冻结的金额，转账时检查的可用余额不再包含这笔预授权
+		_, err = q.UpdateHoldStatus(ctx, UpdateHoldStatusParams{
+			ID:				hold.ID,
+			Status:			util.HoldCaptured,
+			CapturedAmount:	arg.Amount,
+		})
+		if err != nil {
+			return err
+		}
+
+		// 创建预授权时已经检查过转账限额，已经冻结的资金必须能够扣除
+		transfer, err := store.createTransfer(ctx, q, TransferTxParams{
+			FromAccountID:	hold.AccountID,
+			ToAccountID:	hold.ToAccountID,
+			Amount:			arg.Amount,
+			RequestedBy:	hold.CreatedBy,
+		})
+		if err != nil {
+			return err
+		}
+		result.TransferTxResult, err = store.settleTransfer(ctx, q, transfer)
+		if err != nil {
+			return err
+		}
+
+		result.Hold, err = q.UpdateHoldStatus(ctx, UpdateHoldStatusParams{
+			ID:				hold.ID,
+			Status:			util.HoldCaptured,
+			CapturedAmount:	arg.Amount,
+			TransferID:		sql.NullInt64{Int64: result.Transfer.ID, Valid: true},
+		})
+		return err
+	})
+	return result, err
+}
+
+// VoidHoldTx releases an active hold without moving any money.
+func (store *SQLStore) VoidHoldTx(ctx context.Context, holdID int64) (Hold, error) {
+	var result Hold
+	err := store.execTx(ctx, func(q *Queries) error {
+		hold, err := lockActiveHold(ctx, q, holdID)
+		if err != nil {
+			return err
+		}
+
+		result, err = q.UpdateHoldStatus(ctx, UpdateHoldStatusParams{
+			ID:		hold.ID,
+			Status:	util.HoldVoided,
+		})
+		return err
+	})
+	return result, err
+}
+
+// lockActiveHold 锁定预授权，已经过期但还没有被清理的预授权也不能再操作
+func lockActiveHold(ctx context.Context, q *Queries, holdID int64) (Hold, error) {
+	hold, err := q.GetHoldForUpdate(ctx, holdID)
+	if err != nil {
+		return hold, err
+	}
+
+	if hold.Status != util.HoldActive {
+		return hold, fmt.Errorf("%w: hold [%d] is %s", ErrInvalidHold, hold.ID, hold.Status)
+	}
+	if !hold.ExpiresAt.After(time.Now()) {
+		return hold, fmt.Errorf("%w: hold [%d] is expired", ErrInvalidHold, hold.ID)
+	}
+	return hold, nil
+}
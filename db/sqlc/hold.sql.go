@@ -0,0 +1,227 @@
+// Code generated by sqlc. DO NOT EDIT.
+// source: hold.sql
+
+package db
+
+import (
+	"context"
+	"database/sql"
+	"time"
+)
+
+const createHold = `-- name: CreateHold :one
+INSERT INTO holds (
+    account_id,
+    to_account_id,
+    amount,
+    created_by,
+    expires_at
+) VALUES (
+    $1, $2, $3, $4, $5
+) RETURNING id, account_id, to_account_id, amount, captured_amount, status, created_by, transfer_id, expires_at, created_at, updated_at
+`
+
+type CreateHoldParams struct {
+	AccountID   int64     `json:"account_id"`
+	ToAccountID int64     `json:"to_account_id"`
+	Amount      int64     `json:"amount"`
+	CreatedBy   string    `json:"created_by"`
+	ExpiresAt   time.Time `json:"expires_at"`
+}
+
+func (q *Queries) CreateHold(ctx context.Context, arg CreateHoldParams) (Hold, error) {
+	row := q.db.QueryRowContext(ctx, createHold,
+		arg.AccountID,
+		arg.ToAccountID,
+		arg.Amount,
+		arg.CreatedBy,
+		arg.ExpiresAt,
+	)
+	var i Hold
+	err := row.Scan(
+		&i.ID,
+		&i.AccountID,
+		&i.ToAccountID,
+		&i.Amount,
+		&i.CapturedAmount,
+		&i.Status,
+		&i.CreatedBy,
+		&i.TransferID,
+		&i.ExpiresAt,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+	)
+	return i, err
+}
+
+const expireHolds = `-- name: ExpireHolds :execrows
+UPDATE holds
+SET status = 'expired',
+    updated_at = now()
+WHERE status = 'active' AND expires_at <= $1
+`
+
+func (q *Queries) ExpireHolds(ctx context.Context, expiresAt time.Time) (int64, error) {
+	result, err := q.db.ExecContext(ctx, expireHolds, expiresAt)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const getActiveHoldsAmount = `-- name: GetActiveHoldsAmount :one
+SELECT COALESCE(SUM(amount), 0)::bigint FROM holds
+WHERE account_id = $1 AND status = 'active' AND expires_at > now()
+`
+
+func (q *Queries) GetActiveHoldsAmount(ctx context.Context, accountID int64) (int64, error) {
+	row := q.db.QueryRowContext(ctx, getActiveHoldsAmount, accountID)
+	var column_1 int64
+	err := row.Scan(&column_1)
+	return column_1, err
+}
+
+const getHold = `-- name: GetHold :one
+SELECT id, account_id, to_account_id, amount, captured_amount, status, created_by, transfer_id, expires_at, created_at, updated_at FROM holds
+WHERE id = $1 LIMIT 1
+`
+
+func (q *Queries) GetHold(ctx context.Context, id int64) (Hold, error) {
+	row := q.db.QueryRowContext(ctx, getHold, id)
+	var i Hold
+	err := row.Scan(
+		&i.ID,
+		&i.AccountID,
+		&i.ToAccountID,
+		&i.Amount,
+		&i.CapturedAmount,
+		&i.Status,
+		&i.CreatedBy,
+		&i.TransferID,
+		&i.ExpiresAt,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+	)
+	return i, err
+}
+
+const getHoldForUpdate = `-- name: GetHoldForUpdate :one
+SELECT id, account_id, to_account_id, amount, captured_amount, status, created_by, transfer_id, expires_at, created_at, updated_at FROM holds
+WHERE id = $1 LIMIT 1
+FOR NO KEY UPDATE
+`
+
+func (q *Queries) GetHoldForUpdate(ctx context.Context, id int64) (Hold, error) {
+	row := q.db.QueryRowContext(ctx, getHoldForUpdate, id)
+	var i Hold
+	err := row.Scan(
+		&i.ID,
+		&i.AccountID,
+		&i.ToAccountID,
+		&i.Amount,
+		&i.CapturedAmount,
+		&i.Status,
+		&i.CreatedBy,
+		&i.TransferID,
+		&i.ExpiresAt,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+	)
+	return i, err
+}
+
+const listHolds = `-- name: ListHolds :many
+SELECT id, account_id, to_account_id, amount, captured_amount, status, created_by, transfer_id, expires_at, created_at, updated_at FROM holds
+WHERE account_id = $1
+  AND (created_at, id) > ($2::timestamptz, $3::bigint)
+ORDER BY created_at, id
+LIMIT $4
+`
+
+type ListHoldsParams struct {
+	AccountID       int64     `json:"account_id"`
+	CursorCreatedAt time.Time `json:"cursor_created_at"`
+	CursorID        int64     `json:"cursor_id"`
+	PageSize        int32     `json:"page_size"`
+}
+
+func (q *Queries) ListHolds(ctx context.Context, arg ListHoldsParams) ([]Hold, error) {
+	rows, err := q.db.QueryContext(ctx, listHolds,
+		arg.AccountID,
+		arg.CursorCreatedAt,
+		arg.CursorID,
+		arg.PageSize,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []Hold{}
+	for rows.Next() {
+		var i Hold
+		if err := rows.Scan(
+			&i.ID,
+			&i.AccountID,
+			&i.ToAccountID,
+			&i.Amount,
+			&i.CapturedAmount,
+			&i.Status,
+			&i.CreatedBy,
+			&i.TransferID,
+			&i.ExpiresAt,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const updateHoldStatus = `-- name: UpdateHoldStatus :one
+UPDATE holds
+SET status = $2,
+    captured_amount = $3,
+    transfer_id = $4,
+    updated_at = now()
+WHERE id = $1
+RETURNING id, account_id, to_account_id, amount, captured_amount, status, created_by, transfer_id, expires_at, created_at, updated_at
+`
+
+type UpdateHoldStatusParams struct {
+	ID             int64         `json:"id"`
+	Status         string        `json:"status"`
+	CapturedAmount int64         `json:"captured_amount"`
+	TransferID     sql.NullInt64 `json:"transfer_id"`
+}
+
+func (q *Queries) UpdateHoldStatus(ctx context.Context, arg UpdateHoldStatusParams) (Hold, error) {
+	row := q.db.QueryRowContext(ctx, updateHoldStatus,
+		arg.ID,
+		arg.Status,
+		arg.CapturedAmount,
+		arg.TransferID,
+	)
+	var i Hold
+	err := row.Scan(
+		&i.ID,
+		&i.AccountID,
+		&i.ToAccountID,
+		&i.Amount,
+		&i.CapturedAmount,
+		&i.Status,
+		&i.CreatedBy,
+		&i.TransferID,
+		&i.ExpiresAt,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+	)
+	return i, err
+}
@@ -0,0 +1,145 @@
+package db
+
+import (
+	"context"
+	"github.com/stretchr/testify/require"
+	"github.com/techschool/simplebank/util"
+	"testing"
+	"time"
+)
+
+func createActiveHold(t *testing.T, store Store, account1, account2 Account, amount int64, expiresAt time.Time) Hold {
+	hold, err := store.CreateHoldTx(context.Background(), CreateHoldParams{
+		AccountID: account1.ID,
+		ToAccountID: account2.ID,
+		Amount: amount,
+		CreatedBy: account1.Owner,
+		ExpiresAt: expiresAt,
+	})
+	require.NoError(t, err)
+	require.Equal(t, util.HoldActive, hold.Status)
+	require.Equal(t, amount, hold.Amount)
+	require.Zero(t, hold.CapturedAmount)
+	return hold
+}
+
+func TestHoldAvailableBalance(t *testing.T) {
+	store := NewStore(testDB)
+
+	currency := util.RandomCurrency()
+	account1 := setAccountBalance(t, createAccountWithCurrency(t, currency), 100)
+	account2 := createAccountWithCurrency(t, currency)
+
+	createActiveHold(t, store, account1, account2, 70, time.Now().Add(time.Hour))
+
+	held, err := store.GetActiveHoldsAmount(context.Background(), account1.ID)
+	require.NoError(t, err)
+	require.Equal(t, int64(70), held)
+
+	// 冻结的金额不能用于转账和新的预授权
+	_, err = store.TransferTx(context.Background(), TransferTxParams{
+		FromAccountID: account1.ID,
+		ToAccountID: account2.ID,
+		Amount: 40,
+	})
+	require.ErrorIs(t, err, ErrInsufficientFunds)
+
+	_, err = store.CreateHoldTx(context.Background(), CreateHoldParams{
+		AccountID: account1.ID,
+		ToAccountID: account2.ID,
+		Amount: 40,
+		CreatedBy: account1.Owner,
+		ExpiresAt: time.Now().Add(time.Hour),
+	})
+	require.ErrorIs(t, err, ErrInsufficientFunds)
+
+	_, err = store.TransferTx(context.Background(), TransferTxParams{
+		FromAccountID: account1.ID,
+		ToAccountID: account2.ID,
+		Amount: 30,
+	})
+	require.NoError(t, err)
+}
+
+func TestCaptureHoldTx(t *testing.T) {
+	store := NewStore(testDB)
+
+	currency := util.RandomCurrency()
+	account1 := setAccountBalance(t, createAccountWithCurrency(t, currency), 100)
+	account2 := createAccountWithCurrency(t, currency)
+
+	hold := createActiveHold(t, store, account1, account2, 100, time.Now().Add(time.Hour))
+
+	_, err := store.CaptureHoldTx(context.Background(), CaptureHoldTxParams{HoldID: hold.ID, Amount: 101})
+	require.ErrorIs(t, err, ErrInvalidHold)
+
+	// 部分扣款，剩余的金额被释放
+	result, err := store.CaptureHoldTx(context.Background(), CaptureHoldTxParams{HoldID: hold.ID, Amount: 60})
+	require.NoError(t, err)
+	require.Equal(t, util.HoldCaptured, result.Hold.Status)
+	require.Equal(t, int64(60), result.Hold.CapturedAmount)
+	require.Equal(t, result.Transfer.ID, result.Hold.TransferID.Int64)
+	require.Equal(t, int64(60), result.Transfer.Amount)
+	require.Equal(t, int64(-60), result.FromEntry.Amount)
+	require.Equal(t, int64(60), result.ToEntry.Amount)
+	require.Equal(t, int64(40), result.FromAccount.Balance)
+	require.Equal(t, account2.Balance+60, result.ToAccount.Balance)
+
+	held, err := store.GetActiveHoldsAmount(context.Background(), account1.ID)
+	require.NoError(t, err)
+	require.Zero(t, held)
+
+	_, err = store.CaptureHoldTx(context.Background(), CaptureHoldTxParams{HoldID: hold.ID, Amount: 10})
+	require.ErrorIs(t, err, ErrInvalidHold)
+	_, err = store.VoidHoldTx(context.Background(), hold.ID)
+	require.ErrorIs(t, err, ErrInvalidHold)
+}
+
+func TestVoidHoldTx(t *testing.T) {
+	store := NewStore(testDB)
+
+	currency := util.RandomCurrency()
+	account1 := setAccountBalance(t, createAccountWithCurrency(t, currency), 100)
+	account2 := createAccountWithCurrency(t, currency)
+
+	hold := createActiveHold(t, store, account1, account2, 50, time.Now().Add(time.Hour))
+
+	voided, err := store.VoidHoldTx(context.Background(), hold.ID)
+	require.NoError(t, err)
+	require.Equal(t, util.HoldVoided, voided.Status)
+
+	account, err := store.GetAccount(context.Background(), account1.ID)
+	require.NoError(t, err)
+	require.Equal(t, int64(100), account.Balance)
+
+	held, err := store.GetActiveHoldsAmount(context.Background(), account1.ID)
+	require.NoError(t, err)
+	require.Zero(t, held)
+}
+
+func TestExpireHolds(t *testing.T) {
+	store := NewStore(testDB)
+
+	currency := util.RandomCurrency()
+	account1 := setAccountBalance(t, createAccountWithCurrency(t, currency), 100)
+	account2 := createAccountWithCurrency(t, currency)
+
+	hold := createActiveHold(t, store, account1, account2, 50, time.Now().Add(time.Second))
+	time.Sleep(time.Second)
+
+	// 过期的预授权不再冻结资金，也不能再扣款
+	held, err := store.GetActiveHoldsAmount(context.Background(), account1.ID)
+	require.NoError(t, err)
+	require.Zero(t, held)
+
+	_, err = store.CaptureHoldTx(context.Background(), CaptureHoldTxParams{HoldID: hold.ID, Amount: 50})
+	require.ErrorIs(t, err, ErrInvalidHold)
+
+	count, err := store.ExpireHolds(context.Background(), time.Now())
+	require.NoError(t, err)
+	require.GreaterOrEqual(t, count, int64(1))
+
+	hold, err = store.GetHold(context.Background(), hold.ID)
+	require.NoError(t, err)
+	require.Equal(t, util.HoldExpired, hold.Status)
+}
@@ -0,0 +1,72 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"time"
+)
+
+// ErrIdempotencyKeyReused is returned when an idempotency key is used again with a different request.
+var ErrIdempotencyKeyReused = errors.New("idempotency key has been used by a different request")
+
+// IdempotentTransferTxParams contains the input parameters of idempotent transfer translation.
+type IdempotentTransferTxParams struct {
+	TransferTxParams
+	Username		string		`json:"username"`
+	IdempotencyKey	string		`json:"idempotency_key"`
+	// RequestHash 请求内容的指纹，同一个 key 只能用于相同的请求
+	RequestHash		string		`json:"request_hash"`
+	ExpiresAt		time.Time	`json:"expires_at"`
+}
+
+// IdempotentTransferTx performs TransferTx at most once for the same idempotency key.
+// A replay with the same key and request returns the result of the first transfer,
+// the key and the transfer are committed in the same translation.
+func (store *SQLStore) IdempotentTransferTx(ctx context.Context, arg IdempotentTransferTxParams) (TransferTxResult, error) {
+	var result TransferTxResult
+	err := store.execTx(ctx, func(q *Queries) error {
+		// 并发请求使用同一个 key 时，插入会等待先到的事务结束
+		_, err := q.CreateIdempotencyKey(ctx, CreateIdempotencyKeyParams{
+			Username:		arg.Username,
+			IdempotencyKey:	arg.IdempotencyKey,
+			RequestHash:	arg.RequestHash,
+			ExpiresAt:		arg.ExpiresAt,
+		})
+		if err == sql.ErrNoRows {
+			// key 已经存在且没有过期，返回第一次转账的结果
+			key, err := q.GetIdempotencyKey(ctx, GetIdempotencyKeyParams{
+				Username:		arg.Username,
+				IdempotencyKey:	arg.IdempotencyKey,
+			})
+			if err != nil {
+				return err
+			}
+			if key.RequestHash != arg.RequestHash {
+				return ErrIdempotencyKeyReused
+			}
+			result.Replayed = true
+			return json.Unmarshal(key.Response, &result)
+		}
+		if err != nil {
+			return err
+		}
+
+		result, err = store.transfer(ctx, q, arg.TransferTxParams)
+		if err != nil {
+			return err
+		}
+
+		response, err := json.Marshal(result)
+		if err != nil {
+			return err
+		}
+		return q.UpdateIdempotencyKeyResponse(ctx, UpdateIdempotencyKeyResponseParams{
+			Username:		arg.Username,
+			IdempotencyKey:	arg.IdempotencyKey,
+			Response:		response,
+		})
+	})
+	return result, err
+}
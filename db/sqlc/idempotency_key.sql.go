@@ -0,0 +1,109 @@
+// Code generated by sqlc. DO NOT EDIT.
+// source: idempotency_key.sql
+
+package db
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+)
+
+const createIdempotencyKey = `-- name: CreateIdempotencyKey :one
+INSERT INTO idempotency_keys (
+    username,
+    idempotency_key,
+    request_hash,
+    expires_at
+) VALUES (
+    $1, $2, $3, $4
+) ON CONFLICT (username, idempotency_key) DO UPDATE
+SET request_hash = EXCLUDED.request_hash,
+    response = '{}',
+    created_at = now(),
+    expires_at = EXCLUDED.expires_at
+WHERE idempotency_keys.expires_at <= now()
+RETURNING username, idempotency_key, request_hash, response, created_at, expires_at
+`
+
+type CreateIdempotencyKeyParams struct {
+	Username       string    `json:"username"`
+	IdempotencyKey string    `json:"idempotency_key"`
+	RequestHash    string    `json:"request_hash"`
+	ExpiresAt      time.Time `json:"expires_at"`
+}
+
+// 同一个用户的 key 已经存在且没有过期时不返回任何行，过期的 key 可以重新使用
+func (q *Queries) CreateIdempotencyKey(ctx context.Context, arg CreateIdempotencyKeyParams) (IdempotencyKey, error) {
+	row := q.db.QueryRowContext(ctx, createIdempotencyKey,
+		arg.Username,
+		arg.IdempotencyKey,
+		arg.RequestHash,
+		arg.ExpiresAt,
+	)
+	var i IdempotencyKey
+	err := row.Scan(
+		&i.Username,
+		&i.IdempotencyKey,
+		&i.RequestHash,
+		&i.Response,
+		&i.CreatedAt,
+		&i.ExpiresAt,
+	)
+	return i, err
+}
+
+const deleteExpiredIdempotencyKeys = `-- name: DeleteExpiredIdempotencyKeys :execrows
+DELETE FROM idempotency_keys
+WHERE expires_at <= $1
+`
+
+// 过期的 key 已经可以重新使用，删除后不影响重放
+func (q *Queries) DeleteExpiredIdempotencyKeys(ctx context.Context, expiresAt time.Time) (int64, error) {
+	result, err := q.db.ExecContext(ctx, deleteExpiredIdempotencyKeys, expiresAt)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const getIdempotencyKey = `-- name: GetIdempotencyKey :one
+SELECT username, idempotency_key, request_hash, response, created_at, expires_at FROM idempotency_keys
+WHERE username = $1 AND idempotency_key = $2 LIMIT 1
+`
+
+type GetIdempotencyKeyParams struct {
+	Username       string `json:"username"`
+	IdempotencyKey string `json:"idempotency_key"`
+}
+
+func (q *Queries) GetIdempotencyKey(ctx context.Context, arg GetIdempotencyKeyParams) (IdempotencyKey, error) {
+	row := q.db.QueryRowContext(ctx, getIdempotencyKey, arg.Username, arg.IdempotencyKey)
+	var i IdempotencyKey
+	err := row.Scan(
+		&i.Username,
+		&i.IdempotencyKey,
+		&i.RequestHash,
+		&i.Response,
+		&i.CreatedAt,
+		&i.ExpiresAt,
+	)
+	return i, err
+}
+
+const updateIdempotencyKeyResponse = `-- name: UpdateIdempotencyKeyResponse :exec
+UPDATE idempotency_keys
+SET response = $3
+WHERE username = $1 AND idempotency_key = $2
+`
+
+type UpdateIdempotencyKeyResponseParams struct {
+	Username       string          `json:"username"`
+	IdempotencyKey string          `json:"idempotency_key"`
+	Response       json.RawMessage `json:"response"`
+}
+
+func (q *Queries) UpdateIdempotencyKeyResponse(ctx context.Context, arg UpdateIdempotencyKeyResponseParams) error {
+	_, err := q.db.ExecContext(ctx, updateIdempotencyKeyResponse, arg.Username, arg.IdempotencyKey, arg.Response)
+	return err
+}
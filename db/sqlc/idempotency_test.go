@@ -0,0 +1,130 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"github.com/stretchr/testify/require"
+	"github.com/techschool/simplebank/util"
+	"testing"
+	"time"
+)
+
+func randomIdempotentTransferParams(t *testing.T) IdempotentTransferTxParams {
+	user := createRandomUser(t)
+	currency := util.RandomCurrency()
+	account1 := createAccountWithCurrency(t, currency)
+	account2 := createAccountWithCurrency(t, currency)
+
+	return IdempotentTransferTxParams{
+		TransferTxParams: TransferTxParams{
+			FromAccountID: account1.ID,
+			ToAccountID: account2.ID,
+			Amount: 10,
+		},
+		Username: user.Username,
+		IdempotencyKey: util.RandomString(32),
+		RequestHash: util.RandomString(64),
+		ExpiresAt: time.Now().Add(time.Hour),
+	}
+}
+
+func TestIdempotentTransferTx(t *testing.T) {
+	store := NewStore(testDB)
+	arg := randomIdempotentTransferParams(t)
+
+	result1, err := store.IdempotentTransferTx(context.Background(), arg)
+	require.NoError(t, err)
+	require.NotZero(t, result1.Transfer.ID)
+	require.False(t, result1.Replayed)
+
+	// 重放请求返回第一次的结果，不会再次转账
+	result2, err := store.IdempotentTransferTx(context.Background(), arg)
+	require.NoError(t, err)
+	require.True(t, result2.Replayed)
+	require.Equal(t, result1.Transfer.ID, result2.Transfer.ID)
+	require.Equal(t, result1.FromAccount.Balance, result2.FromAccount.Balance)
+
+	account1, err := testQueries.GetAccount(context.Background(), arg.FromAccountID)
+	require.NoError(t, err)
+	require.Equal(t, result1.FromAccount.Balance, account1.Balance)
+
+	// 同一个 key 用于不同的请求
+	arg.RequestHash = util.RandomString(64)
+	_, err = store.IdempotentTransferTx(context.Background(), arg)
+	require.ErrorIs(t, err, ErrIdempotencyKeyReused)
+}
+
+func TestIdempotentTransferTxConcurrent(t *testing.T) {
+	store := NewStore(testDB)
+	arg := randomIdempotentTransferParams(t)
+
+	n := 5
+	errs := make(chan error)
+	results := make(chan TransferTxResult)
+	for i:=0; i<n; i++ {
+		go func() {
+			result, err := store.IdempotentTransferTx(context.Background(), arg)
+			errs <- err
+			results <- result
+		}()
+	}
+
+	var transferID int64
+	for i:=0; i<n; i++ {
+		err := <-errs
+		require.NoError(t, err)
+		result := <-results
+		if transferID == 0 {
+			transferID = result.Transfer.ID
+		}
+		require.Equal(t, transferID, result.Transfer.ID)
+	}
+
+	transfers := listAccountTransfers(t, arg.FromAccountID, int32(n))
+	require.Len(t, transfers, 1)
+}
+
+func TestIdempotentTransferTxExpiredKey(t *testing.T) {
+	store := NewStore(testDB)
+	arg := randomIdempotentTransferParams(t)
+	arg.ExpiresAt = time.Now().Add(-time.Second)
+
+	result1, err := store.IdempotentTransferTx(context.Background(), arg)
+	require.NoError(t, err)
+
+	// 过期的 key 可以用于新的请求
+	arg.RequestHash = util.RandomString(64)
+	arg.ExpiresAt = time.Now().Add(time.Hour)
+	result2, err := store.IdempotentTransferTx(context.Background(), arg)
+	require.NoError(t, err)
+	require.NotEqual(t, result1.Transfer.ID, result2.Transfer.ID)
+}
+
+func TestDeleteExpiredIdempotencyKeys(t *testing.T) {
+	store := NewStore(testDB)
+	expired := randomIdempotentTransferParams(t)
+	expired.ExpiresAt = time.Now().Add(-time.Second)
+	_, err := store.IdempotentTransferTx(context.Background(), expired)
+	require.NoError(t, err)
+
+	active := randomIdempotentTransferParams(t)
+	_, err = store.IdempotentTransferTx(context.Background(), active)
+	require.NoError(t, err)
+
+	count, err := testQueries.DeleteExpiredIdempotencyKeys(context.Background(), time.Now())
+	require.NoError(t, err)
+	require.GreaterOrEqual(t, count, int64(1))
+
+	_, err = testQueries.GetIdempotencyKey(context.Background(), GetIdempotencyKeyParams{
+		Username:		expired.Username,
+		IdempotencyKey:	expired.IdempotencyKey,
+	})
+	require.ErrorIs(t, err, sql.ErrNoRows)
+
+	// 没有过期的 key 保留，重放请求仍然返回第一次的结果
+	_, err = testQueries.GetIdempotencyKey(context.Background(), GetIdempotencyKeyParams{
+		Username:		active.Username,
+		IdempotencyKey:	active.IdempotencyKey,
+	})
+	require.NoError(t, err)
+}
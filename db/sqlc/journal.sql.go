@@ -0,0 +1,103 @@
+// Code generated by sqlc. DO NOT EDIT.
+// source: journal.sql
+
+package db
+
+import (
+	"context"
+	"database/sql"
+)
+
+const createJournal = `-- name: CreateJournal :one
+INSERT INTO journals (
+    kind,
+    memo,
+    transfer_id,
+    created_by
+) VALUES (
+    $1, $2, $3, $4
+) RETURNING id, kind, memo, transfer_id, created_by, created_at
+`
+
+type CreateJournalParams struct {
+	Kind       string        `json:"kind"`
+	Memo       string        `json:"memo"`
+	TransferID sql.NullInt64 `json:"transfer_id"`
+	CreatedBy  string        `json:"created_by"`
+}
+
+func (q *Queries) CreateJournal(ctx context.Context, arg CreateJournalParams) (Journal, error) {
+	row := q.db.QueryRowContext(ctx, createJournal,
+		arg.Kind,
+		arg.Memo,
+		arg.TransferID,
+		arg.CreatedBy,
+	)
+	var i Journal
+	err := row.Scan(
+		&i.ID,
+		&i.Kind,
+		&i.Memo,
+		&i.TransferID,
+		&i.CreatedBy,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
+const getJournal = `-- name: GetJournal :one
+SELECT id, kind, memo, transfer_id, created_by, created_at FROM journals
+WHERE id = $1 LIMIT 1
+`
+
+func (q *Queries) GetJournal(ctx context.Context, id int64) (Journal, error) {
+	row := q.db.QueryRowContext(ctx, getJournal, id)
+	var i Journal
+	err := row.Scan(
+		&i.ID,
+		&i.Kind,
+		&i.Memo,
+		&i.TransferID,
+		&i.CreatedBy,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
+const listJournalEntries = `-- name: ListJournalEntries :many
+SELECT id, account_id, amount, created_at, transfer_id, journal_id, prev_hash, hash FROM entries
+WHERE journal_id = $1::bigint
+ORDER BY id
+`
+
+func (q *Queries) ListJournalEntries(ctx context.Context, journalID int64) ([]Entry, error) {
+	rows, err := q.db.QueryContext(ctx, listJournalEntries, journalID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []Entry{}
+	for rows.Next() {
+		var i Entry
+		if err := rows.Scan(
+			&i.ID,
+			&i.AccountID,
+			&i.Amount,
+			&i.CreatedAt,
+			&i.TransferID,
+			&i.JournalID,
+			&i.PrevHash,
+			&i.Hash,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
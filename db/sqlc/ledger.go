@@ -0,0 +1,268 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"github.com/techschool/simplebank/util"
+	"sort"
+	"time"
+)
+
+// ErrUnbalancedJournal is returned when the legs of a journal don't sum to zero in every currency.
+var ErrUnbalancedJournal = errors.New("unbalanced journal")
+
+// ErrSystemAccount is returned when a customer operation is requested on an internal ledger account.
+var ErrSystemAccount = errors.New("operation is not allowed on a system account")
+
+// JournalLeg moves money in or out of one account, a positive amount increases the balance.
+type JournalLeg struct {
+	AccountID	int64	`json:"account_id"`
+	Amount		int64	`json:"amount"`
+}
+
+// PostJournalTxParams contains the input parameters of the post journal translation.
+type PostJournalTxParams struct {
+	Kind		string			`json:"kind"`
+	Memo		string			`json:"memo"`
+	TransferID	sql.NullInt64	`json:"transfer_id"`
+	CreatedBy	string			`json:"created_by"`
+	Legs		[]JournalLeg	`json:"legs"`
+}
+
+// PostJournalTxResult is the result of the post journal translation, entries and accounts are in the order of the legs.
+type PostJournalTxResult struct {
+	Journal		Journal		`json:"journal"`
+	Entries		[]Entry		`json:"entries"`
+	Accounts	[]Account	`json:"accounts"`
+}
+
+// PostJournalTx records a journal entry and updates the balance of every account of its legs within a single translation.
+func (store *SQLStore) PostJournalTx(ctx context.Context, arg PostJournalTxParams) (PostJournalTxResult, error) {
+	var result PostJournalTxResult
+	err := store.execTx(ctx, func(q *Queries) error {
+		var err error
+		result, err = postJournal(ctx, q, arg)
+		return err
+	})
+	return result, err
+}
+
+// postJournal 创建分录和每一条流水并更新账户余额，各个币种的流水之和不为 0 时返回 ErrUnbalancedJournal，事务会被回滚
+func postJournal(ctx context.Context, q *Queries, arg PostJournalTxParams) (PostJournalTxResult, error) {
+	var result PostJournalTxResult
+	if len(arg.Legs) < 2 {
+		return result, fmt.Errorf("%w: a journal needs at least two legs", ErrUnbalancedJournal)
+	}
+	for _, leg := range arg.Legs {
+		if leg.Amount == 0 {
+			return result, fmt.Errorf("%w: the amount of account [%d] is zero", ErrUnbalancedJournal, leg.AccountID)
+		}
+	}
+
+	var err error
+	result.Journal, err = q.CreateJournal(ctx, CreateJournalParams{
+		Kind:		arg.Kind,
+		Memo:		arg.Memo,
+		TransferID:	arg.TransferID,
+		CreatedBy:	arg.CreatedBy,
+	})
+	if err != nil {
+		return result, err
+	}
+
+	// 按账户 ID 的顺序锁定账户，避免死锁，锁定后读取账户最后一条流水的哈希，新的流水与之串联
+	order := make([]int, len(arg.Legs))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(i, j int) bool {
+		return arg.Legs[order[i]].AccountID < arg.Legs[order[j]].AccountID
+	})
+
+	heads := make(map[int64]string, len(arg.Legs))
+	for _, i := range order {
+		accountID := arg.Legs[i].AccountID
+		if _, ok := heads[accountID]; ok {
+			continue
+		}
+		account, err := q.GetAccountForUpdate(ctx, accountID)
+		if err != nil {
+			return result, err
+		}
+		heads[accountID] = account.EntryHash
+	}
+
+	// 创建时间精确到微秒，与数据库保存的精度一致，哈希才能被重新计算
+	postedAt := time.Now().UTC().Truncate(time.Microsecond)
+	result.Entries = make([]Entry, len(arg.Legs))
+	for i, leg := range arg.Legs {
+		hash := util.EntryHash(leg.AccountID, leg.Amount, arg.TransferID.Int64, result.Journal.ID, postedAt, heads[leg.AccountID])
+		result.Entries[i], err = q.CreateChainedEntry(ctx, CreateChainedEntryParams{
+			AccountID:	leg.AccountID,
+			Amount:		leg.Amount,
+			TransferID:	arg.TransferID,
+			JournalID:	sql.NullInt64{Int64: result.Journal.ID, Valid: true},
+			PrevHash:	heads[leg.AccountID],
+			Hash:		hash,
+			CreatedAt:	postedAt,
+		})
+		if err != nil {
+			return result, err
+		}
+		heads[leg.AccountID] = hash
+	}
+
+	result.Accounts = make([]Account, len(arg.Legs))
+	totals := make(map[string]int64)
+	for _, i := range order {
+		result.Accounts[i], err = q.AddAccountLedgerBalance(ctx, AddAccountLedgerBalanceParams{
+			ID:			arg.Legs[i].AccountID,
+			Amount:		arg.Legs[i].Amount,
+			EntryHash:	heads[arg.Legs[i].AccountID],
+		})
+		if err != nil {
+			return result, err
+		}
+		totals[result.Accounts[i].Currency] += arg.Legs[i].Amount
+	}
+
+	for currency, total := range totals {
+		if total != 0 {
+			return result, fmt.Errorf("%w: legs of %s sum to %d", ErrUnbalancedJournal, currency, total)
+		}
+	}
+	return result, nil
+}
+
+// transferLegs 同币种转账有转出和转入两条流水，跨币种转账另外通过兑换账户在两个币种内分别平衡
+func transferLegs(ctx context.Context, q *Queries, transfer Transfer) ([]JournalLeg, error) {
+	legs := []JournalLeg{
+		{AccountID: transfer.FromAccountID, Amount: -transfer.Amount},
+		{AccountID: transfer.ToAccountID, Amount: transfer.ToAmount},
+	}
+	if !transfer.ExchangeRateID.Valid {
+		return legs, nil
+	}
+
+	fromExchange, err := exchangeAccount(ctx, q, transfer.FromAccountID)
+	if err != nil {
+		return nil, err
+	}
+	toExchange, err := exchangeAccount(ctx, q, transfer.ToAccountID)
+	if err != nil {
+		return nil, err
+	}
+	return append(legs,
+		JournalLeg{AccountID: fromExchange.ID, Amount: transfer.Amount},
+		JournalLeg{AccountID: toExchange.ID, Amount: -transfer.ToAmount},
+	), nil
+}
+
+// exchangeAccount 查询与账户币种相同的兑换账户
+func exchangeAccount(ctx context.Context, q *Queries, accountID int64) (Account, error) {
+	account, err := q.GetAccount(ctx, accountID)
+	if err != nil {
+		return account, err
+	}
+	return systemAccount(ctx, q, util.SystemExchange, account.Currency)
+}
+
+// EnsureSystemAccounts creates the missing system accounts of every enabled currency in the registry,
+// it returns the number of accounts created.
+func (store *SQLStore) EnsureSystemAccounts(ctx context.Context) (int64, error) {
+	var created int64
+	for _, currency := range util.SupportedCurrencies() {
+		for _, code := range util.SystemCodes {
+			count, err := store.CreateSystemAccount(ctx, CreateSystemAccountParams{
+				Currency:	currency.Code,
+				SystemCode:	code,
+			})
+			if err != nil {
+				return created, fmt.Errorf("cannot create %s system account of %s: %w", code, currency.Code, err)
+			}
+			created += count
+		}
+	}
+	return created, nil
+}
+
+func systemAccount(ctx context.Context, q *Queries, code, currency string) (Account, error) {
+	account, err := q.GetSystemAccount(ctx, GetSystemAccountParams{
+		SystemCode:	code,
+		Currency:	currency,
+	})
+	if err == sql.ErrNoRows {
+		return account, fmt.Errorf("no %s system account of %s: %w", code, currency, err)
+	}
+	return account, err
+}
+
+// CashTxParams contains the input parameters of deposit and withdrawal translations.
+type CashTxParams struct {
+	AccountID	int64	`json:"account_id"`
+	Amount		int64	`json:"amount"`
+	Memo		string	`json:"memo"`
+	CreatedBy	string	`json:"created_by"`
+}
+
+// CashTxResult is the result of deposit and withdrawal translations.
+type CashTxResult struct {
+	Journal	Journal	`json:"journal"`
+	Account	Account	`json:"account"`
+	Entry	Entry	`json:"entry"`
+}
+
+// DepositTx adds cash to a customer account, the cash account of the currency is debited by the same amount.
+func (store *SQLStore) DepositTx(ctx context.Context, arg CashTxParams) (CashTxResult, error) {
+	return store.cashTx(ctx, util.JournalDeposit, arg, arg.Amount)
+}
+
+// WithdrawTx takes cash out of a customer account, the available balance must cover the amount.
+func (store *SQLStore) WithdrawTx(ctx context.Context, arg CashTxParams) (CashTxResult, error) {
+	return store.cashTx(ctx, util.JournalWithdrawal, arg, -arg.Amount)
+}
+
+// cashTx 客户账户与现金账户之间的资金变动，amount 为客户账户余额的变化
+func (store *SQLStore) cashTx(ctx context.Context, kind string, arg CashTxParams, amount int64) (CashTxResult, error) {
+	var result CashTxResult
+	if arg.Amount <= 0 {
+		return result, fmt.Errorf("%w: amount %d must be positive", ErrUnbalancedJournal, arg.Amount)
+	}
+
+	err := store.execTx(ctx, func(q *Queries) error {
+		account, err := q.GetAccount(ctx, arg.AccountID)
+		if err != nil {
+			return err
+		}
+		if account.SystemCode != "" {
+			return fmt.Errorf("%w: account [%d] is the %s account", ErrSystemAccount, account.ID, account.SystemCode)
+		}
+		cash, err := systemAccount(ctx, q, util.SystemCash, account.Currency)
+		if err != nil {
+			return err
+		}
+
+		posted, err := postJournal(ctx, q, PostJournalTxParams{
+			Kind:		kind,
+			Memo:		arg.Memo,
+			CreatedBy:	arg.CreatedBy,
+			Legs: []JournalLeg{
+				{AccountID: account.ID, Amount: amount},
+				{AccountID: cash.ID, Amount: -amount},
+			},
+		})
+		if err != nil {
+			return err
+		}
+		result = CashTxResult{Journal: posted.Journal, Account: posted.Accounts[0], Entry: posted.Entries[0]}
+
+		if amount > 0 {
+			return nil
+		}
+		// 取款时账户的行锁在事务结束前一直持有，此时检查可用余额不会有并发问题
+		return store.checkBalance(ctx, q, result.Account)
+	})
+	return result, err
+}
@@ -0,0 +1,146 @@
+package db
+
+import (
+	"context"
+	"github.com/stretchr/testify/require"
+	"github.com/techschool/simplebank/util"
+	"testing"
+)
+
+func findSystemAccount(t *testing.T, code, currency string) Account {
+	account, err := testQueries.GetSystemAccount(context.Background(), GetSystemAccountParams{
+		SystemCode: code,
+		Currency: currency,
+	})
+	require.NoError(t, err)
+	return account
+}
+
+func TestEnsureSystemAccounts(t *testing.T) {
+	store := NewStore(testDB)
+	_, err := store.EnsureSystemAccounts(context.Background())
+	require.NoError(t, err)
+
+	// 已经存在的系统账户不会重复创建
+	created, err := store.EnsureSystemAccounts(context.Background())
+	require.NoError(t, err)
+	require.Zero(t, created)
+
+	// 注册表中启用的每个币种都有全部的系统账户
+	for _, currency := range util.SupportedCurrencies() {
+		for _, code := range util.SystemCodes {
+			account := findSystemAccount(t, code, currency.Code)
+			require.Equal(t, util.SystemUsername, account.Owner)
+		}
+	}
+}
+
+func TestDepositAndWithdrawTx(t *testing.T) {
+	store := NewStore(testDB)
+	account := setAccountBalance(t, createAccountWithCurrency(t, util.EUR), 0)
+	cash := findSystemAccount(t, util.SystemCash, util.EUR)
+
+	deposit, err := store.DepositTx(context.Background(), CashTxParams{
+		AccountID: account.ID,
+		Amount: 100,
+		Memo: "cash deposit",
+		CreatedBy: account.Owner,
+	})
+	require.NoError(t, err)
+	require.Equal(t, util.JournalDeposit, deposit.Journal.Kind)
+	require.Equal(t, int64(100), deposit.Account.Balance)
+	require.Equal(t, int64(100), deposit.Entry.Amount)
+	require.Equal(t, deposit.Journal.ID, deposit.Entry.JournalID.Int64)
+
+	// 分录的两条流水之和为 0
+	entries, err := testQueries.ListJournalEntries(context.Background(), deposit.Journal.ID)
+	require.NoError(t, err)
+	require.Len(t, entries, 2)
+	require.Equal(t, cash.ID, entries[1].AccountID)
+	require.Zero(t, entries[0].Amount+entries[1].Amount)
+
+	_, err = store.WithdrawTx(context.Background(), CashTxParams{AccountID: account.ID, Amount: 101})
+	require.ErrorIs(t, err, ErrInsufficientFunds)
+
+	withdrawal, err := store.WithdrawTx(context.Background(), CashTxParams{AccountID: account.ID, Amount: 60})
+	require.NoError(t, err)
+	require.Equal(t, util.JournalWithdrawal, withdrawal.Journal.Kind)
+	require.Equal(t, int64(40), withdrawal.Account.Balance)
+	require.Equal(t, int64(-60), withdrawal.Entry.Amount)
+
+	// 失败的取款已经回滚，现金账户只变动了存款和取款的差额
+	requireBalance(t, cash, cash.Balance-40)
+
+	_, err = store.DepositTx(context.Background(), CashTxParams{AccountID: cash.ID, Amount: 10})
+	require.ErrorIs(t, err, ErrSystemAccount)
+}
+
+func TestPostJournalTxUnbalanced(t *testing.T) {
+	store := NewStore(testDB)
+	account1 := createAccountWithCurrency(t, util.USD)
+	account2 := createAccountWithCurrency(t, util.USD)
+	account3 := createAccountWithCurrency(t, util.EUR)
+
+	testCases := [][]JournalLeg{
+		{{AccountID: account1.ID, Amount: 10}},
+		{{AccountID: account1.ID, Amount: 10}, {AccountID: account2.ID, Amount: -9}},
+		{{AccountID: account1.ID, Amount: 10}, {AccountID: account3.ID, Amount: -10}},
+		{{AccountID: account1.ID, Amount: 0}, {AccountID: account2.ID, Amount: 0}},
+	}
+	for _, legs := range testCases {
+		_, err := store.PostJournalTx(context.Background(), PostJournalTxParams{Kind: util.JournalTransfer, Legs: legs})
+		require.ErrorIs(t, err, ErrUnbalancedJournal)
+	}
+
+	// 不平衡的分录不会改变任何账户的余额
+	requireBalance(t, account1, account1.Balance)
+	requireBalance(t, account2, account2.Balance)
+	requireBalance(t, account3, account3.Balance)
+
+	result, err := store.PostJournalTx(context.Background(), PostJournalTxParams{
+		Kind: util.JournalTransfer,
+		Legs: []JournalLeg{{AccountID: account1.ID, Amount: 10}, {AccountID: account2.ID, Amount: -10}},
+	})
+	require.NoError(t, err)
+	require.Len(t, result.Entries, 2)
+	require.Equal(t, account1.Balance+10, result.Accounts[0].Balance)
+	require.Equal(t, account2.Balance-10, result.Accounts[1].Balance)
+}
+
+func TestTransferTxExchangeLegs(t *testing.T) {
+	store := NewStore(testDB)
+	account1 := setAccountBalance(t, createAccountWithCurrency(t, util.USD), 1000)
+	account2 := createAccountWithCurrency(t, util.CAD)
+	exchangeRate := createRandomExchangeRate(t, util.USD, util.CAD, "1.35")
+	usdExchange := findSystemAccount(t, util.SystemExchange, util.USD)
+	cadExchange := findSystemAccount(t, util.SystemExchange, util.CAD)
+
+	result, err := store.TransferTx(context.Background(), TransferTxParams{
+		FromAccountID: account1.ID,
+		ToAccountID: account2.ID,
+		Amount: 100,
+		ExchangeRateID: exchangeRate.ID,
+	})
+	require.NoError(t, err)
+	require.Equal(t, int64(135), result.Transfer.ToAmount)
+
+	// 兑换账户在两个币种内分别平衡分录
+	entries, err := testQueries.ListJournalEntries(context.Background(), result.FromEntry.JournalID.Int64)
+	require.NoError(t, err)
+	require.Len(t, entries, 4)
+	require.Equal(t, usdExchange.ID, entries[2].AccountID)
+	require.Equal(t, int64(100), entries[2].Amount)
+	require.Equal(t, cadExchange.ID, entries[3].AccountID)
+	require.Equal(t, int64(-135), entries[3].Amount)
+	for _, entry := range entries {
+		require.Equal(t, result.Transfer.ID, entry.TransferID.Int64)
+	}
+
+	// 系统账户不能作为转账的账户
+	_, err = store.TransferTx(context.Background(), TransferTxParams{
+		FromAccountID: account1.ID,
+		ToAccountID: usdExchange.ID,
+		Amount: 10,
+	})
+	require.ErrorIs(t, err, ErrSystemAccount)
+}
@@ -0,0 +1,170 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"github.com/techschool/simplebank/util"
+	"strings"
+	"time"
+)
+
+// ErrLimitExceeded is returned when a transfer would exceed a transfer limit, the error is a *LimitExceededError.
+var ErrLimitExceeded = errors.New("transfer limit exceeded")
+
+// 限额的作用范围
+const (
+	LimitScopeDefault	= "default"
+	LimitScopeUser		= "user"
+	LimitScopeAccount	= "account"
+)
+
+// LimitExceededError describes the transfer limit that is exceeded and the remaining allowance of the window.
+// RemainingCount or RemainingAmount is nil if the limit has no restriction on it.
+type LimitExceededError struct {
+	LimitID			int64	`json:"limit_id"`
+	Scope			string	`json:"scope"`
+	Period			string	`json:"period"`
+	Currency		string	`json:"currency"`
+	RemainingCount	*int64	`json:"remaining_count,omitempty"`
+	RemainingAmount	*int64	`json:"remaining_amount,omitempty"`
+}
+
+func (e *LimitExceededError) Error() string {
+	var remaining []string
+	if e.RemainingCount != nil {
+		remaining = append(remaining, fmt.Sprintf("%d transfers", *e.RemainingCount))
+	}
+	if e.RemainingAmount != nil {
+		remaining = append(remaining, util.FormatAmount(*e.RemainingAmount, e.Currency))
+	}
+	return fmt.Sprintf("%v: %s %s limit [%d] allows %s", ErrLimitExceeded, e.Period, e.Scope, e.LimitID,
+		strings.Join(remaining, " and "))
+}
+
+// Is makes errors.Is(err, ErrLimitExceeded) true
+func (e *LimitExceededError) Is(target error) bool {
+	return target == ErrLimitExceeded
+}
+
+// LimitScope returns the scope of the transfer limit
+func LimitScope(limit TransferLimit) string {
+	switch {
+	case limit.AccountID.Valid:
+		return LimitScopeAccount
+	case limit.Owner.Valid:
+		return LimitScopeUser
+	}
+	return LimitScopeDefault
+}
+
+// checkTransferLimits returns a *LimitExceededError if the transfer would exceed any limit of the from account.
+// The account limit takes the place of the default limit of the same period, the user limit applies to
+// all accounts of the user in the currency. The owner of the from account is locked, so concurrent transfers
+// of the user are checked one by one against the transfers history.
+func checkTransferLimits(ctx context.Context, q *Queries, arg TransferTxParams) error {
+	fromAccount, err := q.GetAccount(ctx, arg.FromAccountID)
+	if err != nil {
+		return err
+	}
+
+	limits, err := q.ListApplicableTransferLimits(ctx, ListApplicableTransferLimitsParams{
+		AccountID:	sql.NullInt64{Int64: fromAccount.ID, Valid: true},
+		Owner:		sql.NullString{String: fromAccount.Owner, Valid: true},
+		Currency:	fromAccount.Currency,
+	})
+	if err != nil {
+		return err
+	}
+	limits = effectiveLimits(limits)
+	if len(limits) == 0 {
+		return nil
+	}
+
+	_, err = q.GetUserForUpdate(ctx, fromAccount.Owner)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	for _, limit := range limits {
+		window, ok := util.LimitWindow(limit.Period)
+		if !ok {
+			return fmt.Errorf("transfer limit [%d] has unknown period %s", limit.ID, limit.Period)
+		}
+
+		var count, amount int64
+		if LimitScope(limit) == LimitScopeUser {
+			usage, err := q.GetUserTransferUsage(ctx, GetUserTransferUsageParams{
+				Owner:		fromAccount.Owner,
+				Currency:	fromAccount.Currency,
+				Since:		now.Add(-window),
+			})
+			if err != nil {
+				return err
+			}
+			count, amount = usage.Count, usage.Amount
+		} else {
+			usage, err := q.GetAccountTransferUsage(ctx, GetAccountTransferUsageParams{
+				AccountID:	fromAccount.ID,
+				Since:		now.Add(-window),
+			})
+			if err != nil {
+				return err
+			}
+			count, amount = usage.Count, usage.Amount
+		}
+
+		countExceeded := limit.MaxCount > 0 && count+1 > int64(limit.MaxCount)
+		amountExceeded := limit.MaxAmount > 0 && amount+arg.Amount > limit.MaxAmount
+		if countExceeded || amountExceeded {
+			return newLimitExceededError(limit, count, amount)
+		}
+	}
+	return nil
+}
+
+// effectiveLimits 同一周期有账户限额时不再使用默认限额
+func effectiveLimits(limits []TransferLimit) []TransferLimit {
+	accountPeriods := make(map[string]bool)
+	for _, limit := range limits {
+		if LimitScope(limit) == LimitScopeAccount {
+			accountPeriods[limit.Period] = true
+		}
+	}
+
+	result := make([]TransferLimit, 0, len(limits))
+	for _, limit := range limits {
+		if LimitScope(limit) == LimitScopeDefault && accountPeriods[limit.Period] {
+			continue
+		}
+		result = append(result, limit)
+	}
+	return result
+}
+
+func newLimitExceededError(limit TransferLimit, count, amount int64) *LimitExceededError {
+	err := &LimitExceededError{
+		LimitID:	limit.ID,
+		Scope:		LimitScope(limit),
+		Period:		limit.Period,
+		Currency:	limit.Currency,
+	}
+	if limit.MaxCount > 0 {
+		remaining := maxInt64(int64(limit.MaxCount)-count, 0)
+		err.RemainingCount = &remaining
+	}
+	if limit.MaxAmount > 0 {
+		remaining := maxInt64(limit.MaxAmount-amount, 0)
+		err.RemainingAmount = &remaining
+	}
+	return err
+}
+
+func maxInt64(a, b int64) int64 {
+	if a > b {
+		return a
+	}
+	return b
+}
@@ -1,6 +1,7 @@
 package db
 
 import (
+	"context"
 	"database/sql"
 	_ "github.com/lib/pq"
 	"github.com/techschool/simplebank/util"
@@ -24,5 +25,11 @@ func TestMain(m *testing.M) {
 	}
 	testQueries = New(testDB)
 
+	// 测试使用注册表中所有启用的币种，确保都有系统账户
+	_, err = NewStore(testDB).EnsureSystemAccounts(context.Background())
+	if err != nil {
+		log.Fatal("cannot create system accounts: ", err)
+	}
+
 	os.Exit(m.Run())
 }
\ No newline at end of file
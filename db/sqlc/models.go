@@ -3,7 +3,11 @@
 package db
 
 import (
+	"database/sql"
+	"encoding/json"
 	"time"
+
+	"github.com/google/uuid"
 )
 
 type Account struct {
@@ -12,6 +16,18 @@ type Account struct {
 	Balance   int64     `json:"balance"`
 	Currency  string    `json:"currency"`
 	CreatedAt time.Time `json:"created_at"`
+	// cash, fees, interest_expense, suspense or exchange for system accounts, empty for customer accounts
+	SystemCode string `json:"system_code"`
+	// hash of the last entry of the account
+	EntryHash string `json:"entry_hash"`
+}
+
+type BalanceSnapshot struct {
+	AccountID  int64     `json:"account_id"`
+	SnapshotAt time.Time `json:"snapshot_at"`
+	// sum of the entries of the account created before snapshot_at
+	Balance   int64     `json:"balance"`
+	CreatedAt time.Time `json:"created_at"`
 }
 
 type Entry struct {
@@ -20,6 +36,167 @@ type Entry struct {
 	// can be negative or positive
 	Amount    int64     `json:"amount"`
 	CreatedAt time.Time `json:"created_at"`
+	// the transfer that created the entry
+	TransferID sql.NullInt64 `json:"transfer_id"`
+	JournalID  sql.NullInt64 `json:"journal_id"`
+	PrevHash   string        `json:"prev_hash"`
+	// sha256 of the entry content chained to prev_hash, see util.EntryHash
+	Hash string `json:"hash"`
+}
+
+type ExchangeRate struct {
+	ID            int64  `json:"id"`
+	BaseCurrency  string `json:"base_currency"`
+	QuoteCurrency string `json:"quote_currency"`
+	// 1 unit of base currency = rate units of quote currency
+	Rate      string    `json:"rate"`
+	CreatedBy string    `json:"created_by"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type FraudDecision struct {
+	ID            int64  `json:"id"`
+	Username      string `json:"username"`
+	FromAccountID int64  `json:"from_account_id"`
+	ToAccountID   int64  `json:"to_account_id"`
+	Amount        int64  `json:"amount"`
+	Currency      string `json:"currency"`
+	// allow, review or block
+	Decision string `json:"decision"`
+	// the matched rule, empty if no rule matches
+	Rule       string          `json:"rule"`
+	Reason     string          `json:"reason"`
+	Metadata   json.RawMessage `json:"metadata"`
+	TransferID sql.NullInt64   `json:"transfer_id"`
+	CreatedAt  time.Time       `json:"created_at"`
+}
+
+type Hold struct {
+	ID          int64 `json:"id"`
+	AccountID   int64 `json:"account_id"`
+	ToAccountID int64 `json:"to_account_id"`
+	// must be positive
+	Amount         int64 `json:"amount"`
+	CapturedAmount int64 `json:"captured_amount"`
+	// active, captured, voided or expired
+	Status    string `json:"status"`
+	CreatedBy string `json:"created_by"`
+	// the transfer that settled the captured amount
+	TransferID sql.NullInt64 `json:"transfer_id"`
+	ExpiresAt  time.Time     `json:"expires_at"`
+	CreatedAt  time.Time     `json:"created_at"`
+	UpdatedAt  time.Time     `json:"updated_at"`
+}
+
+type IdempotencyKey struct {
+	Username       string          `json:"username"`
+	IdempotencyKey string          `json:"idempotency_key"`
+	RequestHash    string          `json:"request_hash"`
+	Response       json.RawMessage `json:"response"`
+	CreatedAt      time.Time       `json:"created_at"`
+	ExpiresAt      time.Time       `json:"expires_at"`
+}
+
+type Journal struct {
+	ID int64 `json:"id"`
+	// transfer, reversal, deposit, withdrawal or opening_balance
+	Kind       string        `json:"kind"`
+	Memo       string        `json:"memo"`
+	TransferID sql.NullInt64 `json:"transfer_id"`
+	CreatedBy  string        `json:"created_by"`
+	CreatedAt  time.Time     `json:"created_at"`
+}
+
+type ReconciliationDiscrepancy struct {
+	ID       int64 `json:"id"`
+	ReportID int64 `json:"report_id"`
+	// account_balance, transfer_entries, journal_balance or currency_total
+	Kind       string        `json:"kind"`
+	AccountID  sql.NullInt64 `json:"account_id"`
+	TransferID sql.NullInt64 `json:"transfer_id"`
+	// account balance, expected number of transfer entries, or zero
+	Expected int64 `json:"expected"`
+	// sum of the account entries, number of transfer entries, or sum of the journal legs or balances
+	Actual    int64         `json:"actual"`
+	Detail    string        `json:"detail"`
+	CreatedAt time.Time     `json:"created_at"`
+	JournalID sql.NullInt64 `json:"journal_id"`
+}
+
+type ReconciliationReport struct {
+	ID               int64     `json:"id"`
+	AccountsChecked  int64     `json:"accounts_checked"`
+	TransfersChecked int64     `json:"transfers_checked"`
+	DiscrepancyCount int64     `json:"discrepancy_count"`
+	StartedAt        time.Time `json:"started_at"`
+	CreatedAt        time.Time `json:"created_at"`
+}
+
+type RevokedToken struct {
+	ID        uuid.UUID `json:"id"`
+	Username  string    `json:"username"`
+	ExpiresAt time.Time `json:"expires_at"`
+	RevokedAt time.Time `json:"revoked_at"`
+}
+
+type ScheduledTransfer struct {
+	ID            int64  `json:"id"`
+	Owner         string `json:"owner"`
+	FromAccountID int64  `json:"from_account_id"`
+	ToAccountID   int64  `json:"to_account_id"`
+	Amount        int64  `json:"amount"`
+	// @once, @daily, @weekly, @every <duration> or @monthly <day>
+	Schedule string `json:"schedule"`
+	// active, paused, completed or cancelled
+	Status string `json:"status"`
+	// the pending occurrence, next_run_at is later than it when the run is retried
+	ScheduledFor time.Time    `json:"scheduled_for"`
+	NextRunAt    time.Time    `json:"next_run_at"`
+	EndAt        sql.NullTime `json:"end_at"`
+	RetryCount   int32        `json:"retry_count"`
+	CreatedAt    time.Time    `json:"created_at"`
+	UpdatedAt    time.Time    `json:"updated_at"`
+}
+
+type ScheduledTransferRun struct {
+	ID                  int64     `json:"id"`
+	ScheduledTransferID int64     `json:"scheduled_transfer_id"`
+	ScheduledFor        time.Time `json:"scheduled_for"`
+	Attempt             int32     `json:"attempt"`
+	// succeeded or failed
+	Status     string        `json:"status"`
+	TransferID sql.NullInt64 `json:"transfer_id"`
+	Error      string        `json:"error"`
+	CreatedAt  time.Time     `json:"created_at"`
+}
+
+type Session struct {
+	ID           uuid.UUID `json:"id"`
+	Username     string    `json:"username"`
+	RefreshToken string    `json:"refresh_token"`
+	UserAgent    string    `json:"user_agent"`
+	ClientIp     string    `json:"client_ip"`
+	IsBlocked    bool      `json:"is_blocked"`
+	ExpiresAt    time.Time `json:"expires_at"`
+	CreatedAt    time.Time `json:"created_at"`
+}
+
+type Statement struct {
+	ID          int64     `json:"id"`
+	AccountID   int64     `json:"account_id"`
+	Currency    string    `json:"currency"`
+	PeriodStart time.Time `json:"period_start"`
+	// exclusive, entries created in [period_start, period_end) are included
+	PeriodEnd      time.Time `json:"period_end"`
+	OpeningBalance int64     `json:"opening_balance"`
+	ClosingBalance int64     `json:"closing_balance"`
+	TotalCredits   int64     `json:"total_credits"`
+	// sum of the negative entries, as a positive amount
+	TotalDebits int64 `json:"total_debits"`
+	EntryCount  int64 `json:"entry_count"`
+	// the entries of the period with the counterparty and the running balance
+	Lines     json.RawMessage `json:"lines"`
+	CreatedAt time.Time       `json:"created_at"`
 }
 
 type Transfer struct {
@@ -29,6 +206,40 @@ type Transfer struct {
 	// must be positive
 	Amount    int64     `json:"amount"`
 	CreatedAt time.Time `json:"created_at"`
+	// amount credited to the to account, in its currency
+	ToAmount       int64         `json:"to_amount"`
+	ExchangeRate   string        `json:"exchange_rate"`
+	ExchangeRateID sql.NullInt64 `json:"exchange_rate_id"`
+	// pending, rejected, completed, partially_reversed or reversed
+	Status string `json:"status"`
+	// part of amount that has been reversed, in the currency of the from account
+	ReversedAmount int64 `json:"reversed_amount"`
+	// the original transfer if this transfer is a reversal
+	ReversalOf  sql.NullInt64  `json:"reversal_of"`
+	RequestedBy sql.NullString `json:"requested_by"`
+	// the user who approved or rejected the pending transfer
+	Approver  sql.NullString `json:"approver"`
+	DecidedAt sql.NullTime   `json:"decided_at"`
+	// free-text memo of the sender
+	Memo string `json:"memo"`
+	// client-supplied reference, unique per from account if not empty
+	ExternalReference string          `json:"external_reference"`
+	Metadata          json.RawMessage `json:"metadata"`
+}
+
+type TransferLimit struct {
+	ID        int64          `json:"id"`
+	Owner     sql.NullString `json:"owner"`
+	AccountID sql.NullInt64  `json:"account_id"`
+	Currency  string         `json:"currency"`
+	// daily (rolling 24 hours) or monthly (rolling 30 days)
+	Period string `json:"period"`
+	// 0 means no limit on the number of transfers
+	MaxCount int32 `json:"max_count"`
+	// in minor units of the currency, 0 means no limit on the amount
+	MaxAmount int64     `json:"max_amount"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
 }
 
 type User struct {
@@ -38,4 +249,7 @@ type User struct {
 	Email            string    `json:"email"`
 	PasswordChangeAt time.Time `json:"password_change_at"`
 	CreatedAt        time.Time `json:"created_at"`
+	TokensRevokedAt  time.Time `json:"tokens_revoked_at"`
+	// depositor, banker or admin
+	Role string `json:"role"`
 }
@@ -4,24 +4,109 @@ package db
 
 import (
 	"context"
+	"time"
+
+	"github.com/google/uuid"
 )
 
 type Querier interface {
+	AddAccountLedgerBalance(ctx context.Context, arg AddAccountLedgerBalanceParams) (Account, error)
 	AddaAccountBalance(ctx context.Context, arg AddaAccountBalanceParams) (Account, error)
+	AdvanceScheduledTransfer(ctx context.Context, arg AdvanceScheduledTransferParams) (ScheduledTransfer, error)
+	BlockSession(ctx context.Context, id uuid.UUID) error
+	BlockUserSessions(ctx context.Context, username string) error
+	CountAccounts(ctx context.Context) (int64, error)
+	CountPayeeTransfers(ctx context.Context, arg CountPayeeTransfersParams) (int64, error)
+	CountTransfers(ctx context.Context) (int64, error)
+	CountTransfersSince(ctx context.Context, arg CountTransfersSinceParams) (int64, error)
 	CreateAccount(ctx context.Context, arg CreateAccountParams) (Account, error)
+	CreateBalanceSnapshots(ctx context.Context, snapshotAt time.Time) (int64, error)
+	CreateChainedEntry(ctx context.Context, arg CreateChainedEntryParams) (Entry, error)
 	CreateEntry(ctx context.Context, arg CreateEntryParams) (Entry, error)
+	CreateExchangeRate(ctx context.Context, arg CreateExchangeRateParams) (ExchangeRate, error)
+	CreateFraudDecision(ctx context.Context, arg CreateFraudDecisionParams) (FraudDecision, error)
+	CreateHold(ctx context.Context, arg CreateHoldParams) (Hold, error)
+	CreateIdempotencyKey(ctx context.Context, arg CreateIdempotencyKeyParams) (IdempotencyKey, error)
+	CreateJournal(ctx context.Context, arg CreateJournalParams) (Journal, error)
+	CreateReconciliationDiscrepancy(ctx context.Context, arg CreateReconciliationDiscrepancyParams) (ReconciliationDiscrepancy, error)
+	CreateReconciliationReport(ctx context.Context, arg CreateReconciliationReportParams) (ReconciliationReport, error)
+	CreateRevokedToken(ctx context.Context, arg CreateRevokedTokenParams) error
+	CreateScheduledTransfer(ctx context.Context, arg CreateScheduledTransferParams) (ScheduledTransfer, error)
+	CreateScheduledTransferRun(ctx context.Context, arg CreateScheduledTransferRunParams) (ScheduledTransferRun, error)
+	CreateSession(ctx context.Context, arg CreateSessionParams) (Session, error)
+	CreateStatement(ctx context.Context, arg CreateStatementParams) (Statement, error)
+	CreateSystemAccount(ctx context.Context, arg CreateSystemAccountParams) (int64, error)
 	CreateTransfers(ctx context.Context, arg CreateTransfersParams) (Transfer, error)
 	CreateUser(ctx context.Context, arg CreateUserParams) (User, error)
 	DeleteAccount(ctx context.Context, id int64) error
+	DeleteExpiredIdempotencyKeys(ctx context.Context, expiresAt time.Time) (int64, error)
+	DeleteExpiredRevokedTokens(ctx context.Context, expiresAt time.Time) (int64, error)
+	DeleteTransferLimit(ctx context.Context, id int64) error
+	ExpireHolds(ctx context.Context, expiresAt time.Time) (int64, error)
 	GetAccount(ctx context.Context, id int64) (Account, error)
+	GetAccountByOwner(ctx context.Context, arg GetAccountByOwnerParams) (Account, error)
 	GetAccountForUpdate(ctx context.Context, id int64) (Account, error)
+	GetAccountTransferUsage(ctx context.Context, arg GetAccountTransferUsageParams) (GetAccountTransferUsageRow, error)
+	GetActiveHoldsAmount(ctx context.Context, accountID int64) (int64, error)
 	GetEntry(ctx context.Context, id int64) (Entry, error)
+	GetExchangeRate(ctx context.Context, id int64) (ExchangeRate, error)
+	GetHold(ctx context.Context, id int64) (Hold, error)
+	GetHoldForUpdate(ctx context.Context, id int64) (Hold, error)
+	GetIdempotencyKey(ctx context.Context, arg GetIdempotencyKeyParams) (IdempotencyKey, error)
+	GetJournal(ctx context.Context, id int64) (Journal, error)
+	GetLatestBalanceSnapshot(ctx context.Context, arg GetLatestBalanceSnapshotParams) (BalanceSnapshot, error)
+	GetLatestExchangeRate(ctx context.Context, arg GetLatestExchangeRateParams) (ExchangeRate, error)
+	GetReconciliationReport(ctx context.Context, id int64) (ReconciliationReport, error)
+	GetScheduledTransfer(ctx context.Context, id int64) (ScheduledTransfer, error)
+	GetSession(ctx context.Context, id uuid.UUID) (Session, error)
+	GetStatement(ctx context.Context, id int64) (Statement, error)
+	GetStatementByPeriod(ctx context.Context, arg GetStatementByPeriodParams) (Statement, error)
+	GetSystemAccount(ctx context.Context, arg GetSystemAccountParams) (Account, error)
+	GetTransferByReference(ctx context.Context, arg GetTransferByReferenceParams) (Transfer, error)
+	GetTransferForUpdate(ctx context.Context, id int64) (Transfer, error)
+	GetTransferLimit(ctx context.Context, id int64) (TransferLimit, error)
 	GetTransfers(ctx context.Context, id int64) (Transfer, error)
 	GetUser(ctx context.Context, username string) (User, error)
+	GetUserByEmail(ctx context.Context, email string) (User, error)
+	GetUserForUpdate(ctx context.Context, username string) (User, error)
+	GetUserTransferUsage(ctx context.Context, arg GetUserTransferUsageParams) (GetUserTransferUsageRow, error)
+	IsTokenRevoked(ctx context.Context, id uuid.UUID) (bool, error)
+	ListAccountBalanceMismatches(ctx context.Context) ([]ListAccountBalanceMismatchesRow, error)
+	ListAccountEntryChain(ctx context.Context, arg ListAccountEntryChainParams) ([]Entry, error)
 	ListAccounts(ctx context.Context, arg ListAccountsParams) ([]Account, error)
+	ListAccountsAfter(ctx context.Context, arg ListAccountsAfterParams) ([]Account, error)
+	ListApplicableTransferLimits(ctx context.Context, arg ListApplicableTransferLimitsParams) ([]TransferLimit, error)
+	ListCurrencyImbalances(ctx context.Context) ([]ListCurrencyImbalancesRow, error)
+	ListDailyEntryTotals(ctx context.Context, arg ListDailyEntryTotalsParams) ([]ListDailyEntryTotalsRow, error)
+	ListDueScheduledTransfers(ctx context.Context, arg ListDueScheduledTransfersParams) ([]ScheduledTransfer, error)
 	ListEntries(ctx context.Context, arg ListEntriesParams) ([]Entry, error)
+	ListExchangeRates(ctx context.Context, arg ListExchangeRatesParams) ([]ExchangeRate, error)
+	ListFraudDecisions(ctx context.Context, arg ListFraudDecisionsParams) ([]FraudDecision, error)
+	ListHolds(ctx context.Context, arg ListHoldsParams) ([]Hold, error)
+	ListJournalEntries(ctx context.Context, journalID int64) ([]Entry, error)
+	ListReconciliationDiscrepancies(ctx context.Context, arg ListReconciliationDiscrepanciesParams) ([]ReconciliationDiscrepancy, error)
+	ListReconciliationReports(ctx context.Context, arg ListReconciliationReportsParams) ([]ReconciliationReport, error)
+	ListScheduledTransferRuns(ctx context.Context, arg ListScheduledTransferRunsParams) ([]ScheduledTransferRun, error)
+	ListScheduledTransfers(ctx context.Context, arg ListScheduledTransfersParams) ([]ScheduledTransfer, error)
+	ListStatementAccounts(ctx context.Context, arg ListStatementAccountsParams) ([]Account, error)
+	ListStatementEntries(ctx context.Context, arg ListStatementEntriesParams) ([]ListStatementEntriesRow, error)
+	ListStatements(ctx context.Context, arg ListStatementsParams) ([]ListStatementsRow, error)
+	ListTransferEntryMismatches(ctx context.Context, unsettledStatuses []string) ([]ListTransferEntryMismatchesRow, error)
+	ListTransferLimits(ctx context.Context, arg ListTransferLimitsParams) ([]TransferLimit, error)
 	ListTransfers(ctx context.Context, arg ListTransfersParams) ([]Transfer, error)
+	ListTransfersByReference(ctx context.Context, arg ListTransfersByReferenceParams) ([]Transfer, error)
+	ListUnbalancedJournals(ctx context.Context) ([]ListUnbalancedJournalsRow, error)
+	RevokeUserTokens(ctx context.Context, arg RevokeUserTokensParams) (User, error)
+	SetFraudDecisionTransfer(ctx context.Context, arg SetFraudDecisionTransferParams) (FraudDecision, error)
+	SumAccountEntries(ctx context.Context, arg SumAccountEntriesParams) (int64, error)
 	UpdateAccount(ctx context.Context, arg UpdateAccountParams) (Account, error)
+	UpdateHoldStatus(ctx context.Context, arg UpdateHoldStatusParams) (Hold, error)
+	UpdateIdempotencyKeyResponse(ctx context.Context, arg UpdateIdempotencyKeyResponseParams) error
+	UpdateScheduledTransfer(ctx context.Context, arg UpdateScheduledTransferParams) (ScheduledTransfer, error)
+	UpdateTransferDecision(ctx context.Context, arg UpdateTransferDecisionParams) (Transfer, error)
+	UpdateTransferReversal(ctx context.Context, arg UpdateTransferReversalParams) (Transfer, error)
+	UpdateUserRole(ctx context.Context, arg UpdateUserRoleParams) (User, error)
+	UpsertTransferLimit(ctx context.Context, arg UpsertTransferLimitParams) (TransferLimit, error)
 }
 
 var _ Querier = (*Queries)(nil)
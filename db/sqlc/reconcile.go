@@ -0,0 +1,132 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"github.com/techschool/simplebank/util"
+	"time"
+)
+
+// unsettledTransferStatuses 没有结算的转账，不应该有任何流水
+var unsettledTransferStatuses = []string{util.TransferPending, util.TransferRejected}
+
+// ReconcileTxResult is the result of the reconcile translation.
+type ReconcileTxResult struct {
+	Report			ReconciliationReport			`json:"report"`
+	Discrepancies	[]ReconciliationDiscrepancy	`json:"discrepancies"`
+}
+
+// ReconcileTx compares the balance of every account with the sum of its entries, checks that every settled transfer
+// has exactly one debit and one credit entry, that every journal balances and that the balances of each currency
+// sum to zero. The result is saved as a reconciliation report.
+func (store *SQLStore) ReconcileTx(ctx context.Context) (ReconcileTxResult, error) {
+	var result ReconcileTxResult
+	startedAt := time.Now()
+
+	err := store.execTx(ctx, func(q *Queries) error {
+		accountsChecked, err := q.CountAccounts(ctx)
+		if err != nil {
+			return err
+		}
+		transfersChecked, err := q.CountTransfers(ctx)
+		if err != nil {
+			return err
+		}
+
+		var discrepancies []CreateReconciliationDiscrepancyParams
+		balances, err := q.ListAccountBalanceMismatches(ctx)
+		if err != nil {
+			return err
+		}
+		for _, balance := range balances {
+			discrepancies = append(discrepancies, CreateReconciliationDiscrepancyParams{
+				Kind:		util.DiscrepancyAccountBalance,
+				AccountID:	sql.NullInt64{Int64: balance.ID, Valid: true},
+				Expected:	balance.Balance,
+				Actual:		balance.EntriesTotal,
+				Detail:		fmt.Sprintf("account [%d] balance is %d, but the entries sum to %d",
+					balance.ID, balance.Balance, balance.EntriesTotal),
+			})
+		}
+
+		transfers, err := q.ListTransferEntryMismatches(ctx, unsettledTransferStatuses)
+		if err != nil {
+			return err
+		}
+		for _, transfer := range transfers {
+			discrepancies = append(discrepancies, transferDiscrepancy(transfer))
+		}
+
+		journals, err := q.ListUnbalancedJournals(ctx)
+		if err != nil {
+			return err
+		}
+		for _, journal := range journals {
+			discrepancies = append(discrepancies, CreateReconciliationDiscrepancyParams{
+				Kind:		util.DiscrepancyJournalBalance,
+				JournalID:	sql.NullInt64{Int64: journal.JournalID, Valid: true},
+				Actual:		journal.Total,
+				Detail:		fmt.Sprintf("journal [%d] legs of %s sum to %d", journal.JournalID, journal.Currency, journal.Total),
+			})
+		}
+
+		currencies, err := q.ListCurrencyImbalances(ctx)
+		if err != nil {
+			return err
+		}
+		for _, currency := range currencies {
+			discrepancies = append(discrepancies, CreateReconciliationDiscrepancyParams{
+				Kind:		util.DiscrepancyCurrencyTotal,
+				Actual:		currency.Total,
+				Detail:		fmt.Sprintf("balances of %s accounts sum to %d", currency.Currency, currency.Total),
+			})
+		}
+
+		result.Report, err = q.CreateReconciliationReport(ctx, CreateReconciliationReportParams{
+			AccountsChecked:	accountsChecked,
+			TransfersChecked:	transfersChecked,
+			DiscrepancyCount:	int64(len(discrepancies)),
+			StartedAt:			startedAt,
+		})
+		if err != nil {
+			return err
+		}
+
+		result.Discrepancies = make([]ReconciliationDiscrepancy, 0, len(discrepancies))
+		for _, arg := range discrepancies {
+			arg.ReportID = result.Report.ID
+			discrepancy, err := q.CreateReconciliationDiscrepancy(ctx, arg)
+			if err != nil {
+				return err
+			}
+			result.Discrepancies = append(result.Discrepancies, discrepancy)
+		}
+		return nil
+	})
+	return result, err
+}
+
+// transferDiscrepancy 已结算的转账期望有两条匹配的流水，跨币种的转账另外有两条兑换账户的流水，未结算的转账期望没有流水
+func transferDiscrepancy(transfer ListTransferEntryMismatchesRow) CreateReconciliationDiscrepancyParams {
+	arg := CreateReconciliationDiscrepancyParams{
+		Kind:		util.DiscrepancyTransferEntries,
+		TransferID:	sql.NullInt64{Int64: transfer.ID, Valid: true},
+		Actual:		transfer.EntryCount,
+	}
+
+	for _, status := range unsettledTransferStatuses {
+		if transfer.Status == status {
+			arg.Detail = fmt.Sprintf("%s transfer [%d] has %d entries", transfer.Status, transfer.ID, transfer.EntryCount)
+			return arg
+		}
+	}
+
+	arg.Expected = 2
+	if transfer.Exchanged {
+		arg.Expected = 4
+	}
+	arg.Detail = fmt.Sprintf("%s transfer [%d] has %d entries, %d matching debit and %d matching credit",
+		transfer.Status, transfer.ID, transfer.EntryCount, transfer.FromEntries, transfer.ToEntries)
+	return arg
+}
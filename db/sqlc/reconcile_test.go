@@ -0,0 +1,58 @@
+package db
+
+import (
+	"context"
+	"github.com/stretchr/testify/require"
+	"github.com/techschool/simplebank/util"
+	"testing"
+)
+
+func TestReconcileTx(t *testing.T) {
+	store := NewStore(testDB)
+
+	// 余额和流水一致的账户，转账后仍然一致
+	account1 := setAccountBalance(t, createAccountWithCurrency(t, util.USD), 100)
+	account2 := setAccountBalance(t, createAccountWithCurrency(t, util.USD), 0)
+	account3 := setAccountBalance(t, createAccountWithCurrency(t, util.USD), 0)
+	_, err := testQueries.CreateEntry(context.Background(), CreateEntryParams{AccountID: account1.ID, Amount: 100})
+	require.NoError(t, err)
+
+	transfer, err := store.TransferTx(context.Background(), TransferTxParams{
+		FromAccountID: account1.ID,
+		ToAccountID: account2.ID,
+		Amount: 10,
+	})
+	require.NoError(t, err)
+	require.Equal(t, transfer.Transfer.ID, transfer.FromEntry.TransferID.Int64)
+	require.Equal(t, transfer.Transfer.ID, transfer.ToEntry.TransferID.Int64)
+
+	// 只修改余额而没有流水，对账时会被发现
+	setAccountBalance(t, account3, 50)
+
+	result, err := store.ReconcileTx(context.Background())
+	require.NoError(t, err)
+	require.NotZero(t, result.Report.ID)
+	require.Positive(t, result.Report.AccountsChecked)
+	require.Positive(t, result.Report.TransfersChecked)
+	require.Equal(t, result.Report.DiscrepancyCount, int64(len(result.Discrepancies)))
+
+	var found bool
+	for _, discrepancy := range result.Discrepancies {
+		require.Equal(t, result.Report.ID, discrepancy.ReportID)
+		require.NotEqual(t, account1.ID, discrepancy.AccountID.Int64)
+		require.NotEqual(t, account2.ID, discrepancy.AccountID.Int64)
+		require.NotEqual(t, transfer.Transfer.ID, discrepancy.TransferID.Int64)
+
+		if discrepancy.AccountID.Int64 == account3.ID {
+			found = true
+			require.Equal(t, util.DiscrepancyAccountBalance, discrepancy.Kind)
+			require.Equal(t, int64(50), discrepancy.Expected)
+			require.Zero(t, discrepancy.Actual)
+		}
+	}
+	require.True(t, found)
+
+	report, err := testQueries.GetReconciliationReport(context.Background(), result.Report.ID)
+	require.NoError(t, err)
+	require.Equal(t, result.Report.DiscrepancyCount, report.DiscrepancyCount)
+}
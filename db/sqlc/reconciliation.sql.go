@@ -0,0 +1,412 @@
+// Code generated by sqlc. DO NOT EDIT.
+// source: reconciliation.sql
+
+package db
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+const countAccounts = `-- name: CountAccounts :one
+SELECT COUNT(*) FROM account
+`
+
+func (q *Queries) CountAccounts(ctx context.Context) (int64, error) {
+	row := q.db.QueryRowContext(ctx, countAccounts)
+	var count int64
+	err := row.Scan(&count)
+	return count, err
+}
+
+const countTransfers = `-- name: CountTransfers :one
+SELECT COUNT(*) FROM transfers
+`
+
+func (q *Queries) CountTransfers(ctx context.Context) (int64, error) {
+	row := q.db.QueryRowContext(ctx, countTransfers)
+	var count int64
+	err := row.Scan(&count)
+	return count, err
+}
+
+const createReconciliationDiscrepancy = `-- name: CreateReconciliationDiscrepancy :one
+INSERT INTO reconciliation_discrepancies (
+    report_id,
+    kind,
+    account_id,
+    transfer_id,
+    journal_id,
+    expected,
+    actual,
+    detail
+) VALUES (
+    $1, $2, $3, $4, $5, $6, $7, $8
+) RETURNING id, report_id, kind, account_id, transfer_id, expected, actual, detail, created_at, journal_id
+`
+
+type CreateReconciliationDiscrepancyParams struct {
+	ReportID   int64         `json:"report_id"`
+	Kind       string        `json:"kind"`
+	AccountID  sql.NullInt64 `json:"account_id"`
+	TransferID sql.NullInt64 `json:"transfer_id"`
+	JournalID  sql.NullInt64 `json:"journal_id"`
+	Expected   int64         `json:"expected"`
+	Actual     int64         `json:"actual"`
+	Detail     string        `json:"detail"`
+}
+
+func (q *Queries) CreateReconciliationDiscrepancy(ctx context.Context, arg CreateReconciliationDiscrepancyParams) (ReconciliationDiscrepancy, error) {
+	row := q.db.QueryRowContext(ctx, createReconciliationDiscrepancy,
+		arg.ReportID,
+		arg.Kind,
+		arg.AccountID,
+		arg.TransferID,
+		arg.JournalID,
+		arg.Expected,
+		arg.Actual,
+		arg.Detail,
+	)
+	var i ReconciliationDiscrepancy
+	err := row.Scan(
+		&i.ID,
+		&i.ReportID,
+		&i.Kind,
+		&i.AccountID,
+		&i.TransferID,
+		&i.Expected,
+		&i.Actual,
+		&i.Detail,
+		&i.CreatedAt,
+		&i.JournalID,
+	)
+	return i, err
+}
+
+const createReconciliationReport = `-- name: CreateReconciliationReport :one
+INSERT INTO reconciliation_reports (
+    accounts_checked,
+    transfers_checked,
+    discrepancy_count,
+    started_at
+) VALUES (
+    $1, $2, $3, $4
+) RETURNING id, accounts_checked, transfers_checked, discrepancy_count, started_at, created_at
+`
+
+type CreateReconciliationReportParams struct {
+	AccountsChecked  int64     `json:"accounts_checked"`
+	TransfersChecked int64     `json:"transfers_checked"`
+	DiscrepancyCount int64     `json:"discrepancy_count"`
+	StartedAt        time.Time `json:"started_at"`
+}
+
+func (q *Queries) CreateReconciliationReport(ctx context.Context, arg CreateReconciliationReportParams) (ReconciliationReport, error) {
+	row := q.db.QueryRowContext(ctx, createReconciliationReport,
+		arg.AccountsChecked,
+		arg.TransfersChecked,
+		arg.DiscrepancyCount,
+		arg.StartedAt,
+	)
+	var i ReconciliationReport
+	err := row.Scan(
+		&i.ID,
+		&i.AccountsChecked,
+		&i.TransfersChecked,
+		&i.DiscrepancyCount,
+		&i.StartedAt,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
+const getReconciliationReport = `-- name: GetReconciliationReport :one
+SELECT id, accounts_checked, transfers_checked, discrepancy_count, started_at, created_at FROM reconciliation_reports
+WHERE id = $1 LIMIT 1
+`
+
+func (q *Queries) GetReconciliationReport(ctx context.Context, id int64) (ReconciliationReport, error) {
+	row := q.db.QueryRowContext(ctx, getReconciliationReport, id)
+	var i ReconciliationReport
+	err := row.Scan(
+		&i.ID,
+		&i.AccountsChecked,
+		&i.TransfersChecked,
+		&i.DiscrepancyCount,
+		&i.StartedAt,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
+const listAccountBalanceMismatches = `-- name: ListAccountBalanceMismatches :many
+SELECT a.id, a.balance, COALESCE(SUM(e.amount), 0)::bigint AS entries_total
+FROM account a
+LEFT JOIN entries e ON e.account_id = a.id
+GROUP BY a.id
+HAVING a.balance <> COALESCE(SUM(e.amount), 0)
+ORDER BY a.id
+`
+
+type ListAccountBalanceMismatchesRow struct {
+	ID           int64 `json:"id"`
+	Balance      int64 `json:"balance"`
+	EntriesTotal int64 `json:"entries_total"`
+}
+
+// 账户余额应该等于该账户所有流水的金额之和
+func (q *Queries) ListAccountBalanceMismatches(ctx context.Context) ([]ListAccountBalanceMismatchesRow, error) {
+	rows, err := q.db.QueryContext(ctx, listAccountBalanceMismatches)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []ListAccountBalanceMismatchesRow{}
+	for rows.Next() {
+		var i ListAccountBalanceMismatchesRow
+		if err := rows.Scan(&i.ID, &i.Balance, &i.EntriesTotal); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listCurrencyImbalances = `-- name: ListCurrencyImbalances :many
+SELECT currency, SUM(balance)::bigint AS total
+FROM account
+GROUP BY currency
+HAVING SUM(balance) <> 0
+ORDER BY currency
+`
+
+type ListCurrencyImbalancesRow struct {
+	Currency string `json:"currency"`
+	Total    int64  `json:"total"`
+}
+
+// 包括系统账户在内，各个币种所有账户的余额之和应该为 0
+func (q *Queries) ListCurrencyImbalances(ctx context.Context) ([]ListCurrencyImbalancesRow, error) {
+	rows, err := q.db.QueryContext(ctx, listCurrencyImbalances)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []ListCurrencyImbalancesRow{}
+	for rows.Next() {
+		var i ListCurrencyImbalancesRow
+		if err := rows.Scan(&i.Currency, &i.Total); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listReconciliationDiscrepancies = `-- name: ListReconciliationDiscrepancies :many
+SELECT id, report_id, kind, account_id, transfer_id, expected, actual, detail, created_at, journal_id FROM reconciliation_discrepancies
+WHERE report_id = $1
+  AND (created_at, id) > ($2::timestamptz, $3::bigint)
+ORDER BY created_at, id
+LIMIT $4
+`
+
+type ListReconciliationDiscrepanciesParams struct {
+	ReportID        int64     `json:"report_id"`
+	CursorCreatedAt time.Time `json:"cursor_created_at"`
+	CursorID        int64     `json:"cursor_id"`
+	PageSize        int32     `json:"page_size"`
+}
+
+func (q *Queries) ListReconciliationDiscrepancies(ctx context.Context, arg ListReconciliationDiscrepanciesParams) ([]ReconciliationDiscrepancy, error) {
+	rows, err := q.db.QueryContext(ctx, listReconciliationDiscrepancies,
+		arg.ReportID,
+		arg.CursorCreatedAt,
+		arg.CursorID,
+		arg.PageSize,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []ReconciliationDiscrepancy{}
+	for rows.Next() {
+		var i ReconciliationDiscrepancy
+		if err := rows.Scan(
+			&i.ID,
+			&i.ReportID,
+			&i.Kind,
+			&i.AccountID,
+			&i.TransferID,
+			&i.Expected,
+			&i.Actual,
+			&i.Detail,
+			&i.CreatedAt,
+			&i.JournalID,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listReconciliationReports = `-- name: ListReconciliationReports :many
+SELECT id, accounts_checked, transfers_checked, discrepancy_count, started_at, created_at FROM reconciliation_reports
+WHERE (created_at, id) < ($1::timestamptz, $2::bigint)
+ORDER BY created_at DESC, id DESC
+LIMIT $3
+`
+
+type ListReconciliationReportsParams struct {
+	CursorCreatedAt time.Time `json:"cursor_created_at"`
+	CursorID        int64     `json:"cursor_id"`
+	PageSize        int32     `json:"page_size"`
+}
+
+func (q *Queries) ListReconciliationReports(ctx context.Context, arg ListReconciliationReportsParams) ([]ReconciliationReport, error) {
+	rows, err := q.db.QueryContext(ctx, listReconciliationReports, arg.CursorCreatedAt, arg.CursorID, arg.PageSize)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []ReconciliationReport{}
+	for rows.Next() {
+		var i ReconciliationReport
+		if err := rows.Scan(
+			&i.ID,
+			&i.AccountsChecked,
+			&i.TransfersChecked,
+			&i.DiscrepancyCount,
+			&i.StartedAt,
+			&i.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listTransferEntryMismatches = `-- name: ListTransferEntryMismatches :many
+SELECT t.id, t.status, (t.exchange_rate_id IS NOT NULL)::boolean AS exchanged, COUNT(e.id) AS entry_count,
+    COALESCE(SUM(CASE WHEN e.account_id = t.from_account_id AND e.amount = -t.amount THEN 1 ELSE 0 END), 0)::bigint AS from_entries,
+    COALESCE(SUM(CASE WHEN e.account_id = t.to_account_id AND e.amount = t.to_amount THEN 1 ELSE 0 END), 0)::bigint AS to_entries
+FROM transfers t
+LEFT JOIN entries e ON e.transfer_id = t.id
+GROUP BY t.id
+HAVING CASE WHEN t.status = ANY($1::varchar[]) THEN COUNT(e.id) <> 0
+    ELSE COUNT(e.id) <> CASE WHEN t.exchange_rate_id IS NULL THEN 2 ELSE 4 END
+        OR COALESCE(SUM(CASE WHEN e.account_id = t.from_account_id AND e.amount = -t.amount THEN 1 ELSE 0 END), 0) <> 1
+        OR COALESCE(SUM(CASE WHEN e.account_id = t.to_account_id AND e.amount = t.to_amount THEN 1 ELSE 0 END), 0) <> 1
+    END
+ORDER BY t.id
+`
+
+type ListTransferEntryMismatchesRow struct {
+	ID          int64  `json:"id"`
+	Status      string `json:"status"`
+	Exchanged   bool   `json:"exchanged"`
+	EntryCount  int64  `json:"entry_count"`
+	FromEntries int64  `json:"from_entries"`
+	ToEntries   int64  `json:"to_entries"`
+}
+
+// 已结算的转账应该恰好有一条转出账户的出账和一条转入账户的入账，跨币种的转账另外还有两条兑换账户的流水，
+// 未结算的转账没有流水
+func (q *Queries) ListTransferEntryMismatches(ctx context.Context, unsettledStatuses []string) ([]ListTransferEntryMismatchesRow, error) {
+	rows, err := q.db.QueryContext(ctx, listTransferEntryMismatches, pq.Array(unsettledStatuses))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []ListTransferEntryMismatchesRow{}
+	for rows.Next() {
+		var i ListTransferEntryMismatchesRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.Status,
+			&i.Exchanged,
+			&i.EntryCount,
+			&i.FromEntries,
+			&i.ToEntries,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listUnbalancedJournals = `-- name: ListUnbalancedJournals :many
+SELECT e.journal_id::bigint AS journal_id, a.currency, SUM(e.amount)::bigint AS total
+FROM entries e
+JOIN account a ON a.id = e.account_id
+WHERE e.journal_id IS NOT NULL
+GROUP BY e.journal_id, a.currency
+HAVING SUM(e.amount) <> 0
+ORDER BY e.journal_id, a.currency
+`
+
+type ListUnbalancedJournalsRow struct {
+	JournalID int64  `json:"journal_id"`
+	Currency  string `json:"currency"`
+	Total     int64  `json:"total"`
+}
+
+// 每条分录在各个币种内的流水之和都应该为 0
+func (q *Queries) ListUnbalancedJournals(ctx context.Context) ([]ListUnbalancedJournalsRow, error) {
+	rows, err := q.db.QueryContext(ctx, listUnbalancedJournals)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []ListUnbalancedJournalsRow{}
+	for rows.Next() {
+		var i ListUnbalancedJournalsRow
+		if err := rows.Scan(&i.JournalID, &i.Currency, &i.Total); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
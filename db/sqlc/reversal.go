@@ -0,0 +1,117 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"github.com/techschool/simplebank/util"
+	"math/big"
+)
+
+// ErrInvalidReversal is returned when a transfer cannot be reversed with the requested amount.
+var ErrInvalidReversal = errors.New("invalid reversal")
+
+// ReverseTransferTxParams contains the input parameters of reverse transfer translation.
+type ReverseTransferTxParams struct {
+	TransferID	int64	`json:"transfer_id"`
+	// Amount 退回的金额，使用原转账转出账户的币种，不能超过还没有退回的部分
+	Amount		int64	`json:"amount"`
+	// RequestedBy 发起退款或者撤销的用户，记录在反向的转账上
+	RequestedBy	string	`json:"requested_by"`
+}
+
+// ReverseTransferTxResult is the result of the reverse transfer translation.
+type ReverseTransferTxResult struct {
+	Transfer	Transfer	`json:"transfer"`	// 原转账，已更新退回的金额和状态
+	Reversal	Transfer	`json:"reversal"`	// 反向的转账记录
+	FromAccount	Account		`json:"from_account"`	// 原转账的转入账户，资金从这里扣除
+	ToAccount	Account		`json:"to_account"`	// 原转账的转出账户，资金退回到这里
+	FromEntry	Entry		`json:"from_entry"`
+	ToEntry		Entry		`json:"to_entry"`
+}
+
+// ReverseTransferTx reverses all or part of a transfer.
+// It creates a linked reversal transfer and compensating entries, and updates the status of the original transfer
+// within a single translation. The original transfer is locked, so concurrent reversals cannot exceed its amount.
+func (store *SQLStore) ReverseTransferTx(ctx context.Context, arg ReverseTransferTxParams) (ReverseTransferTxResult, error) {
+	var result ReverseTransferTxResult
+	err := store.execTx(ctx, func(q *Queries) error {
+		original, err := q.GetTransferForUpdate(ctx, arg.TransferID)
+		if err != nil {
+			return err
+		}
+
+		// 只有已经结算的转账可以退回
+		if original.Status != util.TransferCompleted && original.Status != util.TransferPartiallyReversed {
+			return fmt.Errorf("%w: transfer [%d] is %s", ErrInvalidReversal, original.ID, original.Status)
+		}
+		if original.ReversalOf.Valid {
+			return fmt.Errorf("%w: transfer [%d] is a reversal itself", ErrInvalidReversal, original.ID)
+		}
+		remaining := original.Amount - original.ReversedAmount
+		if arg.Amount <= 0 || arg.Amount > remaining {
+			return fmt.Errorf("%w: amount %d exceeds the remaining amount %d of transfer [%d]",
+				ErrInvalidReversal, arg.Amount, remaining, original.ID)
+		}
+
+		// 跨币种转账按比例从转入账户扣除，按累计金额计算，全部退回时扣除的金额正好等于 to_amount
+		reversedAmount := original.ReversedAmount + arg.Amount
+		debit := proportionalAmount(reversedAmount, original.Amount, original.ToAmount) -
+			proportionalAmount(original.ReversedAmount, original.Amount, original.ToAmount)
+		if debit <= 0 {
+			return fmt.Errorf("%w: amount %d is too small to be reversed", ErrInvalidReversal, arg.Amount)
+		}
+
+		exchangeRate := "1"
+		if original.ExchangeRateID.Valid {
+			exchangeRate, err = util.InverseExchangeRate(original.ExchangeRate)
+			if err != nil {
+				return err
+			}
+		}
+
+		result.Reversal, err = q.CreateTransfers(ctx, CreateTransfersParams{
+			FromAccountID:	original.ToAccountID,
+			ToAccountID:	original.FromAccountID,
+			Amount:			debit,
+			ToAmount:		arg.Amount,
+			ExchangeRate:	exchangeRate,
+			ExchangeRateID:	original.ExchangeRateID,
+			ReversalOf:		sql.NullInt64{Int64: original.ID, Valid: true},
+			Status:			util.TransferCompleted,
+			RequestedBy:	sql.NullString{String: arg.RequestedBy, Valid: arg.RequestedBy != ""},
+			Metadata:		metadataValue(nil),
+		})
+		if err != nil {
+			return err
+		}
+
+		// 反向转账的分录与普通转账一样，转出账户按 ID 的顺序加锁，并检查可用余额
+		settled, err := store.settleTransfer(ctx, q, result.Reversal)
+		if err != nil {
+			return err
+		}
+		result.FromAccount, result.ToAccount = settled.FromAccount, settled.ToAccount
+		result.FromEntry, result.ToEntry = settled.FromEntry, settled.ToEntry
+
+		status := util.TransferPartiallyReversed
+		if reversedAmount == original.Amount {
+			status = util.TransferReversed
+		}
+		result.Transfer, err = q.UpdateTransferReversal(ctx, UpdateTransferReversalParams{
+			ID:				original.ID,
+			ReversedAmount:	reversedAmount,
+			Status:			status,
+		})
+		return err
+	})
+	return result, err
+}
+
+// proportionalAmount returns floor(value * part / total) without overflow
+func proportionalAmount(part, total, value int64) int64 {
+	result := new(big.Int).Mul(big.NewInt(value), big.NewInt(part))
+	result.Quo(result, big.NewInt(total))
+	return result.Int64()
+}
@@ -0,0 +1,151 @@
+package db
+
+import (
+	"context"
+	"github.com/stretchr/testify/require"
+	"github.com/techschool/simplebank/util"
+	"testing"
+)
+
+func TestReverseTransferTx(t *testing.T) {
+	store := NewStore(testDB)
+
+	currency := util.RandomCurrency()
+	account1 := createAccountWithCurrency(t, currency)
+	account2 := createAccountWithCurrency(t, currency)
+
+	transferResult, err := store.TransferTx(context.Background(), TransferTxParams{
+		FromAccountID: account1.ID,
+		ToAccountID: account2.ID,
+		Amount: 50,
+	})
+	require.NoError(t, err)
+	original := transferResult.Transfer
+	require.Equal(t, util.TransferCompleted, original.Status)
+
+	// 部分退款
+	result, err := store.ReverseTransferTx(context.Background(), ReverseTransferTxParams{
+		TransferID: original.ID,
+		Amount: 20,
+		RequestedBy: account2.Owner,
+	})
+	require.NoError(t, err)
+	require.Equal(t, util.TransferPartiallyReversed, result.Transfer.Status)
+	require.Equal(t, int64(20), result.Transfer.ReversedAmount)
+
+	reversal := result.Reversal
+	require.Equal(t, account2.ID, reversal.FromAccountID)
+	require.Equal(t, account1.ID, reversal.ToAccountID)
+	require.Equal(t, int64(20), reversal.Amount)
+	require.Equal(t, original.ID, reversal.ReversalOf.Int64)
+	require.Equal(t, account2.Owner, reversal.RequestedBy.String)
+	require.Equal(t, int64(-20), result.FromEntry.Amount)
+	require.Equal(t, int64(20), result.ToEntry.Amount)
+	require.Equal(t, transferResult.ToAccount.Balance-20, result.FromAccount.Balance)
+	require.Equal(t, transferResult.FromAccount.Balance+20, result.ToAccount.Balance)
+
+	// 不能退回超过剩余的金额
+	_, err = store.ReverseTransferTx(context.Background(), ReverseTransferTxParams{
+		TransferID: original.ID,
+		Amount: 31,
+	})
+	require.ErrorIs(t, err, ErrInvalidReversal)
+
+	// 反向的转账不能再撤销
+	_, err = store.ReverseTransferTx(context.Background(), ReverseTransferTxParams{
+		TransferID: reversal.ID,
+		Amount: 1,
+	})
+	require.ErrorIs(t, err, ErrInvalidReversal)
+
+	result, err = store.ReverseTransferTx(context.Background(), ReverseTransferTxParams{
+		TransferID: original.ID,
+		Amount: 30,
+	})
+	require.NoError(t, err)
+	require.Equal(t, util.TransferReversed, result.Transfer.Status)
+
+	updateAccount1, err := testQueries.GetAccount(context.Background(), account1.ID)
+	require.NoError(t, err)
+	updateAccount2, err := testQueries.GetAccount(context.Background(), account2.ID)
+	require.NoError(t, err)
+	require.Equal(t, account1.Balance, updateAccount1.Balance)
+	require.Equal(t, account2.Balance, updateAccount2.Balance)
+}
+
+func TestReverseTransferTxConcurrent(t *testing.T) {
+	store := NewStore(testDB)
+
+	currency := util.RandomCurrency()
+	account1 := createAccountWithCurrency(t, currency)
+	account2 := createAccountWithCurrency(t, currency)
+
+	transferResult, err := store.TransferTx(context.Background(), TransferTxParams{
+		FromAccountID: account1.ID,
+		ToAccountID: account2.ID,
+		Amount: 50,
+	})
+	require.NoError(t, err)
+
+	// 并发退款，总金额不能超过原转账
+	n := 10
+	errs := make(chan error)
+	for i := 0; i < n; i++ {
+		go func() {
+			_, err := store.ReverseTransferTx(context.Background(), ReverseTransferTxParams{
+				TransferID: transferResult.Transfer.ID,
+				Amount: 20,
+			})
+			errs <- err
+		}()
+	}
+
+	succeeded := 0
+	for i := 0; i < n; i++ {
+		err := <-errs
+		if err != nil {
+			require.ErrorIs(t, err, ErrInvalidReversal)
+			continue
+		}
+		succeeded++
+	}
+	require.Equal(t, 2, succeeded)
+
+	transfer, err := testQueries.GetTransfers(context.Background(), transferResult.Transfer.ID)
+	require.NoError(t, err)
+	require.Equal(t, int64(40), transfer.ReversedAmount)
+	require.Equal(t, util.TransferPartiallyReversed, transfer.Status)
+}
+
+func TestReverseTransferTxExchange(t *testing.T) {
+	store := NewStore(testDB)
+
+	account1 := createAccountWithCurrency(t, util.USD)
+	account2 := createAccountWithCurrency(t, util.EUR)
+	exchangeRate := createRandomExchangeRate(t, util.USD, util.EUR, "0.9")
+
+	transferResult, err := store.TransferTx(context.Background(), TransferTxParams{
+		FromAccountID: account1.ID,
+		ToAccountID: account2.ID,
+		Amount: 15,
+		ExchangeRateID: exchangeRate.ID,
+	})
+	require.NoError(t, err)
+	require.Equal(t, int64(13), transferResult.Transfer.ToAmount)
+
+	// 分两次退回，扣除的欧元合计正好等于转入的金额
+	result1, err := store.ReverseTransferTx(context.Background(), ReverseTransferTxParams{
+		TransferID: transferResult.Transfer.ID,
+		Amount: 5,
+	})
+	require.NoError(t, err)
+	result2, err := store.ReverseTransferTx(context.Background(), ReverseTransferTxParams{
+		TransferID: transferResult.Transfer.ID,
+		Amount: 10,
+	})
+	require.NoError(t, err)
+
+	require.Equal(t, int64(13), result1.Reversal.Amount+result2.Reversal.Amount)
+	require.Equal(t, account1.Balance, result2.ToAccount.Balance)
+	require.Equal(t, account2.Balance, result2.FromAccount.Balance)
+}
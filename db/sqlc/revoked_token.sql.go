@@ -0,0 +1,60 @@
+// Code generated by sqlc. DO NOT EDIT.
+// source: revoked_token.sql
+
+package db
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const createRevokedToken = `-- name: CreateRevokedToken :exec
+INSERT INTO revoked_tokens (
+    id,
+    username,
+    expires_at
+) VALUES (
+    $1, $2, $3
+) ON CONFLICT (id) DO NOTHING
+`
+
+type CreateRevokedTokenParams struct {
+	ID        uuid.UUID `json:"id"`
+	Username  string    `json:"username"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+func (q *Queries) CreateRevokedToken(ctx context.Context, arg CreateRevokedTokenParams) error {
+	_, err := q.db.ExecContext(ctx, createRevokedToken, arg.ID, arg.Username, arg.ExpiresAt)
+	return err
+}
+
+const deleteExpiredRevokedTokens = `-- name: DeleteExpiredRevokedTokens :execrows
+DELETE FROM revoked_tokens
+WHERE expires_at <= $1
+`
+
+// 过期的 token 已经无法通过验证，不再需要吊销记录
+func (q *Queries) DeleteExpiredRevokedTokens(ctx context.Context, expiresAt time.Time) (int64, error) {
+	result, err := q.db.ExecContext(ctx, deleteExpiredRevokedTokens, expiresAt)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const isTokenRevoked = `-- name: IsTokenRevoked :one
+SELECT EXISTS (
+    SELECT 1 FROM revoked_tokens
+    WHERE id = $1
+) AS revoked
+`
+
+func (q *Queries) IsTokenRevoked(ctx context.Context, id uuid.UUID) (bool, error) {
+	row := q.db.QueryRowContext(ctx, isTokenRevoked, id)
+	var revoked bool
+	err := row.Scan(&revoked)
+	return revoked, err
+}
@@ -0,0 +1,66 @@
+package db
+
+import (
+	"context"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+	"testing"
+	"time"
+)
+
+func TestRevokeToken(t *testing.T) {
+	user := createRandomUser(t)
+	tokenID := uuid.New()
+
+	revoked, err := testQueries.IsTokenRevoked(context.Background(), tokenID)
+	require.NoError(t, err)
+	require.False(t, revoked)
+
+	arg := CreateRevokedTokenParams{
+		ID:			tokenID,
+		Username:	user.Username,
+		ExpiresAt:	time.Now().Add(time.Minute),
+	}
+	err = testQueries.CreateRevokedToken(context.Background(), arg)
+	require.NoError(t, err)
+
+	// 重复吊销同一个 token 不会报错
+	err = testQueries.CreateRevokedToken(context.Background(), arg)
+	require.NoError(t, err)
+
+	revoked, err = testQueries.IsTokenRevoked(context.Background(), tokenID)
+	require.NoError(t, err)
+	require.True(t, revoked)
+}
+
+func TestDeleteExpiredRevokedTokens(t *testing.T) {
+	user := createRandomUser(t)
+	expiredID := uuid.New()
+	activeID := uuid.New()
+
+	err := testQueries.CreateRevokedToken(context.Background(), CreateRevokedTokenParams{
+		ID:			expiredID,
+		Username:	user.Username,
+		ExpiresAt:	time.Now().Add(-time.Second),
+	})
+	require.NoError(t, err)
+	err = testQueries.CreateRevokedToken(context.Background(), CreateRevokedTokenParams{
+		ID:			activeID,
+		Username:	user.Username,
+		ExpiresAt:	time.Now().Add(time.Minute),
+	})
+	require.NoError(t, err)
+
+	count, err := testQueries.DeleteExpiredRevokedTokens(context.Background(), time.Now())
+	require.NoError(t, err)
+	require.GreaterOrEqual(t, count, int64(1))
+
+	revoked, err := testQueries.IsTokenRevoked(context.Background(), expiredID)
+	require.NoError(t, err)
+	require.False(t, revoked)
+
+	// 还没有过期的 token 仍然是吊销的
+	revoked, err = testQueries.IsTokenRevoked(context.Background(), activeID)
+	require.NoError(t, err)
+	require.True(t, revoked)
+}
@@ -0,0 +1,401 @@
+// Code generated by sqlc. DO NOT EDIT.
+// source: scheduled_transfer.sql
+
+package db
+
+import (
+	"context"
+	"database/sql"
+	"time"
+)
+
+const advanceScheduledTransfer = `-- name: AdvanceScheduledTransfer :one
+UPDATE scheduled_transfers
+SET scheduled_for = $1,
+    next_run_at = $2,
+    retry_count = $3,
+    status = CASE WHEN $4::bool THEN 'completed' ELSE status END,
+    updated_at = now()
+WHERE id = $5
+  AND status = 'active'
+  AND updated_at = $6
+RETURNING id, owner, from_account_id, to_account_id, amount, schedule, status, scheduled_for, next_run_at, end_at, retry_count, created_at, updated_at
+`
+
+type AdvanceScheduledTransferParams struct {
+	ScheduledFor time.Time `json:"scheduled_for"`
+	NextRunAt    time.Time `json:"next_run_at"`
+	RetryCount   int32     `json:"retry_count"`
+	Completed    bool      `json:"completed"`
+	ID           int64     `json:"id"`
+	UpdatedAt    time.Time `json:"updated_at"`
+}
+
+// 只修改执行进度，列出后被修改、取消或者被其他进程执行过的定时转账不会被更新
+func (q *Queries) AdvanceScheduledTransfer(ctx context.Context, arg AdvanceScheduledTransferParams) (ScheduledTransfer, error) {
+	row := q.db.QueryRowContext(ctx, advanceScheduledTransfer,
+		arg.ScheduledFor,
+		arg.NextRunAt,
+		arg.RetryCount,
+		arg.Completed,
+		arg.ID,
+		arg.UpdatedAt,
+	)
+	var i ScheduledTransfer
+	err := row.Scan(
+		&i.ID,
+		&i.Owner,
+		&i.FromAccountID,
+		&i.ToAccountID,
+		&i.Amount,
+		&i.Schedule,
+		&i.Status,
+		&i.ScheduledFor,
+		&i.NextRunAt,
+		&i.EndAt,
+		&i.RetryCount,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+	)
+	return i, err
+}
+
+const createScheduledTransfer = `-- name: CreateScheduledTransfer :one
+INSERT INTO scheduled_transfers (
+    owner,
+    from_account_id,
+    to_account_id,
+    amount,
+    schedule,
+    scheduled_for,
+    next_run_at,
+    end_at
+) VALUES (
+    $1, $2, $3, $4, $5, $6, $7, $8
+) RETURNING id, owner, from_account_id, to_account_id, amount, schedule, status, scheduled_for, next_run_at, end_at, retry_count, created_at, updated_at
+`
+
+type CreateScheduledTransferParams struct {
+	Owner         string       `json:"owner"`
+	FromAccountID int64        `json:"from_account_id"`
+	ToAccountID   int64        `json:"to_account_id"`
+	Amount        int64        `json:"amount"`
+	Schedule      string       `json:"schedule"`
+	ScheduledFor  time.Time    `json:"scheduled_for"`
+	NextRunAt     time.Time    `json:"next_run_at"`
+	EndAt         sql.NullTime `json:"end_at"`
+}
+
+func (q *Queries) CreateScheduledTransfer(ctx context.Context, arg CreateScheduledTransferParams) (ScheduledTransfer, error) {
+	row := q.db.QueryRowContext(ctx, createScheduledTransfer,
+		arg.Owner,
+		arg.FromAccountID,
+		arg.ToAccountID,
+		arg.Amount,
+		arg.Schedule,
+		arg.ScheduledFor,
+		arg.NextRunAt,
+		arg.EndAt,
+	)
+	var i ScheduledTransfer
+	err := row.Scan(
+		&i.ID,
+		&i.Owner,
+		&i.FromAccountID,
+		&i.ToAccountID,
+		&i.Amount,
+		&i.Schedule,
+		&i.Status,
+		&i.ScheduledFor,
+		&i.NextRunAt,
+		&i.EndAt,
+		&i.RetryCount,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+	)
+	return i, err
+}
+
+const createScheduledTransferRun = `-- name: CreateScheduledTransferRun :one
+INSERT INTO scheduled_transfer_runs (
+    scheduled_transfer_id,
+    scheduled_for,
+    attempt,
+    status,
+    transfer_id,
+    error
+) VALUES (
+    $1, $2, $3, $4, $5, $6
+) RETURNING id, scheduled_transfer_id, scheduled_for, attempt, status, transfer_id, error, created_at
+`
+
+type CreateScheduledTransferRunParams struct {
+	ScheduledTransferID int64         `json:"scheduled_transfer_id"`
+	ScheduledFor        time.Time     `json:"scheduled_for"`
+	Attempt             int32         `json:"attempt"`
+	Status              string        `json:"status"`
+	TransferID          sql.NullInt64 `json:"transfer_id"`
+	Error               string        `json:"error"`
+}
+
+func (q *Queries) CreateScheduledTransferRun(ctx context.Context, arg CreateScheduledTransferRunParams) (ScheduledTransferRun, error) {
+	row := q.db.QueryRowContext(ctx, createScheduledTransferRun,
+		arg.ScheduledTransferID,
+		arg.ScheduledFor,
+		arg.Attempt,
+		arg.Status,
+		arg.TransferID,
+		arg.Error,
+	)
+	var i ScheduledTransferRun
+	err := row.Scan(
+		&i.ID,
+		&i.ScheduledTransferID,
+		&i.ScheduledFor,
+		&i.Attempt,
+		&i.Status,
+		&i.TransferID,
+		&i.Error,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
+const getScheduledTransfer = `-- name: GetScheduledTransfer :one
+SELECT id, owner, from_account_id, to_account_id, amount, schedule, status, scheduled_for, next_run_at, end_at, retry_count, created_at, updated_at FROM scheduled_transfers
+WHERE id = $1 LIMIT 1
+`
+
+func (q *Queries) GetScheduledTransfer(ctx context.Context, id int64) (ScheduledTransfer, error) {
+	row := q.db.QueryRowContext(ctx, getScheduledTransfer, id)
+	var i ScheduledTransfer
+	err := row.Scan(
+		&i.ID,
+		&i.Owner,
+		&i.FromAccountID,
+		&i.ToAccountID,
+		&i.Amount,
+		&i.Schedule,
+		&i.Status,
+		&i.ScheduledFor,
+		&i.NextRunAt,
+		&i.EndAt,
+		&i.RetryCount,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+	)
+	return i, err
+}
+
+const listDueScheduledTransfers = `-- name: ListDueScheduledTransfers :many
+SELECT id, owner, from_account_id, to_account_id, amount, schedule, status, scheduled_for, next_run_at, end_at, retry_count, created_at, updated_at FROM scheduled_transfers
+WHERE status = 'active' AND next_run_at <= $1
+ORDER BY next_run_at
+LIMIT $2
+`
+
+type ListDueScheduledTransfersParams struct {
+	NextRunAt time.Time `json:"next_run_at"`
+	Limit     int32     `json:"limit"`
+}
+
+func (q *Queries) ListDueScheduledTransfers(ctx context.Context, arg ListDueScheduledTransfersParams) ([]ScheduledTransfer, error) {
+	rows, err := q.db.QueryContext(ctx, listDueScheduledTransfers, arg.NextRunAt, arg.Limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []ScheduledTransfer{}
+	for rows.Next() {
+		var i ScheduledTransfer
+		if err := rows.Scan(
+			&i.ID,
+			&i.Owner,
+			&i.FromAccountID,
+			&i.ToAccountID,
+			&i.Amount,
+			&i.Schedule,
+			&i.Status,
+			&i.ScheduledFor,
+			&i.NextRunAt,
+			&i.EndAt,
+			&i.RetryCount,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listScheduledTransferRuns = `-- name: ListScheduledTransferRuns :many
+SELECT id, scheduled_transfer_id, scheduled_for, attempt, status, transfer_id, error, created_at FROM scheduled_transfer_runs
+WHERE scheduled_transfer_id = $1
+  AND (created_at, id) < ($2::timestamptz, $3::bigint)
+ORDER BY created_at DESC, id DESC
+LIMIT $4
+`
+
+type ListScheduledTransferRunsParams struct {
+	ScheduledTransferID int64     `json:"scheduled_transfer_id"`
+	CursorCreatedAt     time.Time `json:"cursor_created_at"`
+	CursorID            int64     `json:"cursor_id"`
+	PageSize            int32     `json:"page_size"`
+}
+
+func (q *Queries) ListScheduledTransferRuns(ctx context.Context, arg ListScheduledTransferRunsParams) ([]ScheduledTransferRun, error) {
+	rows, err := q.db.QueryContext(ctx, listScheduledTransferRuns,
+		arg.ScheduledTransferID,
+		arg.CursorCreatedAt,
+		arg.CursorID,
+		arg.PageSize,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []ScheduledTransferRun{}
+	for rows.Next() {
+		var i ScheduledTransferRun
+		if err := rows.Scan(
+			&i.ID,
+			&i.ScheduledTransferID,
+			&i.ScheduledFor,
+			&i.Attempt,
+			&i.Status,
+			&i.TransferID,
+			&i.Error,
+			&i.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listScheduledTransfers = `-- name: ListScheduledTransfers :many
+SELECT id, owner, from_account_id, to_account_id, amount, schedule, status, scheduled_for, next_run_at, end_at, retry_count, created_at, updated_at FROM scheduled_transfers
+WHERE owner = $1
+  AND (created_at, id) > ($2::timestamptz, $3::bigint)
+ORDER BY created_at, id
+LIMIT $4
+`
+
+type ListScheduledTransfersParams struct {
+	Owner           string    `json:"owner"`
+	CursorCreatedAt time.Time `json:"cursor_created_at"`
+	CursorID        int64     `json:"cursor_id"`
+	PageSize        int32     `json:"page_size"`
+}
+
+func (q *Queries) ListScheduledTransfers(ctx context.Context, arg ListScheduledTransfersParams) ([]ScheduledTransfer, error) {
+	rows, err := q.db.QueryContext(ctx, listScheduledTransfers,
+		arg.Owner,
+		arg.CursorCreatedAt,
+		arg.CursorID,
+		arg.PageSize,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []ScheduledTransfer{}
+	for rows.Next() {
+		var i ScheduledTransfer
+		if err := rows.Scan(
+			&i.ID,
+			&i.Owner,
+			&i.FromAccountID,
+			&i.ToAccountID,
+			&i.Amount,
+			&i.Schedule,
+			&i.Status,
+			&i.ScheduledFor,
+			&i.NextRunAt,
+			&i.EndAt,
+			&i.RetryCount,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const updateScheduledTransfer = `-- name: UpdateScheduledTransfer :one
+UPDATE scheduled_transfers
+SET amount = $2,
+    schedule = $3,
+    status = $4,
+    scheduled_for = $5,
+    next_run_at = $6,
+    end_at = $7,
+    retry_count = $8,
+    updated_at = now()
+WHERE id = $1
+RETURNING id, owner, from_account_id, to_account_id, amount, schedule, status, scheduled_for, next_run_at, end_at, retry_count, created_at, updated_at
+`
+
+type UpdateScheduledTransferParams struct {
+	ID           int64        `json:"id"`
+	Amount       int64        `json:"amount"`
+	Schedule     string       `json:"schedule"`
+	Status       string       `json:"status"`
+	ScheduledFor time.Time    `json:"scheduled_for"`
+	NextRunAt    time.Time    `json:"next_run_at"`
+	EndAt        sql.NullTime `json:"end_at"`
+	RetryCount   int32        `json:"retry_count"`
+}
+
+func (q *Queries) UpdateScheduledTransfer(ctx context.Context, arg UpdateScheduledTransferParams) (ScheduledTransfer, error) {
+	row := q.db.QueryRowContext(ctx, updateScheduledTransfer,
+		arg.ID,
+		arg.Amount,
+		arg.Schedule,
+		arg.Status,
+		arg.ScheduledFor,
+		arg.NextRunAt,
+		arg.EndAt,
+		arg.RetryCount,
+	)
+	var i ScheduledTransfer
+	err := row.Scan(
+		&i.ID,
+		&i.Owner,
+		&i.FromAccountID,
+		&i.ToAccountID,
+		&i.Amount,
+		&i.Schedule,
+		&i.Status,
+		&i.ScheduledFor,
+		&i.NextRunAt,
+		&i.EndAt,
+		&i.RetryCount,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+	)
+	return i, err
+}
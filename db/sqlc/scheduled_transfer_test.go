@@ -0,0 +1,186 @@
+package db
+
+import (
+	"context"
+	"math"
+	"database/sql"
+	"github.com/stretchr/testify/require"
+	"github.com/techschool/simplebank/util"
+	"testing"
+	"time"
+)
+
+func createRandomScheduledTransfer(t *testing.T, nextRunAt time.Time) ScheduledTransfer {
+	account1 := createRandomAccount(t)
+	account2 := createRandomAccount(t)
+
+	arg := CreateScheduledTransferParams{
+		Owner:			account1.Owner,
+		FromAccountID:	account1.ID,
+		ToAccountID:	account2.ID,
+		Amount:			util.RandomMoney(),
+		Schedule:		"@daily",
+		ScheduledFor:	nextRunAt,
+		NextRunAt:		nextRunAt,
+		EndAt:			sql.NullTime{Time: nextRunAt.AddDate(0, 1, 0), Valid: true},
+	}
+
+	scheduledTransfer, err := testQueries.CreateScheduledTransfer(context.Background(), arg)
+	require.NoError(t, err)
+	require.NotEmpty(t, scheduledTransfer)
+
+	require.Equal(t, arg.Owner, scheduledTransfer.Owner)
+	require.Equal(t, arg.FromAccountID, scheduledTransfer.FromAccountID)
+	require.Equal(t, arg.ToAccountID, scheduledTransfer.ToAccountID)
+	require.Equal(t, arg.Amount, scheduledTransfer.Amount)
+	require.Equal(t, arg.Schedule, scheduledTransfer.Schedule)
+	require.Equal(t, util.ScheduleActive, scheduledTransfer.Status)
+	require.WithinDuration(t, arg.NextRunAt, scheduledTransfer.NextRunAt, time.Second)
+	require.WithinDuration(t, arg.EndAt.Time, scheduledTransfer.EndAt.Time, time.Second)
+	require.Zero(t, scheduledTransfer.RetryCount)
+	require.NotZero(t, scheduledTransfer.ID)
+	require.NotZero(t, scheduledTransfer.CreatedAt)
+	return scheduledTransfer
+}
+
+func TestCreateScheduledTransfer(t *testing.T) {
+	createRandomScheduledTransfer(t, time.Now().Add(time.Hour))
+}
+
+func TestGetScheduledTransfer(t *testing.T) {
+	scheduledTransfer1 := createRandomScheduledTransfer(t, time.Now().Add(time.Hour))
+	scheduledTransfer2, err := testQueries.GetScheduledTransfer(context.Background(), scheduledTransfer1.ID)
+	require.NoError(t, err)
+
+	require.Equal(t, scheduledTransfer1.ID, scheduledTransfer2.ID)
+	require.Equal(t, scheduledTransfer1.Owner, scheduledTransfer2.Owner)
+	require.Equal(t, scheduledTransfer1.Amount, scheduledTransfer2.Amount)
+	require.WithinDuration(t, scheduledTransfer1.NextRunAt, scheduledTransfer2.NextRunAt, time.Second)
+}
+
+func TestUpdateScheduledTransfer(t *testing.T) {
+	scheduledTransfer1 := createRandomScheduledTransfer(t, time.Now().Add(time.Hour))
+
+	arg := UpdateScheduledTransferParams{
+		ID:				scheduledTransfer1.ID,
+		Amount:			scheduledTransfer1.Amount + 1,
+		Schedule:		"@monthly 1",
+		Status:			util.SchedulePaused,
+		ScheduledFor:	scheduledTransfer1.ScheduledFor,
+		NextRunAt:		scheduledTransfer1.NextRunAt.Add(time.Minute),
+		RetryCount:		2,
+	}
+	scheduledTransfer2, err := testQueries.UpdateScheduledTransfer(context.Background(), arg)
+	require.NoError(t, err)
+
+	require.Equal(t, arg.Amount, scheduledTransfer2.Amount)
+	require.Equal(t, arg.Schedule, scheduledTransfer2.Schedule)
+	require.Equal(t, arg.Status, scheduledTransfer2.Status)
+	require.Equal(t, arg.RetryCount, scheduledTransfer2.RetryCount)
+	require.False(t, scheduledTransfer2.EndAt.Valid)
+	require.WithinDuration(t, arg.NextRunAt, scheduledTransfer2.NextRunAt, time.Second)
+}
+
+func TestListDueScheduledTransfers(t *testing.T) {
+	now := time.Now()
+	due := createRandomScheduledTransfer(t, now.Add(-time.Minute))
+	notDue := createRandomScheduledTransfer(t, now.Add(time.Hour))
+
+	scheduledTransfers, err := testQueries.ListDueScheduledTransfers(context.Background(), ListDueScheduledTransfersParams{
+		NextRunAt:	now,
+		Limit:		1000,
+	})
+	require.NoError(t, err)
+
+	ids := make(map[int64]bool)
+	for _, scheduledTransfer := range scheduledTransfers {
+		require.Equal(t, util.ScheduleActive, scheduledTransfer.Status)
+		require.False(t, scheduledTransfer.NextRunAt.After(now))
+		ids[scheduledTransfer.ID] = true
+	}
+	require.True(t, ids[due.ID])
+	require.False(t, ids[notDue.ID])
+}
+
+func TestScheduledTransferRuns(t *testing.T) {
+	scheduledTransfer := createRandomScheduledTransfer(t, time.Now())
+
+	for i := 0; i < 5; i++ {
+		run, err := testQueries.CreateScheduledTransferRun(context.Background(), CreateScheduledTransferRunParams{
+			ScheduledTransferID:	scheduledTransfer.ID,
+			ScheduledFor:			scheduledTransfer.ScheduledFor,
+			Attempt:				int32(i + 1),
+			Status:					"failed",
+			Error:					ErrInsufficientFunds.Error(),
+		})
+		require.NoError(t, err)
+		require.NotZero(t, run.ID)
+		require.False(t, run.TransferID.Valid)
+	}
+
+	runs, err := testQueries.ListScheduledTransferRuns(context.Background(), ListScheduledTransferRunsParams{
+		ScheduledTransferID:	scheduledTransfer.ID,
+		CursorCreatedAt:		time.Now().Add(time.Hour),
+		CursorID:				math.MaxInt64,
+		PageSize:				5,
+	})
+	require.NoError(t, err)
+	require.Len(t, runs, 5)
+	require.Equal(t, int32(5), runs[0].Attempt)
+}
+
+func TestAdvanceScheduledTransfer(t *testing.T) {
+	scheduledTransfer1 := createRandomScheduledTransfer(t, time.Now())
+
+	next := scheduledTransfer1.ScheduledFor.Add(24 * time.Hour)
+	arg := AdvanceScheduledTransferParams{
+		ID:				scheduledTransfer1.ID,
+		UpdatedAt:		scheduledTransfer1.UpdatedAt,
+		ScheduledFor:	next,
+		NextRunAt:		next,
+	}
+	scheduledTransfer2, err := testQueries.AdvanceScheduledTransfer(context.Background(), arg)
+	require.NoError(t, err)
+	require.Equal(t, util.ScheduleActive, scheduledTransfer2.Status)
+	require.WithinDuration(t, next, scheduledTransfer2.NextRunAt, time.Second)
+
+	// 使用旧的快照推进进度不会生效，同一次执行只推进一次
+	_, err = testQueries.AdvanceScheduledTransfer(context.Background(), arg)
+	require.ErrorIs(t, err, sql.ErrNoRows)
+
+	arg.UpdatedAt = scheduledTransfer2.UpdatedAt
+	arg.Completed = true
+	scheduledTransfer3, err := testQueries.AdvanceScheduledTransfer(context.Background(), arg)
+	require.NoError(t, err)
+	require.Equal(t, util.ScheduleCompleted, scheduledTransfer3.Status)
+}
+
+func TestAdvanceScheduledTransferCancelled(t *testing.T) {
+	scheduledTransfer1 := createRandomScheduledTransfer(t, time.Now())
+
+	// 在列出之后、推进进度之前被取消
+	_, err := testQueries.UpdateScheduledTransfer(context.Background(), UpdateScheduledTransferParams{
+		ID:				scheduledTransfer1.ID,
+		Amount:			scheduledTransfer1.Amount,
+		Schedule:		scheduledTransfer1.Schedule,
+		Status:			util.ScheduleCancelled,
+		ScheduledFor:	scheduledTransfer1.ScheduledFor,
+		NextRunAt:		scheduledTransfer1.NextRunAt,
+		EndAt:			scheduledTransfer1.EndAt,
+	})
+	require.NoError(t, err)
+
+	next := scheduledTransfer1.ScheduledFor.Add(24 * time.Hour)
+	_, err = testQueries.AdvanceScheduledTransfer(context.Background(), AdvanceScheduledTransferParams{
+		ID:				scheduledTransfer1.ID,
+		UpdatedAt:		scheduledTransfer1.UpdatedAt,
+		ScheduledFor:	next,
+		NextRunAt:		next,
+	})
+	require.ErrorIs(t, err, sql.ErrNoRows)
+
+	scheduledTransfer2, err := testQueries.GetScheduledTransfer(context.Background(), scheduledTransfer1.ID)
+	require.NoError(t, err)
+	require.Equal(t, util.ScheduleCancelled, scheduledTransfer2.Status)
+	require.WithinDuration(t, scheduledTransfer1.NextRunAt, scheduledTransfer2.NextRunAt, time.Second)
+}
@@ -0,0 +1,142 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/lib/pq"
+	"time"
+)
+
+// ErrInvalidStatementPeriod is returned when the statement period is empty or hasn't ended yet.
+var ErrInvalidStatementPeriod = errors.New("invalid statement period")
+
+// statementPeriodKey 同一个账户同一期间只有一份对账单
+const statementPeriodKey = "statements_account_id_period_start_period_end_idx"
+
+// StatementLine is an entry in the statement, the lines are saved with the statement as a snapshot.
+type StatementLine struct {
+	EntryID					int64		`json:"entry_id"`
+	PostedAt				time.Time	`json:"posted_at"`
+	// Kind 流水所属分录的类型，例如 transfer、deposit
+	Kind					string		`json:"kind"`
+	Memo					string		`json:"memo"`
+	Amount					int64		`json:"amount"`
+	// Balance 入账这条流水之后的余额
+	Balance					int64		`json:"balance"`
+	// TransferID 和对方账户只有转账的流水才有，否则为 0
+	TransferID				int64		`json:"transfer_id"`
+	CounterpartyAccountID	int64		`json:"counterparty_account_id"`
+	CounterpartyOwner		string		`json:"counterparty_owner"`
+}
+
+// StatementLines decodes the lines saved with the statement.
+func StatementLines(statement Statement) ([]StatementLine, error) {
+	var lines []StatementLine
+	err := json.Unmarshal(statement.Lines, &lines)
+	return lines, err
+}
+
+// CreateStatementTxParams contains the input parameters of CreateStatementTx, entries created in
+// [PeriodStart, PeriodEnd) are included in the statement.
+type CreateStatementTxParams struct {
+	AccountID	int64		`json:"account_id"`
+	PeriodStart	time.Time	`json:"period_start"`
+	PeriodEnd	time.Time	`json:"period_end"`
+}
+
+// CreateStatementTx generates the statement of the account for a period that has ended.
+// A statement never changes once generated, so the existing statement is returned if the period has one.
+func (store *SQLStore) CreateStatementTx(ctx context.Context, arg CreateStatementTxParams) (Statement, error) {
+	if !arg.PeriodStart.Before(arg.PeriodEnd) || arg.PeriodEnd.After(time.Now()) {
+		return Statement{}, fmt.Errorf("%w: [%s, %s) is empty or hasn't ended", ErrInvalidStatementPeriod,
+			arg.PeriodStart.Format(time.RFC3339), arg.PeriodEnd.Format(time.RFC3339))
+	}
+
+	period := GetStatementByPeriodParams{
+		AccountID:		arg.AccountID,
+		PeriodStart:	arg.PeriodStart,
+		PeriodEnd:		arg.PeriodEnd,
+	}
+	statement, err := store.GetStatementByPeriod(ctx, period)
+	if err != sql.ErrNoRows {
+		return statement, err
+	}
+
+	// 期初余额和期间的流水在同一个快照中读取
+	opts := &sql.TxOptions{Isolation: sql.LevelRepeatableRead}
+	err = store.execTxWithOptions(ctx, opts, func(q *Queries) error {
+		var err error
+		statement, err = buildStatement(ctx, q, arg)
+		return err
+	})
+	if pqErr, ok := err.(*pq.Error); ok && pqErr.Constraint == statementPeriodKey {
+		// 并发生成同一期间的对账单时，以先保存的为准
+		return store.GetStatementByPeriod(ctx, period)
+	}
+	return statement, err
+}
+
+// buildStatement 计算期初余额、逐条流水的余额和期末余额，并保存对账单
+func buildStatement(ctx context.Context, q *Queries, arg CreateStatementTxParams) (Statement, error) {
+	account, err := q.GetAccount(ctx, arg.AccountID)
+	if err != nil {
+		return Statement{}, err
+	}
+
+	opening, err := balanceBefore(ctx, q, account.ID, arg.PeriodStart)
+	if err != nil {
+		return Statement{}, err
+	}
+
+	entries, err := q.ListStatementEntries(ctx, ListStatementEntriesParams{
+		AccountID:	account.ID,
+		StartTime:	arg.PeriodStart,
+		EndTime:	arg.PeriodEnd,
+	})
+	if err != nil {
+		return Statement{}, err
+	}
+
+	balance := opening
+	var credits, debits int64
+	lines := make([]StatementLine, len(entries))
+	for i, entry := range entries {
+		balance += entry.Amount
+		if entry.Amount > 0 {
+			credits += entry.Amount
+		} else {
+			debits -= entry.Amount
+		}
+		lines[i] = StatementLine{
+			EntryID:				entry.ID,
+			PostedAt:				entry.CreatedAt,
+			Kind:					entry.Kind,
+			Memo:					entry.Memo,
+			Amount:					entry.Amount,
+			Balance:				balance,
+			TransferID:				entry.TransferID,
+			CounterpartyAccountID:	entry.CounterpartyAccountID,
+			CounterpartyOwner:		entry.CounterpartyOwner,
+		}
+	}
+	data, err := json.Marshal(lines)
+	if err != nil {
+		return Statement{}, err
+	}
+
+	return q.CreateStatement(ctx, CreateStatementParams{
+		AccountID:		account.ID,
+		Currency:		account.Currency,
+		PeriodStart:	arg.PeriodStart,
+		PeriodEnd:		arg.PeriodEnd,
+		OpeningBalance:	opening,
+		ClosingBalance:	balance,
+		TotalCredits:	credits,
+		TotalDebits:	debits,
+		EntryCount:		int64(len(entries)),
+		Lines:			data,
+	})
+}
@@ -0,0 +1,325 @@
+// Code generated by sqlc. DO NOT EDIT.
+// source: statement.sql
+
+package db
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+)
+
+const createStatement = `-- name: CreateStatement :one
+INSERT INTO statements (
+    account_id,
+    currency,
+    period_start,
+    period_end,
+    opening_balance,
+    closing_balance,
+    total_credits,
+    total_debits,
+    entry_count,
+    lines
+) VALUES (
+    $1, $2, $3, $4, $5, $6, $7, $8, $9, $10
+) RETURNING id, account_id, currency, period_start, period_end, opening_balance, closing_balance, total_credits, total_debits, entry_count, lines, created_at
+`
+
+type CreateStatementParams struct {
+	AccountID      int64           `json:"account_id"`
+	Currency       string          `json:"currency"`
+	PeriodStart    time.Time       `json:"period_start"`
+	PeriodEnd      time.Time       `json:"period_end"`
+	OpeningBalance int64           `json:"opening_balance"`
+	ClosingBalance int64           `json:"closing_balance"`
+	TotalCredits   int64           `json:"total_credits"`
+	TotalDebits    int64           `json:"total_debits"`
+	EntryCount     int64           `json:"entry_count"`
+	Lines          json.RawMessage `json:"lines"`
+}
+
+func (q *Queries) CreateStatement(ctx context.Context, arg CreateStatementParams) (Statement, error) {
+	row := q.db.QueryRowContext(ctx, createStatement,
+		arg.AccountID,
+		arg.Currency,
+		arg.PeriodStart,
+		arg.PeriodEnd,
+		arg.OpeningBalance,
+		arg.ClosingBalance,
+		arg.TotalCredits,
+		arg.TotalDebits,
+		arg.EntryCount,
+		arg.Lines,
+	)
+	var i Statement
+	err := row.Scan(
+		&i.ID,
+		&i.AccountID,
+		&i.Currency,
+		&i.PeriodStart,
+		&i.PeriodEnd,
+		&i.OpeningBalance,
+		&i.ClosingBalance,
+		&i.TotalCredits,
+		&i.TotalDebits,
+		&i.EntryCount,
+		&i.Lines,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
+const getStatement = `-- name: GetStatement :one
+SELECT id, account_id, currency, period_start, period_end, opening_balance, closing_balance, total_credits, total_debits, entry_count, lines, created_at FROM statements
+WHERE id = $1 LIMIT 1
+`
+
+func (q *Queries) GetStatement(ctx context.Context, id int64) (Statement, error) {
+	row := q.db.QueryRowContext(ctx, getStatement, id)
+	var i Statement
+	err := row.Scan(
+		&i.ID,
+		&i.AccountID,
+		&i.Currency,
+		&i.PeriodStart,
+		&i.PeriodEnd,
+		&i.OpeningBalance,
+		&i.ClosingBalance,
+		&i.TotalCredits,
+		&i.TotalDebits,
+		&i.EntryCount,
+		&i.Lines,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
+const getStatementByPeriod = `-- name: GetStatementByPeriod :one
+SELECT id, account_id, currency, period_start, period_end, opening_balance, closing_balance, total_credits, total_debits, entry_count, lines, created_at FROM statements
+WHERE account_id = $1 AND period_start = $2 AND period_end = $3
+LIMIT 1
+`
+
+type GetStatementByPeriodParams struct {
+	AccountID   int64     `json:"account_id"`
+	PeriodStart time.Time `json:"period_start"`
+	PeriodEnd   time.Time `json:"period_end"`
+}
+
+func (q *Queries) GetStatementByPeriod(ctx context.Context, arg GetStatementByPeriodParams) (Statement, error) {
+	row := q.db.QueryRowContext(ctx, getStatementByPeriod, arg.AccountID, arg.PeriodStart, arg.PeriodEnd)
+	var i Statement
+	err := row.Scan(
+		&i.ID,
+		&i.AccountID,
+		&i.Currency,
+		&i.PeriodStart,
+		&i.PeriodEnd,
+		&i.OpeningBalance,
+		&i.ClosingBalance,
+		&i.TotalCredits,
+		&i.TotalDebits,
+		&i.EntryCount,
+		&i.Lines,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
+const listStatementAccounts = `-- name: ListStatementAccounts :many
+SELECT a.id, a.owner, a.balance, a.currency, a.created_at, a.system_code, a.entry_hash FROM account a
+WHERE a.system_code = ''
+  AND a.created_at < $1::timestamptz
+  AND a.id > $2::bigint
+  AND NOT EXISTS (
+    SELECT 1 FROM statements s
+    WHERE s.account_id = a.id
+      AND s.period_start = $3::timestamptz
+      AND s.period_end = $1::timestamptz
+  )
+ORDER BY a.id
+LIMIT $4
+`
+
+type ListStatementAccountsParams struct {
+	PeriodEnd   time.Time `json:"period_end"`
+	AfterID     int64     `json:"after_id"`
+	PeriodStart time.Time `json:"period_start"`
+	PageSize    int32     `json:"page_size"`
+}
+
+// 需要生成对账单的客户账户：账户在期末之前已经开立，且还没有该期间的对账单，按 id 分页
+func (q *Queries) ListStatementAccounts(ctx context.Context, arg ListStatementAccountsParams) ([]Account, error) {
+	rows, err := q.db.QueryContext(ctx, listStatementAccounts,
+		arg.PeriodEnd,
+		arg.AfterID,
+		arg.PeriodStart,
+		arg.PageSize,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []Account{}
+	for rows.Next() {
+		var i Account
+		if err := rows.Scan(
+			&i.ID,
+			&i.Owner,
+			&i.Balance,
+			&i.Currency,
+			&i.CreatedAt,
+			&i.SystemCode,
+			&i.EntryHash,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listStatementEntries = `-- name: ListStatementEntries :many
+SELECT e.id, e.amount, e.created_at, COALESCE(e.transfer_id, 0)::bigint AS transfer_id,
+       COALESCE(j.kind, '')::varchar AS kind,
+       COALESCE(NULLIF(t.memo, ''), j.memo, '')::varchar AS memo,
+       COALESCE(c.id, 0)::bigint AS counterparty_account_id,
+       COALESCE(c.owner, '')::varchar AS counterparty_owner
+FROM entries e
+LEFT JOIN journals j ON j.id = e.journal_id
+LEFT JOIN transfers t ON t.id = e.transfer_id
+LEFT JOIN account c ON c.id = CASE WHEN t.from_account_id = e.account_id THEN t.to_account_id ELSE t.from_account_id END
+WHERE e.account_id = $1
+  AND e.created_at >= $2
+  AND e.created_at < $3
+ORDER BY e.created_at, e.id
+`
+
+type ListStatementEntriesParams struct {
+	AccountID int64     `json:"account_id"`
+	StartTime time.Time `json:"start_time"`
+	EndTime   time.Time `json:"end_time"`
+}
+
+type ListStatementEntriesRow struct {
+	ID                    int64     `json:"id"`
+	Amount                int64     `json:"amount"`
+	CreatedAt             time.Time `json:"created_at"`
+	TransferID            int64     `json:"transfer_id"`
+	Kind                  string    `json:"kind"`
+	Memo                  string    `json:"memo"`
+	CounterpartyAccountID int64     `json:"counterparty_account_id"`
+	CounterpartyOwner     string    `json:"counterparty_owner"`
+}
+
+// 账户在 [start_time, end_time) 之间的流水，转账的对方账户从 transfers 中查询
+func (q *Queries) ListStatementEntries(ctx context.Context, arg ListStatementEntriesParams) ([]ListStatementEntriesRow, error) {
+	rows, err := q.db.QueryContext(ctx, listStatementEntries, arg.AccountID, arg.StartTime, arg.EndTime)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []ListStatementEntriesRow{}
+	for rows.Next() {
+		var i ListStatementEntriesRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.Amount,
+			&i.CreatedAt,
+			&i.TransferID,
+			&i.Kind,
+			&i.Memo,
+			&i.CounterpartyAccountID,
+			&i.CounterpartyOwner,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listStatements = `-- name: ListStatements :many
+SELECT id, account_id, currency, period_start, period_end, opening_balance, closing_balance,
+       total_credits, total_debits, entry_count, created_at
+FROM statements
+WHERE account_id = $1
+  AND (created_at, id) < ($2::timestamptz, $3::bigint)
+ORDER BY created_at DESC, id DESC
+LIMIT $4
+`
+
+type ListStatementsParams struct {
+	AccountID       int64     `json:"account_id"`
+	CursorCreatedAt time.Time `json:"cursor_created_at"`
+	CursorID        int64     `json:"cursor_id"`
+	PageSize        int32     `json:"page_size"`
+}
+
+type ListStatementsRow struct {
+	ID             int64     `json:"id"`
+	AccountID      int64     `json:"account_id"`
+	Currency       string    `json:"currency"`
+	PeriodStart    time.Time `json:"period_start"`
+	PeriodEnd      time.Time `json:"period_end"`
+	OpeningBalance int64     `json:"opening_balance"`
+	ClosingBalance int64     `json:"closing_balance"`
+	TotalCredits   int64     `json:"total_credits"`
+	TotalDebits    int64     `json:"total_debits"`
+	EntryCount     int64     `json:"entry_count"`
+	CreatedAt      time.Time `json:"created_at"`
+}
+
+// 列表中不返回流水明细，按 (created_at, id) 倒序的 keyset 分页
+func (q *Queries) ListStatements(ctx context.Context, arg ListStatementsParams) ([]ListStatementsRow, error) {
+	rows, err := q.db.QueryContext(ctx, listStatements,
+		arg.AccountID,
+		arg.CursorCreatedAt,
+		arg.CursorID,
+		arg.PageSize,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []ListStatementsRow{}
+	for rows.Next() {
+		var i ListStatementsRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.AccountID,
+			&i.Currency,
+			&i.PeriodStart,
+			&i.PeriodEnd,
+			&i.OpeningBalance,
+			&i.ClosingBalance,
+			&i.TotalCredits,
+			&i.TotalDebits,
+			&i.EntryCount,
+			&i.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
@@ -0,0 +1,69 @@
+package db
+
+import (
+	"context"
+	"github.com/stretchr/testify/require"
+	"github.com/techschool/simplebank/util"
+	"testing"
+	"time"
+)
+
+func TestCreateStatementTx(t *testing.T) {
+	store := NewStore(testDB)
+	account := setAccountBalance(t, createAccountWithCurrency(t, util.USD), 0)
+	payee := createAccountWithCurrency(t, util.USD)
+
+	opening := depositAmount(t, store, account, 100)
+	deposit := depositAmount(t, store, account, 80)
+	transfer, err := store.TransferTx(context.Background(), TransferTxParams{
+		FromAccountID:	account.ID,
+		ToAccountID:	payee.ID,
+		Amount:			30,
+		Memo:			"lunch",
+	})
+	require.NoError(t, err)
+
+	// 第一笔存款在期间之前，计入期初余额
+	arg := CreateStatementTxParams{
+		AccountID:		account.ID,
+		PeriodStart:	deposit.CreatedAt,
+		PeriodEnd:		time.Now(),
+	}
+	require.True(t, opening.CreatedAt.Before(arg.PeriodStart))
+
+	statement, err := store.CreateStatementTx(context.Background(), arg)
+	require.NoError(t, err)
+	require.Equal(t, int64(100), statement.OpeningBalance)
+	require.Equal(t, int64(150), statement.ClosingBalance)
+	require.Equal(t, int64(80), statement.TotalCredits)
+	require.Equal(t, int64(30), statement.TotalDebits)
+	require.Equal(t, int64(2), statement.EntryCount)
+
+	lines, err := StatementLines(statement)
+	require.NoError(t, err)
+	require.Len(t, lines, 2)
+	require.Equal(t, util.JournalDeposit, lines[0].Kind)
+	require.Equal(t, int64(180), lines[0].Balance)
+	require.Zero(t, lines[0].CounterpartyAccountID)
+	require.Equal(t, transfer.Transfer.ID, lines[1].TransferID)
+	require.Equal(t, payee.ID, lines[1].CounterpartyAccountID)
+	require.Equal(t, payee.Owner, lines[1].CounterpartyOwner)
+	require.Equal(t, "lunch", lines[1].Memo)
+	require.Equal(t, int64(150), lines[1].Balance)
+
+	// 同一期间再次生成时返回已有的对账单，之后的流水不会改变它
+	depositAmount(t, store, account, 10)
+	again, err := store.CreateStatementTx(context.Background(), arg)
+	require.NoError(t, err)
+	require.Equal(t, statement.ID, again.ID)
+	require.Equal(t, statement.ClosingBalance, again.ClosingBalance)
+
+	// 对账单不能修改
+	_, err = testDB.Exec("UPDATE statements SET closing_balance = 0 WHERE id = $1", statement.ID)
+	require.Error(t, err)
+
+	// 没有结束的期间不能生成对账单
+	arg.PeriodEnd = time.Now().Add(time.Hour)
+	_, err = store.CreateStatementTx(context.Background(), arg)
+	require.ErrorIs(t, err, ErrInvalidStatementPeriod)
+}
@@ -3,13 +3,47 @@ package db
 import (
 	"context"
 	"database/sql"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"github.com/lib/pq"
+	"github.com/techschool/simplebank/util"
 )
+
+// ErrInsufficientFunds is returned when a transaction would take an account balance below the allowed floor.
+var ErrInsufficientFunds = errors.New("insufficient funds")
+
+// ErrInvalidExchange is returned when a cross-currency transfer cannot be made with the exchange rate.
+var ErrInvalidExchange = errors.New("invalid currency exchange")
+
+// ErrDuplicateReference is returned when the external reference has been used by another transfer of the from account.
+var ErrDuplicateReference = errors.New("duplicate external reference")
+
+// transferReferenceKey 转出账户和 external_reference 的唯一索引
+const transferReferenceKey = "transfers_external_reference_key"
+
 // Store provide all functions to execute db queries and translations
 // Store 对象提供了所有数据库的操作的查询和事务方法
 type Store interface {
 	Querier
 	TransferTx(context.Context, TransferTxParams) (TransferTxResult, error)
+	IdempotentTransferTx(context.Context, IdempotentTransferTxParams) (TransferTxResult, error)
+	ReverseTransferTx(context.Context, ReverseTransferTxParams) (ReverseTransferTxResult, error)
+	ApproveTransferTx(context.Context, DecideTransferTxParams) (TransferTxResult, error)
+	RejectTransferTx(context.Context, DecideTransferTxParams) (Transfer, error)
+	CreateHoldTx(context.Context, CreateHoldParams) (Hold, error)
+	CaptureHoldTx(context.Context, CaptureHoldTxParams) (CaptureHoldTxResult, error)
+	VoidHoldTx(context.Context, int64) (Hold, error)
+	BatchTransferTx(context.Context, BatchTransferTxParams) (BatchTransferTxResult, error)
+	ReconcileTx(context.Context) (ReconcileTxResult, error)
+	PostJournalTx(context.Context, PostJournalTxParams) (PostJournalTxResult, error)
+	DepositTx(context.Context, CashTxParams) (CashTxResult, error)
+	WithdrawTx(context.Context, CashTxParams) (CashTxResult, error)
+	VerifyEntryChain(context.Context, VerifyEntryChainParams) (VerifyEntryChainResult, error)
+	GetBalanceAt(context.Context, GetBalanceAtParams) (int64, error)
+	ListDailyBalances(context.Context, ListDailyBalancesParams) ([]DailyBalance, error)
+	CreateStatementTx(context.Context, CreateStatementTxParams) (Statement, error)
+	EnsureSystemAccounts(context.Context) (int64, error)
 }
 
 // SQLStore provide all functions to execute db queries and translations
@@ -17,19 +51,37 @@ type Store interface {
 type SQLStore struct {
 	*Queries
 	db *sql.DB
+	options StoreOptions
 }
 
-// NewStore create a Store
+// StoreOptions contains the business rules enforced by SQLStore transactions.
+type StoreOptions struct {
+	// OverdraftLimit 账户余额允许透支的额度，0 表示余额不能为负数
+	OverdraftLimit int64
+}
+
+// NewStore create a Store, account balances must stay non-negative
 func NewStore(db *sql.DB) Store {
+	return NewStoreWithOptions(db, StoreOptions{})
+}
+
+// NewStoreWithOptions create a Store with specific business rules
+func NewStoreWithOptions(db *sql.DB, options StoreOptions) Store {
 	return &SQLStore{
 		db: db,
 		Queries: New(db),
+		options: options,
 	}
 }
 
 // execTx executes a function with database translation
 func (store *SQLStore) execTx(ctx context.Context, fn func(*Queries) error) error {
-	tx, err := store.db.BeginTx(ctx, nil)
+	return store.execTxWithOptions(ctx, nil, fn)
+}
+
+// execTxWithOptions executes a function within a database translation with the isolation level and read only flag of opts
+func (store *SQLStore) execTxWithOptions(ctx context.Context, opts *sql.TxOptions, fn func(*Queries) error) error {
+	tx, err := store.db.BeginTx(ctx, opts)
 	if err != nil {
 		return err
 	}
@@ -50,6 +102,16 @@ type TransferTxParams struct {
 	FromAccountID int64 `json:"from_account_id"`
 	ToAccountID   int64 `json:"to_account_id"`
 	Amount        int64 `json:"amount"`
+	// ExchangeRateID 跨币种转账使用的汇率，0 表示两个账户币种相同
+	ExchangeRateID int64 `json:"exchange_rate_id"`
+	// RequestedBy 发起转账的用户
+	RequestedBy string `json:"requested_by"`
+	// RequireApproval 为 true 时只创建 pending 的转账，审批通过后才结算
+	RequireApproval bool `json:"require_approval"`
+	// Memo 转账的备注，ExternalReference 客户端提供的参考号，同一个转出账户不能重复，Metadata 必须是 JSON 对象
+	Memo string `json:"memo"`
+	ExternalReference string `json:"external_reference"`
+	Metadata json.RawMessage `json:"metadata"`
 }
 
 // TransferTxResult is the result of the transfer translation.
@@ -59,6 +121,8 @@ type TransferTxResult struct {
 	ToAccount   Account  `json:"to_account"`   // accounts 表
 	FromEntry   Entry    `json:"from_entry"`   // entries 表
 	ToEntry     Entry    `json:"to_entry"`     // entries 表
+	// Replayed 为 true 表示使用相同的 idempotency key 重放，返回的是第一次转账的结果
+	Replayed    bool     `json:"-"`
 }
 
 var txKey = struct {}{}
@@ -69,94 +133,168 @@ func (store *SQLStore) TransferTx(ctx context.Context, arg TransferTxParams) (Tr
 	var result TransferTxResult
 	err := store.execTx(ctx, func(q *Queries) error {
 		var err error
+		result, err = store.transfer(ctx, q, arg)
+		return err
+	})
+	return result, err
+}
 
-		txName := ctx.Value(txKey)
-		fmt.Println(txName, "create transfer")
+// transfer checks the transfer limits, creates a transfer record and settles it using the queries of an opened
+// translation, a transfer that requires approval is only recorded as pending.
+func (store *SQLStore) transfer(ctx context.Context, q *Queries, arg TransferTxParams) (TransferTxResult, error) {
+	var result TransferTxResult
 
-		result.Transfer, err = q.CreateTransfers(ctx, CreateTransfersParams{
-			FromAccountID: arg.FromAccountID,
-			ToAccountID:   arg.ToAccountID,
-			Amount:        arg.Amount,
-		})
+	err := checkTransferLimits(ctx, q, arg)
+	if err != nil {
+		return result, err
+	}
+
+	transfer, err := store.createTransfer(ctx, q, arg)
+	if err != nil {
+		return result, err
+	}
+	if transfer.Status == util.TransferPending {
+		result.Transfer = transfer
+		return result, nil
+	}
+	return store.settleTransfer(ctx, q, transfer)
+}
+
+// createTransfer creates the transfer record, the amount is converted to the currency of the to account if needed
+func (store *SQLStore) createTransfer(ctx context.Context, q *Queries, arg TransferTxParams) (Transfer, error) {
+	// 跨币种转账时，转入的金额按汇率换算成转入账户的币种
+	toAmount := arg.Amount
+	var exchangeRate ExchangeRate
+	if arg.ExchangeRateID != 0 {
+		var err error
+		exchangeRate, err = q.GetExchangeRate(ctx, arg.ExchangeRateID)
 		if err != nil {
-			return err
+			return Transfer{}, err
 		}
-
-		fmt.Println(txName, "create entry 1")
-		result.FromEntry, err = q.CreateEntry(ctx, CreateEntryParams{
-			AccountID: arg.FromAccountID,
-			Amount: -arg.Amount,
-		})
+		err = checkExchangeCurrencies(ctx, q, arg, exchangeRate)
 		if err != nil {
-			return err
+			return Transfer{}, err
 		}
-
-		fmt.Println(txName, "create entry 2")
-		result.ToEntry, err = q.CreateEntry(ctx, CreateEntryParams{
-			AccountID: arg.ToAccountID,
-			Amount: arg.Amount,
-		})
+		toAmount, err = util.ConvertAmount(arg.Amount, exchangeRate.BaseCurrency, exchangeRate.QuoteCurrency, exchangeRate.Rate)
 		if err != nil {
-			return err
+			return Transfer{}, fmt.Errorf("%w: %v", ErrInvalidExchange, err)
 		}
-
-		fmt.Println(txName, "get account 1")
-		// get account -> update its balance  need lock
-		//account1, err := q.GetAccountForUpdate(ctx, arg.FromAccountID)
-		//if err != nil {
-		//	return err
-		//}
-
-		fmt.Println(txName, "update account 1")
-
-		if arg.FromAccountID < arg.ToAccountID {
-			//result.FromAccount, err = q.AddaAccountBalance(ctx, AddaAccountBalanceParams{
-			//	ID:     arg.FromAccountID,
-			//	Amount: -arg.Amount,
-			//})
-			//if err != nil {
-			//	return err
-			//}
-			//
-			////fmt.Println(txName, "get account 2")
-			////account2, err := q.GetAccountForUpdate(ctx, arg.ToAccountID)
-			////if err != nil {
-			////	return err
-			////}
-			//
-			//fmt.Println(txName, "update account 2")
-			//result.ToAccount, err = q.AddaAccountBalance(ctx, AddaAccountBalanceParams{
-			//	ID:     arg.ToAccountID,
-			//	Amount: arg.Amount,
-			//})
-			//if err != nil {
-			//	return err
-			//}
-			result.FromAccount, result.ToAccount, err = addMoney(ctx, q, arg.FromAccountID, -arg.Amount, arg.ToAccountID, arg.Amount)
-		} else {
-			result.ToAccount, result.FromAccount, err = addMoney(ctx, q, arg.ToAccountID, arg.Amount, arg.FromAccountID, -arg.Amount)
+		if toAmount <= 0 {
+			return Transfer{}, fmt.Errorf("%w: amount %d is too small to be converted", ErrInvalidExchange, arg.Amount)
 		}
-		return nil
+	}
+
+	status := util.TransferCompleted
+	if arg.RequireApproval {
+		status = util.TransferPending
+	}
+
+	transfer, err := q.CreateTransfers(ctx, CreateTransfersParams{
+		FromAccountID:		arg.FromAccountID,
+		ToAccountID:		arg.ToAccountID,
+		Amount:				arg.Amount,
+		ToAmount:			toAmount,
+		ExchangeRate:		exchangeRateValue(exchangeRate),
+		ExchangeRateID:		sql.NullInt64{Int64: exchangeRate.ID, Valid: arg.ExchangeRateID != 0},
+		Status:				status,
+		RequestedBy:		sql.NullString{String: arg.RequestedBy, Valid: arg.RequestedBy != ""},
+		Memo:				arg.Memo,
+		ExternalReference:	arg.ExternalReference,
+		Metadata:			metadataValue(arg.Metadata),
 	})
-	return result, err
+	if pqErr, ok := err.(*pq.Error); ok && pqErr.Constraint == transferReferenceKey {
+		return transfer, fmt.Errorf("%w: %q has been used by account [%d]",
+			ErrDuplicateReference, arg.ExternalReference, arg.FromAccountID)
+	}
+	return transfer, err
 }
 
-func addMoney(
-	ctx context.Context, q *Queries, accountID1 int64, amount1 int64, accountID2 int64, amount2 int64,
-	) (account1, account2 Account, err error) {
-	account1, err = q.AddaAccountBalance(ctx, AddaAccountBalanceParams{
-		ID: accountID1,
-		Amount: amount1,
-	})
+// metadataValue 没有提供 metadata 时记为空的 JSON 对象
+func metadataValue(metadata json.RawMessage) json.RawMessage {
+	if len(metadata) == 0 {
+		return json.RawMessage("{}")
+	}
+	return metadata
+}
+
+// checkExchangeCurrencies returns ErrInvalidExchange if the exchange rate doesn't match the currencies of the accounts
+func checkExchangeCurrencies(ctx context.Context, q *Queries, arg TransferTxParams, exchangeRate ExchangeRate) error {
+	fromAccount, err := q.GetAccount(ctx, arg.FromAccountID)
 	if err != nil {
-		return
+		return err
+	}
+	toAccount, err := q.GetAccount(ctx, arg.ToAccountID)
+	if err != nil {
+		return err
 	}
 
-	account2, err = q.AddaAccountBalance(ctx, AddaAccountBalanceParams{
-		ID: accountID2,
-		Amount: amount2,
+	if fromAccount.Currency != exchangeRate.BaseCurrency || toAccount.Currency != exchangeRate.QuoteCurrency {
+		return fmt.Errorf("%w: exchange rate [%d] is %s to %s, but accounts are %s to %s",
+			ErrInvalidExchange, exchangeRate.ID, exchangeRate.BaseCurrency, exchangeRate.QuoteCurrency,
+			fromAccount.Currency, toAccount.Currency)
+	}
+	return nil
+}
+
+// settleTransfer posts the journal of the transfer record, which creates the account entries and updates accounts' balance
+func (store *SQLStore) settleTransfer(ctx context.Context, q *Queries, transfer Transfer) (TransferTxResult, error) {
+	result := TransferTxResult{Transfer: transfer}
+
+	legs, err := transferLegs(ctx, q, transfer)
+	if err != nil {
+		return result, err
+	}
+
+	kind := util.JournalTransfer
+	if transfer.ReversalOf.Valid {
+		kind = util.JournalReversal
+	}
+
+	posted, err := postJournal(ctx, q, PostJournalTxParams{
+		Kind:		kind,
+		TransferID:	sql.NullInt64{Int64: transfer.ID, Valid: true},
+		CreatedBy:	transfer.RequestedBy.String,
+		Legs:		legs,
 	})
-	return
+	if err != nil {
+		return result, err
+	}
+	result.FromEntry, result.ToEntry = posted.Entries[0], posted.Entries[1]
+	result.FromAccount, result.ToAccount = posted.Accounts[0], posted.Accounts[1]
+
+	// 系统账户只能通过存款、取款等内部分录变动
+	for _, account := range []Account{result.FromAccount, result.ToAccount} {
+		if account.SystemCode != "" {
+			return result, fmt.Errorf("%w: account [%d] is the %s account", ErrSystemAccount, account.ID, account.SystemCode)
+		}
+	}
+
+	// 转出账户的行锁在事务结束前一直持有，此时检查余额不会有并发问题
+	return result, store.checkBalance(ctx, q, result.FromAccount)
+}
+
+// exchangeRateValue 同币种转账的汇率记为 1
+func exchangeRateValue(exchangeRate ExchangeRate) string {
+	if exchangeRate.ID == 0 {
+		return "1"
+	}
+	return exchangeRate.Rate
 }
 
+// checkBalance returns ErrInsufficientFunds if the available balance of the account is below the allowed floor.
+// The available balance is the balance minus the amount of active holds.
+func (store *SQLStore) checkBalance(ctx context.Context, q *Queries, account Account) error {
+	held, err := q.GetActiveHoldsAmount(ctx, account.ID)
+	if err != nil {
+		return err
+	}
+
+	available := account.Balance - held
+	if available < -store.options.OverdraftLimit {
+		return fmt.Errorf("%w: account [%d] available balance %s is below the limit %s",
+			ErrInsufficientFunds, account.ID, util.FormatAmount(available, account.Currency),
+			util.FormatAmount(-store.options.OverdraftLimit, account.Currency))
+	}
+	return nil
+}
 
@@ -4,14 +4,16 @@ import (
 	"context"
 	"fmt"
 	"github.com/stretchr/testify/require"
+	"github.com/techschool/simplebank/util"
 	"testing"
 )
 
 func TestTransferTx(t *testing.T) {
 	store := NewStore(testDB)
 
-	account1 := createRandomAccount(t)
-	account2 := createRandomAccount(t)
+	currency := util.RandomCurrency()
+	account1 := createAccountWithCurrency(t, currency)
+	account2 := createAccountWithCurrency(t, currency)
 	fmt.Println(">>Before: ", account1.Balance, account2.Balance)
 
 	// run n concurrent transfer translation
@@ -108,8 +110,9 @@ func TestTransferTx(t *testing.T) {
 func TestTransferTxDeadLock(t *testing.T) {
 	store := NewStore(testDB)
 
-	account1 := createRandomAccount(t)
-	account2 := createRandomAccount(t)
+	currency := util.RandomCurrency()
+	account1 := createAccountWithCurrency(t, currency)
+	account2 := createAccountWithCurrency(t, currency)
 	fmt.Println(">>Before: ", account1.Balance, account2.Balance)
 
 	// run n concurrent transfer translation
@@ -154,3 +157,90 @@ func TestTransferTxDeadLock(t *testing.T) {
 	require.Equal(t, account2.Balance, updateAccount2.Balance)
 }
 
+
+// setAccountBalance 把账户余额设置为指定的值
+func setAccountBalance(t *testing.T, account Account, balance int64) Account {
+	account, err := testQueries.UpdateAccount(context.Background(), UpdateAccountParams{
+		ID: account.ID,
+		Balance: balance,
+	})
+	require.NoError(t, err)
+	require.Equal(t, balance, account.Balance)
+	return account
+}
+
+// runConcurrentTransfers 并发执行 n 次相同的转账，返回成功的次数
+func runConcurrentTransfers(t *testing.T, store Store, arg TransferTxParams, n int) int {
+	errs := make(chan error)
+	for i:=0; i<n; i++ {
+		txName := fmt.Sprintf("tx %d", i+1)
+		go func() {
+			ctx := context.WithValue(context.Background(), txKey, txName)
+			_, err := store.TransferTx(ctx, arg)
+			errs <- err
+		}()
+	}
+
+	succeeded := 0
+	for i:=0; i<n; i++ {
+		err := <-errs
+		if err != nil {
+			require.ErrorIs(t, err, ErrInsufficientFunds)
+			continue
+		}
+		succeeded++
+	}
+	return succeeded
+}
+
+func TestTransferTxInsufficientFunds(t *testing.T) {
+	store := NewStore(testDB)
+
+	currency := util.RandomCurrency()
+	account1 := setAccountBalance(t, createAccountWithCurrency(t, currency), 100)
+	account2 := createAccountWithCurrency(t, currency)
+
+	// 余额只够 3 次转账，其余的转账必须失败，余额不能为负数
+	n := 10
+	amount := int64(30)
+	succeeded := runConcurrentTransfers(t, store, TransferTxParams{
+		FromAccountID: account1.ID,
+		ToAccountID: account2.ID,
+		Amount: amount,
+	}, n)
+	require.Equal(t, 3, succeeded)
+
+	updateAccount1, err := testQueries.GetAccount(context.Background(), account1.ID)
+	require.NoError(t, err)
+	updateAccount2, err := testQueries.GetAccount(context.Background(), account2.ID)
+	require.NoError(t, err)
+
+	require.Equal(t, int64(10), updateAccount1.Balance)
+	require.Equal(t, account2.Balance+int64(succeeded)*amount, updateAccount2.Balance)
+
+	// 失败的转账不能留下转账记录
+	transfers := listAccountTransfers(t, account1.ID, int32(n))
+	require.Len(t, transfers, succeeded)
+}
+
+func TestTransferTxOverdraftLimit(t *testing.T) {
+	store := NewStoreWithOptions(testDB, StoreOptions{OverdraftLimit: 50})
+
+	currency := util.RandomCurrency()
+	account1 := setAccountBalance(t, createAccountWithCurrency(t, currency), 100)
+	account2 := createAccountWithCurrency(t, currency)
+
+	// 余额加上透支额度一共 150，只够 5 次转账
+	n := 10
+	amount := int64(30)
+	succeeded := runConcurrentTransfers(t, store, TransferTxParams{
+		FromAccountID: account1.ID,
+		ToAccountID: account2.ID,
+		Amount: amount,
+	}, n)
+	require.Equal(t, 5, succeeded)
+
+	updateAccount1, err := testQueries.GetAccount(context.Background(), account1.ID)
+	require.NoError(t, err)
+	require.Equal(t, int64(-50), updateAccount1.Balance)
+}
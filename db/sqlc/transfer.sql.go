@@ -5,26 +5,99 @@ package db
 
 import (
 	"context"
+	"database/sql"
+	"encoding/json"
+	"time"
 )
 
+const countPayeeTransfers = `-- name: CountPayeeTransfers :one
+SELECT COUNT(*) FROM transfers
+WHERE from_account_id = $1
+  AND to_account_id = $2
+  AND reversal_of IS NULL
+  AND status <> 'rejected'
+`
+
+type CountPayeeTransfersParams struct {
+	FromAccountID int64 `json:"from_account_id"`
+	ToAccountID   int64 `json:"to_account_id"`
+}
+
+func (q *Queries) CountPayeeTransfers(ctx context.Context, arg CountPayeeTransfersParams) (int64, error) {
+	row := q.db.QueryRowContext(ctx, countPayeeTransfers, arg.FromAccountID, arg.ToAccountID)
+	var count int64
+	err := row.Scan(&count)
+	return count, err
+}
+
+const countTransfersSince = `-- name: CountTransfersSince :one
+SELECT COUNT(*) FROM transfers
+WHERE from_account_id = $1
+  AND created_at > $2
+  AND reversal_of IS NULL
+`
+
+type CountTransfersSinceParams struct {
+	FromAccountID int64     `json:"from_account_id"`
+	Since         time.Time `json:"since"`
+}
+
+func (q *Queries) CountTransfersSince(ctx context.Context, arg CountTransfersSinceParams) (int64, error) {
+	row := q.db.QueryRowContext(ctx, countTransfersSince, arg.FromAccountID, arg.Since)
+	var count int64
+	err := row.Scan(&count)
+	return count, err
+}
+
 const createTransfers = `-- name: CreateTransfers :one
 INSERT INTO transfers (
     from_account_id,
     to_account_id,
-    amount
+    amount,
+    to_amount,
+    exchange_rate,
+    exchange_rate_id,
+    reversal_of,
+    status,
+    requested_by,
+    memo,
+    external_reference,
+    metadata
 ) VALUES (
-    $1, $2, $3
-) RETURNING id, from_account_id, to_account_id, amount, created_at
+    $1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12
+) RETURNING id, from_account_id, to_account_id, amount, created_at, to_amount, exchange_rate, exchange_rate_id, status, reversed_amount, reversal_of, requested_by, approver, decided_at, memo, external_reference, metadata
 `
 
 type CreateTransfersParams struct {
-	FromAccountID int64 `json:"from_account_id"`
-	ToAccountID   int64 `json:"to_account_id"`
-	Amount        int64 `json:"amount"`
+	FromAccountID     int64           `json:"from_account_id"`
+	ToAccountID       int64           `json:"to_account_id"`
+	Amount            int64           `json:"amount"`
+	ToAmount          int64           `json:"to_amount"`
+	ExchangeRate      string          `json:"exchange_rate"`
+	ExchangeRateID    sql.NullInt64   `json:"exchange_rate_id"`
+	ReversalOf        sql.NullInt64   `json:"reversal_of"`
+	Status            string          `json:"status"`
+	RequestedBy       sql.NullString  `json:"requested_by"`
+	Memo              string          `json:"memo"`
+	ExternalReference string          `json:"external_reference"`
+	Metadata          json.RawMessage `json:"metadata"`
 }
 
 func (q *Queries) CreateTransfers(ctx context.Context, arg CreateTransfersParams) (Transfer, error) {
-	row := q.db.QueryRowContext(ctx, createTransfers, arg.FromAccountID, arg.ToAccountID, arg.Amount)
+	row := q.db.QueryRowContext(ctx, createTransfers,
+		arg.FromAccountID,
+		arg.ToAccountID,
+		arg.Amount,
+		arg.ToAmount,
+		arg.ExchangeRate,
+		arg.ExchangeRateID,
+		arg.ReversalOf,
+		arg.Status,
+		arg.RequestedBy,
+		arg.Memo,
+		arg.ExternalReference,
+		arg.Metadata,
+	)
 	var i Transfer
 	err := row.Scan(
 		&i.ID,
@@ -32,12 +105,92 @@ func (q *Queries) CreateTransfers(ctx context.Context, arg CreateTransfersParams
 		&i.ToAccountID,
 		&i.Amount,
 		&i.CreatedAt,
+		&i.ToAmount,
+		&i.ExchangeRate,
+		&i.ExchangeRateID,
+		&i.Status,
+		&i.ReversedAmount,
+		&i.ReversalOf,
+		&i.RequestedBy,
+		&i.Approver,
+		&i.DecidedAt,
+		&i.Memo,
+		&i.ExternalReference,
+		&i.Metadata,
+	)
+	return i, err
+}
+
+const getTransferByReference = `-- name: GetTransferByReference :one
+SELECT id, from_account_id, to_account_id, amount, created_at, to_amount, exchange_rate, exchange_rate_id, status, reversed_amount, reversal_of, requested_by, approver, decided_at, memo, external_reference, metadata FROM transfers
+WHERE from_account_id = $1
+  AND external_reference = $2
+LIMIT 1
+`
+
+type GetTransferByReferenceParams struct {
+	FromAccountID     int64  `json:"from_account_id"`
+	ExternalReference string `json:"external_reference"`
+}
+
+func (q *Queries) GetTransferByReference(ctx context.Context, arg GetTransferByReferenceParams) (Transfer, error) {
+	row := q.db.QueryRowContext(ctx, getTransferByReference, arg.FromAccountID, arg.ExternalReference)
+	var i Transfer
+	err := row.Scan(
+		&i.ID,
+		&i.FromAccountID,
+		&i.ToAccountID,
+		&i.Amount,
+		&i.CreatedAt,
+		&i.ToAmount,
+		&i.ExchangeRate,
+		&i.ExchangeRateID,
+		&i.Status,
+		&i.ReversedAmount,
+		&i.ReversalOf,
+		&i.RequestedBy,
+		&i.Approver,
+		&i.DecidedAt,
+		&i.Memo,
+		&i.ExternalReference,
+		&i.Metadata,
+	)
+	return i, err
+}
+
+const getTransferForUpdate = `-- name: GetTransferForUpdate :one
+SELECT id, from_account_id, to_account_id, amount, created_at, to_amount, exchange_rate, exchange_rate_id, status, reversed_amount, reversal_of, requested_by, approver, decided_at, memo, external_reference, metadata FROM transfers
+WHERE id = $1 LIMIT 1
+FOR NO KEY UPDATE
+`
+
+func (q *Queries) GetTransferForUpdate(ctx context.Context, id int64) (Transfer, error) {
+	row := q.db.QueryRowContext(ctx, getTransferForUpdate, id)
+	var i Transfer
+	err := row.Scan(
+		&i.ID,
+		&i.FromAccountID,
+		&i.ToAccountID,
+		&i.Amount,
+		&i.CreatedAt,
+		&i.ToAmount,
+		&i.ExchangeRate,
+		&i.ExchangeRateID,
+		&i.Status,
+		&i.ReversedAmount,
+		&i.ReversalOf,
+		&i.RequestedBy,
+		&i.Approver,
+		&i.DecidedAt,
+		&i.Memo,
+		&i.ExternalReference,
+		&i.Metadata,
 	)
 	return i, err
 }
 
 const getTransfers = `-- name: GetTransfers :one
-SELECT id, from_account_id, to_account_id, amount, created_at FROM transfers
+SELECT id, from_account_id, to_account_id, amount, created_at, to_amount, exchange_rate, exchange_rate_id, status, reversed_amount, reversal_of, requested_by, approver, decided_at, memo, external_reference, metadata FROM transfers
 WHERE id=$1 LIMIT 1
 `
 
@@ -50,33 +203,59 @@ func (q *Queries) GetTransfers(ctx context.Context, id int64) (Transfer, error)
 		&i.ToAccountID,
 		&i.Amount,
 		&i.CreatedAt,
+		&i.ToAmount,
+		&i.ExchangeRate,
+		&i.ExchangeRateID,
+		&i.Status,
+		&i.ReversedAmount,
+		&i.ReversalOf,
+		&i.RequestedBy,
+		&i.Approver,
+		&i.DecidedAt,
+		&i.Memo,
+		&i.ExternalReference,
+		&i.Metadata,
 	)
 	return i, err
 }
 
 const listTransfers = `-- name: ListTransfers :many
-SELECT id, from_account_id, to_account_id, amount, created_at FROM transfers
-WHERE
-    from_account_id = $1 OR
-    to_account_id = $2
-ORDER BY id
-LIMIT $3
-OFFSET $4
+SELECT id, from_account_id, to_account_id, amount, created_at, to_amount, exchange_rate, exchange_rate_id, status, reversed_amount, reversal_of, requested_by, approver, decided_at, memo, external_reference, metadata FROM transfers
+WHERE ((from_account_id = $1 AND $2::varchar <> 'in')
+    OR (to_account_id = $1 AND $2::varchar <> 'out'))
+  AND created_at >= $3
+  AND created_at < $4
+  AND (CASE WHEN from_account_id = $1 THEN amount ELSE to_amount END)
+    BETWEEN $5::bigint AND $6::bigint
+  AND (created_at, id) < ($7::timestamptz, $8::bigint)
+ORDER BY created_at DESC, id DESC
+LIMIT $9
 `
 
 type ListTransfersParams struct {
-	FromAccountID int64 `json:"from_account_id"`
-	ToAccountID   int64 `json:"to_account_id"`
-	Limit         int32 `json:"limit"`
-	Offset        int32 `json:"offset"`
+	AccountID       int64     `json:"account_id"`
+	Direction       string    `json:"direction"`
+	StartTime       time.Time `json:"start_time"`
+	EndTime         time.Time `json:"end_time"`
+	MinAmount       int64     `json:"min_amount"`
+	MaxAmount       int64     `json:"max_amount"`
+	CursorCreatedAt time.Time `json:"cursor_created_at"`
+	CursorID        int64     `json:"cursor_id"`
+	PageSize        int32     `json:"page_size"`
 }
 
+// 账户转出和转入的转账，按 (created_at, id) 倒序的 keyset 分页，金额使用该账户的币种
 func (q *Queries) ListTransfers(ctx context.Context, arg ListTransfersParams) ([]Transfer, error) {
 	rows, err := q.db.QueryContext(ctx, listTransfers,
-		arg.FromAccountID,
-		arg.ToAccountID,
-		arg.Limit,
-		arg.Offset,
+		arg.AccountID,
+		arg.Direction,
+		arg.StartTime,
+		arg.EndTime,
+		arg.MinAmount,
+		arg.MaxAmount,
+		arg.CursorCreatedAt,
+		arg.CursorID,
+		arg.PageSize,
 	)
 	if err != nil {
 		return nil, err
@@ -91,6 +270,18 @@ func (q *Queries) ListTransfers(ctx context.Context, arg ListTransfersParams) ([
 			&i.ToAccountID,
 			&i.Amount,
 			&i.CreatedAt,
+			&i.ToAmount,
+			&i.ExchangeRate,
+			&i.ExchangeRateID,
+			&i.Status,
+			&i.ReversedAmount,
+			&i.ReversalOf,
+			&i.RequestedBy,
+			&i.Approver,
+			&i.DecidedAt,
+			&i.Memo,
+			&i.ExternalReference,
+			&i.Metadata,
 		); err != nil {
 			return nil, err
 		}
@@ -104,3 +295,142 @@ func (q *Queries) ListTransfers(ctx context.Context, arg ListTransfersParams) ([
 	}
 	return items, nil
 }
+
+const listTransfersByReference = `-- name: ListTransfersByReference :many
+SELECT transfers.id, transfers.from_account_id, transfers.to_account_id, transfers.amount, transfers.created_at, transfers.to_amount, transfers.exchange_rate, transfers.exchange_rate_id, transfers.status, transfers.reversed_amount, transfers.reversal_of, transfers.requested_by, transfers.approver, transfers.decided_at, transfers.memo, transfers.external_reference, transfers.metadata FROM transfers
+JOIN account ON account.id = transfers.from_account_id
+WHERE transfers.external_reference = $1
+  AND account.owner = $2
+ORDER BY transfers.id
+`
+
+type ListTransfersByReferenceParams struct {
+	ExternalReference string `json:"external_reference"`
+	Owner             string `json:"owner"`
+}
+
+func (q *Queries) ListTransfersByReference(ctx context.Context, arg ListTransfersByReferenceParams) ([]Transfer, error) {
+	rows, err := q.db.QueryContext(ctx, listTransfersByReference, arg.ExternalReference, arg.Owner)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []Transfer{}
+	for rows.Next() {
+		var i Transfer
+		if err := rows.Scan(
+			&i.ID,
+			&i.FromAccountID,
+			&i.ToAccountID,
+			&i.Amount,
+			&i.CreatedAt,
+			&i.ToAmount,
+			&i.ExchangeRate,
+			&i.ExchangeRateID,
+			&i.Status,
+			&i.ReversedAmount,
+			&i.ReversalOf,
+			&i.RequestedBy,
+			&i.Approver,
+			&i.DecidedAt,
+			&i.Memo,
+			&i.ExternalReference,
+			&i.Metadata,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const updateTransferDecision = `-- name: UpdateTransferDecision :one
+UPDATE transfers
+SET status = $2,
+    approver = $3,
+    decided_at = $4
+WHERE id = $1
+RETURNING id, from_account_id, to_account_id, amount, created_at, to_amount, exchange_rate, exchange_rate_id, status, reversed_amount, reversal_of, requested_by, approver, decided_at, memo, external_reference, metadata
+`
+
+type UpdateTransferDecisionParams struct {
+	ID        int64          `json:"id"`
+	Status    string         `json:"status"`
+	Approver  sql.NullString `json:"approver"`
+	DecidedAt sql.NullTime   `json:"decided_at"`
+}
+
+func (q *Queries) UpdateTransferDecision(ctx context.Context, arg UpdateTransferDecisionParams) (Transfer, error) {
+	row := q.db.QueryRowContext(ctx, updateTransferDecision,
+		arg.ID,
+		arg.Status,
+		arg.Approver,
+		arg.DecidedAt,
+	)
+	var i Transfer
+	err := row.Scan(
+		&i.ID,
+		&i.FromAccountID,
+		&i.ToAccountID,
+		&i.Amount,
+		&i.CreatedAt,
+		&i.ToAmount,
+		&i.ExchangeRate,
+		&i.ExchangeRateID,
+		&i.Status,
+		&i.ReversedAmount,
+		&i.ReversalOf,
+		&i.RequestedBy,
+		&i.Approver,
+		&i.DecidedAt,
+		&i.Memo,
+		&i.ExternalReference,
+		&i.Metadata,
+	)
+	return i, err
+}
+
+const updateTransferReversal = `-- name: UpdateTransferReversal :one
+UPDATE transfers
+SET reversed_amount = $2,
+    status = $3
+WHERE id = $1
+RETURNING id, from_account_id, to_account_id, amount, created_at, to_amount, exchange_rate, exchange_rate_id, status, reversed_amount, reversal_of, requested_by, approver, decided_at, memo, external_reference, metadata
+`
+
+type UpdateTransferReversalParams struct {
+	ID             int64  `json:"id"`
+	ReversedAmount int64  `json:"reversed_amount"`
+	Status         string `json:"status"`
+}
+
+func (q *Queries) UpdateTransferReversal(ctx context.Context, arg UpdateTransferReversalParams) (Transfer, error) {
+	row := q.db.QueryRowContext(ctx, updateTransferReversal, arg.ID, arg.ReversedAmount, arg.Status)
+	var i Transfer
+	err := row.Scan(
+		&i.ID,
+		&i.FromAccountID,
+		&i.ToAccountID,
+		&i.Amount,
+		&i.CreatedAt,
+		&i.ToAmount,
+		&i.ExchangeRate,
+		&i.ExchangeRateID,
+		&i.Status,
+		&i.ReversedAmount,
+		&i.ReversalOf,
+		&i.RequestedBy,
+		&i.Approver,
+		&i.DecidedAt,
+		&i.Memo,
+		&i.ExternalReference,
+		&i.Metadata,
+	)
+	return i, err
+}
@@ -0,0 +1,257 @@
+// Code generated by sqlc. DO NOT EDIT.
+// source: transfer_limit.sql
+
+package db
+
+import (
+	"context"
+	"database/sql"
+	"time"
+)
+
+const deleteTransferLimit = `-- name: DeleteTransferLimit :exec
+DELETE FROM transfer_limits
+WHERE id = $1
+`
+
+func (q *Queries) DeleteTransferLimit(ctx context.Context, id int64) error {
+	_, err := q.db.ExecContext(ctx, deleteTransferLimit, id)
+	return err
+}
+
+const getAccountTransferUsage = `-- name: GetAccountTransferUsage :one
+SELECT COUNT(*) AS count, COALESCE(SUM(amount), 0)::bigint AS amount
+FROM (
+    SELECT t.amount FROM transfers t
+    WHERE t.from_account_id = $1
+      AND t.created_at > $2
+      AND t.reversal_of IS NULL
+      AND t.status <> 'rejected'
+    UNION ALL
+    SELECT h.amount FROM holds h
+    WHERE h.account_id = $1
+      AND h.created_at > $2
+      AND h.status = 'active'
+      AND h.expires_at > now()
+) usage
+`
+
+type GetAccountTransferUsageParams struct {
+	AccountID int64     `json:"account_id"`
+	Since     time.Time `json:"since"`
+}
+
+type GetAccountTransferUsageRow struct {
+	Count  int64 `json:"count"`
+	Amount int64 `json:"amount"`
+}
+
+// 未释放的预授权也占用转账限额，扣款后由对应的转账计入
+func (q *Queries) GetAccountTransferUsage(ctx context.Context, arg GetAccountTransferUsageParams) (GetAccountTransferUsageRow, error) {
+	row := q.db.QueryRowContext(ctx, getAccountTransferUsage, arg.AccountID, arg.Since)
+	var i GetAccountTransferUsageRow
+	err := row.Scan(&i.Count, &i.Amount)
+	return i, err
+}
+
+const getTransferLimit = `-- name: GetTransferLimit :one
+SELECT id, owner, account_id, currency, period, max_count, max_amount, created_at, updated_at FROM transfer_limits
+WHERE id = $1 LIMIT 1
+`
+
+func (q *Queries) GetTransferLimit(ctx context.Context, id int64) (TransferLimit, error) {
+	row := q.db.QueryRowContext(ctx, getTransferLimit, id)
+	var i TransferLimit
+	err := row.Scan(
+		&i.ID,
+		&i.Owner,
+		&i.AccountID,
+		&i.Currency,
+		&i.Period,
+		&i.MaxCount,
+		&i.MaxAmount,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+	)
+	return i, err
+}
+
+const getUserTransferUsage = `-- name: GetUserTransferUsage :one
+SELECT COUNT(*) AS count, COALESCE(SUM(usage.amount), 0)::bigint AS amount
+FROM (
+    SELECT t.from_account_id AS account_id, t.amount FROM transfers t
+    WHERE t.created_at > $1
+      AND t.reversal_of IS NULL
+      AND t.status <> 'rejected'
+    UNION ALL
+    SELECT h.account_id, h.amount FROM holds h
+    WHERE h.created_at > $1
+      AND h.status = 'active'
+      AND h.expires_at > now()
+) usage
+JOIN account a ON a.id = usage.account_id
+WHERE a.owner = $2
+  AND a.currency = $3
+`
+
+type GetUserTransferUsageParams struct {
+	Since    time.Time `json:"since"`
+	Owner    string    `json:"owner"`
+	Currency string    `json:"currency"`
+}
+
+type GetUserTransferUsageRow struct {
+	Count  int64 `json:"count"`
+	Amount int64 `json:"amount"`
+}
+
+func (q *Queries) GetUserTransferUsage(ctx context.Context, arg GetUserTransferUsageParams) (GetUserTransferUsageRow, error) {
+	row := q.db.QueryRowContext(ctx, getUserTransferUsage, arg.Since, arg.Owner, arg.Currency)
+	var i GetUserTransferUsageRow
+	err := row.Scan(&i.Count, &i.Amount)
+	return i, err
+}
+
+const listApplicableTransferLimits = `-- name: ListApplicableTransferLimits :many
+SELECT id, owner, account_id, currency, period, max_count, max_amount, created_at, updated_at FROM transfer_limits
+WHERE account_id = $1
+   OR (owner = $2 AND currency = $3)
+   OR (owner IS NULL AND account_id IS NULL AND currency = $3)
+ORDER BY id
+`
+
+type ListApplicableTransferLimitsParams struct {
+	AccountID sql.NullInt64  `json:"account_id"`
+	Owner     sql.NullString `json:"owner"`
+	Currency  string         `json:"currency"`
+}
+
+func (q *Queries) ListApplicableTransferLimits(ctx context.Context, arg ListApplicableTransferLimitsParams) ([]TransferLimit, error) {
+	rows, err := q.db.QueryContext(ctx, listApplicableTransferLimits, arg.AccountID, arg.Owner, arg.Currency)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []TransferLimit{}
+	for rows.Next() {
+		var i TransferLimit
+		if err := rows.Scan(
+			&i.ID,
+			&i.Owner,
+			&i.AccountID,
+			&i.Currency,
+			&i.Period,
+			&i.MaxCount,
+			&i.MaxAmount,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listTransferLimits = `-- name: ListTransferLimits :many
+SELECT id, owner, account_id, currency, period, max_count, max_amount, created_at, updated_at FROM transfer_limits
+WHERE (created_at, id) > ($1::timestamptz, $2::bigint)
+ORDER BY created_at, id
+LIMIT $3
+`
+
+type ListTransferLimitsParams struct {
+	CursorCreatedAt time.Time `json:"cursor_created_at"`
+	CursorID        int64     `json:"cursor_id"`
+	PageSize        int32     `json:"page_size"`
+}
+
+func (q *Queries) ListTransferLimits(ctx context.Context, arg ListTransferLimitsParams) ([]TransferLimit, error) {
+	rows, err := q.db.QueryContext(ctx, listTransferLimits, arg.CursorCreatedAt, arg.CursorID, arg.PageSize)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []TransferLimit{}
+	for rows.Next() {
+		var i TransferLimit
+		if err := rows.Scan(
+			&i.ID,
+			&i.Owner,
+			&i.AccountID,
+			&i.Currency,
+			&i.Period,
+			&i.MaxCount,
+			&i.MaxAmount,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const upsertTransferLimit = `-- name: UpsertTransferLimit :one
+INSERT INTO transfer_limits (
+    owner,
+    account_id,
+    currency,
+    period,
+    max_count,
+    max_amount
+) VALUES (
+    $1, $2, $3, $4, $5, $6
+)
+ON CONFLICT ((COALESCE(owner, '')), (COALESCE(account_id, 0)), currency, period) DO UPDATE
+SET max_count = EXCLUDED.max_count,
+    max_amount = EXCLUDED.max_amount,
+    updated_at = now()
+RETURNING id, owner, account_id, currency, period, max_count, max_amount, created_at, updated_at
+`
+
+type UpsertTransferLimitParams struct {
+	Owner     sql.NullString `json:"owner"`
+	AccountID sql.NullInt64  `json:"account_id"`
+	Currency  string         `json:"currency"`
+	Period    string         `json:"period"`
+	MaxCount  int32          `json:"max_count"`
+	MaxAmount int64          `json:"max_amount"`
+}
+
+func (q *Queries) UpsertTransferLimit(ctx context.Context, arg UpsertTransferLimitParams) (TransferLimit, error) {
+	row := q.db.QueryRowContext(ctx, upsertTransferLimit,
+		arg.Owner,
+		arg.AccountID,
+		arg.Currency,
+		arg.Period,
+		arg.MaxCount,
+		arg.MaxAmount,
+	)
+	var i TransferLimit
+	err := row.Scan(
+		&i.ID,
+		&i.Owner,
+		&i.AccountID,
+		&i.Currency,
+		&i.Period,
+		&i.MaxCount,
+		&i.MaxAmount,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+	)
+	return i, err
+}
@@ -0,0 +1,173 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"github.com/stretchr/testify/require"
+	"github.com/techschool/simplebank/util"
+	"testing"
+	"time"
+)
+
+func TestUpsertTransferLimit(t *testing.T) {
+	account := createRandomAccount(t)
+
+	arg := UpsertTransferLimitParams{
+		AccountID: sql.NullInt64{Int64: account.ID, Valid: true},
+		Currency: account.Currency,
+		Period: util.LimitDaily,
+		MaxCount: 3,
+	}
+	limit1, err := testQueries.UpsertTransferLimit(context.Background(), arg)
+	require.NoError(t, err)
+	require.Equal(t, LimitScopeAccount, LimitScope(limit1))
+	require.Equal(t, int32(3), limit1.MaxCount)
+
+	// 同一范围和周期的限额被更新，而不是新增
+	arg.MaxCount = 5
+	arg.MaxAmount = 1000
+	limit2, err := testQueries.UpsertTransferLimit(context.Background(), arg)
+	require.NoError(t, err)
+	require.Equal(t, limit1.ID, limit2.ID)
+	require.Equal(t, int32(5), limit2.MaxCount)
+	require.Equal(t, int64(1000), limit2.MaxAmount)
+
+	err = testQueries.DeleteTransferLimit(context.Background(), limit1.ID)
+	require.NoError(t, err)
+	_, err = testQueries.GetTransferLimit(context.Background(), limit1.ID)
+	require.ErrorIs(t, err, sql.ErrNoRows)
+}
+
+func TestTransferTxAccountLimit(t *testing.T) {
+	store := NewStore(testDB)
+
+	currency := util.RandomCurrency()
+	account1 := setAccountBalance(t, createAccountWithCurrency(t, currency), 1000)
+	account2 := createAccountWithCurrency(t, currency)
+
+	_, err := testQueries.UpsertTransferLimit(context.Background(), UpsertTransferLimitParams{
+		AccountID: sql.NullInt64{Int64: account1.ID, Valid: true},
+		Currency: account1.Currency,
+		Period: util.LimitDaily,
+		MaxCount: 2,
+		MaxAmount: 100,
+	})
+	require.NoError(t, err)
+
+	arg := TransferTxParams{
+		FromAccountID: account1.ID,
+		ToAccountID: account2.ID,
+		Amount: 60,
+	}
+	_, err = store.TransferTx(context.Background(), arg)
+	require.NoError(t, err)
+
+	// 第二笔转账超出金额限额，返回剩余的额度
+	_, err = store.TransferTx(context.Background(), arg)
+	require.ErrorIs(t, err, ErrLimitExceeded)
+
+	var limitErr *LimitExceededError
+	require.True(t, errors.As(err, &limitErr))
+	require.Equal(t, LimitScopeAccount, limitErr.Scope)
+	require.Equal(t, int64(1), *limitErr.RemainingCount)
+	require.Equal(t, int64(40), *limitErr.RemainingAmount)
+
+	arg.Amount = 40
+	_, err = store.TransferTx(context.Background(), arg)
+	require.NoError(t, err)
+
+	// 次数也用完了
+	arg.Amount = 1
+	_, err = store.TransferTx(context.Background(), arg)
+	require.True(t, errors.As(err, &limitErr))
+	require.Equal(t, int64(0), *limitErr.RemainingCount)
+}
+
+func TestHoldTransferLimit(t *testing.T) {
+	store := NewStore(testDB)
+
+	currency := util.RandomCurrency()
+	account1 := setAccountBalance(t, createAccountWithCurrency(t, currency), 1000)
+	account2 := createAccountWithCurrency(t, currency)
+
+	_, err := testQueries.UpsertTransferLimit(context.Background(), UpsertTransferLimitParams{
+		AccountID: sql.NullInt64{Int64: account1.ID, Valid: true},
+		Currency: account1.Currency,
+		Period: util.LimitDaily,
+		MaxAmount: 100,
+	})
+	require.NoError(t, err)
+
+	hold := createActiveHold(t, store, account1, account2, 60, time.Now().Add(time.Hour))
+
+	// 未释放的预授权占用限额，转账和新的预授权都不能超出剩余的额度
+	_, err = store.TransferTx(context.Background(), TransferTxParams{
+		FromAccountID: account1.ID,
+		ToAccountID: account2.ID,
+		Amount: 50,
+	})
+	var limitErr *LimitExceededError
+	require.True(t, errors.As(err, &limitErr))
+	require.Equal(t, int64(40), *limitErr.RemainingAmount)
+
+	_, err = store.CreateHoldTx(context.Background(), CreateHoldParams{
+		AccountID: account1.ID,
+		ToAccountID: account2.ID,
+		Amount: 50,
+		CreatedBy: account1.Owner,
+		ExpiresAt: time.Now().Add(time.Hour),
+	})
+	require.ErrorIs(t, err, ErrLimitExceeded)
+
+	// 扣款后由转账计入限额，不会重复计算
+	_, err = store.CaptureHoldTx(context.Background(), CaptureHoldTxParams{HoldID: hold.ID, Amount: 60})
+	require.NoError(t, err)
+
+	_, err = store.TransferTx(context.Background(), TransferTxParams{
+		FromAccountID: account1.ID,
+		ToAccountID: account2.ID,
+		Amount: 40,
+	})
+	require.NoError(t, err)
+}
+
+func TestTransferTxUserLimitConcurrent(t *testing.T) {
+	store := NewStore(testDB)
+
+	account1 := setAccountBalance(t, createAccountWithCurrency(t, util.USD), 1000)
+	account2 := createAccountWithCurrency(t, util.USD)
+
+	_, err := testQueries.UpsertTransferLimit(context.Background(), UpsertTransferLimitParams{
+		Owner: sql.NullString{String: account1.Owner, Valid: true},
+		Currency: util.USD,
+		Period: util.LimitDaily,
+		MaxAmount: 50,
+	})
+	require.NoError(t, err)
+
+	// 并发的转账逐个检查限额，成功的转账总额不能超出
+	n := 10
+	errs := make(chan error)
+	for i := 0; i < n; i++ {
+		go func() {
+			_, err := store.TransferTx(context.Background(), TransferTxParams{
+				FromAccountID: account1.ID,
+				ToAccountID: account2.ID,
+				Amount: 10,
+			})
+			errs <- err
+		}()
+	}
+
+	succeeded := 0
+	for i := 0; i < n; i++ {
+		err := <-errs
+		if err != nil {
+			require.ErrorIs(t, err, ErrLimitExceeded)
+			continue
+		}
+		succeeded++
+	}
+	require.Equal(t, 5, succeeded)
+}
@@ -2,6 +2,8 @@ package db
 
 import (
 	"context"
+	"encoding/json"
+	"math"
 	"github.com/stretchr/testify/require"
 	"github.com/techschool/simplebank/util"
 	"testing"
@@ -14,11 +16,18 @@ func createOneTransfer(t *testing.T, account1, account2 Account) Transfer {
 		ToAccountID: account2.ID,
 		Amount: util.RandomMoney(),
 	}
+	arg.ToAmount = arg.Amount
+	arg.ExchangeRate = "1"
+	arg.Status = util.TransferCompleted
+	arg.Metadata = json.RawMessage("{}")
 	transfer, err := testQueries.CreateTransfers(context.Background(),arg)
 	require.NoError(t, err)
 	require.Equal(t, transfer.ToAccountID, arg.ToAccountID)
 	require.Equal(t, transfer.FromAccountID, arg.FromAccountID)
 	require.Equal(t, transfer.Amount, arg.Amount)
+	require.Equal(t, transfer.ToAmount, arg.ToAmount)
+	require.False(t, transfer.ExchangeRateID.Valid)
+	require.Equal(t, util.TransferCompleted, transfer.Status)
 
 	require.NotZero(t, transfer.ID)
 	require.NotZero(t, transfer.CreatedAt)
@@ -47,28 +56,150 @@ func TestGetTransfers(t *testing.T) {
 	require.WithinDuration(t, transfer1.CreatedAt, transfer2.CreatedAt, time.Second)
 }
 
+// listAccountTransfers 不加任何过滤条件，查询账户最新的转账
+func listAccountTransfers(t *testing.T, accountID int64, pageSize int32) []Transfer {
+	transfers, err := testQueries.ListTransfers(context.Background(), ListTransfersParams{
+		AccountID: accountID,
+		StartTime: time.Time{},
+		EndTime: time.Now().Add(time.Hour),
+		MinAmount: 0,
+		MaxAmount: math.MaxInt64,
+		CursorCreatedAt: time.Now().Add(time.Hour),
+		CursorID: math.MaxInt64,
+		PageSize: pageSize,
+	})
+	require.NoError(t, err)
+	return transfers
+}
+
 func TestListTransfers(t *testing.T) {
 	account1 := createRandomAccount(t)
 	account2 := createRandomAccount(t)
+	account3 := createRandomAccount(t)
+
+	var sent, received []Transfer
+	for i:=0; i<5; i++ {
+		sent = append(sent, createOneTransfer(t, account1, account2))
+		received = append(received, createOneTransfer(t, account3, account1))
+	}
+	// 与该账户无关的转账不会被查询到
+	createOneTransfer(t, account2, account3)
 
-	for i:=0; i<10; i++ {
-		createOneTransfer(t, account1, account2)
+	// 按时间倒序分页
+	transfers := listAccountTransfers(t, account1.ID, 5)
+	require.Len(t, transfers, 5)
+	require.Equal(t, received[4].ID, transfers[0].ID)
+	for i := 1; i < len(transfers); i++ {
+		require.Less(t, transfers[i].ID, transfers[i-1].ID)
 	}
 
 	arg := ListTransfersParams{
-		FromAccountID: account1.ID,
-		ToAccountID: account2.ID,
-		Limit: 5,
-		Offset: 5,
+		AccountID: account1.ID,
+		EndTime: time.Now().Add(time.Hour),
+		MaxAmount: math.MaxInt64,
+		CursorCreatedAt: transfers[4].CreatedAt,
+		CursorID: transfers[4].ID,
+		PageSize: 10,
 	}
+	nextPage, err := testQueries.ListTransfers(context.Background(), arg)
+	require.NoError(t, err)
+	require.Len(t, nextPage, 5)
+	require.Less(t, nextPage[0].ID, transfers[4].ID)
 
-	transfers, err := testQueries.ListTransfers(context.Background(), arg)
+	// 只查询转出的转账
+	arg.CursorCreatedAt = time.Now().Add(time.Hour)
+	arg.CursorID = math.MaxInt64
+	arg.Direction = util.TransferDirectionOut
+	transfers, err = testQueries.ListTransfers(context.Background(), arg)
 	require.NoError(t, err)
-	require.Equal(t, len(transfers), 5)
+	require.Len(t, transfers, 5)
+	for _, transfer := range transfers {
+		require.Equal(t, account1.ID, transfer.FromAccountID)
+	}
 
+	// 按金额的范围查询转入的转账
+	arg.Direction = util.TransferDirectionIn
+	arg.MinAmount = received[2].ToAmount
+	arg.MaxAmount = received[2].ToAmount
+	transfers, err = testQueries.ListTransfers(context.Background(), arg)
+	require.NoError(t, err)
+	require.NotEmpty(t, transfers)
 	for _, transfer := range transfers {
-		require.NotEmpty(t, transfer)
-		require.True(t, transfer.FromAccountID == account1.ID || transfer.ToAccountID == account1.ID)
+		require.Equal(t, account1.ID, transfer.ToAccountID)
+		require.Equal(t, received[2].ToAmount, transfer.ToAmount)
 	}
+
+	// 时间范围之外没有转账
+	arg.Direction = ""
+	arg.MinAmount = 0
+	arg.MaxAmount = math.MaxInt64
+	arg.StartTime = time.Now().Add(time.Minute)
+	transfers, err = testQueries.ListTransfers(context.Background(), arg)
+	require.NoError(t, err)
+	require.Empty(t, transfers)
 }
 
+
+func TestTransferTxReference(t *testing.T) {
+	store := NewStore(testDB)
+
+	account1 := createAccountWithCurrency(t, util.USD)
+	account2 := createAccountWithCurrency(t, util.USD)
+	account3 := createAccountWithCurrency(t, util.USD)
+	reference := util.RandomString(12)
+
+	arg := TransferTxParams{
+		FromAccountID: account1.ID,
+		ToAccountID: account2.ID,
+		Amount: 10,
+		Memo: "rent for May",
+		ExternalReference: reference,
+		Metadata: json.RawMessage(`{"invoice": "INV-1001"}`),
+	}
+	result, err := store.TransferTx(context.Background(), arg)
+	require.NoError(t, err)
+	require.Equal(t, arg.Memo, result.Transfer.Memo)
+	require.Equal(t, reference, result.Transfer.ExternalReference)
+	require.JSONEq(t, string(arg.Metadata), string(result.Transfer.Metadata))
+
+	// 同一个转出账户不能重复使用参考号，失败的转账不会扣款
+	_, err = store.TransferTx(context.Background(), arg)
+	require.ErrorIs(t, err, ErrDuplicateReference)
+
+	// 其他账户可以使用相同的参考号，没有参考号和 metadata 的转账不受影响
+	_, err = store.TransferTx(context.Background(), TransferTxParams{
+		FromAccountID: account3.ID,
+		ToAccountID: account2.ID,
+		Amount: 10,
+		ExternalReference: reference,
+	})
+	require.NoError(t, err)
+	for i := 0; i < 2; i++ {
+		result, err := store.TransferTx(context.Background(), TransferTxParams{
+			FromAccountID: account1.ID,
+			ToAccountID: account2.ID,
+			Amount: 10,
+		})
+		require.NoError(t, err)
+		require.JSONEq(t, "{}", string(result.Transfer.Metadata))
+	}
+
+	transfers, err := testQueries.ListTransfersByReference(context.Background(), ListTransfersByReferenceParams{
+		ExternalReference: reference,
+		Owner: account1.Owner,
+	})
+	require.NoError(t, err)
+	require.Len(t, transfers, 1)
+	require.Equal(t, result.Transfer.ID, transfers[0].ID)
+
+	transfer, err := testQueries.GetTransferByReference(context.Background(), GetTransferByReferenceParams{
+		FromAccountID: account3.ID,
+		ExternalReference: reference,
+	})
+	require.NoError(t, err)
+	require.Equal(t, account3.ID, transfer.FromAccountID)
+
+	updated, err := testQueries.GetAccount(context.Background(), account1.ID)
+	require.NoError(t, err)
+	require.Equal(t, account1.Balance-30, updated.Balance)
+}
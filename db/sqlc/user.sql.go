@@ -5,6 +5,7 @@ package db
 
 import (
 	"context"
+	"time"
 )
 
 const createUser = `-- name: CreateUser :one
@@ -12,10 +13,11 @@ INSERT INTO users (
     username,
     hashed_password,
     full_name,
-    email
+    email,
+    role
 ) VALUES (
-    $1, $2, $3, $4
-) RETURNING username, hashed_password, full_name, email, password_change_at, created_at
+    $1, $2, $3, $4, $5
+) RETURNING username, hashed_password, full_name, email, password_change_at, created_at, tokens_revoked_at, role
 `
 
 type CreateUserParams struct {
@@ -23,6 +25,7 @@ type CreateUserParams struct {
 	HashedPassword string `json:"hashed_password"`
 	FullName       string `json:"full_name"`
 	Email          string `json:"email"`
+	Role           string `json:"role"`
 }
 
 func (q *Queries) CreateUser(ctx context.Context, arg CreateUserParams) (User, error) {
@@ -31,6 +34,7 @@ func (q *Queries) CreateUser(ctx context.Context, arg CreateUserParams) (User, e
 		arg.HashedPassword,
 		arg.FullName,
 		arg.Email,
+		arg.Role,
 	)
 	var i User
 	err := row.Scan(
@@ -40,12 +44,14 @@ func (q *Queries) CreateUser(ctx context.Context, arg CreateUserParams) (User, e
 		&i.Email,
 		&i.PasswordChangeAt,
 		&i.CreatedAt,
+		&i.TokensRevokedAt,
+		&i.Role,
 	)
 	return i, err
 }
 
 const getUser = `-- name: GetUser :one
-SELECT username, hashed_password, full_name, email, password_change_at, created_at FROM users
+SELECT username, hashed_password, full_name, email, password_change_at, created_at, tokens_revoked_at, role FROM users
 WHERE username = $1 LIMIT 1
 `
 
@@ -59,6 +65,107 @@ func (q *Queries) GetUser(ctx context.Context, username string) (User, error) {
 		&i.Email,
 		&i.PasswordChangeAt,
 		&i.CreatedAt,
+		&i.TokensRevokedAt,
+		&i.Role,
+	)
+	return i, err
+}
+
+const getUserByEmail = `-- name: GetUserByEmail :one
+SELECT username, hashed_password, full_name, email, password_change_at, created_at, tokens_revoked_at, role FROM users
+WHERE email = $1 LIMIT 1
+`
+
+func (q *Queries) GetUserByEmail(ctx context.Context, email string) (User, error) {
+	row := q.db.QueryRowContext(ctx, getUserByEmail, email)
+	var i User
+	err := row.Scan(
+		&i.Username,
+		&i.HashedPassword,
+		&i.FullName,
+		&i.Email,
+		&i.PasswordChangeAt,
+		&i.CreatedAt,
+		&i.TokensRevokedAt,
+		&i.Role,
+	)
+	return i, err
+}
+
+const getUserForUpdate = `-- name: GetUserForUpdate :one
+SELECT username, hashed_password, full_name, email, password_change_at, created_at, tokens_revoked_at, role FROM users
+WHERE username = $1 LIMIT 1
+FOR NO KEY UPDATE
+`
+
+func (q *Queries) GetUserForUpdate(ctx context.Context, username string) (User, error) {
+	row := q.db.QueryRowContext(ctx, getUserForUpdate, username)
+	var i User
+	err := row.Scan(
+		&i.Username,
+		&i.HashedPassword,
+		&i.FullName,
+		&i.Email,
+		&i.PasswordChangeAt,
+		&i.CreatedAt,
+		&i.TokensRevokedAt,
+		&i.Role,
+	)
+	return i, err
+}
+
+const revokeUserTokens = `-- name: RevokeUserTokens :one
+UPDATE users
+SET tokens_revoked_at = $1
+WHERE username = $2
+RETURNING username, hashed_password, full_name, email, password_change_at, created_at, tokens_revoked_at, role
+`
+
+type RevokeUserTokensParams struct {
+	RevokedAt time.Time `json:"revoked_at"`
+	Username  string    `json:"username"`
+}
+
+func (q *Queries) RevokeUserTokens(ctx context.Context, arg RevokeUserTokensParams) (User, error) {
+	row := q.db.QueryRowContext(ctx, revokeUserTokens, arg.RevokedAt, arg.Username)
+	var i User
+	err := row.Scan(
+		&i.Username,
+		&i.HashedPassword,
+		&i.FullName,
+		&i.Email,
+		&i.PasswordChangeAt,
+		&i.CreatedAt,
+		&i.TokensRevokedAt,
+		&i.Role,
+	)
+	return i, err
+}
+
+const updateUserRole = `-- name: UpdateUserRole :one
+UPDATE users
+SET role = $1
+WHERE username = $2
+RETURNING username, hashed_password, full_name, email, password_change_at, created_at, tokens_revoked_at, role
+`
+
+type UpdateUserRoleParams struct {
+	Role     string `json:"role"`
+	Username string `json:"username"`
+}
+
+func (q *Queries) UpdateUserRole(ctx context.Context, arg UpdateUserRoleParams) (User, error) {
+	row := q.db.QueryRowContext(ctx, updateUserRole, arg.Role, arg.Username)
+	var i User
+	err := row.Scan(
+		&i.Username,
+		&i.HashedPassword,
+		&i.FullName,
+		&i.Email,
+		&i.PasswordChangeAt,
+		&i.CreatedAt,
+		&i.TokensRevokedAt,
+		&i.Role,
 	)
 	return i, err
 }
@@ -17,6 +17,7 @@ func createRandomUser(t *testing.T) User {
 		HashedPassword: hashPassword,
 		FullName: 		util.RandomFullName(),	// RandomOwner 一致
 		Email: 			util.RandomEmail(),
+		Role:			util.DepositorRole,
 	}
 
 	user, err := testQueries.CreateUser(context.Background(), arg)
@@ -28,6 +29,7 @@ func createRandomUser(t *testing.T) User {
 	require.Equal(t, arg.HashedPassword, user.HashedPassword)
 	require.Equal(t, arg.FullName, user.FullName)
 	require.Equal(t, arg.Email, user.Email)
+	require.Equal(t, arg.Role, user.Role)
 
 	require.NotZero(t, user.CreatedAt)
 	require.True(t, user.PasswordChangeAt.IsZero())
@@ -51,10 +53,36 @@ func TestGetUser(t *testing.T) {
 	require.WithinDuration(t, user2.CreatedAt, user1.CreatedAt, time.Second)
 }
 
+func TestGetUserByEmail(t *testing.T) {
+	user1 := createRandomUser(t)
+	user2, err := testQueries.GetUserByEmail(context.Background(), user1.Email)
+	require.NoError(t, err)
+	require.Equal(t, user1.Username, user2.Username)
+}
 
+func TestRevokeUserTokens(t *testing.T) {
+	user1 := createRandomUser(t)
+	require.True(t, user1.TokensRevokedAt.IsZero())
 
+	arg := RevokeUserTokensParams{
+		RevokedAt:	time.Now(),
+		Username:	user1.Username,
+	}
+	user2, err := testQueries.RevokeUserTokens(context.Background(), arg)
+	require.NoError(t, err)
+	require.Equal(t, user1.Username, user2.Username)
+	require.WithinDuration(t, arg.RevokedAt, user2.TokensRevokedAt, time.Second)
+}
 
+func TestUpdateUserRole(t *testing.T) {
+	user1 := createRandomUser(t)
 
-
-
-
+	arg := UpdateUserRoleParams{
+		Role:		util.BankerRole,
+		Username:	user1.Username,
+	}
+	user2, err := testQueries.UpdateUserRole(context.Background(), arg)
+	require.NoError(t, err)
+	require.Equal(t, user1.Username, user2.Username)
+	require.Equal(t, util.BankerRole, user2.Role)
+}
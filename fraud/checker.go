@@ -0,0 +1,70 @@
+package fraud
+
+import (
+	"context"
+	"time"
+)
+
+// Decision is the outcome of screening a transfer
+type Decision string
+
+const (
+	// Allow 转账正常执行
+	Allow Decision = "allow"
+	// Review 转账先创建为待审批，由银行职员人工审核
+	Review Decision = "review"
+	// Block 拒绝转账
+	Block Decision = "block"
+)
+
+// IsValid returns true if the decision is one of the known decisions
+func (decision Decision) IsValid() bool {
+	switch decision {
+	case Allow, Review, Block:
+		return true
+	}
+	return false
+}
+
+// Checker screens a transfer before money moves
+type Checker interface {
+	// Check returns the decision for the transfer, it must not move any money
+	Check(ctx context.Context, input Input) (Result, error)
+}
+
+// Transfer is the transfer to be screened
+type Transfer struct {
+	FromAccountID	int64	`json:"from_account_id"`
+	ToAccountID		int64	`json:"to_account_id"`
+	Amount			int64	`json:"amount"`
+	Currency		string	`json:"currency"`
+}
+
+// History gives access to the transfer history of the sender, it is only queried when a rule needs it
+type History interface {
+	// PayeeTransferCount returns the number of earlier transfers from the sender account to the payee account
+	PayeeTransferCount(ctx context.Context) (int64, error)
+	// TransferCountSince returns the number of transfers from the sender account since the time
+	TransferCountSince(ctx context.Context, since time.Time) (int64, error)
+}
+
+// Metadata describes the request that creates the transfer
+type Metadata struct {
+	Username	string	`json:"username"`
+	ClientIP	string	`json:"client_ip"`
+	UserAgent	string	`json:"user_agent"`
+}
+
+// Input contains everything a Checker can use to screen a transfer
+type Input struct {
+	Transfer	Transfer
+	History		History
+	Metadata	Metadata
+}
+
+// Result is the decision of a Checker, Rule is empty if no rule matches
+type Result struct {
+	Decision	Decision	`json:"decision"`
+	Rule		string		`json:"rule"`
+	Reason		string		`json:"reason"`
+}
@@ -0,0 +1,164 @@
+package fraud
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/techschool/simplebank/util"
+	"io/ioutil"
+	"strings"
+	"time"
+)
+
+// Rule is a declarative fraud rule, it matches a transfer when all of its conditions match
+type Rule struct {
+	Name		string		`json:"name"`
+	Action		Decision	`json:"action"`
+	Conditions	Conditions	`json:"conditions"`
+}
+
+// Conditions of a rule, unset conditions are ignored
+type Conditions struct {
+	// Currency 只对该币种的转账生效
+	Currency	string		`json:"currency,omitempty"`
+	// MinAmount 转账金额不小于该值，使用 Currency 的最小单位，必须同时设置 Currency
+	MinAmount	int64		`json:"min_amount,omitempty"`
+	// NewPayee 为 true 时只匹配第一次向收款账户转账
+	NewPayee	bool		`json:"new_payee,omitempty"`
+	// Window 和 MinCount: 转出账户在 Window 时间内已经有至少 MinCount 笔转账
+	Window		Duration	`json:"window,omitempty"`
+	MinCount	int64		`json:"min_count,omitempty"`
+}
+
+// Duration is a time.Duration written as a string like "10m" in JSON
+type Duration time.Duration
+
+// UnmarshalJSON parses the duration string
+func (d *Duration) UnmarshalJSON(data []byte) error {
+	var value string
+	if err := json.Unmarshal(data, &value); err != nil {
+		return err
+	}
+	duration, err := time.ParseDuration(value)
+	if err != nil {
+		return err
+	}
+	*d = Duration(duration)
+	return nil
+}
+
+// MarshalJSON writes the duration as a string
+func (d Duration) MarshalJSON() ([]byte, error) {
+	return json.Marshal(time.Duration(d).String())
+}
+
+// validate checks the rule can be evaluated
+func (rule Rule) validate() error {
+	if rule.Name == "" {
+		return fmt.Errorf("rule name is required")
+	}
+	if !rule.Action.IsValid() {
+		return fmt.Errorf("rule %s has unknown action %q", rule.Name, rule.Action)
+	}
+	// 金额使用最小单位，不同币种的金额不能直接比较
+	if rule.Conditions.MinAmount > 0 && rule.Conditions.Currency == "" {
+		return fmt.Errorf("rule %s must set currency with min_amount", rule.Name)
+	}
+	if rule.Conditions.Currency != "" {
+		if _, ok := util.LookupCurrency(rule.Conditions.Currency); !ok {
+			return fmt.Errorf("rule %s has unknown currency %q", rule.Name, rule.Conditions.Currency)
+		}
+	}
+	if (rule.Conditions.Window > 0) != (rule.Conditions.MinCount > 0) {
+		return fmt.Errorf("rule %s must set both window and min_count", rule.Name)
+	}
+	return nil
+}
+
+// match returns the reasons if the transfer matches all conditions of the rule
+func (rule Rule) match(ctx context.Context, input Input, now time.Time) (bool, string, error) {
+	conditions := rule.Conditions
+	var reasons []string
+
+	if conditions.Currency != "" {
+		if input.Transfer.Currency != conditions.Currency {
+			return false, "", nil
+		}
+	}
+	if conditions.MinAmount > 0 {
+		if input.Transfer.Amount < conditions.MinAmount {
+			return false, "", nil
+		}
+		reasons = append(reasons, fmt.Sprintf("amount %d >= %d", input.Transfer.Amount, conditions.MinAmount))
+	}
+	if conditions.NewPayee {
+		count, err := input.History.PayeeTransferCount(ctx)
+		if err != nil {
+			return false, "", err
+		}
+		if count > 0 {
+			return false, "", nil
+		}
+		reasons = append(reasons, "new payee")
+	}
+	if conditions.MinCount > 0 {
+		window := time.Duration(conditions.Window)
+		count, err := input.History.TransferCountSince(ctx, now.Add(-window))
+		if err != nil {
+			return false, "", err
+		}
+		if count < conditions.MinCount {
+			return false, "", nil
+		}
+		reasons = append(reasons, fmt.Sprintf("%d transfers in %s", count, window))
+	}
+	return true, strings.Join(reasons, ", "), nil
+}
+
+// RuleEngine is a Checker evaluating rules in order, the first matching rule decides.
+// A transfer matching no rule is allowed.
+type RuleEngine struct {
+	rules	[]Rule
+}
+
+// NewRuleEngine creates a RuleEngine with the rules
+func NewRuleEngine(rules []Rule) (*RuleEngine, error) {
+	for _, rule := range rules {
+		if err := rule.validate(); err != nil {
+			return nil, err
+		}
+	}
+	return &RuleEngine{rules: rules}, nil
+}
+
+// LoadRuleEngine creates a RuleEngine with the rules in a JSON file, an empty path means no rules
+func LoadRuleEngine(path string) (*RuleEngine, error) {
+	if path == "" {
+		return NewRuleEngine(nil)
+	}
+
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var rules []Rule
+	if err := json.Unmarshal(data, &rules); err != nil {
+		return nil, fmt.Errorf("cannot parse fraud rules %s: %w", path, err)
+	}
+	return NewRuleEngine(rules)
+}
+
+// Check evaluates the rules in order
+func (engine *RuleEngine) Check(ctx context.Context, input Input) (Result, error) {
+	now := time.Now()
+	for _, rule := range engine.rules {
+		matched, reason, err := rule.match(ctx, input, now)
+		if err != nil {
+			return Result{}, err
+		}
+		if matched {
+			return Result{Decision: rule.Action, Rule: rule.Name, Reason: reason}, nil
+		}
+	}
+	return Result{Decision: Allow}, nil
+}
@@ -0,0 +1,156 @@
+package fraud
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/stretchr/testify/require"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// fakeHistory 固定的转账记录，recent 是转出账户最近的转账时间
+type fakeHistory struct {
+	payeeCount	int64
+	recent		[]time.Time
+}
+
+func (history fakeHistory) PayeeTransferCount(ctx context.Context) (int64, error) {
+	return history.payeeCount, nil
+}
+
+func (history fakeHistory) TransferCountSince(ctx context.Context, since time.Time) (int64, error) {
+	var count int64
+	for _, createdAt := range history.recent {
+		if createdAt.After(since) {
+			count++
+		}
+	}
+	return count, nil
+}
+
+func TestRuleEngine(t *testing.T) {
+	now := time.Now()
+	rules := []Rule{
+		{
+			Name:		"trusted_small_amount",
+			Action:		Allow,
+			Conditions:	Conditions{Currency: "USD"},
+		},
+		{
+			Name:		"new_payee_large_amount",
+			Action:		Review,
+			Conditions:	Conditions{NewPayee: true, Currency: "EUR", MinAmount: 1000},
+		},
+		{
+			Name:		"high_velocity",
+			Action:		Block,
+			Conditions:	Conditions{Window: Duration(10 * time.Minute), MinCount: 3},
+		},
+	}
+	engine, err := NewRuleEngine(rules)
+	require.NoError(t, err)
+
+	testCases := []struct{
+		name		string
+		transfer	Transfer
+		history		fakeHistory
+		decision	Decision
+		rule		string
+	}{
+		// 第一条规则只对 USD 生效，其余的用例使用 EUR 测试后面的规则
+		{
+			name:		"FirstMatchWins",
+			transfer:	Transfer{Amount: 5000, Currency: "USD"},
+			history:	fakeHistory{recent: []time.Time{now, now, now}},
+			decision:	Allow,
+			rule:		"trusted_small_amount",
+		},
+		{
+			name:		"NewPayeeLargeAmount",
+			transfer:	Transfer{Amount: 1000, Currency: "EUR"},
+			decision:	Review,
+			rule:		"new_payee_large_amount",
+		},
+		{
+			name:		"KnownPayee",
+			transfer:	Transfer{Amount: 1000, Currency: "EUR"},
+			history:	fakeHistory{payeeCount: 1},
+			decision:	Allow,
+		},
+		{
+			name:		"HighVelocity",
+			transfer:	Transfer{Amount: 10, Currency: "EUR"},
+			history:	fakeHistory{recent: []time.Time{now, now.Add(-time.Minute), now.Add(-5 * time.Minute)}},
+			decision:	Block,
+			rule:		"high_velocity",
+		},
+		{
+			name:		"OutsideWindow",
+			transfer:	Transfer{Amount: 10, Currency: "EUR"},
+			history:	fakeHistory{recent: []time.Time{now, now.Add(-time.Minute), now.Add(-time.Hour)}},
+			decision:	Allow,
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			result, err := engine.Check(context.Background(), Input{Transfer: tc.transfer, History: tc.history})
+			require.NoError(t, err)
+			require.Equal(t, tc.decision, result.Decision)
+			require.Equal(t, tc.rule, result.Rule)
+		})
+	}
+}
+
+func TestNewRuleEngineInvalidRule(t *testing.T) {
+	_, err := NewRuleEngine([]Rule{{Name: "unknown_action", Action: "hold"}})
+	require.Error(t, err)
+
+	_, err = NewRuleEngine([]Rule{{Name: "missing_window", Action: Block, Conditions: Conditions{MinCount: 3}}})
+	require.Error(t, err)
+
+	_, err = NewRuleEngine([]Rule{{Action: Block}})
+	require.Error(t, err)
+
+	// 不同币种的最小单位不能直接比较，金额条件必须指定币种
+	_, err = NewRuleEngine([]Rule{{Name: "large_amount", Action: Review, Conditions: Conditions{MinAmount: 10000}}})
+	require.Error(t, err)
+
+	_, err = NewRuleEngine([]Rule{{Name: "unknown_currency", Action: Review, Conditions: Conditions{Currency: "XXX", MinAmount: 10000}}})
+	require.Error(t, err)
+}
+
+func TestLoadRuleEngine(t *testing.T) {
+	engine, err := LoadRuleEngine("")
+	require.NoError(t, err)
+	result, err := engine.Check(context.Background(), Input{})
+	require.NoError(t, err)
+	require.Equal(t, Allow, result.Decision)
+
+	// 仓库中的默认规则必须可以加载
+	engine, err = LoadRuleEngine("../fraud_rules.json")
+	require.NoError(t, err)
+	require.NotEmpty(t, engine.rules)
+
+	dir, err := ioutil.TempDir("", "fraud")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "rules.json")
+	data, err := json.Marshal([]Rule{{Name: "velocity", Action: Review, Conditions: Conditions{Window: Duration(time.Hour), MinCount: 5}}})
+	require.NoError(t, err)
+	require.NoError(t, ioutil.WriteFile(path, data, 0600))
+
+	engine, err = LoadRuleEngine(path)
+	require.NoError(t, err)
+	require.Equal(t, Duration(time.Hour), engine.rules[0].Conditions.Window)
+
+	require.NoError(t, ioutil.WriteFile(path, []byte(`[{"name": "bad", "action": "block", "conditions": {"window": "soon"}}]`), 0600))
+	_, err = LoadRuleEngine(path)
+	require.Error(t, err)
+}
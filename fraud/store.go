@@ -0,0 +1,79 @@
+package fraud
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	db "github.com/techschool/simplebank/db/sqlc"
+	"time"
+)
+
+// storeHistory 从数据库中查询转出账户的转账记录，供风控规则使用
+type storeHistory struct {
+	store			db.Store
+	fromAccountID	int64
+	toAccountID		int64
+}
+
+func (history storeHistory) PayeeTransferCount(ctx context.Context) (int64, error) {
+	return history.store.CountPayeeTransfers(ctx, db.CountPayeeTransfersParams{
+		FromAccountID:	history.fromAccountID,
+		ToAccountID:	history.toAccountID,
+	})
+}
+
+func (history storeHistory) TransferCountSince(ctx context.Context, since time.Time) (int64, error) {
+	return history.store.CountTransfersSince(ctx, db.CountTransfersSinceParams{
+		FromAccountID:	history.fromAccountID,
+		Since:			since,
+	})
+}
+
+// Screen checks the transfer before money moves and records the decision,
+// the caller decides what to do with a review or block decision.
+func Screen(ctx context.Context, checker Checker, store db.Store, arg db.TransferTxParams, currency string, metadata Metadata) (db.FraudDecision, error) {
+	input := Input{
+		Transfer: Transfer{
+			FromAccountID:	arg.FromAccountID,
+			ToAccountID:	arg.ToAccountID,
+			Amount:			arg.Amount,
+			Currency:		currency,
+		},
+		History: storeHistory{
+			store:			store,
+			fromAccountID:	arg.FromAccountID,
+			toAccountID:	arg.ToAccountID,
+		},
+		Metadata:	metadata,
+	}
+
+	result, err := checker.Check(ctx, input)
+	if err != nil {
+		return db.FraudDecision{}, err
+	}
+
+	data, err := json.Marshal(metadata)
+	if err != nil {
+		return db.FraudDecision{}, err
+	}
+	return store.CreateFraudDecision(ctx, db.CreateFraudDecisionParams{
+		Username:		metadata.Username,
+		FromAccountID:	arg.FromAccountID,
+		ToAccountID:	arg.ToAccountID,
+		Amount:			arg.Amount,
+		Currency:		currency,
+		Decision:		string(result.Decision),
+		Rule:			result.Rule,
+		Reason:			result.Reason,
+		Metadata:		data,
+	})
+}
+
+// LinkTransfer links the recorded decision to the transfer it screened.
+func LinkTransfer(ctx context.Context, store db.Store, decision db.FraudDecision, transfer db.Transfer) error {
+	_, err := store.SetFraudDecisionTransfer(ctx, db.SetFraudDecisionTransferParams{
+		ID:			decision.ID,
+		TransferID:	sql.NullInt64{Int64: transfer.ID, Valid: true},
+	})
+	return err
+}
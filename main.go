@@ -1,12 +1,16 @@
 package main
 
 import (
+	"context"
 	"database/sql"
 	_ "github.com/lib/pq"
 	"github.com/techschool/simplebank/api"
 	db "github.com/techschool/simplebank/db/sqlc"
+	"github.com/techschool/simplebank/fraud"
 	"github.com/techschool/simplebank/util"
+	"github.com/techschool/simplebank/worker"
 	"log"
+	"os"
 )
 
 
@@ -20,7 +24,26 @@ func main() {
 		log.Fatal("cannot connect to db: ", err)
 	}
 
-	store := db.NewStore(conn)
+	store := db.NewStoreWithOptions(conn, db.StoreOptions{
+		OverdraftLimit: config.OverdraftLimit,
+	})
+
+	// 币种注册表中新启用的币种在启动时补齐系统账户
+	created, err := store.EnsureSystemAccounts(context.Background())
+	if err != nil {
+		log.Fatalf("cannot create system accounts: %v", err)
+	}
+	if created > 0 {
+		log.Printf("created %d system accounts", created)
+	}
+
+	// 带子命令运行时只执行该命令，不启动服务
+	if len(os.Args) > 1 {
+		runCommand(store, os.Args[1:])
+		return
+	}
+	runWorkers(config, store)
+
 	server, err := api.NewServer(config, store)
 	if err != nil {
 		log.Fatalf("cannot create server: %v", err)
@@ -32,3 +55,81 @@ func main() {
 	}
 }
 
+// runWorkers 在后台运行定时任务，间隔为 0 的任务不运行
+func runWorkers(config util.Config, store db.Store) {
+	if config.SchedulerInterval > 0 {
+		approvalThresholds, err := util.ParseCurrencyAmounts(config.ApprovalThresholds)
+		if err != nil {
+			log.Fatalf("cannot parse approval thresholds, err: %v", err)
+		}
+		fraudChecker, err := fraud.LoadRuleEngine(config.FraudRulesPath)
+		if err != nil {
+			log.Fatalf("cannot load fraud rules, err: %v", err)
+		}
+		processor := worker.NewScheduledTransferProcessor(store, worker.ScheduledTransferOptions{
+			MaxRetries:					config.ScheduledTransferMaxRetries,
+			RetryDelay:					config.ScheduledTransferRetryDelay,
+			IdempotencyKeyRetention:	config.IdempotencyKeyRetention,
+			ApprovalThresholds:			approvalThresholds,
+			FraudChecker:				fraudChecker,
+		})
+		go worker.Run(context.Background(), "scheduled_transfer", config.SchedulerInterval, processor.ProcessDue)
+	}
+
+	if config.IdempotencyKeyCleanupInterval > 0 {
+		cleaner := worker.NewIdempotencyKeyCleaner(store)
+		go worker.Run(context.Background(), "idempotency_key_cleanup", config.IdempotencyKeyCleanupInterval, cleaner.DeleteExpired)
+	}
+
+	if config.RevokedTokenCleanupInterval > 0 {
+		cleaner := worker.NewRevokedTokenCleaner(store)
+		go worker.Run(context.Background(), "revoked_token_cleanup", config.RevokedTokenCleanupInterval, cleaner.DeleteExpired)
+	}
+
+	if config.HoldExpiryInterval > 0 {
+		expirer := worker.NewHoldExpirer(store)
+		go worker.Run(context.Background(), "hold_expiry", config.HoldExpiryInterval, expirer.ExpireDue)
+	}
+
+	if config.ReconcileInterval > 0 {
+		reconciler := worker.NewReconciler(store)
+		go worker.Run(context.Background(), "reconcile", config.ReconcileInterval, reconciler.Reconcile)
+	}
+
+	if config.BalanceSnapshotInterval > 0 {
+		snapshotter := worker.NewBalanceSnapshotter(store)
+		go worker.Run(context.Background(), "balance_snapshot", config.BalanceSnapshotInterval, snapshotter.SnapshotDue)
+	}
+
+	if config.StatementInterval > 0 {
+		generator := worker.NewStatementGenerator(store)
+		go worker.Run(context.Background(), "statement", config.StatementInterval, generator.GenerateDue)
+	}
+}
+
+// runCommand 执行命令行的子命令，对账发现差异或者流水的哈希链断开时以非 0 的状态码退出
+func runCommand(store db.Store, args []string) {
+	switch args[0] {
+	case "reconcile":
+		result, err := worker.NewReconciler(store).Run(context.Background())
+		if err != nil {
+			log.Fatalf("cannot reconcile: %v", err)
+		}
+		if result.Report.DiscrepancyCount > 0 {
+			os.Exit(1)
+		}
+	case "verify-entries":
+		result, err := store.VerifyEntryChain(context.Background(), db.VerifyEntryChainParams{})
+		if err != nil {
+			log.Fatalf("cannot verify entries: %v", err)
+		}
+		log.Printf("checked %d entries of %d accounts", result.EntriesChecked, result.AccountsChecked)
+		if result.Break != nil {
+			log.Printf("hash chain of account [%d] is broken at entry [%d]: %s",
+				result.Break.AccountID, result.Break.EntryID, result.Break.Reason)
+			os.Exit(1)
+		}
+	default:
+		log.Fatalf("unknown command: %s", args[0])
+	}
+}
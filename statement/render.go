@@ -0,0 +1,139 @@
+package statement
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"github.com/techschool/simplebank/util"
+	"io"
+	"strconv"
+	"time"
+)
+
+const dateLayout = "2006-01-02"
+
+// Write renders the statement in the format
+func Write(w io.Writer, format Format, statement Statement) error {
+	switch format {
+	case CSV:
+		return writeCSV(w, statement)
+	case JSON:
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(statement)
+	case Text:
+		return writeText(w, statement)
+	}
+	return fmt.Errorf("unsupported statement format %q", format)
+}
+
+var csvHeader = []string{
+	"date", "entry_id", "kind", "memo", "transfer_id", "counterparty_account_id", "counterparty_owner",
+	"amount", "balance", "currency",
+}
+
+func writeCSV(w io.Writer, statement Statement) error {
+	writer := csv.NewWriter(w)
+	records := [][]string{
+		csvHeader,
+		{statement.PeriodStart.UTC().Format(time.RFC3339), "", "opening_balance", "", "", "", "",
+			"", formatInt(statement.OpeningBalance), statement.Currency},
+	}
+	for _, line := range statement.Lines {
+		records = append(records, []string{
+			line.PostedAt.UTC().Format(time.RFC3339Nano),
+			formatInt(line.EntryID),
+			line.Kind,
+			line.Memo,
+			formatID(line.TransferID),
+			formatID(line.CounterpartyAccountID),
+			line.CounterpartyOwner,
+			formatInt(line.Amount),
+			formatInt(line.Balance),
+			statement.Currency,
+		})
+	}
+	records = append(records, []string{statement.PeriodEnd.UTC().Format(time.RFC3339), "", "closing_balance", "", "", "", "",
+		"", formatInt(statement.ClosingBalance), statement.Currency})
+
+	// WriteAll 会 flush 并返回写入的错误
+	return writer.WriteAll(records)
+}
+
+// 定宽文本的列宽，超出的内容会被截断
+const (
+	descriptionWidth	= 30
+	counterpartyWidth	= 20
+	amountWidth			= 18
+)
+
+var textRow = fmt.Sprintf("%%-10s  %%-%ds  %%-%ds  %%%ds  %%%ds\n", descriptionWidth, counterpartyWidth, amountWidth, amountWidth)
+
+func writeText(w io.Writer, statement Statement) error {
+	money := func(amount int64) string {
+		return util.FormatAmount(amount, statement.Currency)
+	}
+
+	var err error
+	printf := func(format string, args ...interface{}) {
+		if err == nil {
+			_, err = fmt.Fprintf(w, format, args...)
+		}
+	}
+
+	printf("STATEMENT OF ACCOUNT %d (%s)\n", statement.AccountID, statement.Currency)
+	printf("Period: %s to %s\n\n", statement.PeriodStart.UTC().Format(dateLayout), statement.lastDay().Format(dateLayout))
+	printf(textRow, "DATE", "DESCRIPTION", "COUNTERPARTY", "AMOUNT", "BALANCE")
+	printf(textRow, statement.PeriodStart.UTC().Format(dateLayout), "Opening balance", "", "", money(statement.OpeningBalance))
+	for _, line := range statement.Lines {
+		printf(textRow,
+			line.PostedAt.UTC().Format(dateLayout),
+			truncate(description(line.Kind, line.Memo), descriptionWidth),
+			truncate(counterparty(line.CounterpartyAccountID, line.CounterpartyOwner), counterpartyWidth),
+			money(line.Amount),
+			money(line.Balance))
+	}
+	printf(textRow, statement.lastDay().Format(dateLayout), "Closing balance", "", "", money(statement.ClosingBalance))
+	printf("\nTotal credits: %s\nTotal debits: %s\n", money(statement.TotalCredits), money(statement.TotalDebits))
+	return err
+}
+
+// description 流水的说明，有备注时附在类型后面
+func description(kind, memo string) string {
+	if memo == "" {
+		return kind
+	}
+	if kind == "" {
+		return memo
+	}
+	return kind + ": " + memo
+}
+
+// counterparty 转账的对方账户，没有对方账户时为空
+func counterparty(accountID int64, owner string) string {
+	if accountID == 0 {
+		return ""
+	}
+	return fmt.Sprintf("%s #%d", owner, accountID)
+}
+
+// truncate 按字符截断，避免截断多字节的字符
+func truncate(s string, width int) string {
+	runes := []rune(s)
+	if len(runes) <= width {
+		return s
+	}
+	return string(runes[:width-1]) + "~"
+}
+
+func formatInt(value int64) string {
+	return strconv.FormatInt(value, 10)
+}
+
+// formatID 0 表示没有关联的记录，输出为空
+func formatID(id int64) string {
+	if id == 0 {
+		return ""
+	}
+	return formatInt(id)
+}
@@ -0,0 +1,125 @@
+package statement
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"github.com/stretchr/testify/require"
+	db "github.com/techschool/simplebank/db/sqlc"
+	"github.com/techschool/simplebank/util"
+	"strings"
+	"testing"
+	"time"
+)
+
+func sampleStatement(t *testing.T) Statement {
+	start := time.Date(2021, time.March, 1, 0, 0, 0, 0, time.UTC)
+	lines := []db.StatementLine{
+		{
+			EntryID:	11,
+			PostedAt:	start.Add(10 * time.Hour),
+			Kind:		util.JournalDeposit,
+			Memo:		"cash deposit",
+			Amount:		5000,
+			Balance:	15000,
+		},
+		{
+			EntryID:				12,
+			PostedAt:				start.AddDate(0, 0, 3),
+			Kind:					util.JournalTransfer,
+			Memo:					"rent for March, paid to the landlord",
+			Amount:					-2550,
+			Balance:				12450,
+			TransferID:				7,
+			CounterpartyAccountID:	42,
+			CounterpartyOwner:		"landlord",
+		},
+	}
+	data, err := json.Marshal(lines)
+	require.NoError(t, err)
+
+	statement, err := New(db.Statement{
+		ID:				3,
+		AccountID:		9,
+		Currency:		util.USD,
+		PeriodStart:	start,
+		PeriodEnd:		start.AddDate(0, 1, 0),
+		OpeningBalance:	10000,
+		ClosingBalance:	12450,
+		TotalCredits:	5000,
+		TotalDebits:	2550,
+		EntryCount:		2,
+		Lines:			data,
+	})
+	require.NoError(t, err)
+	require.Len(t, statement.Lines, 2)
+	return statement
+}
+
+func TestWriteCSV(t *testing.T) {
+	statement := sampleStatement(t)
+
+	var buf bytes.Buffer
+	require.NoError(t, Write(&buf, CSV, statement))
+
+	records, err := csv.NewReader(&buf).ReadAll()
+	require.NoError(t, err)
+	require.Len(t, records, 5)
+	require.Equal(t, csvHeader, records[0])
+	require.Equal(t, "opening_balance", records[1][2])
+	require.Equal(t, "10000", records[1][8])
+	require.Equal(t, []string{"2021-03-04T00:00:00Z", "12", util.JournalTransfer, "rent for March, paid to the landlord",
+		"7", "42", "landlord", "-2550", "12450", util.USD}, records[3])
+	// 没有对方账户的流水对应的列为空
+	require.Equal(t, "", records[2][5])
+	require.Equal(t, "closing_balance", records[4][2])
+	require.Equal(t, "12450", records[4][8])
+}
+
+func TestWriteJSON(t *testing.T) {
+	statement := sampleStatement(t)
+
+	var buf bytes.Buffer
+	require.NoError(t, Write(&buf, JSON, statement))
+
+	var got Statement
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &got))
+	require.Equal(t, statement.OpeningBalance, got.OpeningBalance)
+	require.Equal(t, statement.ClosingBalance, got.ClosingBalance)
+	require.Len(t, got.Lines, 2)
+	require.Equal(t, "landlord", got.Lines[1].CounterpartyOwner)
+}
+
+func TestWriteText(t *testing.T) {
+	statement := sampleStatement(t)
+
+	var buf bytes.Buffer
+	require.NoError(t, Write(&buf, Text, statement))
+	text := buf.String()
+
+	require.Contains(t, text, "STATEMENT OF ACCOUNT 9 (USD)")
+	require.Contains(t, text, "Period: 2021-03-01 to 2021-03-31")
+	require.Contains(t, text, "Total debits: 25.50 USD")
+
+	// 表格的每一行宽度相同，过长的说明被截断
+	var rows []string
+	for _, row := range strings.Split(text, "\n") {
+		if strings.HasPrefix(row, "2021-") {
+			rows = append(rows, row)
+		}
+	}
+	require.Len(t, rows, 4)
+	for _, row := range rows {
+		require.Len(t, row, 10+2+descriptionWidth+2+counterpartyWidth+2+amountWidth+2+amountWidth)
+	}
+	require.Contains(t, rows[2], "transfer: rent for March, pai~")
+	require.Contains(t, rows[2], "landlord #42")
+	require.True(t, strings.HasSuffix(rows[3], "124.50 USD"))
+}
+
+func TestWriteUnsupportedFormat(t *testing.T) {
+	var buf bytes.Buffer
+	require.Error(t, Write(&buf, Format("pdf"), sampleStatement(t)))
+	require.False(t, Format("pdf").IsValid())
+	require.Equal(t, "statement-9-2021-03.txt", sampleStatement(t).FileName(Text))
+}
@@ -0,0 +1,96 @@
+package statement
+
+import (
+	db "github.com/techschool/simplebank/db/sqlc"
+	"time"
+)
+
+// Format is the rendering of a statement
+type Format string
+
+const (
+	// CSV 每条流水一行，期初和期末余额分别是第一行和最后一行，金额使用最小单位
+	CSV Format = "csv"
+	// JSON 完整的对账单，金额使用最小单位
+	JSON Format = "json"
+	// Text 定宽的纯文本，金额按币种格式化，适合打印或者邮件正文
+	Text Format = "text"
+)
+
+// IsValid returns true if the format is one of the supported formats
+func (format Format) IsValid() bool {
+	switch format {
+	case CSV, JSON, Text:
+		return true
+	}
+	return false
+}
+
+// ContentType returns the MIME type of the format
+func (format Format) ContentType() string {
+	switch format {
+	case CSV:
+		return "text/csv; charset=utf-8"
+	case JSON:
+		return "application/json; charset=utf-8"
+	}
+	return "text/plain; charset=utf-8"
+}
+
+// Extension returns the file name extension of the format
+func (format Format) Extension() string {
+	if format == Text {
+		return "txt"
+	}
+	return string(format)
+}
+
+// Statement is a saved statement with its lines decoded
+type Statement struct {
+	ID				int64				`json:"id"`
+	AccountID		int64				`json:"account_id"`
+	Currency		string				`json:"currency"`
+	// PeriodEnd 不包含在期间内，月度对账单是下个月的第一天
+	PeriodStart		time.Time			`json:"period_start"`
+	PeriodEnd		time.Time			`json:"period_end"`
+	OpeningBalance	int64				`json:"opening_balance"`
+	ClosingBalance	int64				`json:"closing_balance"`
+	TotalCredits	int64				`json:"total_credits"`
+	TotalDebits		int64				`json:"total_debits"`
+	Lines			[]db.StatementLine	`json:"lines"`
+	CreatedAt		time.Time			`json:"created_at"`
+}
+
+// New decodes the lines of a saved statement
+func New(statement db.Statement) (Statement, error) {
+	lines, err := db.StatementLines(statement)
+	if err != nil {
+		return Statement{}, err
+	}
+	if lines == nil {
+		lines = []db.StatementLine{}
+	}
+	return Statement{
+		ID:				statement.ID,
+		AccountID:		statement.AccountID,
+		Currency:		statement.Currency,
+		PeriodStart:	statement.PeriodStart,
+		PeriodEnd:		statement.PeriodEnd,
+		OpeningBalance:	statement.OpeningBalance,
+		ClosingBalance:	statement.ClosingBalance,
+		TotalCredits:	statement.TotalCredits,
+		TotalDebits:	statement.TotalDebits,
+		Lines:			lines,
+		CreatedAt:		statement.CreatedAt,
+	}, nil
+}
+
+// FileName returns the file name of the statement in the format, e.g. statement-12-2021-03.csv
+func (statement Statement) FileName(format Format) string {
+	return "statement-" + formatInt(statement.AccountID) + "-" + statement.PeriodStart.UTC().Format("2006-01") + "." + format.Extension()
+}
+
+// lastDay 期间的最后一天，PeriodEnd 本身不包含在期间内
+func (statement Statement) lastDay() time.Time {
+	return statement.PeriodEnd.UTC().Add(-time.Microsecond)
+}
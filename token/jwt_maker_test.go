@@ -14,22 +14,26 @@ func TestJWTMaker(t *testing.T) {
 	require.NoError(t, err)
 
 	username := util.RandomOwnerName()
+	role := util.DepositorRole
 	duration := time.Minute
 
 	issuedAt := time.Now()
 	expiredAt := issuedAt.Add(duration)
 
-	token, err := maker.CreateToken(username,duration)
+	token, payload, err := maker.CreateToken(username, role, TokenTypeAccessToken, duration)
 	require.NoError(t, err)
 	require.NotEmpty(t, token)
+	require.NotEmpty(t, payload)
 	fmt.Println(token)
 
-	payload, err := maker.VerifyToken(token)
+	payload, err = maker.VerifyToken(token, TokenTypeAccessToken)
 	require.NoError(t, err)
 	require.NotEmpty(t, payload)
 	fmt.Printf("%+v", payload)
 
 	require.NotZero(t, payload.ID)
+	require.Equal(t, username, payload.Username)
+	require.Equal(t, role, payload.Role)
 	require.WithinDuration(t, issuedAt, payload.IssuedAt, time.Second)
 	require.WithinDuration(t, expiredAt, payload.ExpiredAt, time.Second)
 }
@@ -38,18 +42,19 @@ func TestExpiredJWTToken(t *testing.T) {
 	maker, err := NewJWTMaker(util.RandomString(32))
 	require.NoError(t, err)
 
-	token, err := maker.CreateToken(util.RandomOwnerName(), -time.Minute)
+	token, payload, err := maker.CreateToken(util.RandomOwnerName(), util.DepositorRole, TokenTypeAccessToken, -time.Minute)
 	require.NoError(t, err)
 	require.NotEmpty(t, token)
+	require.NotEmpty(t, payload)
 
-	payload, err := maker.VerifyToken(token)
+	payload, err = maker.VerifyToken(token, TokenTypeAccessToken)
 	require.Error(t, err)
 	require.EqualError(t, err, ErrExpiredToken.Error())
 	require.Nil(t, payload)
 }
 
 func TestInvalidJWTTokenAlgNone(t *testing.T) {
-	payload, err := NewPayload(util.RandomOwnerName(), time.Minute)
+	payload, err := NewPayload(util.RandomOwnerName(), util.DepositorRole, TokenTypeAccessToken, time.Minute)
 	require.NoError(t, err)
 
 	jwtToken := jwt.NewWithClaims(jwt.SigningMethodNone, payload)
@@ -61,9 +66,21 @@ func TestInvalidJWTTokenAlgNone(t *testing.T) {
 	maker, err := NewJWTMaker(util.RandomString(32))
 	require.NoError(t, err)
 
-	payload, err = maker.VerifyToken(token)
+	payload, err = maker.VerifyToken(token, TokenTypeAccessToken)
 	require.Error(t, err)
 	require.EqualError(t, err, ErrInvalidToken.Error())
 	require.Nil(t, payload)
 }
 
+
+func TestJWTTokenWrongType(t *testing.T) {
+	maker, err := NewJWTMaker(util.RandomString(32))
+	require.NoError(t, err)
+
+	token, _, err := maker.CreateToken(util.RandomOwnerName(), util.DepositorRole, TokenTypeAccessToken, time.Minute)
+	require.NoError(t, err)
+
+	payload, err := maker.VerifyToken(token, TokenTypeRefreshToken)
+	require.ErrorIs(t, err, ErrWrongTokenType)
+	require.Nil(t, payload)
+}
@@ -0,0 +1,52 @@
+package token
+
+import (
+	"crypto/ed25519"
+	"encoding/base64"
+	"fmt"
+	"strings"
+)
+
+// ParsePrivateKey decodes a base64 encoded Ed25519 private key.
+// Both the 32 bytes seed and the 64 bytes private key are accepted.
+func ParsePrivateKey(encoded string) (ed25519.PrivateKey, error) {
+	data, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, fmt.Errorf("cannot decode private key: %v", err)
+	}
+
+	switch len(data) {
+	case ed25519.SeedSize:
+		return ed25519.NewKeyFromSeed(data), nil
+	case ed25519.PrivateKeySize:
+		return ed25519.PrivateKey(data), nil
+	}
+	return nil, fmt.Errorf("invalid key size: must be %d or %d bytes", ed25519.SeedSize, ed25519.PrivateKeySize)
+}
+
+// ParsePublicKeys decodes a list of base64 encoded Ed25519 public keys
+// in the form of "kid1:key1,kid2:key2".
+func ParsePublicKeys(encoded string) (map[string]ed25519.PublicKey, error) {
+	publicKeys := make(map[string]ed25519.PublicKey)
+	for _, item := range strings.Split(encoded, ",") {
+		item = strings.TrimSpace(item)
+		if len(item) == 0 {
+			continue
+		}
+
+		fields := strings.SplitN(item, ":", 2)
+		if len(fields) != 2 || len(fields[0]) == 0 {
+			return nil, fmt.Errorf("invalid public key format: %s", item)
+		}
+
+		data, err := base64.StdEncoding.DecodeString(fields[1])
+		if err != nil {
+			return nil, fmt.Errorf("cannot decode public key %s: %v", fields[0], err)
+		}
+		if len(data) != ed25519.PublicKeySize {
+			return nil, fmt.Errorf("invalid size of public key %s: must be exactly %d bytes", fields[0], ed25519.PublicKeySize)
+		}
+		publicKeys[fields[0]] = ed25519.PublicKey(data)
+	}
+	return publicKeys, nil
+}
@@ -1,13 +1,21 @@
 package token
 
-import "time"
+import (
+	"crypto/ed25519"
+	"time"
+)
 
 // Maker is an interface for managing tokens
 type Maker interface {
-	// CreateToken create a new token for specific username and duration
-	CreateToken(username string, duration time.Duration) (string, error)
+	// CreateToken create a new token of the type for specific username, role and duration
+	CreateToken(username string, role string, tokenType TokenType, duration time.Duration) (string, *Payload, error)
 
-	// VerifyToken checks if the token is valid or not
-	VerifyToken(token string) (*Payload, error)
+	// VerifyToken checks if the token is valid and of the expected type
+	VerifyToken(token string, tokenType TokenType) (*Payload, error)
 }
 
+// PublicKeyProvider is implemented by makers which sign tokens with asymmetric keys
+type PublicKeyProvider interface {
+	// PublicKeys returns the public keys used to verify tokens, indexed by key ID
+	PublicKeys() map[string]ed25519.PublicKey
+}
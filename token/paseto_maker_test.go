@@ -12,21 +12,24 @@ func TestPasetoMaker(t *testing.T) {
 	require.NoError(t, err)
 
 	username := util.RandomOwnerName()
+	role := util.DepositorRole
 	duration := time.Minute
 
 	issuedAt := time.Now()
 	expiredAt := issuedAt.Add(duration)
 
-	token, err := maker.CreateToken(username, duration)
+	token, payload, err := maker.CreateToken(username, role, TokenTypeAccessToken, duration)
 	require.NoError(t, err)
 	require.NotEmpty(t, token)
+	require.NotEmpty(t, payload)
 
-	payload, err := maker.VerifyToken(token)
+	payload, err = maker.VerifyToken(token, TokenTypeAccessToken)
 	require.NoError(t, err)
 	require.NotEmpty(t, token)
 
 	require.NotZero(t, payload.ID)
 	require.Equal(t, username, payload.Username)
+	require.Equal(t, role, payload.Role)
 	require.WithinDuration(t, issuedAt, payload.IssuedAt, time.Second)
 	require.WithinDuration(t, expiredAt, payload.ExpiredAt, time.Second)
 }
@@ -35,12 +38,29 @@ func TestExpiredPasetoToken(t *testing.T) {
 	maker, err := NewPasetoMaker(util.RandomString(32))
 	require.NoError(t, err)
 
-	token, err := maker.CreateToken(util.RandomOwnerName(), -time.Minute)
+	token, payload, err := maker.CreateToken(util.RandomOwnerName(), util.DepositorRole, TokenTypeAccessToken, -time.Minute)
 	require.NoError(t, err)
 	require.NotEmpty(t, token)
+	require.NotEmpty(t, payload)
 
-	payload, err := maker.VerifyToken(token)
+	payload, err = maker.VerifyToken(token, TokenTypeAccessToken)
 	require.Error(t, err)
 	require.EqualError(t, err, ErrExpiredToken.Error())
 	require.Nil(t, payload)
-}
\ No newline at end of file
+}
+func TestPasetoTokenWrongType(t *testing.T) {
+	maker, err := NewPasetoMaker(util.RandomString(32))
+	require.NoError(t, err)
+
+	// refresh token 不能当作 access token 使用，反之亦然
+	token, _, err := maker.CreateToken(util.RandomOwnerName(), util.DepositorRole, TokenTypeRefreshToken, time.Minute)
+	require.NoError(t, err)
+
+	payload, err := maker.VerifyToken(token, TokenTypeAccessToken)
+	require.ErrorIs(t, err, ErrWrongTokenType)
+	require.Nil(t, payload)
+
+	payload, err = maker.VerifyToken(token, TokenTypeRefreshToken)
+	require.NoError(t, err)
+	require.Equal(t, TokenTypeRefreshToken, payload.Type)
+}
@@ -0,0 +1,98 @@
+package token
+
+import (
+	"crypto/ed25519"
+	"fmt"
+	"github.com/o1egl/paseto"
+	"time"
+)
+
+// PasetoPublicMaker is a PASETO v2.public token maker.
+// Tokens are signed with an Ed25519 private key, and the ID of that key is put in the footer,
+// so keys can be rotated while tokens signed by older keys are still accepted.
+type PasetoPublicMaker struct {
+	paseto		*paseto.V2
+	keyID		string
+	privateKey	ed25519.PrivateKey
+	publicKeys	map[string]ed25519.PublicKey
+}
+
+// tokenFooter is the unencrypted footer of a v2.public token
+type tokenFooter struct {
+	KeyID	string	`json:"kid"`
+}
+
+// NewPasetoPublicMaker creates a new PasetoPublicMaker.
+// keyID identifies the signing key, verificationKeys holds other public keys that are still accepted.
+func NewPasetoPublicMaker(keyID string, privateKey ed25519.PrivateKey, verificationKeys map[string]ed25519.PublicKey) (Maker, error) {
+	if len(keyID) == 0 {
+		return nil, fmt.Errorf("key id must not be empty")
+	}
+	if len(privateKey) != ed25519.PrivateKeySize {
+		return nil, fmt.Errorf("invalid key size: must be exactly %d bytes", ed25519.PrivateKeySize)
+	}
+
+	publicKeys := make(map[string]ed25519.PublicKey, len(verificationKeys)+1)
+	for id, publicKey := range verificationKeys {
+		if len(publicKey) != ed25519.PublicKeySize {
+			return nil, fmt.Errorf("invalid size of public key %s: must be exactly %d bytes", id, ed25519.PublicKeySize)
+		}
+		publicKeys[id] = publicKey
+	}
+	publicKeys[keyID] = privateKey.Public().(ed25519.PublicKey)
+
+	maker := &PasetoPublicMaker{
+		paseto:		paseto.NewV2(),
+		keyID:		keyID,
+		privateKey:	privateKey,
+		publicKeys:	publicKeys,
+	}
+	return maker, nil
+}
+
+func (maker *PasetoPublicMaker) CreateToken(username string, role string, tokenType TokenType, duration time.Duration) (string, *Payload, error) {
+	payload, err := NewPayload(username, role, tokenType, duration)
+	if err != nil {
+		return "", payload, err
+	}
+	token, err := maker.paseto.Sign(maker.privateKey, payload, tokenFooter{KeyID: maker.keyID})
+	return token, payload, err
+}
+
+func (maker *PasetoPublicMaker) VerifyToken(token string, tokenType TokenType) (*Payload, error) {
+	var footer tokenFooter
+	err := paseto.ParseFooter(token, &footer)
+	if err != nil {
+		return nil, ErrInvalidToken
+	}
+
+	publicKey, ok := maker.publicKeys[footer.KeyID]
+	if !ok {
+		return nil, ErrInvalidToken
+	}
+
+	payload := &Payload{}
+	err = maker.paseto.Verify(token, publicKey, payload, nil)
+	if err != nil {
+		return nil, ErrInvalidToken
+	}
+
+	err = payload.Valid()
+	if err != nil {
+		return nil, err
+	}
+	err = payload.checkType(tokenType)
+	if err != nil {
+		return nil, err
+	}
+	return payload, nil
+}
+
+// PublicKeys returns all public keys accepted by the maker, indexed by key ID.
+func (maker *PasetoPublicMaker) PublicKeys() map[string]ed25519.PublicKey {
+	publicKeys := make(map[string]ed25519.PublicKey, len(maker.publicKeys))
+	for id, publicKey := range maker.publicKeys {
+		publicKeys[id] = publicKey
+	}
+	return publicKeys
+}
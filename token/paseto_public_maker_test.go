@@ -0,0 +1,127 @@
+package token
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/base64"
+	"github.com/stretchr/testify/require"
+	"github.com/techschool/simplebank/util"
+	"testing"
+	"time"
+)
+
+func randomPrivateKey(t *testing.T) ed25519.PrivateKey {
+	_, privateKey, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	return privateKey
+}
+
+func TestPasetoPublicMaker(t *testing.T) {
+	maker, err := NewPasetoPublicMaker("key1", randomPrivateKey(t), nil)
+	require.NoError(t, err)
+
+	username := util.RandomOwnerName()
+	role := util.DepositorRole
+	duration := time.Minute
+
+	issuedAt := time.Now()
+	expiredAt := issuedAt.Add(duration)
+
+	token, payload, err := maker.CreateToken(username, role, TokenTypeAccessToken, duration)
+	require.NoError(t, err)
+	require.NotEmpty(t, token)
+	require.NotEmpty(t, payload)
+
+	payload, err = maker.VerifyToken(token, TokenTypeAccessToken)
+	require.NoError(t, err)
+	require.NotEmpty(t, payload)
+
+	require.NotZero(t, payload.ID)
+	require.Equal(t, username, payload.Username)
+	require.Equal(t, role, payload.Role)
+	require.WithinDuration(t, issuedAt, payload.IssuedAt, time.Second)
+	require.WithinDuration(t, expiredAt, payload.ExpiredAt, time.Second)
+}
+
+func TestExpiredPasetoPublicToken(t *testing.T) {
+	maker, err := NewPasetoPublicMaker("key1", randomPrivateKey(t), nil)
+	require.NoError(t, err)
+
+	token, payload, err := maker.CreateToken(util.RandomOwnerName(), util.DepositorRole, TokenTypeAccessToken, -time.Minute)
+	require.NoError(t, err)
+	require.NotEmpty(t, token)
+	require.NotEmpty(t, payload)
+
+	payload, err = maker.VerifyToken(token, TokenTypeAccessToken)
+	require.Error(t, err)
+	require.EqualError(t, err, ErrExpiredToken.Error())
+	require.Nil(t, payload)
+}
+
+func TestPasetoPublicKeyRotation(t *testing.T) {
+	oldKey := randomPrivateKey(t)
+	oldMaker, err := NewPasetoPublicMaker("key1", oldKey, nil)
+	require.NoError(t, err)
+
+	token, _, err := oldMaker.CreateToken(util.RandomOwnerName(), util.DepositorRole, TokenTypeAccessToken, time.Minute)
+	require.NoError(t, err)
+
+	// 新的签名密钥上线后，旧密钥签发的 token 仍然可以验证
+	verificationKeys := map[string]ed25519.PublicKey{
+		"key1": oldKey.Public().(ed25519.PublicKey),
+	}
+	newMaker, err := NewPasetoPublicMaker("key2", randomPrivateKey(t), verificationKeys)
+	require.NoError(t, err)
+
+	payload, err := newMaker.VerifyToken(token, TokenTypeAccessToken)
+	require.NoError(t, err)
+	require.NotEmpty(t, payload)
+	require.Len(t, newMaker.(PublicKeyProvider).PublicKeys(), 2)
+
+	// 旧密钥下线后，旧 token 不再有效
+	retiredMaker, err := NewPasetoPublicMaker("key2", randomPrivateKey(t), nil)
+	require.NoError(t, err)
+
+	payload, err = retiredMaker.VerifyToken(token, TokenTypeAccessToken)
+	require.EqualError(t, err, ErrInvalidToken.Error())
+	require.Nil(t, payload)
+}
+
+func TestInvalidPasetoPublicTokenSignature(t *testing.T) {
+	maker1, err := NewPasetoPublicMaker("key1", randomPrivateKey(t), nil)
+	require.NoError(t, err)
+
+	// 使用相同 key ID 但不同密钥签发的 token
+	maker2, err := NewPasetoPublicMaker("key1", randomPrivateKey(t), nil)
+	require.NoError(t, err)
+
+	token, _, err := maker2.CreateToken(util.RandomOwnerName(), util.DepositorRole, TokenTypeAccessToken, time.Minute)
+	require.NoError(t, err)
+
+	payload, err := maker1.VerifyToken(token, TokenTypeAccessToken)
+	require.EqualError(t, err, ErrInvalidToken.Error())
+	require.Nil(t, payload)
+}
+
+func TestParseKeys(t *testing.T) {
+	privateKey := randomPrivateKey(t)
+
+	parsedKey, err := ParsePrivateKey(base64.StdEncoding.EncodeToString(privateKey.Seed()))
+	require.NoError(t, err)
+	require.Equal(t, privateKey, parsedKey)
+
+	parsedKey, err = ParsePrivateKey(base64.StdEncoding.EncodeToString(privateKey))
+	require.NoError(t, err)
+	require.Equal(t, privateKey, parsedKey)
+
+	_, err = ParsePrivateKey(base64.StdEncoding.EncodeToString([]byte(util.RandomString(10))))
+	require.Error(t, err)
+
+	publicKey := privateKey.Public().(ed25519.PublicKey)
+	publicKeys, err := ParsePublicKeys("key1:" + base64.StdEncoding.EncodeToString(publicKey))
+	require.NoError(t, err)
+	require.Equal(t, publicKey, publicKeys["key1"])
+
+	_, err = ParsePublicKeys("invalid")
+	require.Error(t, err)
+}
@@ -10,18 +10,31 @@ import (
 var (
 	ErrInvalidToken = errors.New("token is valid")
 	ErrExpiredToken = errors.New("token has expired")
+	ErrWrongTokenType = errors.New("token type is not accepted")
+)
+
+// TokenType distinguishes access tokens from refresh tokens signed by the same maker.
+type TokenType string
+
+const (
+	// TokenTypeAccessToken 有效期短，用于访问接口
+	TokenTypeAccessToken TokenType = "access"
+	// TokenTypeRefreshToken 有效期长，只能用于换取新的 access token
+	TokenTypeRefreshToken TokenType = "refresh"
 )
 
 // Payload contains the payload data of token.
 type Payload struct {
 	ID			uuid.UUID	`json:"id"`
 	Username	string		`json:"username"`
+	Role		string		`json:"role"`
+	Type		TokenType	`json:"token_type"`
 	IssuedAt	time.Time	`json:"issued_at"`
 	ExpiredAt	time.Time	`json:"expired_at"`
 }
 
-// NewPayload creates a new token payload with a specific username and duration.
-func NewPayload(username string, duration time.Duration) (*Payload, error) {
+// NewPayload creates a new token payload with a specific username, role, token type and duration.
+func NewPayload(username string, role string, tokenType TokenType, duration time.Duration) (*Payload, error) {
 	tokenID, err := uuid.NewRandom()
 	if err != nil {
 		return nil, err
@@ -30,6 +43,8 @@ func NewPayload(username string, duration time.Duration) (*Payload, error) {
 	payload := &Payload{
 		ID:			tokenID,
 		Username: 	username,
+		Role:		role,
+		Type:		tokenType,
 		IssuedAt: 	time.Now(),
 		ExpiredAt: 	time.Now().Add(duration),
 	}
@@ -43,3 +58,12 @@ func (payload *Payload) Valid() error {
 	}
 	return nil
 }
+
+// checkType returns ErrWrongTokenType if the token is not of the expected type, tokens issued before the
+// type was added have no type and are rejected.
+func (payload *Payload) checkType(tokenType TokenType) error {
+	if payload.Type != tokenType {
+		return ErrWrongTokenType
+	}
+	return nil
+}
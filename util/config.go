@@ -12,7 +12,38 @@ type Config struct {
 	DBSource 			string `mapstructure:"DB_SOURCE"`
 	ServerAddress 		string `mapstructure:"SERVER_ADDRESS"`
 	TokenSymmetricKey 	string `mapstructure:"TOKEN_SYMMETRIC_KEY"`
+	// 配置了 TOKEN_PRIVATE_KEY 时使用 Ed25519 签名的 v2.public token
+	TokenKeyID			string `mapstructure:"TOKEN_KEY_ID"`
+	TokenPrivateKey		string `mapstructure:"TOKEN_PRIVATE_KEY"`
+	TokenVerificationKeys string `mapstructure:"TOKEN_VERIFICATION_KEYS"`
 	AccessTokenDuration time.Duration `mapstructure:"ACCESS_TOKEN_DURATION"`
+	RefreshTokenDuration time.Duration `mapstructure:"REFRESH_TOKEN_DURATION"`
+	// 清理过期的吊销记录的间隔，为 0 时不清理
+	RevokedTokenCleanupInterval time.Duration `mapstructure:"REVOKED_TOKEN_CLEANUP_INTERVAL"`
+	OverdraftLimit		int64 `mapstructure:"OVERDRAFT_LIMIT"`
+	// 转账的 Idempotency-Key 保留的时间，过期后可以重新使用
+	IdempotencyKeyRetention time.Duration `mapstructure:"IDEMPOTENCY_KEY_RETENTION"`
+	// 清理过期 Idempotency-Key 的间隔，为 0 时不清理
+	IdempotencyKeyCleanupInterval time.Duration `mapstructure:"IDEMPOTENCY_KEY_CLEANUP_INTERVAL"`
+	// 定时转账的检查间隔，失败后最多重试的次数和重试的间隔
+	SchedulerInterval	time.Duration `mapstructure:"SCHEDULER_INTERVAL"`
+	ScheduledTransferMaxRetries int32 `mapstructure:"SCHEDULED_TRANSFER_MAX_RETRIES"`
+	ScheduledTransferRetryDelay time.Duration `mapstructure:"SCHEDULED_TRANSFER_RETRY_DELAY"`
+	// 单笔转账超过该币种的金额时需要审批，格式为 USD:1000000,EUR:1000000，金额使用最小单位
+	ApprovalThresholds	string `mapstructure:"APPROVAL_THRESHOLDS"`
+	// 预授权的有效期，以及清理过期预授权的间隔
+	HoldTTL				time.Duration `mapstructure:"HOLD_TTL"`
+	HoldExpiryInterval	time.Duration `mapstructure:"HOLD_EXPIRY_INTERVAL"`
+	// 风控规则的 JSON 文件，为空时不使用任何规则
+	FraudRulesPath		string `mapstructure:"FRAUD_RULES_PATH"`
+	// 列表接口每页最多返回的记录数
+	MaxPageSize			int32 `mapstructure:"MAX_PAGE_SIZE"`
+	// 后台对账的间隔，为 0 时只能通过 reconcile 子命令手动对账
+	ReconcileInterval	time.Duration `mapstructure:"RECONCILE_INTERVAL"`
+	// 生成每日余额快照的检查间隔，为 0 时历史余额查询从第一条流水开始累加
+	BalanceSnapshotInterval	time.Duration `mapstructure:"BALANCE_SNAPSHOT_INTERVAL"`
+	// 检查是否需要生成月度对账单的间隔，为 0 时不自动生成
+	StatementInterval	time.Duration `mapstructure:"STATEMENT_INTERVAL"`
 
 }
 
@@ -1,18 +1,125 @@
 package util
 
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
 const (
 	USD = "USD"
 	CAD = "CAD"
 	EUR = "EUR"
-	RAM = "RAM"
+	CNY = "CNY"
+	JPY = "JPY"
+	GBP = "GBP"
 )
 
-func IsSupportCurrency(currency string) bool {
-	switch currency {
-	case USD, CAD, EUR, RAM:
-		return true
+// Currency is an ISO 4217 currency known by the bank.
+// Amounts are always stored in minor units, e.g. cents for USD.
+type Currency struct {
+	Code		string	`json:"code"`
+	NumericCode	string	`json:"numeric_code"`
+	Name		string	`json:"name"`
+	// MinorUnits 最小单位的位数，USD 是 2 位(美分)，JPY 没有更小的单位
+	MinorUnits	int		`json:"minor_units"`
+	// Enabled 只有启用的币种可以开户和转账
+	Enabled		bool	`json:"enabled"`
+}
+
+// currencies 币种注册表，所有币种相关的校验和格式化都从这里读取
+var currencies = map[string]Currency{
+	USD: {Code: USD, NumericCode: "840", Name: "US Dollar", MinorUnits: 2, Enabled: true},
+	CAD: {Code: CAD, NumericCode: "124", Name: "Canadian Dollar", MinorUnits: 2, Enabled: true},
+	EUR: {Code: EUR, NumericCode: "978", Name: "Euro", MinorUnits: 2, Enabled: true},
+	CNY: {Code: CNY, NumericCode: "156", Name: "Yuan Renminbi", MinorUnits: 2, Enabled: true},
+	JPY: {Code: JPY, NumericCode: "392", Name: "Yen", MinorUnits: 0, Enabled: true},
+	GBP: {Code: GBP, NumericCode: "826", Name: "Pound Sterling", MinorUnits: 2, Enabled: false},
+}
+
+// LookupCurrency returns the registered currency with the code, whether enabled or not
+func LookupCurrency(code string) (Currency, bool) {
+	currency, ok := currencies[code]
+	return currency, ok
+}
+
+// IsSupportCurrency returns true if the currency is registered and enabled
+func IsSupportCurrency(code string) bool {
+	currency, ok := LookupCurrency(code)
+	return ok && currency.Enabled
+}
+
+// SupportedCurrencies returns all enabled currencies sorted by code
+func SupportedCurrencies() []Currency {
+	result := make([]Currency, 0, len(currencies))
+	for _, currency := range currencies {
+		if currency.Enabled {
+			result = append(result, currency)
+		}
 	}
-	return false
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Code < result[j].Code
+	})
+	return result
 }
 
+// FormatAmount formats an amount in minor units, e.g. 12345 USD is "123.45 USD".
+// Unknown currencies are formatted without decimal point.
+func FormatAmount(amount int64, code string) string {
+	currency, ok := LookupCurrency(code)
+	if !ok || currency.MinorUnits == 0 {
+		return fmt.Sprintf("%d %s", amount, code)
+	}
 
+	sign := ""
+	value := uint64(amount)
+	if amount < 0 {
+		sign = "-"
+		value = uint64(-amount)
+	}
+
+	digits := fmt.Sprintf("%0*d", currency.MinorUnits+1, value)
+	point := len(digits) - currency.MinorUnits
+	return fmt.Sprintf("%s%s.%s %s", sign, digits[:point], digits[point:], code)
+}
+
+// currencyCodes 按字母顺序返回所有启用的币种代码
+func currencyCodes() []string {
+	supported := SupportedCurrencies()
+	codes := make([]string, len(supported))
+	for i, currency := range supported {
+		codes[i] = currency.Code
+	}
+	return codes
+}
+
+// ParseCurrencyAmounts parses amounts per currency in minor units, e.g. "USD:1000000,JPY:100000".
+// An empty string returns an empty map.
+func ParseCurrencyAmounts(value string) (map[string]int64, error) {
+	result := make(map[string]int64)
+	for _, item := range strings.Split(value, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+
+		parts := strings.SplitN(item, ":", 2)
+		if len(parts) != 2 {
+			return nil, fmt.Errorf("invalid currency amount %q, expected CURRENCY:AMOUNT", item)
+		}
+		code := strings.TrimSpace(parts[0])
+		if _, ok := LookupCurrency(code); !ok {
+			return nil, fmt.Errorf("unknown currency %q", code)
+		}
+		if _, ok := result[code]; ok {
+			return nil, fmt.Errorf("duplicate currency %q", code)
+		}
+		amount, err := strconv.ParseInt(strings.TrimSpace(parts[1]), 10, 64)
+		if err != nil || amount < 0 {
+			return nil, fmt.Errorf("invalid amount for currency %q", code)
+		}
+		result[code] = amount
+	}
+	return result, nil
+}
@@ -0,0 +1,63 @@
+package util
+
+import (
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestIsSupportCurrency(t *testing.T) {
+	require.True(t, IsSupportCurrency(USD))
+	require.True(t, IsSupportCurrency(JPY))
+	require.False(t, IsSupportCurrency(GBP))  // 已注册但没有启用
+	require.False(t, IsSupportCurrency("RMB"))
+	require.False(t, IsSupportCurrency("usd"))
+
+	for i := 0; i < 20; i++ {
+		require.True(t, IsSupportCurrency(RandomCurrency()))
+	}
+}
+
+func TestLookupCurrency(t *testing.T) {
+	currency, ok := LookupCurrency(EUR)
+	require.True(t, ok)
+	require.Equal(t, "978", currency.NumericCode)
+	require.Equal(t, 2, currency.MinorUnits)
+
+	_, ok = LookupCurrency("XXX")
+	require.False(t, ok)
+}
+
+func TestSupportedCurrencies(t *testing.T) {
+	supported := SupportedCurrencies()
+	require.NotEmpty(t, supported)
+	for i, currency := range supported {
+		require.True(t, currency.Enabled)
+		if i > 0 {
+			require.Less(t, supported[i-1].Code, currency.Code)
+		}
+	}
+}
+
+func TestFormatAmount(t *testing.T) {
+	require.Equal(t, "123.45 USD", FormatAmount(12345, USD))
+	require.Equal(t, "0.05 EUR", FormatAmount(5, EUR))
+	require.Equal(t, "-1.50 CAD", FormatAmount(-150, CAD))
+	require.Equal(t, "0.00 USD", FormatAmount(0, USD))
+	require.Equal(t, "1234 JPY", FormatAmount(1234, JPY))
+	require.Equal(t, "42 XXX", FormatAmount(42, "XXX"))
+}
+
+func TestParseCurrencyAmounts(t *testing.T) {
+	amounts, err := ParseCurrencyAmounts("USD:1000000, JPY:50000")
+	require.NoError(t, err)
+	require.Equal(t, map[string]int64{USD: 1000000, JPY: 50000}, amounts)
+
+	amounts, err = ParseCurrencyAmounts("")
+	require.NoError(t, err)
+	require.Empty(t, amounts)
+
+	for _, value := range []string{"USD", "XXX:100", "USD:abc", "USD:-1", "USD:1,USD:2"} {
+		_, err = ParseCurrencyAmounts(value)
+		require.Error(t, err, value)
+	}
+}
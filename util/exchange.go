@@ -0,0 +1,71 @@
+package util
+
+import (
+	"fmt"
+	"math/big"
+	"regexp"
+)
+
+// exchangeRatePattern 汇率字段是 numeric(20, 10)，整数部分和小数部分最多各 10 位
+var exchangeRatePattern = regexp.MustCompile(`^[0-9]{1,10}(\.[0-9]{1,10})?$`)
+
+// IsValidExchangeRate returns true if the rate is a positive decimal that fits in the exchange_rates table
+func IsValidExchangeRate(rate string) bool {
+	if !exchangeRatePattern.MatchString(rate) {
+		return false
+	}
+	r, ok := new(big.Rat).SetString(rate)
+	return ok && r.Sign() > 0
+}
+
+// ConvertAmount converts an amount in minor units of the base currency to minor units of the quote currency.
+// The rate is quoted in major units, the fraction of the result is truncated.
+func ConvertAmount(amount int64, baseCurrency, quoteCurrency, rate string) (int64, error) {
+	r, ok := new(big.Rat).SetString(rate)
+	if !ok || r.Sign() <= 0 {
+		return 0, fmt.Errorf("invalid exchange rate: %s", rate)
+	}
+	base, ok := LookupCurrency(baseCurrency)
+	if !ok {
+		return 0, fmt.Errorf("unknown currency: %s", baseCurrency)
+	}
+	quote, ok := LookupCurrency(quoteCurrency)
+	if !ok {
+		return 0, fmt.Errorf("unknown currency: %s", quoteCurrency)
+	}
+
+	// 例如 100 JPY 兑换 USD，rate = 0.0067，结果是 0.67 USD，即 67 美分
+	converted := new(big.Rat).Mul(new(big.Rat).SetInt64(amount), r)
+	converted.Mul(converted, minorUnitsScale(quote.MinorUnits-base.MinorUnits))
+	// 不足一个最小单位的部分直接舍去
+	result := new(big.Int).Quo(converted.Num(), converted.Denom())
+	if !result.IsInt64() {
+		return 0, fmt.Errorf("converted amount overflows: %d * %s", amount, rate)
+	}
+	return result.Int64(), nil
+}
+
+// InverseExchangeRate returns 1 / rate, rounded to 10 decimal places as stored in the exchange_rates table
+func InverseExchangeRate(rate string) (string, error) {
+	r, ok := new(big.Rat).SetString(rate)
+	if !ok || r.Sign() <= 0 {
+		return "", fmt.Errorf("invalid exchange rate: %s", rate)
+	}
+	return new(big.Rat).Inv(r).FloatString(10), nil
+}
+
+// minorUnitsScale returns 10^exp as a rational number, exp can be negative
+func minorUnitsScale(exp int) *big.Rat {
+	scale := new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(abs(exp))), nil)
+	if exp < 0 {
+		return new(big.Rat).SetFrac(big.NewInt(1), scale)
+	}
+	return new(big.Rat).SetInt(scale)
+}
+
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
@@ -0,0 +1,62 @@
+package util
+
+import (
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestConvertAmount(t *testing.T) {
+	amount, err := ConvertAmount(100, USD, EUR, "1.2345000000")
+	require.NoError(t, err)
+	require.Equal(t, int64(123), amount)
+
+	amount, err = ConvertAmount(3, USD, EUR, "0.3")
+	require.NoError(t, err)
+	require.Equal(t, int64(0), amount)
+
+	// 100 JPY = 0.67 USD = 67 美分
+	amount, err = ConvertAmount(100, JPY, USD, "0.0067")
+	require.NoError(t, err)
+	require.Equal(t, int64(67), amount)
+
+	// 1.00 USD = 149 JPY
+	amount, err = ConvertAmount(100, USD, JPY, "149.5")
+	require.NoError(t, err)
+	require.Equal(t, int64(149), amount)
+
+	_, err = ConvertAmount(100, USD, EUR, "abc")
+	require.Error(t, err)
+
+	_, err = ConvertAmount(100, USD, EUR, "-1")
+	require.Error(t, err)
+
+	_, err = ConvertAmount(100, USD, "XXX", "1")
+	require.Error(t, err)
+
+	_, err = ConvertAmount(1<<62, USD, EUR, "4")
+	require.Error(t, err)
+}
+
+func TestIsValidExchangeRate(t *testing.T) {
+	require.True(t, IsValidExchangeRate("0.92"))
+	require.True(t, IsValidExchangeRate("7"))
+	require.False(t, IsValidExchangeRate("0"))
+	require.False(t, IsValidExchangeRate("-1.5"))
+	require.False(t, IsValidExchangeRate("1/3"))
+	require.False(t, IsValidExchangeRate("1e-3"))
+	require.False(t, IsValidExchangeRate("0.00000000001"))
+	require.False(t, IsValidExchangeRate("10000000000"))
+}
+
+func TestInverseExchangeRate(t *testing.T) {
+	rate, err := InverseExchangeRate("0.8000000000")
+	require.NoError(t, err)
+	require.Equal(t, "1.2500000000", rate)
+
+	rate, err = InverseExchangeRate("3")
+	require.NoError(t, err)
+	require.Equal(t, "0.3333333333", rate)
+
+	_, err = InverseExchangeRate("0")
+	require.Error(t, err)
+}
@@ -0,0 +1,17 @@
+package util
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"time"
+)
+
+// EntryHash 计算流水的哈希，内容包括账户、金额、所属的转账和分录、创建时间以及同一个账户上一条流水的哈希，
+// 没有的 id 记为 0，创建时间精确到微秒，与数据库保存的精度一致
+func EntryHash(accountID, amount, transferID, journalID int64, createdAt time.Time, prevHash string) string {
+	content := fmt.Sprintf("%d|%d|%d|%d|%d|%s", accountID, amount, transferID, journalID,
+		createdAt.UnixNano()/int64(time.Microsecond), prevHash)
+	sum := sha256.Sum256([]byte(content))
+	return hex.EncodeToString(sum[:])
+}
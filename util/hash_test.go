@@ -0,0 +1,21 @@
+package util
+
+import (
+	"github.com/stretchr/testify/require"
+	"testing"
+	"time"
+)
+
+func TestEntryHash(t *testing.T) {
+	createdAt := time.Date(2021, time.March, 1, 9, 0, 0, 123456000, time.UTC)
+
+	hash := EntryHash(1, -100, 2, 3, createdAt, "")
+	require.Len(t, hash, 64)
+	require.Equal(t, hash, EntryHash(1, -100, 2, 3, createdAt.In(time.FixedZone("CST", 8*3600)), ""))
+
+	// 任何一项内容变化都会得到不同的哈希
+	require.NotEqual(t, hash, EntryHash(1, 100, 2, 3, createdAt, ""))
+	require.NotEqual(t, hash, EntryHash(1, -100, 0, 3, createdAt, ""))
+	require.NotEqual(t, hash, EntryHash(1, -100, 2, 3, createdAt.Add(time.Microsecond), ""))
+	require.NotEqual(t, hash, EntryHash(1, -100, 2, 3, createdAt, hash))
+}
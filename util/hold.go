@@ -0,0 +1,9 @@
+package util
+
+// 预授权的状态
+const (
+	HoldActive		= "active"
+	HoldCaptured	= "captured"
+	HoldVoided		= "voided"
+	HoldExpired		= "expired"
+)
@@ -0,0 +1,26 @@
+package util
+
+// SystemUsername 系统内部账户的所有者，不能登录
+const SystemUsername = "system"
+
+// 系统内部账户的类型，每个币种各有一个
+const (
+	SystemCash				= "cash"
+	SystemFees				= "fees"
+	SystemInterestExpense	= "interest_expense"
+	SystemSuspense			= "suspense"
+	// SystemExchange 跨币种转账时在两个币种内分别平衡分录
+	SystemExchange			= "exchange"
+)
+
+// SystemCodes 所有系统内部账户的类型，启用的每个币种都需要这些账户
+var SystemCodes = []string{SystemCash, SystemFees, SystemInterestExpense, SystemSuspense, SystemExchange}
+
+// 会计分录的类型
+const (
+	JournalTransfer			= "transfer"
+	JournalReversal			= "reversal"
+	JournalDeposit			= "deposit"
+	JournalWithdrawal		= "withdrawal"
+	JournalOpeningBalance	= "opening_balance"
+)
@@ -0,0 +1,26 @@
+package util
+
+import "time"
+
+// 转账限额的统计周期，都是滚动的时间窗口
+const (
+	LimitDaily		= "daily"
+	LimitMonthly	= "monthly"
+)
+
+// LimitWindow returns the rolling window of the limit period
+func LimitWindow(period string) (time.Duration, bool) {
+	switch period {
+	case LimitDaily:
+		return 24 * time.Hour, true
+	case LimitMonthly:
+		return 30 * 24 * time.Hour, true
+	}
+	return 0, false
+}
+
+// IsSupportLimitPeriod returns true if the limit period is supported
+func IsSupportLimitPeriod(period string) bool {
+	_, ok := LimitWindow(period)
+	return ok
+}
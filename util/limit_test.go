@@ -0,0 +1,19 @@
+package util
+
+import (
+	"github.com/stretchr/testify/require"
+	"testing"
+	"time"
+)
+
+func TestLimitWindow(t *testing.T) {
+	window, ok := LimitWindow(LimitDaily)
+	require.True(t, ok)
+	require.Equal(t, 24*time.Hour, window)
+
+	window, ok = LimitWindow(LimitMonthly)
+	require.True(t, ok)
+	require.Equal(t, 30*24*time.Hour, window)
+
+	require.False(t, IsSupportLimitPeriod("weekly"))
+}
@@ -0,0 +1,17 @@
+package util
+
+import "strings"
+
+// MaskName masks a full name to be shown to other users, only the first letter of each word is kept.
+// For example "Ricardo Zhang" is masked as "R****** Z****" and "张三丰" as "张**"
+func MaskName(name string) string {
+	words := strings.Fields(name)
+	for i, word := range words {
+		runes := []rune(word)
+		for j := 1; j < len(runes); j++ {
+			runes[j] = '*'
+		}
+		words[i] = string(runes)
+	}
+	return strings.Join(words, " ")
+}
@@ -0,0 +1,14 @@
+package util
+
+import (
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestMaskName(t *testing.T) {
+	require.Equal(t, "R****** Z****", MaskName("Ricardo Zhang"))
+	require.Equal(t, "R****** Z****", MaskName("  Ricardo   Zhang "))
+	require.Equal(t, "张**", MaskName("张三丰"))
+	require.Equal(t, "A", MaskName("A"))
+	require.Equal(t, "", MaskName(""))
+}
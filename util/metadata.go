@@ -0,0 +1,15 @@
+package util
+
+import "encoding/json"
+
+// MaxMetadataSize 转账 metadata 的最大字节数
+const MaxMetadataSize = 4096
+
+// IsValidMetadata returns true if the metadata is a JSON object no larger than MaxMetadataSize
+func IsValidMetadata(metadata []byte) bool {
+	if len(metadata) > MaxMetadataSize {
+		return false
+	}
+	var object map[string]interface{}
+	return json.Unmarshal(metadata, &object) == nil && object != nil
+}
@@ -0,0 +1,20 @@
+package util
+
+import (
+	"github.com/stretchr/testify/require"
+	"strings"
+	"testing"
+)
+
+func TestIsValidMetadata(t *testing.T) {
+	require.True(t, IsValidMetadata([]byte(`{}`)))
+	require.True(t, IsValidMetadata([]byte(`{"invoice": "INV-1", "lines": [1, 2]}`)))
+
+	require.False(t, IsValidMetadata([]byte(`null`)))
+	require.False(t, IsValidMetadata([]byte(`[1, 2]`)))
+	require.False(t, IsValidMetadata([]byte(`"text"`)))
+	require.False(t, IsValidMetadata([]byte(`{"invoice":`)))
+
+	large := `{"data": "` + strings.Repeat("a", MaxMetadataSize) + `"}`
+	require.False(t, IsValidMetadata([]byte(large)))
+}
@@ -40,13 +40,11 @@ func RandomMoney() int64 {
 	return RandomInt(100, 800)
 }
 
-// RandomCurrency generate a random currency code
+// RandomCurrency generate a random enabled currency code
 func RandomCurrency() string {
-	currencies := []string{
-		"USD", "RMB", "EUR", "CAD",
-	}
-	n := len(currencies)
-	return currencies[rand.Intn(n)]
+	codes := currencyCodes()
+	n := len(codes)
+	return codes[rand.Intn(n)]
 }
 
 func RandomUserName() string {
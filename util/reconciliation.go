@@ -0,0 +1,13 @@
+package util
+
+// 对账发现的差异类型
+const (
+	// DiscrepancyAccountBalance 账户余额与流水金额之和不一致
+	DiscrepancyAccountBalance	= "account_balance"
+	// DiscrepancyTransferEntries 转账没有恰好两条对应的流水
+	DiscrepancyTransferEntries	= "transfer_entries"
+	// DiscrepancyJournalBalance 分录在某个币种内的流水之和不为 0
+	DiscrepancyJournalBalance	= "journal_balance"
+	// DiscrepancyCurrencyTotal 某个币种所有账户的余额之和不为 0
+	DiscrepancyCurrencyTotal	= "currency_total"
+)
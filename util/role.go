@@ -0,0 +1,17 @@
+package util
+
+// Roles of users
+const (
+	DepositorRole = "depositor"
+	BankerRole    = "banker"
+	AdminRole     = "admin"
+)
+
+// IsSupportRole checks if the role is one of the known roles
+func IsSupportRole(role string) bool {
+	switch role {
+	case DepositorRole, BankerRole, AdminRole:
+		return true
+	}
+	return false
+}
@@ -0,0 +1,133 @@
+package util
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// MinScheduleInterval 定时转账的最小间隔
+const MinScheduleInterval = time.Minute
+
+// 定时转账的状态
+const (
+	ScheduleActive		= "active"
+	SchedulePaused		= "paused"
+	ScheduleCompleted	= "completed"
+	ScheduleCancelled	= "cancelled"
+)
+
+// Schedule is the recurrence rule of a scheduled transfer.
+type Schedule interface {
+	// First returns the first run time at or after start
+	First(start time.Time) time.Time
+	// Next returns the run time after last, zero time means there are no more runs
+	Next(last time.Time) time.Time
+}
+
+// ParseSchedule parses a cron-like recurrence rule, supported rules are:
+//	@once			run only once
+//	@every 72h		run at a fixed interval, the interval is at least 1 minute
+//	@daily			same as @every 24h
+//	@weekly			same as @every 168h
+//	@monthly 1		run on the day of each month, clamped to the last day of short months
+func ParseSchedule(rule string) (Schedule, error) {
+	fields := strings.Fields(rule)
+	if len(fields) == 0 {
+		return nil, fmt.Errorf("empty schedule")
+	}
+
+	switch {
+	case fields[0] == "@once" && len(fields) == 1:
+		return onceSchedule{}, nil
+	case fields[0] == "@daily" && len(fields) == 1:
+		return intervalSchedule{interval: 24 * time.Hour}, nil
+	case fields[0] == "@weekly" && len(fields) == 1:
+		return intervalSchedule{interval: 7 * 24 * time.Hour}, nil
+	case fields[0] == "@every" && len(fields) == 2:
+		interval, err := time.ParseDuration(fields[1])
+		if err != nil {
+			return nil, fmt.Errorf("invalid schedule interval: %v", err)
+		}
+		if interval < MinScheduleInterval {
+			return nil, fmt.Errorf("schedule interval must be at least %v", MinScheduleInterval)
+		}
+		return intervalSchedule{interval: interval}, nil
+	case fields[0] == "@monthly" && len(fields) == 2:
+		day, err := strconv.Atoi(fields[1])
+		if err != nil || day < 1 || day > 31 {
+			return nil, fmt.Errorf("invalid day of month: %s", fields[1])
+		}
+		return monthlySchedule{day: day}, nil
+	}
+	return nil, fmt.Errorf("unsupported schedule: %s", rule)
+}
+
+// IsValidSchedule returns true if the rule can be parsed by ParseSchedule
+func IsValidSchedule(rule string) bool {
+	_, err := ParseSchedule(rule)
+	return err == nil
+}
+
+// NextRunAfter returns the next run after last that is not before now, missed runs are skipped.
+// ok is false if there are no more runs, or the next run is after end. A zero end means no end date.
+func NextRunAfter(schedule Schedule, last, now, end time.Time) (next time.Time, ok bool) {
+	next = schedule.Next(last)
+	for !next.IsZero() && next.Before(now) {
+		next = schedule.Next(next)
+	}
+	if next.IsZero() || (!end.IsZero() && next.After(end)) {
+		return time.Time{}, false
+	}
+	return next, true
+}
+
+type onceSchedule struct{}
+
+func (onceSchedule) First(start time.Time) time.Time {
+	return start
+}
+
+func (onceSchedule) Next(last time.Time) time.Time {
+	return time.Time{}
+}
+
+type intervalSchedule struct {
+	interval time.Duration
+}
+
+func (schedule intervalSchedule) First(start time.Time) time.Time {
+	return start
+}
+
+func (schedule intervalSchedule) Next(last time.Time) time.Time {
+	return last.Add(schedule.interval)
+}
+
+type monthlySchedule struct {
+	day int
+}
+
+func (schedule monthlySchedule) First(start time.Time) time.Time {
+	runAt := schedule.inMonth(start.Year(), start.Month(), start)
+	if runAt.Before(start) {
+		return schedule.inMonth(start.Year(), start.Month()+1, start)
+	}
+	return runAt
+}
+
+func (schedule monthlySchedule) Next(last time.Time) time.Time {
+	return schedule.inMonth(last.Year(), last.Month()+1, last)
+}
+
+// inMonth 返回指定月份的执行时间，时分秒与 clock 相同
+func (schedule monthlySchedule) inMonth(year int, month time.Month, clock time.Time) time.Time {
+	// time.Date 会把 13 月规范化为下一年的 1 月，day 为 0 时是上个月的最后一天
+	lastDay := time.Date(year, month+1, 0, 0, 0, 0, 0, clock.Location()).Day()
+	day := schedule.day
+	if day > lastDay {
+		day = lastDay
+	}
+	return time.Date(year, month, day, clock.Hour(), clock.Minute(), clock.Second(), clock.Nanosecond(), clock.Location())
+}
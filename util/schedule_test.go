@@ -0,0 +1,81 @@
+package util
+
+import (
+	"github.com/stretchr/testify/require"
+	"testing"
+	"time"
+)
+
+func TestParseSchedule(t *testing.T) {
+	validRules := []string{"@once", "@daily", "@weekly", "@every 72h", "@every 1m", "@monthly 1", "@monthly 31"}
+	for _, rule := range validRules {
+		require.True(t, IsValidSchedule(rule), rule)
+	}
+
+	invalidRules := []string{"", "@hourly", "@every", "@every 30s", "@every abc", "@monthly", "@monthly 0", "@monthly 32", "@daily 1", "0 0 1 * *"}
+	for _, rule := range invalidRules {
+		require.False(t, IsValidSchedule(rule), rule)
+	}
+}
+
+func TestIntervalSchedule(t *testing.T) {
+	start := time.Date(2021, time.March, 10, 8, 30, 0, 0, time.UTC)
+
+	schedule, err := ParseSchedule("@every 36h")
+	require.NoError(t, err)
+	require.Equal(t, start, schedule.First(start))
+	require.Equal(t, start.Add(36*time.Hour), schedule.Next(start))
+
+	schedule, err = ParseSchedule("@once")
+	require.NoError(t, err)
+	require.Equal(t, start, schedule.First(start))
+	require.True(t, schedule.Next(start).IsZero())
+}
+
+func TestMonthlySchedule(t *testing.T) {
+	schedule, err := ParseSchedule("@monthly 31")
+	require.NoError(t, err)
+
+	start := time.Date(2021, time.January, 15, 9, 0, 0, 0, time.UTC)
+	first := schedule.First(start)
+	require.Equal(t, time.Date(2021, time.January, 31, 9, 0, 0, 0, time.UTC), first)
+
+	// 2 月没有 31 号，在最后一天执行
+	second := schedule.Next(first)
+	require.Equal(t, time.Date(2021, time.February, 28, 9, 0, 0, 0, time.UTC), second)
+	require.Equal(t, time.Date(2021, time.March, 31, 9, 0, 0, 0, time.UTC), schedule.Next(second))
+
+	schedule, err = ParseSchedule("@monthly 1")
+	require.NoError(t, err)
+
+	// 本月的 1 号已经过了，从下个月开始
+	require.Equal(t, time.Date(2021, time.February, 1, 9, 0, 0, 0, time.UTC), schedule.First(start))
+	december := time.Date(2021, time.December, 1, 9, 0, 0, 0, time.UTC)
+	require.Equal(t, time.Date(2022, time.January, 1, 9, 0, 0, 0, time.UTC), schedule.Next(december))
+	require.Equal(t, december, schedule.First(december))
+}
+
+func TestNextRunAfter(t *testing.T) {
+	schedule, err := ParseSchedule("@daily")
+	require.NoError(t, err)
+
+	last := time.Date(2021, time.March, 1, 8, 0, 0, 0, time.UTC)
+	now := last.Add(time.Hour)
+	next, ok := NextRunAfter(schedule, last, now, time.Time{})
+	require.True(t, ok)
+	require.Equal(t, last.AddDate(0, 0, 1), next)
+
+	// 错过的执行直接跳过
+	now = last.Add(50 * time.Hour)
+	next, ok = NextRunAfter(schedule, last, now, time.Time{})
+	require.True(t, ok)
+	require.Equal(t, last.AddDate(0, 0, 3), next)
+
+	_, ok = NextRunAfter(schedule, last, now, last.AddDate(0, 0, 2))
+	require.False(t, ok)
+
+	schedule, err = ParseSchedule("@once")
+	require.NoError(t, err)
+	_, ok = NextRunAfter(schedule, last, now, time.Time{})
+	require.False(t, ok)
+}
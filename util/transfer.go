@@ -0,0 +1,16 @@
+package util
+
+// 转账的状态
+const (
+	TransferPending				= "pending"
+	TransferRejected			= "rejected"
+	TransferCompleted			= "completed"
+	TransferPartiallyReversed	= "partially_reversed"
+	TransferReversed			= "reversed"
+)
+
+// 账户流水和转账的方向，in 为转入，out 为转出
+const (
+	TransferDirectionIn		= "in"
+	TransferDirectionOut	= "out"
+)
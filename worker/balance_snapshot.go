@@ -0,0 +1,41 @@
+package worker
+
+import (
+	"context"
+	db "github.com/techschool/simplebank/db/sqlc"
+	"log"
+	"time"
+)
+
+// snapshotDelay 快照在 UTC 零点之后延迟一段时间再生成，等待零点前开始的事务提交
+const snapshotDelay = time.Hour
+
+// BalanceSnapshotter saves the daily balance snapshots used by the point-in-time balance queries.
+type BalanceSnapshotter struct {
+	store	db.Store
+}
+
+// NewBalanceSnapshotter creates a new BalanceSnapshotter.
+func NewBalanceSnapshotter(store db.Store) *BalanceSnapshotter {
+	return &BalanceSnapshotter{store: store}
+}
+
+// SnapshotDue saves the balance at the start of the current UTC day for accounts that have new entries.
+func (snapshotter *BalanceSnapshotter) SnapshotDue(ctx context.Context) error {
+	return snapshotter.snapshotDue(ctx, time.Now())
+}
+
+func (snapshotter *BalanceSnapshotter) snapshotDue(ctx context.Context, now time.Time) error {
+	year, month, day := now.Add(-snapshotDelay).UTC().Date()
+	snapshotAt := time.Date(year, month, day, 0, 0, 0, 0, time.UTC)
+
+	// 同一时刻的快照已经存在时不会重复生成，重复执行是安全的
+	count, err := snapshotter.store.CreateBalanceSnapshots(ctx, snapshotAt)
+	if err != nil {
+		return err
+	}
+	if count > 0 {
+		log.Printf("saved %d balance snapshots at %s", count, snapshotAt.Format(time.RFC3339))
+	}
+	return nil
+}
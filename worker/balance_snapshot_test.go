@@ -0,0 +1,30 @@
+package worker
+
+import (
+	"context"
+	"database/sql"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/require"
+	mockdb "github.com/techschool/simplebank/db/mock"
+	"testing"
+	"time"
+)
+
+func TestSnapshotDueBalances(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	store := mockdb.NewMockStore(ctrl)
+	snapshotter := NewBalanceSnapshotter(store)
+
+	// 零点之后的一小时内仍然生成前一天零点的快照
+	now := time.Date(2021, time.March, 2, 0, 30, 0, 0, time.UTC)
+	store.EXPECT().CreateBalanceSnapshots(gomock.Any(), gomock.Eq(time.Date(2021, time.March, 1, 0, 0, 0, 0, time.UTC))).
+		Times(1).Return(int64(3), nil)
+	require.NoError(t, snapshotter.snapshotDue(context.Background(), now))
+
+	now = time.Date(2021, time.March, 2, 9, 0, 0, 0, time.UTC)
+	store.EXPECT().CreateBalanceSnapshots(gomock.Any(), gomock.Eq(time.Date(2021, time.March, 2, 0, 0, 0, 0, time.UTC))).
+		Times(1).Return(int64(0), sql.ErrConnDone)
+	require.ErrorIs(t, snapshotter.snapshotDue(context.Background(), now), sql.ErrConnDone)
+}
@@ -0,0 +1,35 @@
+package worker
+
+import (
+	"context"
+	db "github.com/techschool/simplebank/db/sqlc"
+	"log"
+	"time"
+)
+
+// HoldExpirer releases the holds that are not captured or voided before they expire.
+type HoldExpirer struct {
+	store	db.Store
+}
+
+// NewHoldExpirer creates a new HoldExpirer.
+func NewHoldExpirer(store db.Store) *HoldExpirer {
+	return &HoldExpirer{store: store}
+}
+
+// ExpireDue marks all active holds that have expired by now as expired.
+func (expirer *HoldExpirer) ExpireDue(ctx context.Context) error {
+	return expirer.expireDue(ctx, time.Now())
+}
+
+func (expirer *HoldExpirer) expireDue(ctx context.Context, now time.Time) error {
+	// 过期的预授权在计算可用余额时已经不再计入，这里只更新状态
+	count, err := expirer.store.ExpireHolds(ctx, now)
+	if err != nil {
+		return err
+	}
+	if count > 0 {
+		log.Printf("expired %d holds", count)
+	}
+	return nil
+}
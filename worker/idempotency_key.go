@@ -0,0 +1,34 @@
+package worker
+
+import (
+	"context"
+	db "github.com/techschool/simplebank/db/sqlc"
+	"log"
+	"time"
+)
+
+// IdempotencyKeyCleaner deletes the idempotency keys that have expired.
+type IdempotencyKeyCleaner struct {
+	store	db.Store
+}
+
+// NewIdempotencyKeyCleaner creates a new IdempotencyKeyCleaner.
+func NewIdempotencyKeyCleaner(store db.Store) *IdempotencyKeyCleaner {
+	return &IdempotencyKeyCleaner{store: store}
+}
+
+// DeleteExpired deletes all idempotency keys that have expired by now.
+func (cleaner *IdempotencyKeyCleaner) DeleteExpired(ctx context.Context) error {
+	return cleaner.deleteExpired(ctx, time.Now())
+}
+
+func (cleaner *IdempotencyKeyCleaner) deleteExpired(ctx context.Context, now time.Time) error {
+	count, err := cleaner.store.DeleteExpiredIdempotencyKeys(ctx, now)
+	if err != nil {
+		return err
+	}
+	if count > 0 {
+		log.Printf("deleted %d expired idempotency keys", count)
+	}
+	return nil
+}
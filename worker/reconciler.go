@@ -0,0 +1,40 @@
+package worker
+
+import (
+	"context"
+	db "github.com/techschool/simplebank/db/sqlc"
+	"log"
+)
+
+// Reconciler verifies that account balances agree with the entries and saves the result as a report.
+type Reconciler struct {
+	store	db.Store
+}
+
+// NewReconciler creates a new Reconciler.
+func NewReconciler(store db.Store) *Reconciler {
+	return &Reconciler{store: store}
+}
+
+// Reconcile runs one reconciliation, every discrepancy found is logged.
+func (reconciler *Reconciler) Reconcile(ctx context.Context) error {
+	_, err := reconciler.Run(ctx)
+	return err
+}
+
+// Run runs one reconciliation and returns the saved report with its discrepancies.
+func (reconciler *Reconciler) Run(ctx context.Context) (db.ReconcileTxResult, error) {
+	result, err := reconciler.store.ReconcileTx(ctx)
+	if err != nil {
+		return result, err
+	}
+
+	// 差异只记录下来，不会自动修复，需要人工核查
+	for _, discrepancy := range result.Discrepancies {
+		log.Printf("reconciliation report [%d] found %s discrepancy: %s",
+			result.Report.ID, discrepancy.Kind, discrepancy.Detail)
+	}
+	log.Printf("reconciliation report [%d] checked %d accounts and %d transfers, found %d discrepancies",
+		result.Report.ID, result.Report.AccountsChecked, result.Report.TransfersChecked, result.Report.DiscrepancyCount)
+	return result, nil
+}
@@ -0,0 +1,33 @@
+package worker
+
+import (
+	"context"
+	"database/sql"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/require"
+	db "github.com/techschool/simplebank/db/sqlc"
+	mockdb "github.com/techschool/simplebank/db/mock"
+	"github.com/techschool/simplebank/util"
+	"testing"
+)
+
+func TestReconcile(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	result := db.ReconcileTxResult{
+		Report: db.ReconciliationReport{ID: 1, AccountsChecked: 10, TransfersChecked: 20, DiscrepancyCount: 1},
+		Discrepancies: []db.ReconciliationDiscrepancy{
+			{ID: 1, ReportID: 1, Kind: util.DiscrepancyAccountBalance, Expected: 100, Actual: 90},
+		},
+	}
+
+	store := mockdb.NewMockStore(ctrl)
+	store.EXPECT().ReconcileTx(gomock.Any()).Times(1).Return(result, nil)
+	got, err := NewReconciler(store).Run(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, result, got)
+
+	store.EXPECT().ReconcileTx(gomock.Any()).Times(1).Return(db.ReconcileTxResult{}, sql.ErrConnDone)
+	require.ErrorIs(t, NewReconciler(store).Reconcile(context.Background()), sql.ErrConnDone)
+}
@@ -0,0 +1,34 @@
+package worker
+
+import (
+	"context"
+	db "github.com/techschool/simplebank/db/sqlc"
+	"log"
+	"time"
+)
+
+// RevokedTokenCleaner deletes the revoked tokens that have expired, an expired token cannot be verified anyway.
+type RevokedTokenCleaner struct {
+	store	db.Store
+}
+
+// NewRevokedTokenCleaner creates a new RevokedTokenCleaner.
+func NewRevokedTokenCleaner(store db.Store) *RevokedTokenCleaner {
+	return &RevokedTokenCleaner{store: store}
+}
+
+// DeleteExpired deletes all revoked tokens that have expired by now.
+func (cleaner *RevokedTokenCleaner) DeleteExpired(ctx context.Context) error {
+	return cleaner.deleteExpired(ctx, time.Now())
+}
+
+func (cleaner *RevokedTokenCleaner) deleteExpired(ctx context.Context, now time.Time) error {
+	count, err := cleaner.store.DeleteExpiredRevokedTokens(ctx, now)
+	if err != nil {
+		return err
+	}
+	if count > 0 {
+		log.Printf("deleted %d expired revoked tokens", count)
+	}
+	return nil
+}
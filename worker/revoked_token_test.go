@@ -0,0 +1,25 @@
+package worker
+
+import (
+	"context"
+	"database/sql"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/require"
+	mockdb "github.com/techschool/simplebank/db/mock"
+	"testing"
+	"time"
+)
+
+func TestDeleteExpiredRevokedTokens(t *testing.T) {
+	now := time.Date(2021, time.March, 1, 9, 0, 0, 0, time.UTC)
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	store := mockdb.NewMockStore(ctrl)
+	store.EXPECT().DeleteExpiredRevokedTokens(gomock.Any(), gomock.Eq(now)).Times(1).Return(int64(3), nil)
+	require.NoError(t, NewRevokedTokenCleaner(store).deleteExpired(context.Background(), now))
+
+	store.EXPECT().DeleteExpiredRevokedTokens(gomock.Any(), gomock.Eq(now)).Times(1).Return(int64(0), sql.ErrConnDone)
+	require.ErrorIs(t, NewRevokedTokenCleaner(store).deleteExpired(context.Background(), now), sql.ErrConnDone)
+}
@@ -0,0 +1,211 @@
+package worker
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	db "github.com/techschool/simplebank/db/sqlc"
+	"github.com/techschool/simplebank/fraud"
+	"github.com/techschool/simplebank/util"
+	"log"
+	"time"
+)
+
+// 每次执行定时转账的结果
+const (
+	RunSucceeded	= "succeeded"
+	RunFailed		= "failed"
+)
+
+// ScheduledTransferOptions contains the settings of ScheduledTransferProcessor.
+type ScheduledTransferOptions struct {
+	// BatchSize 每次最多处理的定时转账数量
+	BatchSize		int32
+	// MaxRetries 失败后最多重试的次数，用完后跳过本次执行
+	MaxRetries		int32
+	RetryDelay		time.Duration
+	// IdempotencyKeyRetention 保证同一次执行最多转账一次
+	IdempotencyKeyRetention	time.Duration
+	// ApprovalThresholds 与接口创建的转账相同，超过该币种金额的转账先创建为待审批
+	ApprovalThresholds	map[string]int64
+	// FraudChecker 与接口创建的转账相同，每次执行前进行风控检查
+	FraudChecker	fraud.Checker
+}
+
+var errTransferBlocked = errors.New("transfer is blocked by fraud screening")
+
+// ScheduledTransferProcessor runs the due scheduled transfers.
+type ScheduledTransferProcessor struct {
+	store	db.Store
+	options	ScheduledTransferOptions
+}
+
+// NewScheduledTransferProcessor creates a new ScheduledTransferProcessor.
+func NewScheduledTransferProcessor(store db.Store, options ScheduledTransferOptions) *ScheduledTransferProcessor {
+	if options.BatchSize <= 0 {
+		options.BatchSize = 100
+	}
+	if options.FraudChecker == nil {
+		// 没有规则时所有转账都被允许，但仍然记录风控结果
+		options.FraudChecker = &fraud.RuleEngine{}
+	}
+	return &ScheduledTransferProcessor{
+		store:		store,
+		options:	options,
+	}
+}
+
+// ProcessDue runs all scheduled transfers that are due now.
+func (processor *ScheduledTransferProcessor) ProcessDue(ctx context.Context) error {
+	return processor.processDue(ctx, time.Now())
+}
+
+func (processor *ScheduledTransferProcessor) processDue(ctx context.Context, now time.Time) error {
+	scheduledTransfers, err := processor.store.ListDueScheduledTransfers(ctx, db.ListDueScheduledTransfersParams{
+		NextRunAt:	now,
+		Limit:		processor.options.BatchSize,
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, scheduledTransfer := range scheduledTransfers {
+		// 单个定时转账出错不影响其他的定时转账
+		err = processor.run(ctx, scheduledTransfer, now)
+		if err != nil {
+			log.Printf("cannot run scheduled transfer [%d], err: %v", scheduledTransfer.ID, err)
+		}
+	}
+	return nil
+}
+
+// run executes one occurrence of the scheduled transfer, records the result and moves it to the next run
+func (processor *ScheduledTransferProcessor) run(ctx context.Context, scheduledTransfer db.ScheduledTransfer, now time.Time) error {
+	schedule, err := util.ParseSchedule(scheduledTransfer.Schedule)
+	if err != nil {
+		return err
+	}
+
+	fromAccount, err := processor.store.GetAccount(ctx, scheduledTransfer.FromAccountID)
+	if err != nil {
+		return err
+	}
+
+	// 同一次执行使用相同的 key，进程在记录结果前退出时，重试不会重复转账
+	idempotencyKey := fmt.Sprintf("scheduled_transfer:%d:%d", scheduledTransfer.ID, scheduledTransfer.ScheduledFor.Unix())
+	transferArg := db.TransferTxParams{
+		FromAccountID:		scheduledTransfer.FromAccountID,
+		ToAccountID:		scheduledTransfer.ToAccountID,
+		Amount:				scheduledTransfer.Amount,
+		RequestedBy:		scheduledTransfer.Owner,
+		RequireApproval:	processor.requireApproval(scheduledTransfer.Amount, fromAccount.Currency),
+	}
+
+	// 已经转账的执行只需要重放结果，不再重复风控检查
+	replay, err := processor.transferred(ctx, scheduledTransfer.Owner, idempotencyKey, now)
+	if err != nil {
+		return err
+	}
+	var decision db.FraudDecision
+	if !replay {
+		decision, err = fraud.Screen(ctx, processor.options.FraudChecker, processor.store, transferArg, fromAccount.Currency, fraud.Metadata{
+			Username:	scheduledTransfer.Owner,
+		})
+		if err != nil {
+			return err
+		}
+		if decision.Decision == string(fraud.Review) {
+			transferArg.RequireApproval = true
+		}
+	}
+
+	var result db.TransferTxResult
+	var transferErr error
+	if decision.Decision == string(fraud.Block) {
+		transferErr = errTransferBlocked
+	} else {
+		result, transferErr = processor.store.IdempotentTransferTx(ctx, db.IdempotentTransferTxParams{
+			TransferTxParams:	transferArg,
+			Username:			scheduledTransfer.Owner,
+			IdempotencyKey:		idempotencyKey,
+			RequestHash:		idempotencyKey,
+			ExpiresAt:			now.Add(processor.options.IdempotencyKeyRetention),
+		})
+	}
+	if transferErr == nil && !result.Replayed && decision.ID != 0 {
+		// 转账已经完成，关联失败只记录日志
+		err = fraud.LinkTransfer(ctx, processor.store, decision, result.Transfer)
+		if err != nil {
+			log.Printf("cannot link fraud decision [%d] to transfer [%d], err: %v", decision.ID, result.Transfer.ID, err)
+		}
+	}
+
+	attempt := scheduledTransfer.RetryCount + 1
+	runArg := db.CreateScheduledTransferRunParams{
+		ScheduledTransferID:	scheduledTransfer.ID,
+		ScheduledFor:			scheduledTransfer.ScheduledFor,
+		Attempt:				attempt,
+		Status:					RunSucceeded,
+	}
+	if transferErr != nil {
+		runArg.Status = RunFailed
+		runArg.Error = transferErr.Error()
+	} else {
+		runArg.TransferID = sql.NullInt64{Int64: result.Transfer.ID, Valid: true}
+	}
+	_, err = processor.store.CreateScheduledTransferRun(ctx, runArg)
+	if err != nil {
+		return err
+	}
+
+	// 只推进执行进度，执行期间通过接口做的修改不会被覆盖
+	arg := db.AdvanceScheduledTransferParams{
+		ID:				scheduledTransfer.ID,
+		UpdatedAt:		scheduledTransfer.UpdatedAt,
+		ScheduledFor:	scheduledTransfer.ScheduledFor,
+	}
+	if transferErr != nil && transferErr != errTransferBlocked && attempt <= processor.options.MaxRetries {
+		// 失败后稍后重试同一次执行，被风控拒绝的执行不再重试
+		arg.NextRunAt = now.Add(processor.options.RetryDelay)
+		arg.RetryCount = attempt
+	} else {
+		next, ok := util.NextRunAfter(schedule, scheduledTransfer.ScheduledFor, now, scheduledTransfer.EndAt.Time)
+		if !ok {
+			arg.Completed = true
+			next = scheduledTransfer.ScheduledFor
+		}
+		arg.ScheduledFor = next
+		arg.NextRunAt = next
+		arg.RetryCount = 0
+	}
+
+	_, err = processor.store.AdvanceScheduledTransfer(ctx, arg)
+	if errors.Is(err, sql.ErrNoRows) {
+		// 列出后被修改、取消或者已经被其他进程执行过，以数据库中的状态为准
+		log.Printf("scheduled transfer [%d] changed while running, skip advancing it", scheduledTransfer.ID)
+		return nil
+	}
+	return err
+}
+
+// transferred returns true if the occurrence with the idempotency key has already moved money
+func (processor *ScheduledTransferProcessor) transferred(ctx context.Context, owner, idempotencyKey string, now time.Time) (bool, error) {
+	saved, err := processor.store.GetIdempotencyKey(ctx, db.GetIdempotencyKeyParams{
+		Username:		owner,
+		IdempotencyKey:	idempotencyKey,
+	})
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return false, nil
+		}
+		return false, err
+	}
+	return saved.ExpiresAt.After(now), nil
+}
+
+// requireApproval 转账金额超过该币种的审批金额时需要审批，没有配置的币种不需要审批
+func (processor *ScheduledTransferProcessor) requireApproval(amount int64, currency string) bool {
+	threshold, ok := processor.options.ApprovalThresholds[currency]
+	return ok && amount > threshold
+}
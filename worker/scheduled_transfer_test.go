@@ -0,0 +1,366 @@
+package worker
+
+import (
+	"context"
+	"database/sql"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/require"
+	mockdb "github.com/techschool/simplebank/db/mock"
+	db "github.com/techschool/simplebank/db/sqlc"
+	"github.com/techschool/simplebank/fraud"
+	"github.com/techschool/simplebank/util"
+	"testing"
+	"time"
+)
+
+func randomScheduledTransfer(schedule string, scheduledFor time.Time) db.ScheduledTransfer {
+	return db.ScheduledTransfer{
+		ID:				util.RandomInt(1, 1000),
+		Owner:			util.RandomOwnerName(),
+		FromAccountID:	util.RandomInt(1, 1000),
+		ToAccountID:	util.RandomInt(1, 1000),
+		Amount:			util.RandomMoney(),
+		Schedule:		schedule,
+		Status:			util.ScheduleActive,
+		ScheduledFor:	scheduledFor,
+		NextRunAt:		scheduledFor,
+		UpdatedAt:		scheduledFor.Add(-time.Hour),
+	}
+}
+
+// fraudCheckerFunc 测试中使用的 fraud.Checker
+type fraudCheckerFunc func(ctx context.Context, input fraud.Input) (fraud.Result, error)
+
+func (fn fraudCheckerFunc) Check(ctx context.Context, input fraud.Input) (fraud.Result, error) {
+	return fn(ctx, input)
+}
+
+func TestProcessDueScheduledTransfers(t *testing.T) {
+	now := time.Date(2021, time.March, 1, 9, 0, 0, 0, time.UTC)
+	options := ScheduledTransferOptions{
+		MaxRetries:	2,
+		RetryDelay:	10 * time.Minute,
+		IdempotencyKeyRetention: time.Hour,
+		ApprovalThresholds: map[string]int64{util.USD: 1000},
+	}
+
+	testCases := []struct{
+		name				string
+		scheduledTransfer	db.ScheduledTransfer
+		transferErr			error
+		requireApproval		bool
+		// decision 风控检查的结果，默认允许
+		decision			fraud.Decision
+		// replayed 本次执行已经转账，只重放结果
+		replayed			bool
+		checkRun			func(t *testing.T, run db.CreateScheduledTransferRunParams)
+		checkUpdate			func(t *testing.T, scheduledTransfer db.ScheduledTransfer, arg db.AdvanceScheduledTransferParams)
+	}{
+		{
+			name: "Succeeded",
+			scheduledTransfer: randomScheduledTransfer("@monthly 1", now),
+			checkRun: func(t *testing.T, run db.CreateScheduledTransferRunParams) {
+				require.Equal(t, RunSucceeded, run.Status)
+				require.True(t, run.TransferID.Valid)
+				require.Equal(t, int32(1), run.Attempt)
+			},
+			checkUpdate: func(t *testing.T, scheduledTransfer db.ScheduledTransfer, arg db.AdvanceScheduledTransferParams) {
+				next := time.Date(2021, time.April, 1, 9, 0, 0, 0, time.UTC)
+				require.False(t, arg.Completed)
+				require.Equal(t, next, arg.ScheduledFor)
+				require.Equal(t, next, arg.NextRunAt)
+				require.Zero(t, arg.RetryCount)
+			},
+		},
+		{
+			name: "AboveApprovalThreshold",
+			scheduledTransfer: func() db.ScheduledTransfer {
+				scheduledTransfer := randomScheduledTransfer("@once", now)
+				scheduledTransfer.Amount = 1001
+				return scheduledTransfer
+			}(),
+			requireApproval: true,
+			checkRun: func(t *testing.T, run db.CreateScheduledTransferRunParams) {
+				require.Equal(t, RunSucceeded, run.Status)
+				require.True(t, run.TransferID.Valid)
+			},
+			checkUpdate: func(t *testing.T, scheduledTransfer db.ScheduledTransfer, arg db.AdvanceScheduledTransferParams) {
+				require.True(t, arg.Completed)
+			},
+		},
+		{
+			name: "FraudReview",
+			scheduledTransfer: randomScheduledTransfer("@once", now),
+			decision: fraud.Review,
+			requireApproval: true,
+			checkRun: func(t *testing.T, run db.CreateScheduledTransferRunParams) {
+				require.Equal(t, RunSucceeded, run.Status)
+				require.True(t, run.TransferID.Valid)
+			},
+			checkUpdate: func(t *testing.T, scheduledTransfer db.ScheduledTransfer, arg db.AdvanceScheduledTransferParams) {
+				require.True(t, arg.Completed)
+			},
+		},
+		{
+			name: "FraudBlocked",
+			scheduledTransfer: randomScheduledTransfer("@monthly 1", now),
+			decision: fraud.Block,
+			checkRun: func(t *testing.T, run db.CreateScheduledTransferRunParams) {
+				require.Equal(t, RunFailed, run.Status)
+				require.False(t, run.TransferID.Valid)
+				require.Equal(t, errTransferBlocked.Error(), run.Error)
+			},
+			checkUpdate: func(t *testing.T, scheduledTransfer db.ScheduledTransfer, arg db.AdvanceScheduledTransferParams) {
+				// 被风控拒绝的执行不再重试，等待下一次
+				next := time.Date(2021, time.April, 1, 9, 0, 0, 0, time.UTC)
+				require.False(t, arg.Completed)
+				require.Equal(t, next, arg.ScheduledFor)
+				require.Zero(t, arg.RetryCount)
+			},
+		},
+		{
+			name: "Replayed",  // 上一次执行已经转账但没有记录结果，不再重复风控检查
+			scheduledTransfer: func() db.ScheduledTransfer {
+				scheduledTransfer := randomScheduledTransfer("@once", now.Add(-10*time.Minute))
+				scheduledTransfer.RetryCount = 1
+				return scheduledTransfer
+			}(),
+			replayed: true,
+			checkRun: func(t *testing.T, run db.CreateScheduledTransferRunParams) {
+				require.Equal(t, RunSucceeded, run.Status)
+				require.True(t, run.TransferID.Valid)
+			},
+			checkUpdate: func(t *testing.T, scheduledTransfer db.ScheduledTransfer, arg db.AdvanceScheduledTransferParams) {
+				require.True(t, arg.Completed)
+			},
+		},
+		{
+			name: "FailedWillRetry",
+			scheduledTransfer: randomScheduledTransfer("@monthly 1", now),
+			transferErr: db.ErrInsufficientFunds,
+			checkRun: func(t *testing.T, run db.CreateScheduledTransferRunParams) {
+				require.Equal(t, RunFailed, run.Status)
+				require.False(t, run.TransferID.Valid)
+				require.Equal(t, db.ErrInsufficientFunds.Error(), run.Error)
+			},
+			checkUpdate: func(t *testing.T, scheduledTransfer db.ScheduledTransfer, arg db.AdvanceScheduledTransferParams) {
+				// 同一次执行稍后重试
+				require.False(t, arg.Completed)
+				require.Equal(t, scheduledTransfer.ScheduledFor, arg.ScheduledFor)
+				require.Equal(t, now.Add(options.RetryDelay), arg.NextRunAt)
+				require.Equal(t, int32(1), arg.RetryCount)
+			},
+		},
+		{
+			name: "RetriesExhausted",
+			scheduledTransfer: func() db.ScheduledTransfer {
+				scheduledTransfer := randomScheduledTransfer("@daily", now.Add(-20*time.Minute))
+				scheduledTransfer.RetryCount = 2
+				return scheduledTransfer
+			}(),
+			transferErr: db.ErrInsufficientFunds,
+			checkRun: func(t *testing.T, run db.CreateScheduledTransferRunParams) {
+				require.Equal(t, RunFailed, run.Status)
+				require.Equal(t, int32(3), run.Attempt)
+			},
+			checkUpdate: func(t *testing.T, scheduledTransfer db.ScheduledTransfer, arg db.AdvanceScheduledTransferParams) {
+				// 跳过本次执行，等待下一次
+				next := scheduledTransfer.ScheduledFor.Add(24 * time.Hour)
+				require.False(t, arg.Completed)
+				require.Equal(t, next, arg.ScheduledFor)
+				require.Equal(t, next, arg.NextRunAt)
+				require.Zero(t, arg.RetryCount)
+			},
+		},
+		{
+			name: "Completed",
+			scheduledTransfer: randomScheduledTransfer("@once", now),
+			checkRun: func(t *testing.T, run db.CreateScheduledTransferRunParams) {
+				require.Equal(t, RunSucceeded, run.Status)
+			},
+			checkUpdate: func(t *testing.T, scheduledTransfer db.ScheduledTransfer, arg db.AdvanceScheduledTransferParams) {
+				require.True(t, arg.Completed)
+			},
+		},
+		{
+			name: "EndDateReached",
+			scheduledTransfer: func() db.ScheduledTransfer {
+				scheduledTransfer := randomScheduledTransfer("@weekly", now)
+				scheduledTransfer.EndAt = sql.NullTime{Time: now.AddDate(0, 0, 3), Valid: true}
+				return scheduledTransfer
+			}(),
+			checkRun: func(t *testing.T, run db.CreateScheduledTransferRunParams) {
+				require.Equal(t, RunSucceeded, run.Status)
+			},
+			checkUpdate: func(t *testing.T, scheduledTransfer db.ScheduledTransfer, arg db.AdvanceScheduledTransferParams) {
+				require.True(t, arg.Completed)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			scheduledTransfer := tc.scheduledTransfer
+			store := mockdb.NewMockStore(ctrl)
+			store.EXPECT().
+				ListDueScheduledTransfers(gomock.Any(), gomock.Eq(db.ListDueScheduledTransfersParams{NextRunAt: now, Limit: 100})).
+				Times(1).
+				Return([]db.ScheduledTransfer{scheduledTransfer}, nil)
+			store.EXPECT().
+				GetAccount(gomock.Any(), gomock.Eq(scheduledTransfer.FromAccountID)).
+				Times(1).
+				Return(db.Account{ID: scheduledTransfer.FromAccountID, Owner: scheduledTransfer.Owner, Currency: util.USD}, nil)
+
+			saved, savedErr := db.IdempotencyKey{}, sql.ErrNoRows
+			if tc.replayed {
+				saved, savedErr = db.IdempotencyKey{Username: scheduledTransfer.Owner, ExpiresAt: now.Add(time.Hour)}, nil
+			}
+			store.EXPECT().
+				GetIdempotencyKey(gomock.Any(), gomock.Any()).
+				Times(1).
+				DoAndReturn(func(_ context.Context, arg db.GetIdempotencyKeyParams) (db.IdempotencyKey, error) {
+					require.Equal(t, scheduledTransfer.Owner, arg.Username)
+					require.NotEmpty(t, arg.IdempotencyKey)
+					return saved, savedErr
+				})
+
+			decision := tc.decision
+			if decision == "" {
+				decision = fraud.Allow
+			}
+			screened, linked, transferred := 1, 0, 1
+			if tc.replayed {
+				screened = 0
+			}
+			if decision == fraud.Block {
+				transferred = 0
+			}
+			if screened == 1 && transferred == 1 && tc.transferErr == nil {
+				linked = 1
+			}
+
+			fraudDecision := db.FraudDecision{ID: util.RandomInt(1, 1000), Decision: string(decision)}
+			store.EXPECT().
+				CreateFraudDecision(gomock.Any(), gomock.Any()).
+				Times(screened).
+				DoAndReturn(func(_ context.Context, arg db.CreateFraudDecisionParams) (db.FraudDecision, error) {
+					require.Equal(t, scheduledTransfer.Owner, arg.Username)
+					require.Equal(t, scheduledTransfer.Amount, arg.Amount)
+					require.Equal(t, util.USD, arg.Currency)
+					return fraudDecision, nil
+				})
+
+			transfer := db.Transfer{ID: util.RandomInt(1, 1000)}
+			store.EXPECT().
+				SetFraudDecisionTransfer(gomock.Any(), gomock.Eq(db.SetFraudDecisionTransferParams{
+					ID:			fraudDecision.ID,
+					TransferID:	sql.NullInt64{Int64: transfer.ID, Valid: true},
+				})).
+				Times(linked)
+
+			store.EXPECT().
+				IdempotentTransferTx(gomock.Any(), gomock.Any()).
+				Times(transferred).
+				DoAndReturn(func(_ context.Context, arg db.IdempotentTransferTxParams) (db.TransferTxResult, error) {
+					require.Equal(t, scheduledTransfer.Owner, arg.Username)
+					require.Equal(t, scheduledTransfer.FromAccountID, arg.FromAccountID)
+					require.Equal(t, scheduledTransfer.ToAccountID, arg.ToAccountID)
+					require.Equal(t, scheduledTransfer.Amount, arg.Amount)
+					require.Equal(t, scheduledTransfer.Owner, arg.RequestedBy)
+					require.Equal(t, tc.requireApproval, arg.RequireApproval)
+					require.NotEmpty(t, arg.IdempotencyKey)
+					if tc.transferErr != nil {
+						return db.TransferTxResult{}, tc.transferErr
+					}
+					return db.TransferTxResult{Transfer: transfer, Replayed: tc.replayed}, nil
+				})
+
+			store.EXPECT().
+				CreateScheduledTransferRun(gomock.Any(), gomock.Any()).
+				Times(1).
+				DoAndReturn(func(_ context.Context, arg db.CreateScheduledTransferRunParams) (db.ScheduledTransferRun, error) {
+					require.Equal(t, scheduledTransfer.ID, arg.ScheduledTransferID)
+					require.Equal(t, scheduledTransfer.ScheduledFor, arg.ScheduledFor)
+					tc.checkRun(t, arg)
+					return db.ScheduledTransferRun{}, nil
+				})
+
+			store.EXPECT().
+				AdvanceScheduledTransfer(gomock.Any(), gomock.Any()).
+				Times(1).
+				DoAndReturn(func(_ context.Context, arg db.AdvanceScheduledTransferParams) (db.ScheduledTransfer, error) {
+					require.Equal(t, scheduledTransfer.ID, arg.ID)
+					require.Equal(t, scheduledTransfer.UpdatedAt, arg.UpdatedAt)
+					tc.checkUpdate(t, scheduledTransfer, arg)
+					return db.ScheduledTransfer{}, nil
+				})
+
+			options := options
+			options.FraudChecker = fraudCheckerFunc(func(ctx context.Context, input fraud.Input) (fraud.Result, error) {
+				require.Equal(t, scheduledTransfer.Owner, input.Metadata.Username)
+				return fraud.Result{Decision: decision}, nil
+			})
+			processor := NewScheduledTransferProcessor(store, options)
+			err := processor.processDue(context.Background(), now)
+			require.NoError(t, err)
+		})
+	}
+}
+
+func TestProcessDueCancelledWhileRunning(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	now := time.Date(2021, time.March, 1, 9, 0, 0, 0, time.UTC)
+	scheduledTransfer := randomScheduledTransfer("@daily", now)
+
+	store := mockdb.NewMockStore(ctrl)
+	store.EXPECT().
+		ListDueScheduledTransfers(gomock.Any(), gomock.Any()).
+		Times(1).
+		Return([]db.ScheduledTransfer{scheduledTransfer}, nil)
+	store.EXPECT().
+		GetAccount(gomock.Any(), gomock.Eq(scheduledTransfer.FromAccountID)).
+		Times(1).
+		Return(db.Account{ID: scheduledTransfer.FromAccountID, Currency: util.USD}, nil)
+	store.EXPECT().GetIdempotencyKey(gomock.Any(), gomock.Any()).Times(1).Return(db.IdempotencyKey{}, sql.ErrNoRows)
+	store.EXPECT().CreateFraudDecision(gomock.Any(), gomock.Any()).Times(1).Return(db.FraudDecision{ID: 1, Decision: string(fraud.Allow)}, nil)
+	store.EXPECT().SetFraudDecisionTransfer(gomock.Any(), gomock.Any()).Times(1)
+	store.EXPECT().
+		IdempotentTransferTx(gomock.Any(), gomock.Any()).
+		Times(1).
+		Return(db.TransferTxResult{Transfer: db.Transfer{ID: 7}}, nil)
+	store.EXPECT().CreateScheduledTransferRun(gomock.Any(), gomock.Any()).Times(1)
+
+	// 执行期间定时转账被取消，推进进度时没有匹配的记录，不能覆盖取消的状态
+	store.EXPECT().
+		AdvanceScheduledTransfer(gomock.Any(), gomock.Any()).
+		Times(1).
+		Return(db.ScheduledTransfer{}, sql.ErrNoRows)
+	store.EXPECT().UpdateScheduledTransfer(gomock.Any(), gomock.Any()).Times(0)
+
+	processor := NewScheduledTransferProcessor(store, ScheduledTransferOptions{})
+	err := processor.processDue(context.Background(), now)
+	require.NoError(t, err)
+}
+
+func TestProcessDueListError(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	store := mockdb.NewMockStore(ctrl)
+	store.EXPECT().
+		ListDueScheduledTransfers(gomock.Any(), gomock.Any()).
+		Times(1).
+		Return(nil, sql.ErrConnDone)
+	store.EXPECT().IdempotentTransferTx(gomock.Any(), gomock.Any()).Times(0)
+
+	processor := NewScheduledTransferProcessor(store, ScheduledTransferOptions{})
+	err := processor.ProcessDue(context.Background())
+	require.ErrorIs(t, err, sql.ErrConnDone)
+}
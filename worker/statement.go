@@ -0,0 +1,78 @@
+package worker
+
+import (
+	"context"
+	db "github.com/techschool/simplebank/db/sqlc"
+	"log"
+	"time"
+)
+
+// statementDelay 月末之后延迟一段时间再生成对账单，等待月末前开始的事务提交
+const statementDelay = time.Hour
+
+// statementBatchSize 每次查询需要生成对账单的账户数量
+const statementBatchSize = 100
+
+// StatementGenerator generates the monthly statements of customer accounts after the month ends.
+type StatementGenerator struct {
+	store	db.Store
+}
+
+// NewStatementGenerator creates a new StatementGenerator.
+func NewStatementGenerator(store db.Store) *StatementGenerator {
+	return &StatementGenerator{store: store}
+}
+
+// GenerateDue generates the statements of the last month for the accounts that don't have one yet.
+func (generator *StatementGenerator) GenerateDue(ctx context.Context) error {
+	return generator.generateDue(ctx, time.Now())
+}
+
+func (generator *StatementGenerator) generateDue(ctx context.Context, now time.Time) error {
+	periodStart, periodEnd := lastMonth(now.Add(-statementDelay))
+
+	var afterID int64
+	var count int
+	for {
+		accounts, err := generator.store.ListStatementAccounts(ctx, db.ListStatementAccountsParams{
+			PeriodStart:	periodStart,
+			PeriodEnd:		periodEnd,
+			AfterID:		afterID,
+			PageSize:		statementBatchSize,
+		})
+		if err != nil {
+			return err
+		}
+
+		for _, account := range accounts {
+			// 单个账户出错不影响其他账户，下次执行时会重新生成
+			_, err = generator.store.CreateStatementTx(ctx, db.CreateStatementTxParams{
+				AccountID:		account.ID,
+				PeriodStart:	periodStart,
+				PeriodEnd:		periodEnd,
+			})
+			if err != nil {
+				log.Printf("cannot generate statement of account [%d], err: %v", account.ID, err)
+				continue
+			}
+			count++
+		}
+
+		if len(accounts) < statementBatchSize {
+			break
+		}
+		afterID = accounts[len(accounts)-1].ID
+	}
+
+	if count > 0 {
+		log.Printf("generated %d statements for %s", count, periodStart.Format("2006-01"))
+	}
+	return nil
+}
+
+// lastMonth 返回 t 所在 UTC 月份的上一个月，期末是 t 所在月份的第一天
+func lastMonth(t time.Time) (time.Time, time.Time) {
+	year, month, _ := t.UTC().Date()
+	end := time.Date(year, month, 1, 0, 0, 0, 0, time.UTC)
+	return end.AddDate(0, -1, 0), end
+}
@@ -0,0 +1,66 @@
+package worker
+
+import (
+	"context"
+	"database/sql"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/require"
+	mockdb "github.com/techschool/simplebank/db/mock"
+	db "github.com/techschool/simplebank/db/sqlc"
+	"testing"
+	"time"
+)
+
+func TestGenerateDueStatements(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	store := mockdb.NewMockStore(ctrl)
+	generator := NewStatementGenerator(store)
+
+	// 1 月 1 日零点之后的一小时内仍然是 11 月的对账单
+	now := time.Date(2021, time.January, 1, 0, 30, 0, 0, time.UTC)
+	start := time.Date(2020, time.November, 1, 0, 0, 0, 0, time.UTC)
+	end := time.Date(2020, time.December, 1, 0, 0, 0, 0, time.UTC)
+
+	// 第一页满了之后从最后一个账户之后继续查询
+	page := make([]db.Account, statementBatchSize)
+	for i := range page {
+		page[i] = db.Account{ID: int64(i + 1)}
+	}
+	store.EXPECT().
+		ListStatementAccounts(gomock.Any(), gomock.Eq(db.ListStatementAccountsParams{
+			PeriodStart:	start,
+			PeriodEnd:		end,
+			PageSize:		statementBatchSize,
+		})).
+		Times(1).
+		Return(page, nil)
+	store.EXPECT().
+		ListStatementAccounts(gomock.Any(), gomock.Eq(db.ListStatementAccountsParams{
+			PeriodStart:	start,
+			PeriodEnd:		end,
+			AfterID:		statementBatchSize,
+			PageSize:		statementBatchSize,
+		})).
+		Times(1).
+		Return([]db.Account{{ID: 500}}, nil)
+
+	// 单个账户出错不影响其他账户
+	store.EXPECT().
+		CreateStatementTx(gomock.Any(), gomock.Eq(db.CreateStatementTxParams{AccountID: 1, PeriodStart: start, PeriodEnd: end})).
+		Times(1).
+		Return(db.Statement{}, sql.ErrConnDone)
+	store.EXPECT().
+		CreateStatementTx(gomock.Any(), gomock.Any()).
+		Times(statementBatchSize).
+		Return(db.Statement{}, nil)
+
+	require.NoError(t, generator.generateDue(context.Background(), now))
+}
+
+func TestLastMonth(t *testing.T) {
+	start, end := lastMonth(time.Date(2021, time.March, 31, 23, 0, 0, 0, time.UTC))
+	require.Equal(t, time.Date(2021, time.February, 1, 0, 0, 0, 0, time.UTC), start)
+	require.Equal(t, time.Date(2021, time.March, 1, 0, 0, 0, 0, time.UTC), end)
+}
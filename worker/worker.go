@@ -0,0 +1,24 @@
+package worker
+
+import (
+	"context"
+	"log"
+	"time"
+)
+
+// Run calls fn every interval until ctx is done, errors are logged and the next tick is still run.
+func Run(ctx context.Context, name string, interval time.Duration, fn func(ctx context.Context) error) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := fn(ctx); err != nil {
+				log.Printf("worker %s failed, err: %v", name, err)
+			}
+		}
+	}
+}